@@ -1,17 +1,37 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/Jakeito/TestWebsite/backend/internal/auth"
 	"github.com/Jakeito/TestWebsite/backend/internal/config"
 	"github.com/Jakeito/TestWebsite/backend/internal/database"
 	"github.com/Jakeito/TestWebsite/backend/internal/handlers"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/mailer"
+	"github.com/Jakeito/TestWebsite/backend/internal/maintenance"
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
 	"github.com/Jakeito/TestWebsite/backend/internal/middleware"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/preview"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/spam"
+	"github.com/Jakeito/TestWebsite/backend/internal/storage"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+	"github.com/Jakeito/TestWebsite/backend/migrations"
 )
 
 func main() {
@@ -21,22 +41,69 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Structured JSON logs; the standard log package is routed through it too
+	logger := logging.New(os.Stdout, cfg.LogLevel)
+	slog.SetDefault(logger)
+
 	// Connect to database
 	db, err := database.New(cfg.DatabaseURL())
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer db.Close()
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+	}()
 
 	// Run migrations
-	if err := db.RunMigrations("./migrations"); err != nil {
+	migrationSet, err := database.LoadMigrations(migrations.Files)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, migrationSet, os.Args[2:]); err != nil {
+			log.Fatalf("migrate failed: %v", err)
+		}
+		return
+	}
+
+	migrateStart := time.Now()
+	applied, err := db.MigrateUp(context.Background(), migrationSet)
+	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
+	metrics.MigrationDuration.Set(time.Since(migrateStart).Seconds())
+	metrics.MigrationsApplied.Set(float64(applied))
+	metrics.RegisterDBStats(metrics.Default, db.Stats)
+
+	// Set up image storage
+	blobs, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up image storage: %v", err)
+	}
+
+	// Handle subcommands
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], db, blobs, storage.Durable(cfg)); err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// Images on disk that is wiped on every deploy would be lost while their
+	// rows remain, so the server won't start that way
+	if !storage.Durable(cfg) {
+		log.Fatalf("Local image storage in %s is not durable here; set STORAGE_BACKEND=s3, or attach a persistent disk and set STORAGE_LOCAL_DURABLE=true", cfg.StorageLocalDir)
+	}
 
 	// Seed gallery images from public/images directory
-	if err := db.SeedGalleryImages(); err != nil {
+	seedStart := time.Now()
+	if err := db.SeedGalleryImages(blobs); err != nil {
 		log.Printf("Warning: Failed to seed gallery images: %v", err)
 	}
+	metrics.SeedDuration.Set(time.Since(seedStart).Seconds())
 
 	// Create admin user if it doesn't exist
 	if err := createAdminUser(db, cfg); err != nil {
@@ -44,116 +111,298 @@ func main() {
 	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, cfg.JWTSecret)
-	aboutHandler := handlers.NewAboutHandler(db)
-	resumeHandler := handlers.NewResumeHandler(db)
-	carBuildHandler := handlers.NewCarBuildHandler(db)
-	contactHandler := handlers.NewContactHandler(db)
-	imageHandler := handlers.NewImageHandler(db)
-	galleryHandler := handlers.NewGalleryHandler(db)
+	authHandler := handlers.NewAuthHandler(db, db, cfg.JWTSecret)
+	aboutHandler := handlers.NewAboutHandler(db, db)
+	resumeHandler := handlers.NewResumeHandler(db, db)
+	carBuildHandler := handlers.NewCarBuildHandler(db, db)
+	partHandler := handlers.NewPartHandler(db, db)
+	maintenanceWindow := maintenance.Window{Miles: cfg.MaintenanceUpcomingMiles, Days: cfg.MaintenanceUpcomingDays}
+	maintenanceHandler := handlers.NewMaintenanceHandler(db, maintenanceWindow)
+	revisionHandler := handlers.NewRevisionHandler(db, db, db, db, db)
+	previewHandler := handlers.NewPreviewHandler(preview.NewTokens(cfg.JWTSecret, cfg.PreviewTokenTTL), db, db, db, db)
+	captcha, err := spam.NewVerifier(cfg.CaptchaProvider, cfg.CaptchaSecret)
+	if err != nil {
+		log.Fatalf("Invalid CAPTCHA configuration: %v", err)
+	}
+	if cfg.ContactAutoReply && !captcha.Enabled() {
+		logger.Warn("CONTACT_AUTO_REPLY needs a CAPTCHA_PROVIDER, so no auto-replies will be sent")
+	}
+	contactHandler := handlers.NewContactHandler(db,
+		spam.NewFormTokens(cfg.JWTSecret, cfg.ContactMinSubmitTime, 24*time.Hour, db),
+		captcha,
+		spam.Filter{MaxLinks: cfg.ContactMaxLinks, Blocklist: cfg.ContactBlocklist},
+		mailer.ContactEmails{SiteName: cfg.SiteName, NotifyTo: cfg.ContactNotifyEmail, AutoReply: cfg.ContactAutoReply},
+	)
+	imageHandler := handlers.NewImageHandler(db, db)
+	galleryHandler := handlers.NewGalleryHandler(db, db, blobs)
+	folderHandler := handlers.NewFolderHandler(db)
+	healthHandler := handlers.NewHealthHandler(2*time.Second,
+		handlers.HealthCheck{Name: "database", Check: db.PingContext},
+		handlers.HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+			return db.VerifyMigrations(ctx, migrationSet)
+		}},
+		handlers.HealthCheck{Name: "storage", Check: blobs.Ping},
+	)
 
-	// Setup router
-	r := mux.NewRouter()
-
-	// Serve static files from public directory
-	r.PathPrefix("/public/").Handler(http.StripPrefix("/public/", http.FileServer(http.Dir("./public"))))
-
-	// Health check / root endpoint
-	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok","message":"TestWebsite Backend API"}`))
-	}).Methods("GET")
-
-	// Public routes
-	r.HandleFunc("/api/login", authHandler.Login).Methods("POST")
-	r.HandleFunc("/api/about", aboutHandler.GetAboutContent).Methods("GET")
-	r.HandleFunc("/api/resume", resumeHandler.GetResumeSections).Methods("GET")
-	r.HandleFunc("/api/carbuild", carBuildHandler.GetCarBuildEntries).Methods("GET")
-	r.HandleFunc("/api/contact", contactHandler.SubmitContact).Methods("POST")
-	r.HandleFunc("/api/images", imageHandler.GetImages).Methods("GET")
-	r.HandleFunc("/api/image/{id}", galleryHandler.GetImage).Methods("GET")
-
-	// Protected routes (require authentication)
-	authRouter := r.PathPrefix("/api").Subrouter()
-	authRouter.Use(middleware.AuthMiddleware(cfg.JWTSecret))
-
-	// Admin-only routes
-	adminRouter := authRouter.PathPrefix("").Subrouter()
-	adminRouter.Use(middleware.AdminMiddleware)
-
-	// Admin routes for about content
-	adminRouter.HandleFunc("/about", aboutHandler.CreateAboutContent).Methods("POST")
-	adminRouter.HandleFunc("/about/{id}", aboutHandler.UpdateAboutContent).Methods("PUT")
-	adminRouter.HandleFunc("/about/{id}", aboutHandler.DeleteAboutContent).Methods("DELETE")
-
-	// Admin routes for resume
-	adminRouter.HandleFunc("/resume", resumeHandler.CreateResumeSection).Methods("POST")
-	adminRouter.HandleFunc("/resume/{id}", resumeHandler.UpdateResumeSection).Methods("PUT")
-	adminRouter.HandleFunc("/resume/{id}", resumeHandler.DeleteResumeSection).Methods("DELETE")
-
-	// Admin routes for car build
-	adminRouter.HandleFunc("/carbuild", carBuildHandler.CreateCarBuildEntry).Methods("POST")
-	adminRouter.HandleFunc("/carbuild/{id}", carBuildHandler.UpdateCarBuildEntry).Methods("PUT")
-	adminRouter.HandleFunc("/carbuild/{id}", carBuildHandler.DeleteCarBuildEntry).Methods("DELETE")
-
-	// Admin routes for contact submissions
-	adminRouter.HandleFunc("/contact", contactHandler.GetContactSubmissions).Methods("GET")
-	adminRouter.HandleFunc("/contact/{id}", contactHandler.DeleteContactSubmission).Methods("DELETE")
-
-	// Admin routes for user management
-	adminRouter.HandleFunc("/users", authHandler.CreateUser).Methods("POST")
-
-	// Admin routes for gallery images
-	adminRouter.HandleFunc("/gallery/upload", galleryHandler.UploadImage).Methods("POST")
-	adminRouter.HandleFunc("/gallery/images", galleryHandler.ListImages).Methods("GET")
-	adminRouter.HandleFunc("/gallery/image/{id}", galleryHandler.DeleteImage).Methods("DELETE")
-	adminRouter.HandleFunc("/gallery/reseed", func(w http.ResponseWriter, r *http.Request) {
-		// Clear existing images
-		_, err := db.Exec("DELETE FROM gallery_images")
+	// Per-IP rate limits
+	clientIP, err := middleware.NewClientIPResolver(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	if cfg.BehindProxy && len(cfg.TrustedProxies) == 0 {
+		logger.Warn("TRUSTED_PROXIES is empty behind a proxy, so every client shares the proxy's rate limits")
+	}
+	rateLimit := func(name, spec string) func(http.Handler) http.Handler {
+		rate, err := middleware.ParseRate(spec)
 		if err != nil {
-			http.Error(w, "Failed to clear images", http.StatusInternalServerError)
-			return
+			log.Fatalf("Invalid %s rate limit: %v", name, err)
 		}
-		// Reseed
-		if err := db.SeedGalleryImages(); err != nil {
-			http.Error(w, "Failed to seed images", http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status":"Images reseeded successfully"}`))
-	}).Methods("POST")
+		return middleware.RateLimit(middleware.NewRateLimiter(name, rate), clientIP)
+	}
+
+	// Metrics for Prometheus, scraped with METRICS_TOKEN or from METRICS_PORT
+	var metricsHandler http.Handler
+	if cfg.MetricsToken != "" {
+		metricsHandler = middleware.MetricsAuth(cfg.MetricsToken)(metrics.Default.Handler())
+	}
+
+	// Setup router
+	root := handlers.NewRouter(handlers.API{
+		Auth:         authHandler,
+		About:        aboutHandler,
+		Resume:       resumeHandler,
+		CarBuild:     carBuildHandler,
+		Parts:        partHandler,
+		Maintenance:  maintenanceHandler,
+		Revisions:    revisionHandler,
+		Preview:      previewHandler,
+		Contact:      contactHandler,
+		Images:       imageHandler,
+		Gallery:      galleryHandler,
+		Folders:      folderHandler,
+		Health:       healthHandler,
+		JWTSecret:    cfg.JWTSecret,
+		Sessions:     db,
+		LimitAPI:     rateLimit("api", cfg.APIRateLimit),
+		LimitLogin:   rateLimit("login", cfg.LoginRateLimit),
+		LimitRefresh: rateLimit("refresh", cfg.RefreshRateLimit),
+		LimitContact: rateLimit("contact", cfg.ContactRateLimit),
+		Reseed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Clear existing images
+			if err := store.DeleteAllImages(r.Context(), db, blobs); err != nil {
+				problem.Write(w, problem.Internal, "Failed to clear images")
+				return
+			}
+			// Reseed
+			if err := db.SeedGalleryImages(blobs); err != nil {
+				problem.Write(w, problem.Internal, "Failed to seed images")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":"Images reseeded successfully"}`))
+		}),
+		Metrics: metricsHandler,
+	})
 
 	// Apply CORS middleware
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:5173", "https://testwebsite-hark.onrender.com", "https://test-website-five-mu.vercel.app"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "If-Match", middleware.RequestIDHeader},
+		ExposedHeaders:   []string{middleware.RequestIDHeader, handlers.TotalCountHeader, "Link", "ETag"},
 		AllowCredentials: true,
 	})
 
-	handler := c.Handler(r)
+	handler := middleware.LoggingMiddleware(logger)(middleware.MetricsMiddleware()(c.Handler(root)))
+
+	servers := []*http.Server{newServer(cfg, cfg.ServerPort, handler, logger)}
+	if cfg.MetricsPort != "" {
+		servers = append(servers, newServer(cfg, cfg.MetricsPort, metrics.Default.Handler(), logger))
+	}
+
+	// Outgoing mail is queued in the outbox and sent in the background
+	mail, err := mailer.New(cfg, logger)
+	if err != nil {
+		log.Fatalf("Invalid mail configuration: %v", err)
+	}
+
+	// Maintenance reminders go out through the configured notifier, if any
+	notifier, err := maintenance.NewNotifier(cfg.MaintenanceNotifier,
+		mailer.MaintenanceEmail{SiteName: cfg.SiteName, To: cfg.MaintenanceNotifyEmail}, logger)
+	if err != nil {
+		log.Fatalf("Invalid maintenance notifier: %v", err)
+	}
 
 	// Start server
-	addr := fmt.Sprintf(":%s", cfg.ServerPort)
-	log.Printf("Server starting on %s", addr)
-	if err := http.ListenAndServe(addr, handler); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// Background workers are stopped and waited for once the servers are
+	// done, however they stopped, so none is using the database as it closes
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		mailer.NewWorker(db, mail, logger).Run(workerCtx)
+	}()
+	if notifier != nil && cfg.MaintenanceCheckInterval > 0 {
+		checker := maintenance.NewChecker(db, notifier, logger)
+		checker.Window, checker.Interval = maintenanceWindow, cfg.MaintenanceCheckInterval
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			checker.Run(workerCtx)
+		}()
 	}
-}
 
-func createAdminUser(db *database.DB, cfg *config.Config) error {
-	// Check if admin user exists
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", cfg.AdminEmail).Scan(&exists)
+	err = serve(ctx, cfg, healthHandler, servers)
+	stopWorkers()
+	workers.Wait()
 	if err != nil {
+		log.Printf("Server error: %v", err)
+	}
+}
+
+func newServer(cfg *config.Config, port string, handler http.Handler, logger *slog.Logger) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%s", port),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+}
+
+// serve runs the servers until ctx is cancelled by a shutdown signal or one of
+// them fails. On shutdown, readiness fails first so the load balancer can stop
+// routing here. After ShutdownDelay, in-flight requests get up to
+// ShutdownTimeout to finish before their connections are closed.
+func serve(ctx context.Context, cfg *config.Config, health *handlers.HealthHandler, servers []*http.Server) error {
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			log.Printf("Server starting on %s", srv.Addr)
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("%s: %w", srv.Addr, err)
+			}
+		}(srv)
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		log.Printf("Shutdown signal received, draining for %s", cfg.ShutdownDelay)
+	case serveErr = <-errs:
+		log.Printf("Server failed, shutting down: %v", serveErr)
+	}
+
+	health.SetDraining()
+	if serveErr == nil {
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Timed out waiting for requests on %s, closing connections: %v", srv.Addr, err)
+			srv.Close()
+		}
+	}
+	log.Printf("Server stopped")
+	return serveErr
+}
+
+// runCommand runs a one-off maintenance command instead of starting the server.
+// Images are only dropped from the database once storage is durable.
+func runCommand(name string, db *database.DB, blobs storage.Backend, durable bool) error {
+	switch name {
+	case "migrate-images":
+		if !durable {
+			log.Printf("Image storage is not durable, keeping a copy of each image in the database")
+		}
+		migrated, err := db.MigrateImageBlobs(context.Background(), blobs, !durable)
+		log.Printf("Moved %d images out of the database", migrated)
 		return err
+	case "sanitize-images":
+		sanitized, err := db.SanitizeStoredImages(context.Background(), blobs)
+		log.Printf("Stripped metadata from %d images", sanitized)
+		return err
+	case "generate-variants":
+		generated, err := db.GenerateMissingVariants(context.Background(), blobs)
+		log.Printf("Generated variants for %d images", generated)
+		return err
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+// runMigrate handles `server migrate up`, `server migrate down [n]` and
+// `server migrate status`
+func runMigrate(db *database.DB, migrationSet []database.Migration, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up | down [n] | status")
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx, migrationSet)
+		log.Printf("Applied %d migrations", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := db.MigrateDown(ctx, migrationSet, steps)
+		log.Printf("Rolled back %d migrations", rolledBack)
+		return err
+	case "status":
+		states, err := db.MigrationStatus(ctx, migrationSet)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, st := range states {
+			status, appliedAt := "pending", ""
+			if st.Applied {
+				status, appliedAt = "applied", st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Modified {
+				status = "modified"
+			}
+			if st.Missing {
+				status = "missing"
+			}
+			fmt.Fprintf(tw, "%03d\t%s\t%s\t%s\n", st.Version, st.Name, status, appliedAt)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
 	}
+}
+
+func createAdminUser(users store.UserStore, cfg *config.Config) error {
+	ctx := context.Background()
 
-	if exists {
+	// Check if admin user exists
+	_, err := users.GetUserByEmail(ctx, cfg.AdminEmail)
+	if err == nil {
 		log.Println("Admin user already exists")
 		return nil
 	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
 
 	// Create admin user
 	hashedPassword, err := auth.HashPassword(cfg.AdminPassword)
@@ -161,10 +410,12 @@ func createAdminUser(db *database.DB, cfg *config.Config) error {
 		return err
 	}
 
-	_, err = db.Exec(
-		"INSERT INTO users (email, password_hash, username, is_admin) VALUES ($1, $2, $3, $4)",
-		cfg.AdminEmail, hashedPassword, "Admin", true,
-	)
+	err = users.CreateUser(ctx, &models.User{
+		Email:        cfg.AdminEmail,
+		PasswordHash: hashedPassword,
+		Username:     "Admin",
+		IsAdmin:      true,
+	})
 	if err != nil {
 		return err
 	}
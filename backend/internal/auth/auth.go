@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -13,13 +17,45 @@ var (
 	ErrExpiredToken = errors.New("expired token")
 )
 
+const (
+	// AccessTokenTTL is how long a signed access token stays valid.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a refresh token can be exchanged for a new pair.
+	RefreshTokenTTL = 7 * 24 * time.Hour
+
+	// LockoutThreshold is the number of consecutive failed logins an account
+	// tolerates before it is locked.
+	LockoutThreshold = 5
+	// LockoutBase is the first lockout; each further failure doubles it.
+	LockoutBase = 30 * time.Second
+	// LockoutMax caps the lockout.
+	LockoutMax = time.Hour
+)
+
 type Claims struct {
-	UserID  int    `json:"user_id"`
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"is_admin"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	IsAdmin   bool   `json:"is_admin"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
+// LockoutDuration returns how long an account stays locked after the given
+// number of consecutive failed logins, or zero if it should not be locked
+func LockoutDuration(failures int) time.Duration {
+	if failures < LockoutThreshold {
+		return 0
+	}
+	d := LockoutBase
+	for i := LockoutThreshold; i < failures; i++ {
+		d *= 2
+		if d >= LockoutMax {
+			return LockoutMax
+		}
+	}
+	return d
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	return string(bytes), err
@@ -30,14 +66,23 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateToken(userID int, email string, isAdmin bool, secret string) (string, error) {
+// GenerateToken issues a short-lived access token bound to a session.
+func GenerateToken(userID int, email string, isAdmin bool, sessionID, secret string) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := Claims{
-		UserID:  userID,
-		Email:   email,
-		IsAdmin: isAdmin,
+		UserID:    userID,
+		Email:     email,
+		IsAdmin:   isAdmin,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -54,6 +99,9 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 	})
 
 	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrExpiredToken
+		}
 		return nil, err
 	}
 
@@ -64,3 +112,32 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// GenerateSessionID returns a random identifier for a new session family.
+func GenerateSessionID() (string, error) {
+	return randomString(16)
+}
+
+// GenerateRefreshToken returns an opaque refresh token and the hash to store for it.
+func GenerateRefreshToken() (token string, hash string, err error) {
+	token, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the value persisted for a refresh token so the
+// raw token never touches the database.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
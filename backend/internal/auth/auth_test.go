@@ -2,6 +2,7 @@ package auth
 
 import (
 	"testing"
+	"time"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -46,7 +47,7 @@ func TestGenerateToken(t *testing.T) {
 	isAdmin := false
 	secret := "test-secret"
 	
-	token, err := GenerateToken(userID, email, isAdmin, secret)
+	token, err := GenerateToken(userID, email, isAdmin, "session-1", secret)
 	
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
@@ -63,7 +64,7 @@ func TestValidateToken(t *testing.T) {
 	isAdmin := true
 	secret := "test-secret"
 	
-	token, err := GenerateToken(userID, email, isAdmin, secret)
+	token, err := GenerateToken(userID, email, isAdmin, "session-1", secret)
 	if err != nil {
 		t.Fatalf("Failed to generate token: %v", err)
 	}
@@ -86,6 +87,14 @@ func TestValidateToken(t *testing.T) {
 		t.Errorf("Expected IsAdmin %v, got %v", isAdmin, claims.IsAdmin)
 	}
 	
+	if claims.SessionID != "session-1" {
+		t.Errorf("Expected SessionID %s, got %s", "session-1", claims.SessionID)
+	}
+	
+	if claims.ID == "" {
+		t.Error("Expected token to carry a jti")
+	}
+	
 	// Test invalid token
 	_, err = ValidateToken("invalid-token", secret)
 	if err == nil {
@@ -98,3 +107,48 @@ func TestValidateToken(t *testing.T) {
 		t.Fatal("Expected error for wrong secret")
 	}
 }
+
+func TestGenerateRefreshToken(t *testing.T) {
+	token, hash, err := GenerateRefreshToken()
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	
+	if token == "" || hash == "" {
+		t.Fatal("Refresh token and hash should not be empty")
+	}
+	
+	if token == hash {
+		t.Fatal("Hash should not equal the raw refresh token")
+	}
+	
+	if HashRefreshToken(token) != hash {
+		t.Fatal("HashRefreshToken should be deterministic")
+	}
+	
+	other, _, err := GenerateRefreshToken()
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	
+	if other == token {
+		t.Fatal("Refresh tokens should be unique")
+	}
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:                     0,
+		LockoutThreshold - 1:  0,
+		LockoutThreshold:      LockoutBase,
+		LockoutThreshold + 1:  2 * LockoutBase,
+		LockoutThreshold + 3:  8 * LockoutBase,
+		LockoutThreshold + 50: LockoutMax,
+	}
+
+	for failures, want := range tests {
+		if got := LockoutDuration(failures); got != want {
+			t.Errorf("LockoutDuration(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -18,6 +21,96 @@ type Config struct {
 	ServerPort string
 	AdminEmail string
 	AdminPassword string
+
+	// Log level: debug, info (default), warn or error
+	LogLevel string
+
+	// /metrics is served on the main port to requests bearing MetricsToken,
+	// and without authentication on MetricsPort; either is off when empty
+	MetricsToken string
+	MetricsPort  string
+
+	// HTTP server timeouts. ShutdownDelay is how long the server keeps
+	// accepting requests after reporting not ready, so the load balancer
+	// stops routing to it first; ShutdownTimeout then bounds how long
+	// in-flight requests get to finish.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownDelay     time.Duration
+	ShutdownTimeout   time.Duration
+
+	// Per-IP rate limits as events/duration, e.g. "10/1m"; empty or "off"
+	// disables a limit. APIRateLimit applies to every route.
+	LoginRateLimit   string
+	RefreshRateLimit string
+	ContactRateLimit string
+	APIRateLimit     string
+	// TrustedProxies lists proxy addresses or CIDR ranges whose
+	// X-Forwarded-For header is believed when finding the client IP
+	TrustedProxies []string
+	// BehindProxy is set when requests arrive through a reverse proxy, as
+	// they do on Render
+	BehindProxy bool
+
+	// Contact form spam checks. Submissions sent sooner than
+	// ContactMinSubmitTime after the form loaded, with more than
+	// ContactMaxLinks links (negative for no limit) or containing a
+	// ContactBlocklist term are kept but marked as spam.
+	ContactMinSubmitTime time.Duration
+	ContactMaxLinks      int
+	ContactBlocklist     []string
+	// CAPTCHA provider for the contact form: "none" (default), "turnstile",
+	// "hcaptcha" or "recaptcha"
+	CaptchaProvider string
+	CaptchaSecret   string
+
+	// Outgoing mail: "log" (default) only logs messages, "smtp" sends them
+	// through the SMTP relay
+	MailDriver   string
+	MailFrom     string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	// SiteName appears in email subjects and signatures
+	SiteName string
+	// ContactNotifyEmail is told about each contact form submission and
+	// defaults to AdminEmail; "off" disables it. ContactAutoReply also sends
+	// the submitter a confirmation, when a CAPTCHA is configured.
+	ContactNotifyEmail string
+	ContactAutoReply   bool
+
+	// Preview links to unpublished content stay valid for PreviewTokenTTL
+	PreviewTokenTTL time.Duration
+
+	// Maintenance reminders. A job counts as upcoming once it is within
+	// MaintenanceUpcomingMiles or MaintenanceUpcomingDays of coming due.
+	// Every MaintenanceCheckInterval, jobs that became upcoming or overdue
+	// go to MaintenanceNotifier: "log" (default) logs them, "email" mails
+	// MaintenanceNotifyEmail, which defaults to AdminEmail, and "off" (or a
+	// zero interval) turns reminders off.
+	MaintenanceNotifier      string
+	MaintenanceNotifyEmail   string
+	MaintenanceCheckInterval time.Duration
+	MaintenanceUpcomingMiles int
+	MaintenanceUpcomingDays  int
+
+	// Image storage: "local" (default) or "s3"
+	StorageBackend  string
+	StorageLocalDir string
+	S3Endpoint      string
+	S3Region        string
+	S3Bucket        string
+	S3AccessKey     string
+	S3SecretKey     string
+	S3UsePathStyle  bool
+
+	// StorageLocalDurable says StorageLocalDir survives restarts and
+	// deploys. It defaults to false on Render, where local disk is wiped
+	// unless a persistent disk is attached.
+	StorageLocalDurable bool
 }
 
 func Load() (*Config, error) {
@@ -35,6 +128,96 @@ func Load() (*Config, error) {
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		AdminEmail: getEnv("ADMIN_EMAIL", "admin@example.com"),
 		AdminPassword: getEnv("ADMIN_PASSWORD", "changeme"),
+		LogLevel:      getEnv("LOG_LEVEL", "info"),
+		MetricsToken:  getEnv("METRICS_TOKEN", ""),
+		MetricsPort:   getEnv("METRICS_PORT", ""),
+
+		LoginRateLimit:   getEnv("RATE_LIMIT_LOGIN", "10/1m"),
+		RefreshRateLimit: getEnv("RATE_LIMIT_REFRESH", "30/1m"),
+		ContactRateLimit: getEnv("RATE_LIMIT_CONTACT", "5/10m"),
+		APIRateLimit:     getEnv("RATE_LIMIT_API", "600/1m"),
+		TrustedProxies:   splitList(getEnv("TRUSTED_PROXIES", "")),
+		BehindProxy:      getEnv("BEHIND_PROXY", "false") == "true" || os.Getenv("RENDER") != "",
+
+		ContactBlocklist: splitList(getEnv("CONTACT_BLOCKLIST", "")),
+		CaptchaProvider:  getEnv("CAPTCHA_PROVIDER", "none"),
+		CaptchaSecret:    getEnv("CAPTCHA_SECRET", ""),
+
+		MailDriver:       getEnv("MAIL_DRIVER", "log"),
+		MailFrom:         getEnv("MAIL_FROM", "TestWebsite <noreply@example.com>"),
+		SMTPHost:         getEnv("SMTP_HOST", ""),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		SiteName:         getEnv("SITE_NAME", "TestWebsite"),
+		ContactAutoReply: getEnv("CONTACT_AUTO_REPLY", "false") == "true",
+
+		MaintenanceNotifier: getEnv("MAINTENANCE_NOTIFIER", "log"),
+
+		StorageBackend:  getEnv("STORAGE_BACKEND", "local"),
+		StorageLocalDir: getEnv("STORAGE_LOCAL_DIR", "./data/images"),
+		S3Endpoint:      getEnv("S3_ENDPOINT", ""),
+		S3Region:        getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:        getEnv("S3_BUCKET", ""),
+		S3AccessKey:     getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:     getEnv("S3_SECRET_KEY", ""),
+		S3UsePathStyle:  getEnv("S3_USE_PATH_STYLE", "true") == "true",
+
+		StorageLocalDurable: getEnv("STORAGE_LOCAL_DURABLE", strconv.FormatBool(os.Getenv("RENDER") == "")) == "true",
+	}
+
+	durations := []struct {
+		key    string
+		def    time.Duration
+		target *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", 60 * time.Second, &config.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", 10 * time.Second, &config.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", 120 * time.Second, &config.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", 120 * time.Second, &config.IdleTimeout},
+		{"SHUTDOWN_DELAY", 5 * time.Second, &config.ShutdownDelay},
+		{"SHUTDOWN_TIMEOUT", 30 * time.Second, &config.ShutdownTimeout},
+		{"CONTACT_MIN_SUBMIT_TIME", 3 * time.Second, &config.ContactMinSubmitTime},
+		{"PREVIEW_TOKEN_TTL", 7 * 24 * time.Hour, &config.PreviewTokenTTL},
+		{"MAINTENANCE_CHECK_INTERVAL", time.Hour, &config.MaintenanceCheckInterval},
+	}
+	for _, d := range durations {
+		value, err := getDuration(d.key, d.def)
+		if err != nil {
+			return nil, err
+		}
+		*d.target = value
+	}
+
+	config.ContactNotifyEmail = getEnv("CONTACT_NOTIFY_EMAIL", config.AdminEmail)
+	if config.ContactNotifyEmail == "off" {
+		config.ContactNotifyEmail = ""
+	}
+
+	maxLinks, err := getInt("CONTACT_MAX_LINKS", 2)
+	if err != nil {
+		return nil, err
+	}
+	config.ContactMaxLinks = maxLinks
+
+	config.MaintenanceNotifyEmail = getEnv("MAINTENANCE_NOTIFY_EMAIL", config.AdminEmail)
+	windows := []struct {
+		key    string
+		def    int
+		target *int
+	}{
+		{"MAINTENANCE_UPCOMING_MILES", 500, &config.MaintenanceUpcomingMiles},
+		{"MAINTENANCE_UPCOMING_DAYS", 14, &config.MaintenanceUpcomingDays},
+	}
+	for _, w := range windows {
+		value, err := getInt(w.key, w.def)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("invalid integer %q for %s", os.Getenv(w.key), w.key)
+		}
+		*w.target = value
 	}
 
 	return config, nil
@@ -47,6 +230,43 @@ func (c *Config) DatabaseURL() string {
 	)
 }
 
+// getDuration parses a duration such as "30s" or "2m" from the environment
+func getDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q for %s", value, key)
+	}
+	return d, nil
+}
+
+// getInt parses an integer from the environment
+func getInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q for %s", value, key)
+	}
+	return n, nil
+}
+
+// splitList splits a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -67,3 +68,152 @@ func TestGetEnv(t *testing.T) {
 		t.Errorf("Expected 'default', got '%s'", result)
 	}
 }
+
+func TestLoadDurations(t *testing.T) {
+	os.Setenv("HTTP_WRITE_TIMEOUT", "45s")
+	os.Setenv("SHUTDOWN_DELAY", "0s")
+	defer func() {
+		os.Unsetenv("HTTP_WRITE_TIMEOUT")
+		os.Unsetenv("SHUTDOWN_DELAY")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.WriteTimeout != 45*time.Second {
+		t.Errorf("Expected WriteTimeout 45s, got %v", cfg.WriteTimeout)
+	}
+	if cfg.ShutdownDelay != 0 {
+		t.Errorf("Expected ShutdownDelay 0, got %v", cfg.ShutdownDelay)
+	}
+	if cfg.ReadHeaderTimeout != 10*time.Second {
+		t.Errorf("Expected default ReadHeaderTimeout 10s, got %v", cfg.ReadHeaderTimeout)
+	}
+}
+
+func TestLoadInvalidDuration(t *testing.T) {
+	for _, value := range []string{"soon", "30", "-5s"} {
+		os.Setenv("SHUTDOWN_TIMEOUT", value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Expected an error for SHUTDOWN_TIMEOUT=%q", value)
+		}
+	}
+	os.Unsetenv("SHUTDOWN_TIMEOUT")
+}
+
+func TestLoadContactSpam(t *testing.T) {
+	os.Setenv("CONTACT_MAX_LINKS", "0")
+	os.Setenv("CONTACT_BLOCKLIST", "casino, crypto ,")
+	defer func() {
+		os.Unsetenv("CONTACT_MAX_LINKS")
+		os.Unsetenv("CONTACT_BLOCKLIST")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.ContactMaxLinks != 0 {
+		t.Errorf("Expected ContactMaxLinks 0, got %d", cfg.ContactMaxLinks)
+	}
+	if len(cfg.ContactBlocklist) != 2 || cfg.ContactBlocklist[1] != "crypto" {
+		t.Errorf("Unexpected ContactBlocklist %q", cfg.ContactBlocklist)
+	}
+	if cfg.ContactMinSubmitTime != 3*time.Second || cfg.CaptchaProvider != "none" {
+		t.Errorf("Unexpected defaults %v, %q", cfg.ContactMinSubmitTime, cfg.CaptchaProvider)
+	}
+
+	os.Setenv("CONTACT_MAX_LINKS", "many")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for CONTACT_MAX_LINKS=many")
+	}
+}
+
+func TestLoadContactNotifyEmail(t *testing.T) {
+	os.Setenv("ADMIN_EMAIL", "owner@example.com")
+	defer os.Unsetenv("ADMIN_EMAIL")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.ContactNotifyEmail != "owner@example.com" {
+		t.Errorf("Expected notifications to default to ADMIN_EMAIL, got %q", cfg.ContactNotifyEmail)
+	}
+
+	os.Setenv("CONTACT_NOTIFY_EMAIL", "off")
+	defer os.Unsetenv("CONTACT_NOTIFY_EMAIL")
+	if cfg, _ = Load(); cfg.ContactNotifyEmail != "" {
+		t.Errorf("Expected notifications to be off, got %q", cfg.ContactNotifyEmail)
+	}
+}
+
+func TestLoadMaintenance(t *testing.T) {
+	os.Setenv("ADMIN_EMAIL", "owner@example.com")
+	os.Setenv("MAINTENANCE_UPCOMING_MILES", "1000")
+	defer func() {
+		os.Unsetenv("ADMIN_EMAIL")
+		os.Unsetenv("MAINTENANCE_UPCOMING_MILES")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.MaintenanceNotifier != "log" || cfg.MaintenanceNotifyEmail != "owner@example.com" {
+		t.Errorf("Unexpected notifier defaults %q, %q", cfg.MaintenanceNotifier, cfg.MaintenanceNotifyEmail)
+	}
+	if cfg.MaintenanceUpcomingMiles != 1000 || cfg.MaintenanceUpcomingDays != 14 || cfg.MaintenanceCheckInterval != time.Hour {
+		t.Errorf("Unexpected window %d miles, %d days every %v",
+			cfg.MaintenanceUpcomingMiles, cfg.MaintenanceUpcomingDays, cfg.MaintenanceCheckInterval)
+	}
+
+	os.Setenv("MAINTENANCE_UPCOMING_MILES", "-1")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for MAINTENANCE_UPCOMING_MILES=-1")
+	}
+}
+
+func TestLoadBehindProxy(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.BehindProxy {
+		t.Error("Expected BehindProxy to default to false")
+	}
+
+	os.Setenv("RENDER", "true")
+	defer os.Unsetenv("RENDER")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.BehindProxy {
+		t.Error("Expected BehindProxy on Render")
+	}
+}
+
+func TestLoadStorageDurable(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.StorageLocalDurable {
+		t.Error("Expected local storage to be durable by default")
+	}
+
+	// Render wipes local disk unless a persistent disk is attached
+	os.Setenv("RENDER", "true")
+	defer os.Unsetenv("RENDER")
+	if cfg, _ = Load(); cfg.StorageLocalDurable {
+		t.Error("Expected local storage not to be durable on Render")
+	}
+	os.Setenv("STORAGE_LOCAL_DURABLE", "true")
+	defer os.Unsetenv("STORAGE_LOCAL_DURABLE")
+	if cfg, _ = Load(); !cfg.StorageLocalDurable {
+		t.Error("Expected STORAGE_LOCAL_DURABLE to override the default")
+	}
+}
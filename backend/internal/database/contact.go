@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.ContactStore = (*DB)(nil)
+
+func (db *DB) CreateContactSubmission(ctx context.Context, submission *models.ContactSubmission, emails []store.OutboxEmail) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO contact_submissions (name, email, subject, message, is_spam, spam_reason)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, is_read, created_at`,
+		submission.Name, submission.Email, nullString(submission.Subject), submission.Message,
+		submission.IsSpam, nullString(submission.SpamReason),
+	).Scan(&submission.ID, &submission.IsRead, &submission.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := queueEmails(ctx, tx, emails); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const contactColumns = `c.id, c.name, c.email, c.subject, c.message, c.is_read, c.is_archived, c.is_starred,
+	c.is_spam, c.spam_reason, r.reply_count, r.last_replied_at, c.created_at`
+
+// contactFrom joins the submissions in table to a summary of their replies
+func contactFrom(table string) string {
+	return table + ` c CROSS JOIN LATERAL (
+		SELECT COUNT(*) AS reply_count, MAX(created_at) AS last_replied_at
+		FROM contact_replies WHERE submission_id = c.id
+	) r`
+}
+
+func scanContactSubmission(row rowScanner) (*models.ContactSubmission, error) {
+	var submission models.ContactSubmission
+	var subject, spamReason sql.NullString
+	var lastRepliedAt sql.NullTime
+	if err := row.Scan(
+		&submission.ID, &submission.Name, &submission.Email, &subject, &submission.Message,
+		&submission.IsRead, &submission.IsArchived, &submission.IsStarred,
+		&submission.IsSpam, &spamReason, &submission.ReplyCount, &lastRepliedAt, &submission.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	submission.Subject = subject.String
+	submission.SpamReason = spamReason.String
+	if lastRepliedAt.Valid {
+		submission.LastRepliedAt = &lastRepliedAt.Time
+	}
+	return &submission, nil
+}
+
+func (db *DB) ListContactSubmissions(ctx context.Context, filter store.ContactFilter) ([]models.ContactSubmission, int, error) {
+	var q listQuery
+	flag := func(column string, value *bool) {
+		if value != nil {
+			q.add(column + " = " + q.arg(*value))
+		}
+	}
+
+	flag("c.is_spam", filter.Spam)
+	flag("c.is_archived", filter.Archived)
+	flag("c.is_read", filter.Read)
+	flag("c.is_starred", filter.Starred)
+	if filter.Since != nil {
+		q.add("c.created_at >= " + q.arg(*filter.Since))
+	}
+	if filter.Until != nil {
+		q.add("c.created_at < " + q.arg(*filter.Until))
+	}
+	if filter.Search != "" {
+		q.add("c.search_vector @@ websearch_to_tsquery('english', " + q.arg(filter.Search) + ")")
+	}
+
+	total, err := db.count(ctx, "contact_submissions c", &q)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := q.page(filter.ListOptions, store.ContactSorts, "c.", "c.created_at DESC, c.id DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT " + contactColumns + " FROM " + contactFrom("contact_submissions") + q.conditions() + page
+	rows, err := db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var submissions []models.ContactSubmission
+	for rows.Next() {
+		submission, err := scanContactSubmission(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		submissions = append(submissions, *submission)
+	}
+	return submissions, total, rows.Err()
+}
+
+func (db *DB) GetContactSubmission(ctx context.Context, id int) (*models.ContactSubmission, error) {
+	submission, err := scanContactSubmission(db.QueryRowContext(ctx,
+		"SELECT "+contactColumns+" FROM "+contactFrom("contact_submissions")+" WHERE c.id = $1", id,
+	))
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT r.id, r.submission_id, r.author_id, COALESCE(u.username, ''), r.note, r.created_at
+		FROM contact_replies r LEFT JOIN users u ON u.id = r.author_id
+		WHERE r.submission_id = $1 ORDER BY r.created_at, r.id`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reply models.ContactReply
+		var authorID sql.NullInt64
+		if err := rows.Scan(&reply.ID, &reply.SubmissionID, &authorID, &reply.Author, &reply.Note, &reply.CreatedAt); err != nil {
+			return nil, err
+		}
+		if authorID.Valid {
+			author := int(authorID.Int64)
+			reply.AuthorID = &author
+		}
+		submission.Replies = append(submission.Replies, reply)
+	}
+	return submission, rows.Err()
+}
+
+func (db *DB) UpdateContactSubmission(ctx context.Context, id int, update models.ContactSubmissionUpdate) (*models.ContactSubmission, error) {
+	var sets []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if update.IsRead != nil {
+		sets = append(sets, "is_read = "+arg(*update.IsRead))
+	}
+	if update.IsArchived != nil {
+		sets = append(sets, "is_archived = "+arg(*update.IsArchived))
+	}
+	if update.IsStarred != nil {
+		sets = append(sets, "is_starred = "+arg(*update.IsStarred))
+	}
+	if update.IsSpam != nil {
+		// Keep the original reason unless the spam flag actually changes
+		isSpam := arg(*update.IsSpam)
+		sets = append(sets,
+			"is_spam = "+isSpam,
+			fmt.Sprintf(`spam_reason = CASE WHEN is_spam = %[1]s THEN spam_reason
+				WHEN %[1]s THEN %[2]s ELSE NULL END`, isSpam, arg(store.ManualSpamReason)),
+		)
+	}
+	if len(sets) == 0 {
+		return db.GetContactSubmission(ctx, id)
+	}
+
+	submission, err := scanContactSubmission(db.QueryRowContext(ctx,
+		fmt.Sprintf(`WITH updated AS (UPDATE contact_submissions SET %s WHERE id = %s RETURNING *)
+			SELECT %s FROM %s`,
+			strings.Join(sets, ", "), arg(id), contactColumns, contactFrom("updated")),
+		args...,
+	))
+	return submission, notFound(err)
+}
+
+func (db *DB) CountUnreadContactSubmissions(ctx context.Context) (int, error) {
+	var count int
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM contact_submissions WHERE NOT is_read AND NOT is_spam AND NOT is_archived",
+	).Scan(&count)
+	return count, err
+}
+
+func (db *DB) CreateContactReply(ctx context.Context, reply *models.ContactReply) error {
+	var authorID sql.NullInt64
+	if reply.AuthorID != nil {
+		authorID = sql.NullInt64{Int64: int64(*reply.AuthorID), Valid: true}
+	}
+	err := db.QueryRowContext(ctx,
+		`WITH r AS (
+			INSERT INTO contact_replies (submission_id, author_id, note) VALUES ($1, $2, $3)
+			RETURNING id, author_id, created_at
+		)
+		SELECT r.id, r.created_at, COALESCE(u.username, '') FROM r LEFT JOIN users u ON u.id = r.author_id`,
+		reply.SubmissionID, authorID, reply.Note,
+	).Scan(&reply.ID, &reply.CreatedAt, &reply.Author)
+	if isForeignKeyViolation(err) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+func (db *DB) DeleteContactSubmission(ctx context.Context, id int) error {
+	return db.execAffected(ctx, "DELETE FROM contact_submissions WHERE id = $1", id)
+}
+
+func (db *DB) UseFormNonce(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	if _, err := db.ExecContext(ctx, "DELETE FROM contact_form_nonces WHERE expires_at < CURRENT_TIMESTAMP"); err != nil {
+		return false, err
+	}
+	result, err := db.ExecContext(ctx,
+		`INSERT INTO contact_form_nonces (nonce, expires_at)
+		VALUES ($1, CURRENT_TIMESTAMP + make_interval(secs => $2)) ON CONFLICT (nonce) DO NOTHING`,
+		nonce, time.Until(expires).Seconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
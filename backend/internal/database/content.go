@@ -0,0 +1,526 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+	"github.com/lib/pq"
+)
+
+var (
+	_ store.AboutStore    = (*DB)(nil)
+	_ store.ResumeStore   = (*DB)(nil)
+	_ store.CarBuildStore = (*DB)(nil)
+)
+
+// execAffected runs a write against a single row and returns store.ErrNotFound
+// if no row matched
+func (db *DB) execAffected(ctx context.Context, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// notFound maps sql.ErrNoRows from a single-row query to store.ErrNotFound
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+// updateMissed explains why a versioned update matched no row: the record is
+// gone, or it has moved past the expected version
+func (db *DB) updateMissed(ctx context.Context, table string, id, version int) error {
+	if version == 0 {
+		return store.ErrNotFound
+	}
+	var exists bool
+	if err := db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = $1 AND deleted_at IS NULL)", id,
+	).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return store.ErrVersionConflict
+	}
+	return store.ErrNotFound
+}
+
+// versioned scans the row a versioned update returned, explaining a miss
+func (db *DB) versioned(ctx context.Context, row *sql.Row, table string, id, version int, dest ...interface{}) error {
+	err := row.Scan(dest...)
+	if err == sql.ErrNoRows {
+		return db.updateMissed(ctx, table, id, version)
+	}
+	return err
+}
+
+// revise records a revision of content as it is after a change in tx, then
+// commits tx
+func revise(ctx context.Context, tx *sql.Tx, contentType string, id, version int, action string, author *int, content interface{}) error {
+	snapshot, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	var authorID sql.NullInt64
+	if author != nil {
+		authorID = sql.NullInt64{Int64: int64(*author), Valid: true}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO content_revisions (content_type, content_id, version, action, author_id, snapshot)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		contentType, id, version, action, authorID, snapshot,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setDeleted moves a record into the trash, or out of it when deleted is
+// false, bumping its version. The row holds columns, or nothing if the record
+// is missing or already where it was being moved.
+func setDeleted(ctx context.Context, tx *sql.Tx, table, columns string, id int, deleted bool) *sql.Row {
+	set, was := "CURRENT_TIMESTAMP", "IS NULL"
+	if !deleted {
+		set, was = "NULL", "IS NOT NULL"
+	}
+	return tx.QueryRowContext(ctx,
+		"UPDATE "+table+" SET deleted_at = "+set+", version = version + 1 WHERE id = $1 AND deleted_at "+was+" RETURNING "+columns,
+		id,
+	)
+}
+
+// status adds the conditions of a content status filter. Live content is
+// published, or scheduled for a time that has passed.
+func (q *listQuery) status(f store.StatusFilter) {
+	if f.Status != "" {
+		q.add("status = " + q.arg(f.Status))
+	}
+	if f.Live {
+		q.add("(status = 'published' OR (status = 'scheduled' AND publish_at <= CURRENT_TIMESTAMP))")
+	}
+}
+
+const aboutColumns = "id, title, content, image_url, status, publish_at, version, created_at, updated_at"
+
+func scanAboutContent(row rowScanner) (*models.AboutContent, error) {
+	var content models.AboutContent
+	var imageURL sql.NullString
+	var publishAt sql.NullTime
+	if err := row.Scan(
+		&content.ID, &content.Title, &content.Content, &imageURL, &content.Status, &publishAt,
+		&content.Version, &content.CreatedAt, &content.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	content.ImageURL = imageURL.String
+	content.PublishAt = timePtr(publishAt)
+	return &content, nil
+}
+
+func (db *DB) ListAboutContent(ctx context.Context, filter store.AboutFilter) ([]models.AboutContent, int, error) {
+	var q listQuery
+	q.add("deleted_at IS NULL")
+	q.status(filter.StatusFilter)
+	total, err := db.count(ctx, "about_content", &q)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := q.page(filter.ListOptions, store.AboutSorts, "", "created_at DESC, id DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+aboutColumns+" FROM about_content"+q.conditions()+page, q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var contents []models.AboutContent
+	for rows.Next() {
+		content, err := scanAboutContent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		contents = append(contents, *content)
+	}
+	return contents, total, rows.Err()
+}
+
+func (db *DB) GetAboutContent(ctx context.Context, id int) (*models.AboutContent, error) {
+	content, err := scanAboutContent(db.QueryRowContext(ctx,
+		"SELECT "+aboutColumns+" FROM about_content WHERE id = $1 AND deleted_at IS NULL", id,
+	))
+	return content, notFound(err)
+}
+
+func (db *DB) CreateAboutContent(ctx context.Context, content *models.AboutContent, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO about_content (title, content, image_url, status, publish_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, version, created_at, updated_at`,
+		content.Title, content.Content, nullString(content.ImageURL), content.Status, nullTime(content.PublishAt),
+	).Scan(&content.ID, &content.Version, &content.CreatedAt, &content.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return revise(ctx, tx, models.AboutContentType, content.ID, content.Version, models.RevisionCreate, author, content)
+}
+
+func (db *DB) UpdateAboutContent(ctx context.Context, content *models.AboutContent, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = db.versioned(ctx, tx.QueryRowContext(ctx,
+		`UPDATE about_content SET title = $1, content = $2, image_url = $3, status = $4, publish_at = $5,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6 AND deleted_at IS NULL AND ($7 = 0 OR version = $7) RETURNING version, created_at, updated_at`,
+		content.Title, content.Content, nullString(content.ImageURL), content.Status, nullTime(content.PublishAt),
+		content.ID, content.Version,
+	), "about_content", content.ID, content.Version, &content.Version, &content.CreatedAt, &content.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return revise(ctx, tx, models.AboutContentType, content.ID, content.Version, models.RevisionUpdate, author, content)
+}
+
+func (db *DB) DeleteAboutContent(ctx context.Context, id int, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	content, err := scanAboutContent(setDeleted(ctx, tx, "about_content", aboutColumns, id, true))
+	if err != nil {
+		return notFound(err)
+	}
+	return revise(ctx, tx, models.AboutContentType, id, content.Version, models.RevisionDelete, author, content)
+}
+
+func (db *DB) RestoreAboutContent(ctx context.Context, id int, author *int) (*models.AboutContent, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	content, err := scanAboutContent(setDeleted(ctx, tx, "about_content", aboutColumns, id, false))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return content, revise(ctx, tx, models.AboutContentType, id, content.Version, models.RevisionRestore, author, content)
+}
+
+const resumeColumns = `id, section_type, title, subtitle, description, start_date, end_date,
+	display_order, status, publish_at, version, created_at, updated_at`
+
+func scanResumeSection(row rowScanner) (*models.ResumeSection, error) {
+	var section models.ResumeSection
+	var subtitle, description sql.NullString
+	var startDate, endDate, publishAt sql.NullTime
+	if err := row.Scan(
+		&section.ID, &section.SectionType, &section.Title, &subtitle, &description,
+		&startDate, &endDate, &section.DisplayOrder, &section.Status, &publishAt,
+		&section.Version, &section.CreatedAt, &section.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	section.PublishAt = timePtr(publishAt)
+
+	section.Subtitle = subtitle.String
+	section.Description = description.String
+	if startDate.Valid {
+		section.StartDate = &startDate.Time
+	}
+	if endDate.Valid {
+		section.EndDate = &endDate.Time
+	}
+	return &section, nil
+}
+
+func (db *DB) ListResumeSections(ctx context.Context, filter store.ResumeFilter) ([]models.ResumeSection, int, error) {
+	var q listQuery
+	q.add("deleted_at IS NULL")
+	if filter.SectionType != "" {
+		q.add("section_type = " + q.arg(filter.SectionType))
+	}
+	q.status(filter.StatusFilter)
+	total, err := db.count(ctx, "resume_sections", &q)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := q.page(filter.ListOptions, store.ResumeSorts, "", "display_order, start_date DESC, id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+resumeColumns+" FROM resume_sections"+q.conditions()+page, q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var sections []models.ResumeSection
+	for rows.Next() {
+		section, err := scanResumeSection(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		sections = append(sections, *section)
+	}
+	return sections, total, rows.Err()
+}
+
+func (db *DB) GetResumeSection(ctx context.Context, id int) (*models.ResumeSection, error) {
+	section, err := scanResumeSection(db.QueryRowContext(ctx,
+		"SELECT "+resumeColumns+" FROM resume_sections WHERE id = $1 AND deleted_at IS NULL", id,
+	))
+	return section, notFound(err)
+}
+
+func (db *DB) CreateResumeSection(ctx context.Context, section *models.ResumeSection, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO resume_sections (section_type, title, subtitle, description, start_date, end_date, display_order,
+		status, publish_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, version, created_at, updated_at`,
+		section.SectionType, section.Title, nullString(section.Subtitle), nullString(section.Description),
+		nullTime(section.StartDate), nullTime(section.EndDate), section.DisplayOrder,
+		section.Status, nullTime(section.PublishAt),
+	).Scan(&section.ID, &section.Version, &section.CreatedAt, &section.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return revise(ctx, tx, models.ResumeContentType, section.ID, section.Version, models.RevisionCreate, author, section)
+}
+
+func (db *DB) UpdateResumeSection(ctx context.Context, section *models.ResumeSection, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = db.versioned(ctx, tx.QueryRowContext(ctx,
+		`UPDATE resume_sections SET section_type = $1, title = $2, subtitle = $3, description = $4,
+		start_date = $5, end_date = $6, display_order = $7, status = $8, publish_at = $9,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $10 AND deleted_at IS NULL AND ($11 = 0 OR version = $11) RETURNING version, created_at, updated_at`,
+		section.SectionType, section.Title, nullString(section.Subtitle), nullString(section.Description),
+		nullTime(section.StartDate), nullTime(section.EndDate), section.DisplayOrder,
+		section.Status, nullTime(section.PublishAt), section.ID, section.Version,
+	), "resume_sections", section.ID, section.Version, &section.Version, &section.CreatedAt, &section.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return revise(ctx, tx, models.ResumeContentType, section.ID, section.Version, models.RevisionUpdate, author, section)
+}
+
+func (db *DB) DeleteResumeSection(ctx context.Context, id int, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	section, err := scanResumeSection(setDeleted(ctx, tx, "resume_sections", resumeColumns, id, true))
+	if err != nil {
+		return notFound(err)
+	}
+	return revise(ctx, tx, models.ResumeContentType, id, section.Version, models.RevisionDelete, author, section)
+}
+
+func (db *DB) RestoreResumeSection(ctx context.Context, id int, author *int) (*models.ResumeSection, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	section, err := scanResumeSection(setDeleted(ctx, tx, "resume_sections", resumeColumns, id, false))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return section, revise(ctx, tx, models.ResumeContentType, id, section.Version, models.RevisionRestore, author, section)
+}
+
+const carBuildColumns = `id, title, description, date, category, cost, mileage, image_urls,
+	display_order, status, publish_at, version, created_at, updated_at`
+
+func scanCarBuildEntry(row rowScanner) (*models.CarBuildEntry, error) {
+	var entry models.CarBuildEntry
+	var category sql.NullString
+	var cost sql.NullFloat64
+	var mileage sql.NullInt64
+	var publishAt sql.NullTime
+	var imageURLs pq.StringArray
+	if err := row.Scan(
+		&entry.ID, &entry.Title, &entry.Description, &entry.Date, &category,
+		&cost, &mileage, &imageURLs, &entry.DisplayOrder, &entry.Status, &publishAt,
+		&entry.Version, &entry.CreatedAt, &entry.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	entry.PublishAt = timePtr(publishAt)
+
+	entry.Category = category.String
+	if cost.Valid {
+		entry.Cost = &cost.Float64
+	}
+	entry.Mileage = intPtr(mileage)
+	if len(imageURLs) > 0 {
+		entry.ImageURLs = imageURLs
+	}
+	return &entry, nil
+}
+
+func (db *DB) ListCarBuildEntries(ctx context.Context, filter store.CarBuildFilter) ([]models.CarBuildEntry, int, error) {
+	var q listQuery
+	q.add("deleted_at IS NULL")
+	if filter.Category != "" {
+		q.add("category = " + q.arg(filter.Category))
+	}
+	if filter.Since != nil {
+		q.add("date >= " + q.arg(*filter.Since))
+	}
+	if filter.Until != nil {
+		q.add("date < " + q.arg(*filter.Until))
+	}
+	if filter.MinCost != nil {
+		q.add("cost >= " + q.arg(*filter.MinCost))
+	}
+	if filter.MaxCost != nil {
+		q.add("cost <= " + q.arg(*filter.MaxCost))
+	}
+	q.status(filter.StatusFilter)
+	total, err := db.count(ctx, "car_build_entries", &q)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := q.page(filter.ListOptions, store.CarBuildSorts, "", "display_order, date DESC, id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+carBuildColumns+" FROM car_build_entries"+q.conditions()+page, q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []models.CarBuildEntry
+	for rows.Next() {
+		entry, err := scanCarBuildEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, total, rows.Err()
+}
+
+func (db *DB) GetCarBuildEntry(ctx context.Context, id int) (*models.CarBuildEntry, error) {
+	entry, err := scanCarBuildEntry(db.QueryRowContext(ctx,
+		"SELECT "+carBuildColumns+" FROM car_build_entries WHERE id = $1 AND deleted_at IS NULL", id,
+	))
+	return entry, notFound(err)
+}
+
+func (db *DB) CreateCarBuildEntry(ctx context.Context, entry *models.CarBuildEntry, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO car_build_entries (title, description, date, category, cost, mileage, image_urls,
+		display_order, status, publish_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, version, created_at, updated_at`,
+		entry.Title, entry.Description, entry.Date, nullString(entry.Category),
+		nullFloat64(entry.Cost), entry.Mileage, pq.Array(entry.ImageURLs), entry.DisplayOrder,
+		entry.Status, nullTime(entry.PublishAt),
+	).Scan(&entry.ID, &entry.Version, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return revise(ctx, tx, models.CarBuildContentType, entry.ID, entry.Version, models.RevisionCreate, author, entry)
+}
+
+func (db *DB) UpdateCarBuildEntry(ctx context.Context, entry *models.CarBuildEntry, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = db.versioned(ctx, tx.QueryRowContext(ctx,
+		`UPDATE car_build_entries SET title = $1, description = $2, date = $3, category = $4,
+		cost = $5, mileage = $6, image_urls = $7, display_order = $8, status = $9, publish_at = $10,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $11 AND deleted_at IS NULL AND ($12 = 0 OR version = $12) RETURNING version, created_at, updated_at`,
+		entry.Title, entry.Description, entry.Date, nullString(entry.Category),
+		nullFloat64(entry.Cost), entry.Mileage, pq.Array(entry.ImageURLs), entry.DisplayOrder,
+		entry.Status, nullTime(entry.PublishAt), entry.ID, entry.Version,
+	), "car_build_entries", entry.ID, entry.Version, &entry.Version, &entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return revise(ctx, tx, models.CarBuildContentType, entry.ID, entry.Version, models.RevisionUpdate, author, entry)
+}
+
+func (db *DB) DeleteCarBuildEntry(ctx context.Context, id int, author *int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	entry, err := scanCarBuildEntry(setDeleted(ctx, tx, "car_build_entries", carBuildColumns, id, true))
+	if err != nil {
+		return notFound(err)
+	}
+	return revise(ctx, tx, models.CarBuildContentType, id, entry.Version, models.RevisionDelete, author, entry)
+}
+
+func (db *DB) RestoreCarBuildEntry(ctx context.Context, id int, author *int) (*models.CarBuildEntry, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry, err := scanCarBuildEntry(setDeleted(ctx, tx, "car_build_entries", carBuildColumns, id, false))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return entry, revise(ctx, tx, models.CarBuildContentType, id, entry.Version, models.RevisionRestore, author, entry)
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.FolderStore = (*DB)(nil)
+
+// folderColumns is the select list scanned by scanFolder
+const folderColumns = `f.id, f.slug, f.title, f.description, f.cover_image_id, f.visibility, f.sort_order,
+	(SELECT COUNT(*) FROM gallery_images i WHERE i.folder = f.slug), f.created_at, f.updated_at`
+
+func scanFolder(row rowScanner) (*models.Folder, error) {
+	var folder models.Folder
+	var description sql.NullString
+	var coverImageID sql.NullInt64
+	if err := row.Scan(
+		&folder.ID, &folder.Slug, &folder.Title, &description, &coverImageID, &folder.Visibility,
+		&folder.SortOrder, &folder.ImageCount, &folder.CreatedAt, &folder.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	folder.Description = description.String
+	folder.CoverImageID = intPtr(coverImageID)
+	if folder.CoverImageID != nil {
+		folder.CoverURL = fmt.Sprintf("/api/image/%d", *folder.CoverImageID)
+	}
+	return &folder, nil
+}
+
+func (db *DB) GetFolder(ctx context.Context, slug string) (*models.Folder, error) {
+	folder, err := scanFolder(db.QueryRowContext(ctx, "SELECT "+folderColumns+" FROM folders f WHERE f.slug = $1", slug))
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	return folder, err
+}
+
+func (db *DB) ListFolders(ctx context.Context, publicOnly bool) ([]models.Folder, error) {
+	query := "SELECT " + folderColumns + " FROM folders f"
+	if publicOnly {
+		query += " WHERE f.visibility = 'public'"
+	}
+	query += " ORDER BY f.sort_order, f.title"
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, *folder)
+	}
+	return folders, rows.Err()
+}
+
+func (db *DB) CreateFolder(ctx context.Context, folder *models.Folder) error {
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO folders (slug, title, description, cover_image_id, visibility, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`,
+		folder.Slug, folder.Title, nullString(folder.Description), folder.CoverImageID, folder.Visibility, folder.SortOrder,
+	).Scan(&folder.ID, &folder.CreatedAt, &folder.UpdatedAt)
+	if err != nil {
+		return folderWriteError(err)
+	}
+
+	folder.ImageCount = 0
+	folder.CoverURL = ""
+	if folder.CoverImageID != nil {
+		folder.CoverURL = fmt.Sprintf("/api/image/%d", *folder.CoverImageID)
+	}
+	return nil
+}
+
+func (db *DB) UpdateFolder(ctx context.Context, folder *models.Folder) error {
+	result, err := db.ExecContext(ctx,
+		`UPDATE folders SET slug = $1, title = $2, description = $3, cover_image_id = $4, visibility = $5,
+		sort_order = $6, updated_at = CURRENT_TIMESTAMP WHERE id = $7`,
+		folder.Slug, folder.Title, nullString(folder.Description), folder.CoverImageID, folder.Visibility, folder.SortOrder, folder.ID,
+	)
+	if err != nil {
+		return folderWriteError(err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNotFound
+	}
+
+	updated, err := scanFolder(db.QueryRowContext(ctx, "SELECT "+folderColumns+" FROM folders f WHERE f.id = $1", folder.ID))
+	if err != nil {
+		return err
+	}
+	*folder = *updated
+	return nil
+}
+
+func (db *DB) DeleteFolder(ctx context.Context, id int) error {
+	result, err := db.ExecContext(ctx, "DELETE FROM folders WHERE id = $1", id)
+	if isForeignKeyViolation(err) {
+		return store.ErrInUse
+	}
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// folderWriteError maps constraint violations on folders to store errors
+func folderWriteError(err error) error {
+	switch {
+	case isUniqueViolation(err):
+		return store.ErrConflict
+	case isForeignKeyViolation(err):
+		return store.ErrInvalidReference
+	}
+	return err
+}
@@ -0,0 +1,599 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/imaging"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/storage"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+	"github.com/lib/pq"
+)
+
+var _ store.ImageStore = (*DB)(nil)
+
+// galleryImageColumns is the select list scanned by scanGalleryImage
+const galleryImageColumns = `id, folder, filename, content_type, COALESCE(caption, ''), COALESCE(alt_text, ''),
+	COALESCE(display_order, 0), width, height, taken_at, camera_make, camera_model, lens_model, exposure_time,
+	f_number, iso, focal_length, created_at, COALESCE(updated_at, created_at)`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGalleryImage(row rowScanner) (*models.GalleryImage, error) {
+	var image models.GalleryImage
+	var width, height, iso sql.NullInt64
+	var takenAt sql.NullTime
+	var cameraMake, cameraModel, lensModel, exposureTime sql.NullString
+	var fNumber, focalLength sql.NullFloat64
+	if err := row.Scan(
+		&image.ID, &image.Folder, &image.Filename, &image.ContentType, &image.Caption, &image.AltText,
+		&image.DisplayOrder, &width, &height, &takenAt, &cameraMake, &cameraModel, &lensModel, &exposureTime,
+		&fNumber, &iso, &focalLength, &image.CreatedAt, &image.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	image.URL = fmt.Sprintf("/api/image/%d", image.ID)
+	image.Width = intPtr(width)
+	image.Height = intPtr(height)
+	image.ISO = intPtr(iso)
+	if takenAt.Valid {
+		image.TakenAt = &takenAt.Time
+	}
+	image.CameraMake = cameraMake.String
+	image.CameraModel = cameraModel.String
+	image.LensModel = lensModel.String
+	image.ExposureTime = exposureTime.String
+	if fNumber.Valid {
+		image.FNumber = &fNumber.Float64
+	}
+	if focalLength.Valid {
+		image.FocalLength = &focalLength.Float64
+	}
+	return &image, nil
+}
+
+// ListImages returns the images of a folder, in display order by default
+func (db *DB) ListImages(ctx context.Context, folder string, opts store.ListOptions) ([]models.GalleryImage, int, error) {
+	var q listQuery
+	q.add("folder = " + q.arg(folder))
+	total, err := db.count(ctx, "gallery_images", &q)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := q.page(opts, store.ImageSorts, "", "display_order, created_at DESC, id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx,
+		"SELECT "+galleryImageColumns+" FROM gallery_images"+q.conditions()+page, q.args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	images := []models.GalleryImage{}
+	for rows.Next() {
+		image, err := scanGalleryImage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		images = append(images, *image)
+	}
+	return images, total, rows.Err()
+}
+
+func (db *DB) ImageAltTexts(ctx context.Context, ids []int, publicOnly bool) (map[int]string, error) {
+	alts := make(map[int]string)
+	if len(ids) == 0 {
+		return alts, nil
+	}
+	query := "SELECT i.id, COALESCE(i.alt_text, '') FROM gallery_images i JOIN folders f ON f.slug = i.folder WHERE i.id = ANY($1)"
+	if publicOnly {
+		query += " AND f.visibility = 'public'"
+	}
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var alt string
+		if err := rows.Scan(&id, &alt); err != nil {
+			return nil, err
+		}
+		alts[id] = alt
+	}
+	return alts, rows.Err()
+}
+
+func (db *DB) GetImageFile(ctx context.Context, id int) (*store.ImageFile, error) {
+	var key, hash sql.NullString
+	var width sql.NullInt64
+	file := store.ImageFile{ID: id}
+	err := db.QueryRowContext(ctx,
+		"SELECT folder, storage_key, content_type, width, content_hash, created_at FROM gallery_images WHERE id = $1",
+		id,
+	).Scan(&file.Folder, &key, &file.ContentType, &width, &hash, &file.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	file.Key = key.String
+	file.Hash = hash.String
+	file.Width = int(width.Int64)
+	return &file, nil
+}
+
+func (db *DB) ListVariants(ctx context.Context, imageID int) ([]store.VariantFile, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, variant, format, content_type, width, height, storage_key, size_bytes, content_hash, created_at
+		FROM gallery_image_variants WHERE image_id = $1`,
+		imageID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []store.VariantFile
+	for rows.Next() {
+		var v store.VariantFile
+		var hash sql.NullString
+		if err := rows.Scan(&v.ID, &v.Variant, &v.Format, &v.ContentType, &v.Width, &v.Height, &v.Key, &v.Size, &hash, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		v.Hash = hash.String
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+func (db *DB) LegacyImageData(ctx context.Context, id int) ([]byte, error) {
+	var data []byte
+	err := db.QueryRowContext(ctx, "SELECT image_data FROM gallery_images WHERE id = $1", id).Scan(&data)
+	if err == sql.ErrNoRows || (err == nil && data == nil) {
+		return nil, store.ErrNotFound
+	}
+	return data, err
+}
+
+func (db *DB) SaveContentHash(ctx context.Context, imageID, variantID int, hash string) error {
+	if variantID != 0 {
+		_, err := db.ExecContext(ctx, "UPDATE gallery_image_variants SET content_hash = $1 WHERE id = $2", hash, variantID)
+		return err
+	}
+	_, err := db.ExecContext(ctx, "UPDATE gallery_images SET content_hash = $1 WHERE id = $2", hash, imageID)
+	return err
+}
+
+func (db *DB) CreateImage(ctx context.Context, image *store.NewImage) (int, error) {
+	var id int
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO gallery_images (folder, filename, storage_key, size_bytes, content_type, content_hash)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		image.Folder, image.Filename, image.Key, image.Size, image.ContentType, image.Hash,
+	).Scan(&id)
+	return id, err
+}
+
+func (db *DB) SaveImageMetadata(ctx context.Context, id int, meta *imaging.Metadata) error {
+	if meta == nil {
+		meta = &imaging.Metadata{}
+	}
+
+	var takenAt sql.NullTime
+	if meta.TakenAt != nil {
+		takenAt = sql.NullTime{Time: *meta.TakenAt, Valid: true}
+	}
+	var fNumber, focalLength sql.NullFloat64
+	if meta.FNumber != nil {
+		fNumber = sql.NullFloat64{Float64: *meta.FNumber, Valid: true}
+	}
+	if meta.FocalLength != nil {
+		focalLength = sql.NullFloat64{Float64: *meta.FocalLength, Valid: true}
+	}
+	var iso sql.NullInt64
+	if meta.ISO != nil {
+		iso = sql.NullInt64{Int64: int64(*meta.ISO), Valid: true}
+	}
+
+	_, err := db.ExecContext(ctx,
+		`UPDATE gallery_images SET taken_at = $1, camera_make = $2, camera_model = $3, lens_model = $4,
+		exposure_time = $5, f_number = $6, iso = $7, focal_length = $8, sanitized_at = CURRENT_TIMESTAMP
+		WHERE id = $9`,
+		takenAt, nullString(meta.CameraMake), nullString(meta.CameraModel), nullString(meta.LensModel),
+		nullString(meta.ExposureTime), fNumber, iso, focalLength, id,
+	)
+	return err
+}
+
+func (db *DB) SaveVariants(ctx context.Context, imageID, width, height int, variants []store.VariantFile) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE gallery_images SET width = $1, height = $2 WHERE id = $3",
+		width, height, imageID,
+	); err != nil {
+		return err
+	}
+
+	for _, v := range variants {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO gallery_image_variants (image_id, variant, format, content_type, width, height, storage_key, size_bytes, content_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (image_id, variant, format) DO UPDATE SET content_type = EXCLUDED.content_type,
+			width = EXCLUDED.width, height = EXCLUDED.height, storage_key = EXCLUDED.storage_key,
+			size_bytes = EXCLUDED.size_bytes, content_hash = EXCLUDED.content_hash`,
+			imageID, v.Variant, v.Format, v.ContentType, v.Width, v.Height, v.Key, v.Size, v.Hash,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateImage applies a partial update. Moving an image to another folder
+// without an explicit order places it last in that folder.
+func (db *DB) UpdateImage(ctx context.Context, id int, update models.GalleryImageUpdate) (*models.GalleryImage, error) {
+	var sets []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if update.Caption != nil {
+		sets = append(sets, "caption = "+arg(nullString(*update.Caption)))
+	}
+	if update.AltText != nil {
+		sets = append(sets, "alt_text = "+arg(nullString(*update.AltText)))
+	}
+	if update.Folder != nil {
+		folder := arg(*update.Folder)
+		sets = append(sets, "folder = "+folder)
+		if update.DisplayOrder == nil {
+			sets = append(sets, fmt.Sprintf(
+				`display_order = CASE WHEN folder = %[1]s THEN display_order ELSE
+				(SELECT COALESCE(MAX(display_order), -1) + 1 FROM gallery_images WHERE folder = %[1]s) END`,
+				folder,
+			))
+		}
+	}
+	if update.DisplayOrder != nil {
+		sets = append(sets, "display_order = "+arg(*update.DisplayOrder))
+	}
+	sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+
+	image, err := scanGalleryImage(db.QueryRowContext(ctx,
+		fmt.Sprintf("UPDATE gallery_images SET %s WHERE id = %s RETURNING %s",
+			strings.Join(sets, ", "), arg(id), galleryImageColumns),
+		args...,
+	))
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	if isForeignKeyViolation(err) {
+		return nil, store.ErrInvalidReference
+	}
+	return image, err
+}
+
+func (db *DB) ReorderImages(ctx context.Context, folder string, ids []int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id FROM gallery_images WHERE folder = $1 ORDER BY display_order, created_at DESC FOR UPDATE",
+		folder,
+	)
+	if err != nil {
+		return err
+	}
+	var current []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	order, err := mergeOrder(current, ids)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range order {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE gallery_images SET display_order = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND display_order IS DISTINCT FROM $1",
+			i, id,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// mergeOrder puts the requested IDs first, followed by the rest of current in
+// its existing order
+func mergeOrder(current, requested []int) ([]int, error) {
+	inFolder := make(map[int]bool, len(current))
+	for _, id := range current {
+		inFolder[id] = true
+	}
+
+	order := make([]int, 0, len(current))
+	listed := make(map[int]bool, len(requested))
+	for _, id := range requested {
+		if !inFolder[id] || listed[id] {
+			return nil, fmt.Errorf("%w: %d", store.ErrImageNotInFolder, id)
+		}
+		listed[id] = true
+		order = append(order, id)
+	}
+	for _, id := range current {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+	return order, nil
+}
+
+// queryKeys returns the storage keys selected by query
+func queryKeys(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key sql.NullString
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		if key.Valid {
+			keys = append(keys, key.String)
+		}
+	}
+	return keys, rows.Err()
+}
+
+func (db *DB) DeleteImage(ctx context.Context, id int) ([]string, error) {
+	keys, err := queryKeys(ctx, db, "SELECT storage_key FROM gallery_image_variants WHERE image_id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+
+	var key sql.NullString
+	err = db.QueryRowContext(ctx, "DELETE FROM gallery_images WHERE id = $1 RETURNING storage_key", id).Scan(&key)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Valid {
+		keys = append(keys, key.String)
+	}
+	return keys, nil
+}
+
+func (db *DB) DeleteAllImages(ctx context.Context) ([]string, error) {
+	keys, err := queryKeys(ctx, db, "SELECT storage_key FROM gallery_image_variants")
+	if err != nil {
+		return nil, err
+	}
+
+	originals, err := queryKeys(ctx, db, "DELETE FROM gallery_images RETURNING storage_key")
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, originals...), nil
+}
+
+// SanitizeStoredImages strips EXIF from images that were stored before
+// uploads were sanitized, rewriting the stored object in place and
+// regenerating its variants so they pick up the corrected orientation
+func (db *DB) SanitizeStoredImages(ctx context.Context, blobs storage.Backend) (int, error) {
+	sanitized := 0
+	lastID := 0
+
+	for {
+		var id int
+		var key, contentType string
+		err := db.QueryRowContext(ctx,
+			`SELECT id, storage_key, content_type FROM gallery_images
+			WHERE storage_key IS NOT NULL AND sanitized_at IS NULL AND id > $1
+			ORDER BY id LIMIT 1`,
+			lastID,
+		).Scan(&id, &key, &contentType)
+		if err == sql.ErrNoRows {
+			return sanitized, nil
+		}
+		if err != nil {
+			return sanitized, err
+		}
+		lastID = id
+
+		body, _, err := blobs.Get(ctx, key)
+		if err != nil {
+			log.Printf("Error reading image %d: %v", id, err)
+			continue
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			log.Printf("Error reading image %d: %v", id, err)
+			continue
+		}
+
+		clean, meta, err := imaging.Sanitize(data)
+		if err != nil {
+			log.Printf("Error sanitizing image %d: %v", id, err)
+			continue
+		}
+
+		if !bytes.Equal(clean, data) {
+			if err := blobs.Put(ctx, key, bytes.NewReader(clean), int64(len(clean)), contentType); err != nil {
+				return sanitized, fmt.Errorf("error storing image %d: %w", id, err)
+			}
+			if _, err := db.ExecContext(ctx,
+				"UPDATE gallery_images SET size_bytes = $1, content_hash = $2 WHERE id = $3",
+				len(clean), store.ContentHash(clean), id,
+			); err != nil {
+				return sanitized, err
+			}
+			if err := store.GenerateVariants(ctx, db, blobs, id, key, contentType, clean); err != nil {
+				log.Printf("Error generating variants for image %d: %v", id, err)
+			}
+		}
+
+		if err := db.SaveImageMetadata(ctx, id, meta); err != nil {
+			return sanitized, err
+		}
+
+		sanitized++
+	}
+}
+
+// GenerateMissingVariants creates variants for stored images that were
+// uploaded before variants existed
+func (db *DB) GenerateMissingVariants(ctx context.Context, blobs storage.Backend) (int, error) {
+	generated := 0
+	lastID := 0
+
+	for {
+		var id int
+		var key, contentType string
+		err := db.QueryRowContext(ctx,
+			`SELECT id, storage_key, content_type FROM gallery_images
+			WHERE storage_key IS NOT NULL AND width IS NULL AND content_type <> 'image/gif' AND id > $1
+			ORDER BY id LIMIT 1`,
+			lastID,
+		).Scan(&id, &key, &contentType)
+		if err == sql.ErrNoRows {
+			return generated, nil
+		}
+		if err != nil {
+			return generated, err
+		}
+		lastID = id
+
+		body, _, err := blobs.Get(ctx, key)
+		if err != nil {
+			log.Printf("Error reading image %d: %v", id, err)
+			continue
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			log.Printf("Error reading image %d: %v", id, err)
+			continue
+		}
+
+		if err := store.GenerateVariants(ctx, db, blobs, id, key, contentType, data); err != nil {
+			log.Printf("Error generating variants for image %d: %v", id, err)
+			continue
+		}
+
+		generated++
+	}
+}
+
+// MigrateImageBlobs copies images still held in gallery_images.image_data to
+// the storage backend and clears the column, unless keepData is set because
+// the backend may lose its copy. Rows are handled one at a time so only a
+// single image is held in memory.
+func (db *DB) MigrateImageBlobs(ctx context.Context, blobs storage.Backend, keepData bool) (int, error) {
+	migrated := 0
+	lastID := 0
+
+	for {
+		var id int
+		var folder, filename, contentType string
+		var data []byte
+		err := db.QueryRowContext(ctx,
+			`SELECT id, folder, filename, content_type, image_data FROM gallery_images
+			WHERE storage_key IS NULL AND image_data IS NOT NULL AND id > $1
+			ORDER BY id LIMIT 1`,
+			lastID,
+		).Scan(&id, &folder, &filename, &contentType, &data)
+		if err == sql.ErrNoRows {
+			return migrated, nil
+		}
+		if err != nil {
+			return migrated, err
+		}
+		lastID = id
+
+		data, meta, err := imaging.Sanitize(data)
+		if err != nil {
+			log.Printf("Error sanitizing image %d, skipping: %v", id, err)
+			continue
+		}
+
+		key, err := storage.NewKey(folder, filename)
+		if err != nil {
+			return migrated, err
+		}
+
+		if err := blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+			return migrated, fmt.Errorf("error storing image %d: %w", id, err)
+		}
+
+		if _, err := db.ExecContext(ctx,
+			`UPDATE gallery_images SET storage_key = $1, size_bytes = $2, content_hash = $3,
+			image_data = CASE WHEN $5 THEN image_data END WHERE id = $4`,
+			key, len(data), store.ContentHash(data), id, keepData,
+		); err != nil {
+			blobs.Delete(ctx, key)
+			return migrated, fmt.Errorf("error updating image %d: %w", id, err)
+		}
+
+		if err := db.SaveImageMetadata(ctx, id, meta); err != nil {
+			log.Printf("Error saving metadata for image %d: %v", id, err)
+		}
+
+		if err := store.GenerateVariants(ctx, db, blobs, id, key, contentType, data); err != nil {
+			log.Printf("Error generating variants for image %d: %v", id, err)
+		}
+
+		log.Printf("Migrated image %d (%s/%s) to %s", id, folder, filename, key)
+		migrated++
+	}
+}
+
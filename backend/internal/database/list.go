@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// listQuery collects the conditions and placeholder arguments of a list query
+type listQuery struct {
+	where []string
+	args  []interface{}
+}
+
+// arg adds a placeholder argument and returns its $n reference
+func (q *listQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// add appends a condition built with arg
+func (q *listQuery) add(condition string) {
+	q.where = append(q.where, condition)
+}
+
+// conditions returns the WHERE clause, or an empty string if there is none
+func (q *listQuery) conditions() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+// page returns the ORDER BY, LIMIT and OFFSET clauses for opts. The sort field
+// must be one of allowed and is qualified with prefix; defaultOrder is used
+// when no field is given and breaks ties otherwise.
+func (q *listQuery) page(opts store.ListOptions, allowed []string, prefix, defaultOrder string) (string, error) {
+	order := defaultOrder
+	if opts.Sort != "" {
+		if !slices.Contains(allowed, opts.Sort) {
+			return "", fmt.Errorf("unknown sort field %q", opts.Sort)
+		}
+		order = prefix + opts.Sort
+		if opts.Desc {
+			order += " DESC"
+		}
+		order += ", " + defaultOrder
+	}
+
+	clause := " ORDER BY " + order
+	if opts.Limit > 0 {
+		clause += " LIMIT " + q.arg(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		clause += " OFFSET " + q.arg(opts.Offset)
+	}
+	return clause, nil
+}
+
+// count returns the number of rows in from that match the conditions so far
+func (db *DB) count(ctx context.Context, from string, q *listQuery) (int, error) {
+	var total int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+from+q.conditions(), q.args...).Scan(&total)
+	return total, err
+}
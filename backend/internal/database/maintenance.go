@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.MaintenanceStore = (*DB)(nil)
+
+const maintenanceColumns = `id, name, description, interval_miles, interval_months, last_done_mileage,
+	last_done_at, notified_status, created_at, updated_at`
+
+func scanMaintenanceSchedule(row rowScanner) (*models.MaintenanceSchedule, error) {
+	var schedule models.MaintenanceSchedule
+	var description sql.NullString
+	var intervalMiles, intervalMonths, lastDoneMileage sql.NullInt64
+	var lastDoneAt sql.NullTime
+	if err := row.Scan(
+		&schedule.ID, &schedule.Name, &description, &intervalMiles, &intervalMonths, &lastDoneMileage,
+		&lastDoneAt, &schedule.NotifiedStatus, &schedule.CreatedAt, &schedule.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	schedule.Description = description.String
+	schedule.IntervalMiles = intPtr(intervalMiles)
+	schedule.IntervalMonths = intPtr(intervalMonths)
+	schedule.LastDoneMileage = intPtr(lastDoneMileage)
+	schedule.LastDoneAt = timePtr(lastDoneAt)
+	return &schedule, nil
+}
+
+func (db *DB) ListMaintenanceSchedules(ctx context.Context) ([]models.MaintenanceSchedule, error) {
+	rows, err := db.QueryContext(ctx, "SELECT "+maintenanceColumns+" FROM maintenance_schedules ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.MaintenanceSchedule{}
+	for rows.Next() {
+		schedule, err := scanMaintenanceSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func (db *DB) GetMaintenanceSchedule(ctx context.Context, id int) (*models.MaintenanceSchedule, error) {
+	schedule, err := scanMaintenanceSchedule(db.QueryRowContext(ctx,
+		"SELECT "+maintenanceColumns+" FROM maintenance_schedules WHERE id = $1", id,
+	))
+	return schedule, notFound(err)
+}
+
+func (db *DB) CreateMaintenanceSchedule(ctx context.Context, schedule *models.MaintenanceSchedule) error {
+	return db.QueryRowContext(ctx,
+		`INSERT INTO maintenance_schedules (name, description, interval_miles, interval_months,
+		last_done_mileage, last_done_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, notified_status, created_at, updated_at`,
+		schedule.Name, nullString(schedule.Description), schedule.IntervalMiles, schedule.IntervalMonths,
+		schedule.LastDoneMileage, nullTime(schedule.LastDoneAt),
+	).Scan(&schedule.ID, &schedule.NotifiedStatus, &schedule.CreatedAt, &schedule.UpdatedAt)
+}
+
+func (db *DB) UpdateMaintenanceSchedule(ctx context.Context, schedule *models.MaintenanceSchedule) error {
+	err := db.QueryRowContext(ctx,
+		`UPDATE maintenance_schedules SET name = $1, description = $2, interval_miles = $3,
+		interval_months = $4, last_done_mileage = $5, last_done_at = $6, notified_status = '',
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7 RETURNING notified_status, created_at, updated_at`,
+		schedule.Name, nullString(schedule.Description), schedule.IntervalMiles, schedule.IntervalMonths,
+		schedule.LastDoneMileage, nullTime(schedule.LastDoneAt), schedule.ID,
+	).Scan(&schedule.NotifiedStatus, &schedule.CreatedAt, &schedule.UpdatedAt)
+	return notFound(err)
+}
+
+func (db *DB) DeleteMaintenanceSchedule(ctx context.Context, id int) error {
+	return db.execAffected(ctx, "DELETE FROM maintenance_schedules WHERE id = $1", id)
+}
+
+func (db *DB) SetMaintenanceNotified(ctx context.Context, statuses map[int]string, emails []store.OutboxEmail) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, status := range statuses {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE maintenance_schedules SET notified_status = $2 WHERE id = $1", id, status,
+		); err != nil {
+			return err
+		}
+	}
+	if err := queueEmails(ctx, tx, emails); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) LatestMileage(ctx context.Context) (*models.MileageReading, error) {
+	var reading models.MileageReading
+	err := db.QueryRowContext(ctx,
+		`SELECT mileage, date, id FROM car_build_entries
+		WHERE mileage IS NOT NULL AND deleted_at IS NULL
+		ORDER BY date DESC, id DESC LIMIT 1`,
+	).Scan(&reading.Mileage, &reading.Date, &reading.EntryID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &reading, nil
+}
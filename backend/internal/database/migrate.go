@@ -0,0 +1,381 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationLockKey identifies the advisory lock held while migrating, so two
+// server instances starting together don't both apply the same migration
+const migrationLockKey int64 = 4012517730
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// legacyMigrationPattern matches versions recorded before migrations were
+// split into up and down files, e.g. "001_initial_schema.sql"
+var legacyMigrationPattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+var ErrMigrationModified = errors.New("migration modified after it was applied")
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of Up
+}
+
+// MigrationState describes a migration as recorded in schema_migrations
+type MigrationState struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // the up file no longer matches the applied checksum
+	Missing   bool // applied but no longer present in the migration files
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  sql.NullString
+	appliedAt time.Time
+}
+
+// LoadMigrations reads NNN_name.up.sql and NNN_name.down.sql files from fsys,
+// sorted by version. Every migration needs an up file; the down file is
+// optional but required to roll it back.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			if strings.HasSuffix(entry.Name(), ".sql") {
+				return nil, fmt.Errorf("migration file %s is not named NNN_name.up.sql or NNN_name.down.sql", entry.Name())
+			}
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named both %s and %s", version, m.Name, match[2])
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+			m.Checksum = migrationChecksum(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func migrationChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// withMigrationLock runs fn on a single connection holding the migration
+// advisory lock, creating schema_migrations first if needed
+func (db *DB) withMigrationLock(ctx context.Context, migrations []Migration, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS name VARCHAR(255);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum VARCHAR(64);
+	`); err != nil {
+		return fmt.Errorf("error creating migrations table: %w", err)
+	}
+
+	if err := upgradeLegacyMigrations(ctx, conn, migrations); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// upgradeLegacyMigrations rewrites rows recorded by file name into the
+// numbered form. Their checksums are taken from the current files since the
+// applied SQL was never recorded.
+func upgradeLegacyMigrations(ctx context.Context, conn *sql.Conn, migrations []Migration) error {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations WHERE version LIKE '%.sql'")
+	if err != nil {
+		return err
+	}
+	var legacy []string
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		legacy = append(legacy, version)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, old := range legacy {
+		match := legacyMigrationPattern.FindStringSubmatch(old)
+		if match == nil {
+			return fmt.Errorf("unrecognised migration version %q in schema_migrations", old)
+		}
+		version, _ := strconv.Atoi(match[1])
+
+		var checksum sql.NullString
+		for _, m := range migrations {
+			if m.Version == version {
+				checksum = sql.NullString{String: m.Checksum, Valid: true}
+			}
+		}
+
+		if _, err := conn.ExecContext(ctx,
+			"UPDATE schema_migrations SET version = $1, name = $2, checksum = $3 WHERE version = $4",
+			strconv.Itoa(version), match[2], checksum, old,
+		); err != nil {
+			return fmt.Errorf("error upgrading migration record %s: %w", old, err)
+		}
+		log.Printf("Upgraded migration record %s", old)
+	}
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, COALESCE(name, ''), checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version string
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(version)
+		if err != nil {
+			return nil, fmt.Errorf("unrecognised migration version %q in schema_migrations", version)
+		}
+		applied[n] = a
+	}
+	return applied, rows.Err()
+}
+
+// MigrateUp applies every pending migration in order, each in its own
+// transaction. It refuses to run if an applied migration's file has changed.
+func (db *DB) MigrateUp(ctx context.Context, migrations []Migration) (int, error) {
+	count := 0
+	err := db.withMigrationLock(ctx, migrations, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if a, ok := applied[m.Version]; ok && a.checksum.Valid && a.checksum.String != m.Checksum {
+				return fmt.Errorf("%w: %03d_%s", ErrMigrationModified, m.Version, m.Name)
+			}
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m.Up,
+				"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+				strconv.Itoa(m.Version), m.Name, m.Checksum,
+			); err != nil {
+				return fmt.Errorf("error applying migration %03d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Applied migration %03d_%s", m.Version, m.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrateDown rolls back the most recently applied migrations, newest first
+func (db *DB) MigrateDown(ctx context.Context, migrations []Migration, steps int) (int, error) {
+	count := 0
+	err := db.withMigrationLock(ctx, migrations, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		byVersion := make(map[int]Migration, len(migrations))
+		for _, m := range migrations {
+			byVersion[m.Version] = m
+		}
+
+		for _, v := range versions {
+			if count == steps {
+				break
+			}
+			m, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("migration %03d_%s is not in the migration files", v, applied[v].name)
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %03d_%s has no down file", m.Version, m.Name)
+			}
+			if err := runMigration(ctx, conn, m.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", strconv.Itoa(m.Version),
+			); err != nil {
+				return fmt.Errorf("error rolling back migration %03d_%s: %w", m.Version, m.Name, err)
+			}
+			log.Printf("Rolled back migration %03d_%s", m.Version, m.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// runMigration executes a migration's SQL and its schema_migrations update in
+// one transaction
+func runMigration(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MigrationStatus lists every known or applied migration in version order
+func (db *DB) MigrationStatus(ctx context.Context, migrations []Migration) ([]MigrationState, error) {
+	var states []MigrationState
+	err := db.withMigrationLock(ctx, migrations, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			state := MigrationState{Version: m.Version, Name: m.Name}
+			if a, ok := applied[m.Version]; ok {
+				state.Applied = true
+				state.AppliedAt = a.appliedAt
+				state.Modified = a.checksum.Valid && a.checksum.String != m.Checksum
+				delete(applied, m.Version)
+			}
+			states = append(states, state)
+		}
+
+		for v, a := range applied {
+			states = append(states, MigrationState{
+				Version: v, Name: a.name, Applied: true, AppliedAt: a.appliedAt, Missing: true,
+			})
+		}
+		return nil
+	})
+
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, err
+}
+
+// VerifyMigrations returns an error unless every migration in the set has
+// been applied. It reads schema_migrations without taking the migration lock,
+// so it is cheap enough to run from a readiness probe.
+func (db *DB) VerifyMigrations(ctx context.Context, migrations []Migration) error {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var pending []int
+	for _, m := range migrations {
+		if !applied[strconv.Itoa(m.Version)] {
+			pending = append(pending, m.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migrations pending, starting at version %d", len(pending), pending[0])
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Jakeito/TestWebsite/backend/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_add_index.up.sql":   {Data: []byte("CREATE INDEX idx ON t(a);")},
+		"002_add_index.down.sql": {Data: []byte("DROP INDEX idx;")},
+		"001_create.up.sql":      {Data: []byte("CREATE TABLE t (a INT);")},
+		"001_create.down.sql":    {Data: []byte("DROP TABLE t;")},
+		"010_no_down.up.sql":     {Data: []byte("SELECT 1;")},
+		"migrations.go":          {Data: []byte("package migrations")},
+	}
+
+	got, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 migrations, got %d", len(got))
+	}
+	for i, want := range []int{1, 2, 10} {
+		if got[i].Version != want {
+			t.Errorf("Migration %d has version %d, want %d", i, got[i].Version, want)
+		}
+	}
+
+	if got[0].Name != "create" || got[0].Up != "CREATE TABLE t (a INT);" || got[0].Down != "DROP TABLE t;" {
+		t.Errorf("Unexpected first migration: %+v", got[0])
+	}
+	if got[0].Checksum != migrationChecksum([]byte(got[0].Up)) || len(got[0].Checksum) != 64 {
+		t.Errorf("Unexpected checksum %q", got[0].Checksum)
+	}
+	if got[2].Down != "" {
+		t.Error("Expected migration without a down file to have empty Down")
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{"001_create.down.sql": {Data: []byte("DROP TABLE t;")}},
+			want: "no up file",
+		},
+		{
+			name: "legacy name",
+			fsys: fstest.MapFS{"001_create.sql": {Data: []byte("CREATE TABLE t (a INT);")}},
+			want: "not named",
+		},
+		{
+			name: "mismatched names",
+			fsys: fstest.MapFS{
+				"001_create.up.sql":  {Data: []byte("CREATE TABLE t (a INT);")},
+				"001_other.down.sql": {Data: []byte("DROP TABLE t;")},
+			},
+			want: "both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	got, err := LoadMigrations(migrations.Files)
+	if err != nil {
+		t.Fatalf("Embedded migrations failed to load: %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatal("No embedded migrations found")
+	}
+
+	for i, m := range got {
+		if m.Version != i+1 {
+			t.Errorf("Migration %03d_%s is out of sequence, expected version %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("Migration %03d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
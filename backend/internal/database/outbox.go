@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.OutboxStore = (*DB)(nil)
+
+// queueEmails adds emails to the outbox as part of tx
+func queueEmails(ctx context.Context, tx *sql.Tx, emails []store.OutboxEmail) error {
+	for _, email := range emails {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO email_outbox (recipient, reply_to, subject, body) VALUES ($1, $2, $3, $4)`,
+			email.To, nullString(email.ReplyTo), email.Subject, email.Body,
+		); err != nil {
+			return fmt.Errorf("error queueing email: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClaimEmails pushes the next attempt of the claimed emails out by lease.
+// SKIP LOCKED lets several server instances run workers side by side.
+func (db *DB) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]store.OutboxEmail, error) {
+	rows, err := db.QueryContext(ctx,
+		`UPDATE email_outbox SET next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, reply_to, subject, body, attempts`,
+		limit, lease.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []store.OutboxEmail
+	for rows.Next() {
+		var email store.OutboxEmail
+		var replyTo sql.NullString
+		if err := rows.Scan(&email.ID, &email.To, &replyTo, &email.Subject, &email.Body, &email.Attempts); err != nil {
+			return nil, err
+		}
+		email.ReplyTo = replyTo.String
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+func (db *DB) MarkEmailSent(ctx context.Context, id int) error {
+	return db.execAffected(ctx,
+		"UPDATE email_outbox SET sent_at = CURRENT_TIMESTAMP, last_error = NULL WHERE id = $1", id)
+}
+
+func (db *DB) MarkEmailFailed(ctx context.Context, id int, retryIn time.Duration, reason string) error {
+	if retryIn == 0 {
+		return db.execAffected(ctx,
+			`UPDATE email_outbox SET attempts = attempts + 1, last_error = $2, failed_at = CURRENT_TIMESTAMP
+			WHERE id = $1`,
+			id, reason,
+		)
+	}
+	return db.execAffected(ctx,
+		`UPDATE email_outbox SET attempts = attempts + 1, last_error = $2,
+			next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		WHERE id = $1`,
+		id, reason, retryIn.Seconds(),
+	)
+}
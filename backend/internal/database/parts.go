@@ -0,0 +1,166 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.PartStore = (*DB)(nil)
+
+const partColumns = `id, entry_id, name, brand, part_number, vendor, category, price, quantity,
+	purchase_date, status, created_at, updated_at`
+
+func scanPart(row rowScanner) (*models.Part, error) {
+	var part models.Part
+	var entryID sql.NullInt64
+	var brand, partNumber, vendor, category sql.NullString
+	var purchaseDate sql.NullTime
+	if err := row.Scan(
+		&part.ID, &entryID, &part.Name, &brand, &partNumber, &vendor, &category,
+		&part.Price, &part.Quantity, &purchaseDate, &part.Status, &part.CreatedAt, &part.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	part.EntryID = intPtr(entryID)
+	part.Brand = brand.String
+	part.PartNumber = partNumber.String
+	part.Vendor = vendor.String
+	part.Category = category.String
+	part.PurchaseDate = timePtr(purchaseDate)
+	return &part, nil
+}
+
+// partConditions adds the conditions of filter to q
+func partConditions(q *listQuery, filter store.PartFilter) {
+	if filter.EntryID != nil {
+		q.add("entry_id = " + q.arg(*filter.EntryID))
+	}
+	if filter.Status != "" {
+		q.add("status = " + q.arg(filter.Status))
+	}
+	if filter.Category != "" {
+		q.add("category = " + q.arg(filter.Category))
+	}
+	if filter.Vendor != "" {
+		q.add("vendor = " + q.arg(filter.Vendor))
+	}
+	if filter.Since != nil {
+		q.add("purchase_date >= " + q.arg(*filter.Since))
+	}
+	if filter.Until != nil {
+		q.add("purchase_date < " + q.arg(*filter.Until))
+	}
+}
+
+func (db *DB) ListParts(ctx context.Context, filter store.PartFilter) ([]models.Part, int, error) {
+	var q listQuery
+	partConditions(&q, filter)
+	total, err := db.count(ctx, "parts", &q)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := q.page(filter.ListOptions, store.PartSorts, "", "purchase_date DESC NULLS LAST, id DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+partColumns+" FROM parts"+q.conditions()+page, q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	parts := []models.Part{}
+	for rows.Next() {
+		part, err := scanPart(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		parts = append(parts, *part)
+	}
+	return parts, total, rows.Err()
+}
+
+func (db *DB) GetPart(ctx context.Context, id int) (*models.Part, error) {
+	part, err := scanPart(db.QueryRowContext(ctx, "SELECT "+partColumns+" FROM parts WHERE id = $1", id))
+	return part, notFound(err)
+}
+
+func (db *DB) CreatePart(ctx context.Context, part *models.Part) error {
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO parts (entry_id, name, brand, part_number, vendor, category, price, quantity, purchase_date, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at`,
+		part.EntryID, part.Name, nullString(part.Brand), nullString(part.PartNumber), nullString(part.Vendor),
+		nullString(part.Category), part.Price, part.Quantity, nullTime(part.PurchaseDate), part.Status,
+	).Scan(&part.ID, &part.CreatedAt, &part.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return store.ErrInvalidReference
+	}
+	return err
+}
+
+func (db *DB) UpdatePart(ctx context.Context, part *models.Part) error {
+	err := db.QueryRowContext(ctx,
+		`UPDATE parts SET entry_id = $1, name = $2, brand = $3, part_number = $4, vendor = $5, category = $6,
+		price = $7, quantity = $8, purchase_date = $9, status = $10, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $11 RETURNING created_at, updated_at`,
+		part.EntryID, part.Name, nullString(part.Brand), nullString(part.PartNumber), nullString(part.Vendor),
+		nullString(part.Category), part.Price, part.Quantity, nullTime(part.PurchaseDate), part.Status, part.ID,
+	).Scan(&part.CreatedAt, &part.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return store.ErrInvalidReference
+	}
+	return notFound(err)
+}
+
+func (db *DB) DeletePart(ctx context.Context, id int) error {
+	return db.execAffected(ctx, "DELETE FROM parts WHERE id = $1", id)
+}
+
+// spendKeys are the expressions parts are grouped by in PartSpend
+var spendKeys = map[string]string{
+	"category": "COALESCE(category, '')",
+	"month":    "COALESCE(TO_CHAR(purchase_date, 'YYYY-MM'), '')",
+	"vendor":   "COALESCE(vendor, '')",
+}
+
+func (db *DB) PartSpend(ctx context.Context, filter store.PartFilter, group string) (*models.PartSpend, error) {
+	key, ok := spendKeys[group]
+	if !ok {
+		return nil, fmt.Errorf("unknown spend group %q", group)
+	}
+	order := "key = '', total DESC, key"
+	if group == "month" {
+		order = "key = '', key"
+	}
+
+	var q listQuery
+	partConditions(&q, filter)
+	q.add("status <> " + q.arg(models.PartReturned))
+	rows, err := db.QueryContext(ctx,
+		"SELECT key, total, parts FROM (SELECT "+key+" AS key, SUM(price * quantity) AS total, COUNT(*) AS parts FROM parts"+
+			q.conditions()+" GROUP BY 1) totals ORDER BY "+order,
+		q.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spend := &models.PartSpend{Group: group, Totals: []models.SpendTotal{}}
+	for rows.Next() {
+		var total models.SpendTotal
+		if err := rows.Scan(&total.Key, &total.Total, &total.Parts); err != nil {
+			return nil, err
+		}
+		spend.Totals = append(spend.Totals, total)
+		spend.Total += total.Total
+	}
+	spend.Total = math.Round(spend.Total*100) / 100
+	return spend, rows.Err()
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.RevisionStore = (*DB)(nil)
+
+const revisionColumns = `r.id, r.content_type, r.content_id, r.version, r.action, r.author_id,
+	COALESCE(u.username, ''), r.created_at`
+
+const revisionFrom = "content_revisions r LEFT JOIN users u ON u.id = r.author_id"
+
+func scanRevision(row rowScanner, extra ...interface{}) (*models.ContentRevision, error) {
+	var revision models.ContentRevision
+	var authorID sql.NullInt64
+	dest := append([]interface{}{
+		&revision.ID, &revision.ContentType, &revision.ContentID, &revision.Version, &revision.Action,
+		&authorID, &revision.Author, &revision.CreatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	revision.AuthorID = intPtr(authorID)
+	return &revision, nil
+}
+
+func (db *DB) ListRevisions(ctx context.Context, contentType string, contentID int, opts store.ListOptions) ([]models.ContentRevision, int, error) {
+	var q listQuery
+	q.add("r.content_type = " + q.arg(contentType))
+	q.add("r.content_id = " + q.arg(contentID))
+	total, err := db.count(ctx, "content_revisions r", &q)
+	if err != nil {
+		return nil, 0, err
+	}
+	page, err := q.page(opts, nil, "r.", "r.version DESC")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+revisionColumns+" FROM "+revisionFrom+q.conditions()+page, q.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var revisions []models.ContentRevision
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		revisions = append(revisions, *revision)
+	}
+	return revisions, total, rows.Err()
+}
+
+func (db *DB) GetRevision(ctx context.Context, contentType string, contentID, version int) (*models.ContentRevision, error) {
+	var snapshot []byte
+	revision, err := scanRevision(db.QueryRowContext(ctx,
+		"SELECT "+revisionColumns+", r.snapshot FROM "+revisionFrom+`
+		WHERE r.content_type = $1 AND r.content_id = $2 AND r.version = $3`,
+		contentType, contentID, version,
+	), &snapshot)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	revision.Snapshot = snapshot
+	return revision, nil
+}
+
+func (db *DB) ListTrash(ctx context.Context) ([]models.TrashItem, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT $1::text, id, title, version, deleted_at FROM about_content WHERE deleted_at IS NOT NULL
+		UNION ALL SELECT $2::text, id, title, version, deleted_at FROM resume_sections WHERE deleted_at IS NOT NULL
+		UNION ALL SELECT $3::text, id, title, version, deleted_at FROM car_build_entries WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC`,
+		models.AboutContentType, models.ResumeContentType, models.CarBuildContentType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.TrashItem
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.ContentType, &item.ID, &item.Title, &item.Version, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -1,14 +1,20 @@
 package database
 
 import (
+	"context"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/storage"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
 )
 
 // SeedGalleryImages populates the gallery_images table from the public/images directory
-func (db *DB) SeedGalleryImages() error {
+func (db *DB) SeedGalleryImages(blobs storage.Backend) error {
+	ctx := context.Background()
+
 	log.Println("Starting image seeding process...")
 
 	// Check if images already exist
@@ -45,9 +51,13 @@ func (db *DB) SeedGalleryImages() error {
 		return nil
 	}
 
-	folders := []string{"gallery", "about", "carbuild", "hero"}
+	folders, err := db.ListFolders(ctx, false)
+	if err != nil {
+		return err
+	}
 
-	for _, folder := range folders {
+	for _, f := range folders {
+		folder := f.Slug
 		folderPath := filepath.Join(baseDir, folder)
 
 		// Check if folder exists
@@ -97,12 +107,8 @@ func (db *DB) SeedGalleryImages() error {
 			// Determine content type
 			contentType := getContentType(filename)
 
-			// Insert into database
-			var id int
-			err = db.QueryRow(
-				"INSERT INTO gallery_images (folder, filename, image_data, content_type) VALUES ($1, $2, $3, $4) RETURNING id",
-				folder, filename, fileData, contentType,
-			).Scan(&id)
+			// Store image and insert into database
+			id, err := store.SaveImage(ctx, db, blobs, folder, filename, contentType, fileData)
 
 			if err != nil {
 				log.Printf("Error inserting image %s: %v", filename, err)
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.SessionStore = (*DB)(nil)
+
+// CreateSession stores the first refresh token of a new session family
+func (db *DB) CreateSession(ctx context.Context, familyID string, userID int, tokenHash string, ttl time.Duration) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO sessions (family_id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))`,
+		familyID, userID, tokenHash, ttl.Seconds(),
+	)
+	return err
+}
+
+// RotateSession exchanges a refresh token for a new one in the same family.
+// Presenting a token that was already rotated means it leaked, so the whole
+// family is revoked and store.ErrRefreshTokenReused is returned.
+func (db *DB) RotateSession(ctx context.Context, oldHash, newHash string, ttl time.Duration) (familyID string, userID int, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	var expired bool
+	var rotatedAt, revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, family_id, user_id, expires_at <= CURRENT_TIMESTAMP, rotated_at, revoked_at
+		FROM sessions WHERE token_hash = $1 FOR UPDATE`,
+		oldHash,
+	).Scan(&id, &familyID, &userID, &expired, &rotatedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return "", 0, store.ErrSessionNotFound
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	if revokedAt.Valid {
+		return "", 0, store.ErrSessionRevoked
+	}
+
+	if rotatedAt.Valid {
+		logging.FromContext(ctx).Warn("refresh token reuse detected, revoking session family", "family_id", familyID)
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL",
+			familyID,
+		); err != nil {
+			return "", 0, fmt.Errorf("error revoking session family: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return "", 0, err
+		}
+		return "", 0, store.ErrRefreshTokenReused
+	}
+
+	if expired {
+		return "", 0, store.ErrSessionExpired
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE sessions SET rotated_at = CURRENT_TIMESTAMP WHERE id = $1", id); err != nil {
+		return "", 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO sessions (family_id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))`,
+		familyID, userID, newHash, ttl.Seconds(),
+	); err != nil {
+		return "", 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", 0, err
+	}
+
+	return familyID, userID, nil
+}
+
+// RevokeSession revokes every refresh token in a session family
+func (db *DB) RevokeSession(ctx context.Context, familyID string) error {
+	_, err := db.ExecContext(ctx,
+		"UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE family_id = $1 AND revoked_at IS NULL",
+		familyID,
+	)
+	return err
+}
+
+// IsSessionActive reports whether a session family can still authenticate requests
+func (db *DB) IsSessionActive(ctx context.Context, familyID string) (bool, error) {
+	var active bool
+	err := db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM sessions WHERE family_id = $1
+		AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP)`,
+		familyID,
+	).Scan(&active)
+	return active, err
+}
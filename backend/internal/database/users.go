@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+var _ store.UserStore = (*DB)(nil)
+
+// The remaining lockout is computed in SQL so it doesn't depend on the
+// database and server clocks agreeing
+const userColumns = `id, email, password_hash, username, is_admin, created_at, updated_at, failed_login_count,
+	GREATEST(EXTRACT(EPOCH FROM (locked_until - CURRENT_TIMESTAMP)), 0)`
+
+func (db *DB) getUser(ctx context.Context, where string, arg interface{}) (*models.User, error) {
+	var user models.User
+	var lockedSeconds sql.NullFloat64
+	err := db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE "+where, arg).Scan(
+		&user.ID, &user.Email, &user.PasswordHash, &user.Username, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt,
+		&user.FailedLogins, &lockedSeconds,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	user.LockedFor = time.Duration(lockedSeconds.Float64 * float64(time.Second))
+	return &user, nil
+}
+
+func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return db.getUser(ctx, "email = $1", email)
+}
+
+func (db *DB) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	return db.getUser(ctx, "id = $1", id)
+}
+
+func (db *DB) CreateUser(ctx context.Context, user *models.User) error {
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO users (email, password_hash, username, is_admin) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		user.Email, user.PasswordHash, user.Username, user.IsAdmin,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if isUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	return err
+}
+
+func (db *DB) RecordLoginFailure(ctx context.Context, userID int) (int, error) {
+	var failures int
+	err := db.QueryRowContext(ctx,
+		"UPDATE users SET failed_login_count = failed_login_count + 1 WHERE id = $1 RETURNING failed_login_count",
+		userID,
+	).Scan(&failures)
+	if err != nil {
+		return 0, notFound(err)
+	}
+	return failures, nil
+}
+
+func (db *DB) LockUser(ctx context.Context, userID int, d time.Duration) error {
+	return db.execAffected(ctx,
+		"UPDATE users SET locked_until = CURRENT_TIMESTAMP + make_interval(secs => $2) WHERE id = $1",
+		userID, d.Seconds(),
+	)
+}
+
+func (db *DB) ResetLoginFailures(ctx context.Context, userID int) error {
+	return db.execAffected(ctx,
+		"UPDATE users SET failed_login_count = 0, locked_until = NULL WHERE id = $1",
+		userID,
+	)
+}
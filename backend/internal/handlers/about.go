@@ -1,69 +1,130 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/Jakeito/TestWebsite/backend/internal/database"
 	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
 )
 
 type AboutHandler struct {
-	DB *database.DB
+	Store store.AboutStore
+	// Images resolves the gallery images embedded in the Markdown
+	Images store.ImageStore
 }
 
-func NewAboutHandler(db *database.DB) *AboutHandler {
-	return &AboutHandler{DB: db}
+func NewAboutHandler(about store.AboutStore, images store.ImageStore) *AboutHandler {
+	return &AboutHandler{Store: about, Images: images}
 }
 
+// listAboutContent lists about content, newest first unless sorted
+// otherwise. When publicOnly is set only live content is listed; otherwise
+// status narrows the list to one status.
+func (h *AboutHandler) listAboutContent(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	q := parseListQuery(r, store.AboutSorts, 0)
+	filter := store.AboutFilter{
+		StatusFilter: q.statusFilter(publicOnly),
+		ListOptions:  q.opts,
+	}
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	contents, total, err := h.Store.ListAboutContent(r.Context(), filter)
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	renderContent(r.Context(), h.Images, contents)
+	q.writeHeaders(w, total)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contents)
+}
+
+// GetAboutContent lists the about content the public may see
 func (h *AboutHandler) GetAboutContent(w http.ResponseWriter, r *http.Request) {
-	rows, err := h.DB.Query(
-		"SELECT id, title, content, image_url, created_at, updated_at FROM about_content ORDER BY created_at DESC",
-	)
+	h.listAboutContent(w, r, true)
+}
+
+// ListAboutContent lists about content in every status
+func (h *AboutHandler) ListAboutContent(w http.ResponseWriter, r *http.Request) {
+	h.listAboutContent(w, r, false)
+}
+
+// validateAboutContent normalizes the status, drops any rendered HTML sent
+// back with the content and returns an error message for each invalid field
+func validateAboutContent(content *models.AboutContent) map[string]string {
+	fields := make(map[string]string)
+	content.ContentHTML = ""
+	checkText(fields, "title", "Title", content.Title, true, maxTitleLength)
+	checkText(fields, "image_url", "Image URL", content.ImageURL, false, 500)
+	checkPublication(fields, &content.Publication)
+	return fields
+}
+
+// showAboutContent returns one piece of content with its ETag. When
+// publicOnly is set, content that isn't live is treated as missing.
+func (h *AboutHandler) showAboutContent(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		problem.Write(w, problem.InvalidID, "Invalid ID")
 		return
 	}
-	defer rows.Close()
 
-	var contents []models.AboutContent
-	for rows.Next() {
-		var content models.AboutContent
-		var imageURL sql.NullString
-		if err := rows.Scan(&content.ID, &content.Title, &content.Content, &imageURL, &content.CreatedAt, &content.UpdatedAt); err != nil {
-			http.Error(w, "Error scanning row", http.StatusInternalServerError)
-			return
-		}
-		if imageURL.Valid {
-			content.ImageURL = imageURL.String
-		}
-		contents = append(contents, content)
+	content, err := h.Store.GetAboutContent(r.Context(), id)
+	if err == nil && publicOnly && !content.Live(time.Now()) {
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Content not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
 	}
 
+	renderContent(r.Context(), h.Images, content)
+	w.Header().Set("ETag", versionETag(content.Version))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(contents)
+	json.NewEncoder(w).Encode(content)
+}
+
+// GetAboutContentByID returns one piece of live content
+func (h *AboutHandler) GetAboutContentByID(w http.ResponseWriter, r *http.Request) {
+	h.showAboutContent(w, r, true)
+}
+
+// ShowAboutContent returns one piece of content in any status
+func (h *AboutHandler) ShowAboutContent(w http.ResponseWriter, r *http.Request) {
+	h.showAboutContent(w, r, false)
 }
 
 func (h *AboutHandler) CreateAboutContent(w http.ResponseWriter, r *http.Request) {
 	var content models.AboutContent
 	if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+	if fields := validateAboutContent(&content); len(fields) > 0 {
+		problem.Validation(w, fields)
 		return
 	}
 
-	err := h.DB.QueryRow(
-		"INSERT INTO about_content (title, content, image_url) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at",
-		content.Title, content.Content, nullString(content.ImageURL),
-	).Scan(&content.ID, &content.CreatedAt, &content.UpdatedAt)
-
-	if err != nil {
-		http.Error(w, "Error creating content", http.StatusInternalServerError)
+	if err := h.Store.CreateAboutContent(r.Context(), &content, currentUserID(r)); err != nil {
+		problem.Write(w, problem.Internal, "Error creating content")
 		return
 	}
 
+	renderContent(r.Context(), h.Images, &content)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(content)
@@ -73,41 +134,129 @@ func (h *AboutHandler) UpdateAboutContent(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid ID")
 		return
 	}
 
 	var content models.AboutContent
 	if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+	if fields := validateAboutContent(&content); len(fields) > 0 {
+		problem.Validation(w, fields)
 		return
 	}
+	content.ID, content.Version = id, 0
 
-	_, err = h.DB.Exec(
-		"UPDATE about_content SET title = $1, content = $2, image_url = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4",
-		content.Title, content.Content, nullString(content.ImageURL), id,
-	)
+	// With If-Match the update only applies to the version the client read
+	if r.Header.Get("If-Match") != "" {
+		current, err := h.Store.GetAboutContent(r.Context(), id)
+		if errors.Is(err, store.ErrNotFound) {
+			problem.Write(w, problem.NotFound, "Content not found")
+			return
+		}
+		if err != nil {
+			problem.Write(w, problem.Internal, "Database error")
+			return
+		}
+		if !checkIfMatch(w, r, current.Version) {
+			return
+		}
+		content.Version = current.Version
+	}
 
+	err = h.Store.UpdateAboutContent(r.Context(), &content, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Content not found")
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeStale(w)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error updating content", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error updating content")
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(content.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Content updated successfully"})
 }
 
+// PatchAboutContent applies a JSON Merge Patch. The write is conditional on
+// the version the patch was applied to, so concurrent edits get 412.
+func (h *AboutHandler) PatchAboutContent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := h.Store.GetAboutContent(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Content not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+	if !checkIfMatch(w, r, current.Version) {
+		return
+	}
+
+	content, err := applyMergePatch(current, patch)
+	if err != nil {
+		problem.Write(w, problem.InvalidBody, err.Error())
+		return
+	}
+	content.ID, content.Version = id, current.Version
+	if fields := validateAboutContent(content); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+
+	err = h.Store.UpdateAboutContent(r.Context(), content, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Content not found")
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeStale(w)
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error updating content")
+		return
+	}
+
+	renderContent(r.Context(), h.Images, content)
+	w.Header().Set("ETag", versionETag(content.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
+
 func (h *AboutHandler) DeleteAboutContent(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid ID")
 		return
 	}
 
-	_, err = h.DB.Exec("DELETE FROM about_content WHERE id = $1", id)
+	err = h.Store.DeleteAboutContent(r.Context(), id, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Content not found")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error deleting content", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error deleting content")
 		return
 	}
 
@@ -1,62 +1,180 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/Jakeito/TestWebsite/backend/internal/auth"
-	"github.com/Jakeito/TestWebsite/backend/internal/database"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/Jakeito/TestWebsite/backend/internal/middleware"
 	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
 )
 
 type AuthHandler struct {
-	DB        *database.DB
+	Users     store.UserStore
+	Sessions  store.SessionStore
 	JWTSecret string
 }
 
-func NewAuthHandler(db *database.DB, jwtSecret string) *AuthHandler {
+func NewAuthHandler(users store.UserStore, sessions store.SessionStore, jwtSecret string) *AuthHandler {
 	return &AuthHandler{
-		DB:        db,
+		Users:     users,
+		Sessions:  sessions,
 		JWTSecret: jwtSecret,
 	}
 }
 
+// Login exchanges credentials for an access/refresh token pair. Repeated
+// failures lock the account with exponential backoff (see auth.LockoutDuration);
+// a locked account is refused without checking the password.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
 		return
 	}
 
-	var user models.User
-	err := h.DB.QueryRow(
-		"SELECT id, email, password_hash, username, is_admin, created_at, updated_at FROM users WHERE email = $1",
-		req.Email,
-	).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Username, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
-
-	if err == sql.ErrNoRows {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	user, err := h.Users.GetUserByEmail(r.Context(), req.Email)
+	if errors.Is(err, store.ErrNotFound) {
+		metrics.Logins.Inc(metrics.ResultFailure)
+		problem.Write(w, problem.InvalidCredentials, "Invalid credentials")
 		return
 	} else if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	if user.LockedFor > 0 {
+		metrics.Logins.Inc(metrics.ResultLocked)
+		middleware.TooManyRequests(w, user.LockedFor, problem.AccountLocked, "Too many failed login attempts, try again later")
 		return
 	}
 
 	if !auth.CheckPasswordHash(req.Password, user.PasswordHash) {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		metrics.Logins.Inc(metrics.ResultFailure)
+		h.recordLoginFailure(r, user.ID)
+		problem.Write(w, problem.InvalidCredentials, "Invalid credentials")
+		return
+	}
+
+	if user.FailedLogins > 0 {
+		if err := h.Users.ResetLoginFailures(r.Context(), user.ID); err != nil {
+			logging.FromContext(r.Context()).Error("error resetting login failures", "user_id", user.ID, "error", err)
+		}
+	}
+
+	sessionID, err := auth.GenerateSessionID()
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error creating session")
+		return
+	}
+
+	refreshToken, refreshHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error creating session")
+		return
+	}
+
+	if err := h.Sessions.CreateSession(r.Context(), sessionID, user.ID, refreshHash, auth.RefreshTokenTTL); err != nil {
+		problem.Write(w, problem.Internal, "Error creating session")
+		return
+	}
+
+	metrics.Logins.Inc(metrics.ResultSuccess)
+	h.writeTokens(w, *user, sessionID, refreshToken)
+}
+
+// recordLoginFailure counts a failed password and locks the account once the
+// failures reach auth.LockoutThreshold
+func (h *AuthHandler) recordLoginFailure(r *http.Request, userID int) {
+	logger := logging.FromContext(r.Context())
+
+	failures, err := h.Users.RecordLoginFailure(r.Context(), userID)
+	if err != nil {
+		logger.Error("error recording login failure", "user_id", userID, "error", err)
+		return
+	}
+
+	if d := auth.LockoutDuration(failures); d > 0 {
+		logger.Warn("locking account after failed logins", "user_id", userID, "failures", failures, "lockout", d.String())
+		if err := h.Users.LockUser(r.Context(), userID, d); err != nil {
+			logger.Error("error locking account", "user_id", userID, "error", err)
+		}
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new access/refresh token pair
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+
+	newToken, newHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error creating session")
+		return
+	}
+
+	sessionID, userID, err := h.Sessions.RotateSession(r.Context(), auth.HashRefreshToken(req.RefreshToken), newHash, auth.RefreshTokenTTL)
+	switch {
+	case errors.Is(err, store.ErrSessionNotFound),
+		errors.Is(err, store.ErrSessionRevoked),
+		errors.Is(err, store.ErrSessionExpired),
+		errors.Is(err, store.ErrRefreshTokenReused):
+		problem.Write(w, problem.InvalidToken, "Invalid or expired refresh token")
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("error rotating session", "error", err)
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	user, err := h.Users.GetUserByID(r.Context(), userID)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.InvalidToken, "Invalid or expired refresh token")
+		return
+	} else if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	h.writeTokens(w, *user, sessionID, newToken)
+}
+
+// Logout revokes the session behind the caller's access token
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims)
+	if !ok {
+		problem.Write(w, problem.Unauthorized, "Authorization header required")
+		return
+	}
+
+	if err := h.Sessions.RevokeSession(r.Context(), claims.SessionID); err != nil {
+		problem.Write(w, problem.Internal, "Database error")
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Email, user.IsAdmin, h.JWTSecret)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) writeTokens(w http.ResponseWriter, user models.User, sessionID, refreshToken string) {
+	token, err := auth.GenerateToken(user.ID, user.Email, user.IsAdmin, sessionID, h.JWTSecret)
 	if err != nil {
-		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error generating token")
 		return
 	}
 
 	response := models.LoginResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		User:         user,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -71,30 +189,30 @@ func (h *AuthHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
 		return
 	}
 
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "Error hashing password", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error hashing password")
 		return
 	}
 
-	var userID int
-	err = h.DB.QueryRow(
-		"INSERT INTO users (email, password_hash, username) VALUES ($1, $2, $3) RETURNING id",
-		req.Email, hashedPassword, req.Username,
-	).Scan(&userID)
-
+	user := models.User{Email: req.Email, PasswordHash: hashedPassword, Username: req.Username}
+	err = h.Users.CreateUser(r.Context(), &user)
+	if errors.Is(err, store.ErrConflict) {
+		problem.Write(w, problem.Conflict, "A user with that email already exists")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error creating user", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error creating user")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":      userID,
+		"id":      user.ID,
 		"message": "User created successfully",
 	})
 }
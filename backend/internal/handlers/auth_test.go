@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/auth"
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+func TestLoginRejectsBadPassword(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.request("POST", "/api/login", models.LoginRequest{Email: testAdminEmail, Password: "wrong"}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = s.request("POST", "/api/login", models.LoginRequest{Email: "nobody@example.com", Password: testAdminPassword}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	s := newTestServer(t)
+	first := s.login(testAdminEmail, testAdminPassword)
+
+	w := s.request("POST", "/api/token/refresh", models.RefreshRequest{RefreshToken: first.RefreshToken}, "")
+	expectStatus(t, w, http.StatusOK)
+	var second models.LoginResponse
+	decode(t, w, &second)
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("Expected a new refresh token, got %q", second.RefreshToken)
+	}
+
+	// Replaying the rotated token revokes the whole family
+	w = s.request("POST", "/api/token/refresh", models.RefreshRequest{RefreshToken: first.RefreshToken}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = s.request("POST", "/api/token/refresh", models.RefreshRequest{RefreshToken: second.RefreshToken}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = s.request("GET", "/api/contact", nil, second.Token)
+	expectStatus(t, w, http.StatusUnauthorized)
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	s := newTestServer(t)
+	session := s.login(testAdminEmail, testAdminPassword)
+
+	w := s.request("GET", "/api/contact", nil, session.Token)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.request("POST", "/api/logout", nil, session.Token)
+	expectStatus(t, w, http.StatusNoContent)
+
+	w = s.request("GET", "/api/contact", nil, session.Token)
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	// Other sessions are unaffected
+	w = s.admin("GET", "/api/contact", nil)
+	expectStatus(t, w, http.StatusOK)
+}
+
+func TestCreateUser(t *testing.T) {
+	s := newTestServer(t)
+	user := map[string]interface{}{"email": "editor@example.com", "password": "secret123", "username": "Editor"}
+
+	w := s.admin("POST", "/api/users", user)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.admin("POST", "/api/users", user)
+	expectStatus(t, w, http.StatusConflict)
+
+	editor := s.login("editor@example.com", "secret123")
+	if editor.User.IsAdmin {
+		t.Fatal("Expected new user to not be an admin")
+	}
+	w = s.request("GET", "/api/contact", nil, editor.Token)
+	expectStatus(t, w, http.StatusForbidden)
+}
+
+func TestLoginMetrics(t *testing.T) {
+	s := newTestServer(t)
+	success := metrics.Logins.Value(metrics.ResultSuccess)
+	failure := metrics.Logins.Value(metrics.ResultFailure)
+
+	s.login(testAdminEmail, testAdminPassword)
+	s.request("POST", "/api/login", models.LoginRequest{Email: testAdminEmail, Password: "wrong"}, "")
+
+	if got := metrics.Logins.Value(metrics.ResultSuccess) - success; got != 1 {
+		t.Errorf("Expected 1 successful login, got %v", got)
+	}
+	if got := metrics.Logins.Value(metrics.ResultFailure) - failure; got != 1 {
+		t.Errorf("Expected 1 failed login, got %v", got)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now()
+	s.store.SetClock(func() time.Time { return now })
+
+	wrong := models.LoginRequest{Email: testAdminEmail, Password: "wrong"}
+	for i := 0; i < auth.LockoutThreshold; i++ {
+		w := s.request("POST", "/api/login", wrong, "")
+		expectStatus(t, w, http.StatusUnauthorized)
+	}
+
+	// Locked out even with the right password
+	right := models.LoginRequest{Email: testAdminEmail, Password: testAdminPassword}
+	w := s.request("POST", "/api/login", right, "")
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if got := w.Header().Get("Retry-After"); got != strconv.Itoa(int(auth.LockoutBase.Seconds())) {
+		t.Fatalf("Expected Retry-After %v, got %q", auth.LockoutBase.Seconds(), got)
+	}
+
+	// Another failure after the lockout doubles it
+	now = now.Add(auth.LockoutBase)
+	w = s.request("POST", "/api/login", wrong, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+	w = s.request("POST", "/api/login", right, "")
+	expectStatus(t, w, http.StatusTooManyRequests)
+	if got := w.Header().Get("Retry-After"); got != strconv.Itoa(int(2*auth.LockoutBase.Seconds())) {
+		t.Fatalf("Expected Retry-After %v, got %q", 2*auth.LockoutBase.Seconds(), got)
+	}
+
+	// A successful login once the lockout ends resets the count
+	now = now.Add(2 * auth.LockoutBase)
+	s.login(testAdminEmail, testAdminPassword)
+	w = s.request("POST", "/api/login", wrong, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+	s.login(testAdminEmail, testAdminPassword)
+}
@@ -1,87 +1,152 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/lib/pq"
-	"github.com/Jakeito/TestWebsite/backend/internal/database"
 	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
 )
 
 type CarBuildHandler struct {
-	DB *database.DB
+	Store store.CarBuildStore
+	// Images resolves the gallery images embedded in the Markdown
+	Images store.ImageStore
 }
 
-func NewCarBuildHandler(db *database.DB) *CarBuildHandler {
-	return &CarBuildHandler{DB: db}
+func NewCarBuildHandler(carBuild store.CarBuildStore, images store.ImageStore) *CarBuildHandler {
+	return &CarBuildHandler{Store: carBuild, Images: images}
 }
 
-func (h *CarBuildHandler) GetCarBuildEntries(w http.ResponseWriter, r *http.Request) {
-	rows, err := h.DB.Query(
-		`SELECT id, title, description, date, category, cost, image_urls, 
-		display_order, created_at, updated_at FROM car_build_entries ORDER BY display_order, date DESC`,
-	)
+// listCarBuildEntries lists car build entries in display order unless sorted
+// otherwise. category, since, until, min_cost and max_cost narrow them down.
+// When publicOnly is set only live entries are listed; otherwise status
+// narrows them to one status.
+func (h *CarBuildHandler) listCarBuildEntries(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	q := parseListQuery(r, store.CarBuildSorts, 0)
+	filter := store.CarBuildFilter{
+		Category:     q.string("category"),
+		MinCost:      q.float("min_cost"),
+		MaxCost:      q.float("max_cost"),
+		StatusFilter: q.statusFilter(publicOnly),
+		ListOptions:  q.opts,
+	}
+	filter.Since, filter.Until = q.dateRange()
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	entries, total, err := h.Store.ListCarBuildEntries(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var entries []models.CarBuildEntry
-	for rows.Next() {
-		var entry models.CarBuildEntry
-		var category sql.NullString
-		var cost sql.NullFloat64
-		var imageURLs pq.StringArray
-		
-		if err := rows.Scan(
-			&entry.ID, &entry.Title, &entry.Description, &entry.Date, &category,
-			&cost, &imageURLs, &entry.DisplayOrder, &entry.CreatedAt, &entry.UpdatedAt,
-		); err != nil {
-			http.Error(w, "Error scanning row", http.StatusInternalServerError)
-			return
-		}
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
 
-		if category.Valid {
-			entry.Category = category.String
-		}
-		if cost.Valid {
-			entry.Cost = &cost.Float64
-		}
-		if len(imageURLs) > 0 {
-			entry.ImageURLs = imageURLs
-		}
+	renderContent(r.Context(), h.Images, entries)
+	q.writeHeaders(w, total)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetCarBuildEntries lists the car build entries the public may see
+func (h *CarBuildHandler) GetCarBuildEntries(w http.ResponseWriter, r *http.Request) {
+	h.listCarBuildEntries(w, r, true)
+}
 
-		entries = append(entries, entry)
+// ListCarBuildEntries lists car build entries in every status
+func (h *CarBuildHandler) ListCarBuildEntries(w http.ResponseWriter, r *http.Request) {
+	h.listCarBuildEntries(w, r, false)
+}
+
+// Largest cost the DECIMAL(10, 2) column can hold
+const maxCarBuildCost = 99999999.99
+
+// validateCarBuildEntry normalizes the status, drops any rendered HTML sent
+// back with the entry and returns an error message for each invalid field
+func validateCarBuildEntry(entry *models.CarBuildEntry) map[string]string {
+	fields := make(map[string]string)
+	entry.DescriptionHTML = ""
+	checkText(fields, "title", "Title", entry.Title, true, maxTitleLength)
+	checkText(fields, "category", "Category", entry.Category, false, 100)
+	if entry.Date.IsZero() {
+		fields["date"] = "Date is required"
+	}
+	if entry.Cost != nil && *entry.Cost < 0 {
+		fields["cost"] = "Cost must not be negative"
+	} else if entry.Cost != nil && *entry.Cost > maxCarBuildCost {
+		fields["cost"] = "Cost is too large"
+	}
+	if entry.Mileage != nil && *entry.Mileage < 0 {
+		fields["mileage"] = "Mileage must not be negative"
+	}
+	if entry.DisplayOrder < 0 {
+		fields["display_order"] = "Display order must not be negative"
+	}
+	checkPublication(fields, &entry.Publication)
+	return fields
+}
+
+// showCarBuildEntry returns one car build entry with its ETag. When
+// publicOnly is set, an entry that isn't live is treated as missing.
+func (h *CarBuildHandler) showCarBuildEntry(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	entry, err := h.Store.GetCarBuildEntry(r.Context(), id)
+	if err == nil && publicOnly && !entry.Live(time.Now()) {
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Car build entry not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
 	}
 
+	renderContent(r.Context(), h.Images, entry)
+	w.Header().Set("ETag", versionETag(entry.Version))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(entries)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetCarBuildEntry returns one live car build entry
+func (h *CarBuildHandler) GetCarBuildEntry(w http.ResponseWriter, r *http.Request) {
+	h.showCarBuildEntry(w, r, true)
+}
+
+// ShowCarBuildEntry returns one car build entry in any status
+func (h *CarBuildHandler) ShowCarBuildEntry(w http.ResponseWriter, r *http.Request) {
+	h.showCarBuildEntry(w, r, false)
 }
 
 func (h *CarBuildHandler) CreateCarBuildEntry(w http.ResponseWriter, r *http.Request) {
 	var entry models.CarBuildEntry
 	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+	if fields := validateCarBuildEntry(&entry); len(fields) > 0 {
+		problem.Validation(w, fields)
 		return
 	}
 
-	err := h.DB.QueryRow(
-		`INSERT INTO car_build_entries (title, description, date, category, cost, image_urls, display_order)
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`,
-		entry.Title, entry.Description, entry.Date, nullString(entry.Category),
-		nullFloat64(entry.Cost), pq.Array(entry.ImageURLs), entry.DisplayOrder,
-	).Scan(&entry.ID, &entry.CreatedAt, &entry.UpdatedAt)
-
-	if err != nil {
-		http.Error(w, "Error creating car build entry", http.StatusInternalServerError)
+	if err := h.Store.CreateCarBuildEntry(r.Context(), &entry, currentUserID(r)); err != nil {
+		problem.Write(w, problem.Internal, "Error creating car build entry")
 		return
 	}
 
+	renderContent(r.Context(), h.Images, &entry)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(entry)
@@ -91,43 +156,129 @@ func (h *CarBuildHandler) UpdateCarBuildEntry(w http.ResponseWriter, r *http.Req
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid ID")
 		return
 	}
 
 	var entry models.CarBuildEntry
 	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
 		return
 	}
+	if fields := validateCarBuildEntry(&entry); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+	entry.ID, entry.Version = id, 0
 
-	_, err = h.DB.Exec(
-		`UPDATE car_build_entries SET title = $1, description = $2, date = $3, category = $4,
-		cost = $5, image_urls = $6, display_order = $7, updated_at = CURRENT_TIMESTAMP WHERE id = $8`,
-		entry.Title, entry.Description, entry.Date, nullString(entry.Category),
-		nullFloat64(entry.Cost), pq.Array(entry.ImageURLs), entry.DisplayOrder, id,
-	)
+	// With If-Match the update only applies to the version the client read
+	if r.Header.Get("If-Match") != "" {
+		current, err := h.Store.GetCarBuildEntry(r.Context(), id)
+		if errors.Is(err, store.ErrNotFound) {
+			problem.Write(w, problem.NotFound, "Car build entry not found")
+			return
+		}
+		if err != nil {
+			problem.Write(w, problem.Internal, "Database error")
+			return
+		}
+		if !checkIfMatch(w, r, current.Version) {
+			return
+		}
+		entry.Version = current.Version
+	}
 
+	err = h.Store.UpdateCarBuildEntry(r.Context(), &entry, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Car build entry not found")
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeStale(w)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error updating car build entry", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error updating car build entry")
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(entry.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Car build entry updated successfully"})
 }
 
+// PatchCarBuildEntry applies a JSON Merge Patch. The write is conditional on
+// the version the patch was applied to, so concurrent edits get 412.
+func (h *CarBuildHandler) PatchCarBuildEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := h.Store.GetCarBuildEntry(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Car build entry not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+	if !checkIfMatch(w, r, current.Version) {
+		return
+	}
+
+	entry, err := applyMergePatch(current, patch)
+	if err != nil {
+		problem.Write(w, problem.InvalidBody, err.Error())
+		return
+	}
+	entry.ID, entry.Version = id, current.Version
+	if fields := validateCarBuildEntry(entry); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+
+	err = h.Store.UpdateCarBuildEntry(r.Context(), entry, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Car build entry not found")
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeStale(w)
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error updating car build entry")
+		return
+	}
+
+	renderContent(r.Context(), h.Images, entry)
+	w.Header().Set("ETag", versionETag(entry.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
 func (h *CarBuildHandler) DeleteCarBuildEntry(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid ID")
 		return
 	}
 
-	_, err = h.DB.Exec("DELETE FROM car_build_entries WHERE id = $1", id)
+	err = h.Store.DeleteCarBuildEntry(r.Context(), id, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Car build entry not found")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error deleting car build entry", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error deleting car build entry")
 		return
 	}
 
@@ -1,41 +1,123 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
-	"github.com/Jakeito/TestWebsite/backend/internal/database"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/mailer"
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
 	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/spam"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// Length limits for contact form fields, in characters
+const (
+	maxContactFieldLength   = 255
+	maxContactMessageLength = 5000
 )
 
 type ContactHandler struct {
-	DB *database.DB
+	Store   store.ContactStore
+	Tokens  *spam.FormTokens
+	Captcha spam.Verifier
+	Filter  spam.Filter
+	// Emails renders the notification and auto-reply queued with each
+	// submission that is not spam
+	Emails mailer.ContactEmails
+}
+
+func NewContactHandler(contact store.ContactStore, tokens *spam.FormTokens, captcha spam.Verifier, filter spam.Filter, emails mailer.ContactEmails) *ContactHandler {
+	return &ContactHandler{Store: contact, Tokens: tokens, Captcha: captcha, Filter: filter, Emails: emails}
 }
 
-func NewContactHandler(db *database.DB) *ContactHandler {
-	return &ContactHandler{DB: db}
+// GetFormToken returns a token the contact form sends back on submit, which
+// proves how long ago the form was loaded
+func (h *ContactHandler) GetFormToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.Tokens.Issue()
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error issuing form token", "error", err)
+		problem.Write(w, problem.Internal, "Error issuing form token")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
 }
 
+// SubmitContact stores a contact form submission. Invalid fields and failed
+// CAPTCHAs are rejected; submissions that trip the honeypot, form token or
+// content checks are accepted as usual but filed as spam, so bots learn
+// nothing and false positives can be recovered.
 func (h *ContactHandler) SubmitContact(w http.ResponseWriter, r *http.Request) {
-	var submission models.ContactSubmission
-	if err := json.NewDecoder(r.Body).Decode(&submission); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var req models.ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
 		return
 	}
 
-	err := h.DB.QueryRow(
-		`INSERT INTO contact_submissions (name, email, subject, message)
-		VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
-		submission.Name, submission.Email, nullString(submission.Subject), submission.Message,
-	).Scan(&submission.ID, &submission.CreatedAt)
+	submission := models.ContactSubmission{
+		Name:    strings.TrimSpace(req.Name),
+		Email:   strings.TrimSpace(req.Email),
+		Subject: strings.TrimSpace(req.Subject),
+		Message: strings.TrimSpace(req.Message),
+	}
+	if fields := validateContact(&submission); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
 
+	if h.Captcha.Enabled() {
+		err := h.Captcha.Verify(r.Context(), req.CaptchaToken)
+		if errors.Is(err, spam.ErrCaptchaFailed) {
+			problem.Write(w, problem.CaptchaFailed, "CAPTCHA verification failed")
+			return
+		}
+		if err != nil {
+			logging.FromContext(r.Context()).Error("error verifying captcha", "error", err)
+			problem.Write(w, problem.Unavailable, "Could not verify CAPTCHA")
+			return
+		}
+	}
+
+	reason, err := h.spamReason(r.Context(), &req, &submission)
 	if err != nil {
-		http.Error(w, "Error submitting contact form", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("error checking form token", "error", err)
+		problem.Write(w, problem.Internal, "Error submitting contact form")
+		return
+	}
+	submission.SpamReason = reason
+	submission.IsSpam = submission.SpamReason != ""
+
+	var emails []store.OutboxEmail
+	if !submission.IsSpam {
+		emails = h.outboxEmails(r, &submission)
+	}
+
+	if err := h.Store.CreateContactSubmission(r.Context(), &submission, emails); err != nil {
+		logging.FromContext(r.Context()).Error("error saving contact submission", "error", err)
+		problem.Write(w, problem.Internal, "Error submitting contact form")
 		return
 	}
 
+	if submission.IsSpam {
+		logging.FromContext(r.Context()).Info("contact submission marked as spam",
+			"id", submission.ID, "reason", submission.SpamReason)
+		metrics.ContactSubmissions.Inc("spam")
+	} else {
+		metrics.ContactSubmissions.Inc("inbox")
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -44,59 +126,244 @@ func (h *ContactHandler) SubmitContact(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// outboxEmails renders the emails to queue for a submission. The auto-reply
+// is only sent once a CAPTCHA has shown a person is asking for it. Rendering
+// problems are logged rather than losing the submission.
+func (h *ContactHandler) outboxEmails(r *http.Request, submission *models.ContactSubmission) []store.OutboxEmail {
+	rendering := h.Emails
+	rendering.AutoReply = rendering.AutoReply && h.Captcha.Enabled()
+	messages, err := rendering.Render(submission)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error rendering contact emails", "error", err)
+		return nil
+	}
+
+	emails := make([]store.OutboxEmail, len(messages))
+	for i, msg := range messages {
+		emails[i] = store.OutboxEmail{To: msg.To, ReplyTo: msg.ReplyTo, Subject: msg.Subject, Body: msg.Body}
+	}
+	return emails
+}
+
+// spamReason returns why a submission looks automated, or "" when it passes.
+// A passing form token is used up.
+func (h *ContactHandler) spamReason(ctx context.Context, req *models.ContactRequest, submission *models.ContactSubmission) (string, error) {
+	if req.Website != "" {
+		return "honeypot field filled in", nil
+	}
+	if err := h.Tokens.Check(ctx, req.FormToken); spam.Rejected(err) {
+		return err.Error(), nil
+	} else if err != nil {
+		return "", err
+	}
+	return h.Filter.Check(submission.Name, submission.Subject, submission.Message), nil
+}
+
+// validateContact returns an error message for each invalid field
+func validateContact(submission *models.ContactSubmission) map[string]string {
+	fields := make(map[string]string)
+
+	if submission.Name == "" {
+		fields["name"] = "Name is required"
+	} else if utf8.RuneCountInString(submission.Name) > maxContactFieldLength {
+		fields["name"] = fmt.Sprintf("Name must be at most %d characters", maxContactFieldLength)
+	}
+
+	if submission.Email == "" {
+		fields["email"] = "Email is required"
+	} else if len(submission.Email) > maxContactFieldLength {
+		fields["email"] = fmt.Sprintf("Email must be at most %d characters", maxContactFieldLength)
+	} else if addr, err := mail.ParseAddress(submission.Email); err != nil || addr.Address != submission.Email {
+		fields["email"] = "Email is not a valid address"
+	}
+
+	if utf8.RuneCountInString(submission.Subject) > maxContactFieldLength {
+		fields["subject"] = fmt.Sprintf("Subject must be at most %d characters", maxContactFieldLength)
+	}
+
+	if submission.Message == "" {
+		fields["message"] = "Message is required"
+	} else if utf8.RuneCountInString(submission.Message) > maxContactMessageLength {
+		fields["message"] = fmt.Sprintf("Message must be at most %d characters", maxContactMessageLength)
+	}
+
+	return fields
+}
+
+// Maximum length of a reply note, in characters
+const maxContactReplyLength = 10000
+
+// GetContactSubmissions lists a page of submissions, newest first unless
+// sorted otherwise. The filter query parameter picks "inbox" (the default),
+// "archived", "spam" or "all"; read, starred, since, until and q narrow it
+// down further.
 func (h *ContactHandler) GetContactSubmissions(w http.ResponseWriter, r *http.Request) {
-	rows, err := h.DB.Query(
-		`SELECT id, name, email, subject, message, is_read, created_at 
-		FROM contact_submissions ORDER BY created_at DESC`,
-	)
+	q := parseListQuery(r, store.ContactSorts, defaultPerPage)
+	filter := store.ContactFilter{ListOptions: q.opts}
+
+	yes, no := true, false
+	switch q.oneOf("filter", "inbox", "inbox", "archived", "spam", "all") {
+	case "inbox":
+		filter.Spam, filter.Archived = &no, &no
+	case "archived":
+		filter.Spam, filter.Archived = &no, &yes
+	case "spam":
+		filter.Spam = &yes
+	}
+	filter.Read = q.bool("read")
+	filter.Starred = q.bool("starred")
+	filter.Since, filter.Until = q.dateRange()
+	filter.Search = q.string("q")
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	submissions, total, err := h.Store.ListContactSubmissions(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("error listing contact submissions", "error", err)
+		problem.Write(w, problem.Internal, "Database error")
 		return
 	}
-	defer rows.Close()
+	if submissions == nil {
+		submissions = []models.ContactSubmission{}
+	}
 
-	var submissions []models.ContactSubmission
-	for rows.Next() {
-		var submission models.ContactSubmission
-		var subject sql.NullString
-		
-		if err := rows.Scan(
-			&submission.ID, &submission.Name, &submission.Email, &subject,
-			&submission.Message, &submission.IsRead, &submission.CreatedAt,
-		); err != nil {
-			http.Error(w, "Error scanning row", http.StatusInternalServerError)
-			return
-		}
+	q.writeHeaders(w, total)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submissions)
+}
 
-		if subject.Valid {
-			submission.Subject = subject.String
-		}
+// GetContactSubmission returns one submission with its reply notes
+func (h *ContactHandler) GetContactSubmission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
 
-		submissions = append(submissions, submission)
+	submission, err := h.Store.GetContactSubmission(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Contact submission not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error fetching contact submission", "id", id, "error", err)
+		problem.Write(w, problem.Internal, "Database error")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(submissions)
+	json.NewEncoder(w).Encode(submission)
 }
 
-func (h *ContactHandler) DeleteContactSubmission(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
+// UpdateContactSubmission changes the read, archived, starred and spam flags
+// of a submission; fields left out of the body are unchanged
+func (h *ContactHandler) UpdateContactSubmission(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
 
-	result, err := h.DB.Exec("DELETE FROM contact_submissions WHERE id = $1", id)
+	var update models.ContactSubmissionUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+	if update.IsRead == nil && update.IsArchived == nil && update.IsStarred == nil && update.IsSpam == nil {
+		problem.Write(w, problem.BadRequest, "No fields to update")
+		return
+	}
+
+	submission, err := h.Store.UpdateContactSubmission(r.Context(), id, update)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Contact submission not found")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("error updating contact submission", "id", id, "error", err)
+		problem.Write(w, problem.Internal, "Database error")
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submission)
+}
+
+// GetUnreadCount returns the number of unread submissions in the inbox
+func (h *ContactHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	count, err := h.Store.CountUnreadContactSubmissions(r.Context())
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("error counting unread contact submissions", "error", err)
+		problem.Write(w, problem.Internal, "Database error")
 		return
 	}
 
-	if rowsAffected == 0 {
-		http.Error(w, "Contact submission not found", http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"unread": count})
+}
+
+// CreateContactReply records a note about how the submission was answered.
+// The note is only kept for reference; no email is sent.
+func (h *ContactHandler) CreateContactReply(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+	reply := models.ContactReply{SubmissionID: id, Note: strings.TrimSpace(req.Note)}
+	if reply.Note == "" {
+		problem.Validation(w, map[string]string{"note": "Note is required"})
+		return
+	}
+	if utf8.RuneCountInString(reply.Note) > maxContactReplyLength {
+		problem.Validation(w, map[string]string{
+			"note": fmt.Sprintf("Note must be at most %d characters", maxContactReplyLength),
+		})
+		return
+	}
+	reply.AuthorID = currentUserID(r)
+
+	err = h.Store.CreateContactReply(r.Context(), &reply)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Contact submission not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error saving contact reply", "id", id, "error", err)
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reply)
+}
+
+func (h *ContactHandler) DeleteContactSubmission(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	err = h.Store.DeleteContactSubmission(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Contact submission not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
 		return
 	}
 
@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/spam"
+)
+
+func TestContactValidation(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		req    models.ContactRequest
+		fields []string
+	}{
+		{"empty", models.ContactRequest{}, []string{"name", "email", "message"}},
+		{"bad email", models.ContactRequest{Name: "Jane", Email: "jane@", Message: "Hi"}, []string{"email"}},
+		{"display name email", models.ContactRequest{Name: "Jane", Email: "Jane <jane@example.com>", Message: "Hi"}, []string{"email"}},
+		{"long subject", models.ContactRequest{Name: "Jane", Email: "jane@example.com", Subject: strings.Repeat("a", 256), Message: "Hi"}, []string{"subject"}},
+		{"long message", models.ContactRequest{Name: "Jane", Email: "jane@example.com", Message: strings.Repeat("a", 5001)}, []string{"message"}},
+		{"blank name", models.ContactRequest{Name: "   ", Email: "jane@example.com", Message: "Hi"}, []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.request("POST", "/api/contact", tt.req, "")
+			expectStatus(t, w, http.StatusBadRequest)
+			var resp struct {
+				Error  string            `json:"error"`
+				Fields map[string]string `json:"fields"`
+			}
+			decode(t, w, &resp)
+			if len(resp.Fields) != len(tt.fields) {
+				t.Fatalf("Expected errors for %v, got %v", tt.fields, resp.Fields)
+			}
+			for _, field := range tt.fields {
+				if resp.Fields[field] == "" {
+					t.Errorf("Expected an error for %s, got %v", field, resp.Fields)
+				}
+			}
+		})
+	}
+}
+
+func TestContactSpamChecks(t *testing.T) {
+	s := newTestServer(t)
+	s.contact.Filter.Blocklist = []string{"casino"}
+
+	valid := models.ContactRequest{Name: "Jane", Email: "jane@example.com", Message: "Nice build!"}
+	tests := []struct {
+		name   string
+		modify func(req *models.ContactRequest)
+		reason string
+	}{
+		{"honeypot", func(req *models.ContactRequest) { req.Website = "http://spam.example" }, "honeypot"},
+		{"missing token", func(req *models.ContactRequest) { req.FormToken = "" }, "missing form token"},
+		{"forged token", func(req *models.ContactRequest) { req.FormToken = "abc.def" }, "invalid form token"},
+		{"links", func(req *models.ContactRequest) { req.Message = "http://a.example http://b.example www.c.example" }, "too many links"},
+		{"blocklist", func(req *models.ContactRequest) { req.Subject = "Online CASINO" }, "blocked term"},
+	}
+
+	for _, tt := range tests {
+		req := valid
+		req.FormToken = s.formToken()
+		tt.modify(&req)
+		w := s.request("POST", "/api/contact", req, "")
+		expectStatus(t, w, http.StatusCreated)
+	}
+
+	req := valid
+	req.FormToken = s.formToken()
+	w := s.request("POST", "/api/contact", req, "")
+	expectStatus(t, w, http.StatusCreated)
+
+	var inbox, junk, all []models.ContactSubmission
+	decode(t, s.admin("GET", "/api/contact", nil), &inbox)
+	decode(t, s.admin("GET", "/api/contact?filter=spam", nil), &junk)
+	decode(t, s.admin("GET", "/api/contact?filter=all", nil), &all)
+
+	if len(inbox) != 1 || inbox[0].IsSpam {
+		t.Fatalf("Expected only the valid submission in the inbox, got %+v", inbox)
+	}
+	if len(junk) != len(tests) || len(all) != len(tests)+1 {
+		t.Fatalf("Expected %d spam of %d submissions, got %d of %d", len(tests), len(tests)+1, len(junk), len(all))
+	}
+	for _, tt := range tests {
+		found := false
+		for _, submission := range junk {
+			found = found || strings.Contains(submission.SpamReason, tt.reason)
+		}
+		if !found {
+			t.Errorf("Expected a submission marked as spam for %s", tt.name)
+		}
+	}
+
+	w = s.admin("GET", "/api/contact?filter=unknown", nil)
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestContactMinimumSubmitTime(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.contact.Tokens = spam.NewFormTokens(testSecret, 3*time.Second, time.Hour, s.store)
+	s.contact.Tokens.SetClock(func() time.Time { return now })
+
+	req := models.ContactRequest{Name: "Jane", Email: "jane@example.com", Message: "Hi", FormToken: s.formToken()}
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+	now = now.Add(5 * time.Second)
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+	// A token that got through once is used up
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+
+	var all []models.ContactSubmission
+	decode(t, s.admin("GET", "/api/contact?filter=all", nil), &all)
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 submissions, got %d", len(all))
+	}
+	// Newest first: only the second submission waited long enough and was
+	// the first to use the token
+	if !all[0].IsSpam || all[0].SpamReason != "form token already used" ||
+		all[1].IsSpam || !all[2].IsSpam || all[2].SpamReason != "submitted too fast" {
+		t.Fatalf("Expected the instant and replayed submissions to be spam, got %+v", all)
+	}
+}
+
+func TestContactCaptcha(t *testing.T) {
+	s := newTestServer(t)
+	s.contact.Captcha = spam.Fake{Token: "pass"}
+
+	req := models.ContactRequest{Name: "Jane", Email: "jane@example.com", Message: "Hi", FormToken: s.formToken()}
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusBadRequest)
+
+	req.CaptchaToken = "wrong"
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusBadRequest)
+
+	req.CaptchaToken = "pass"
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+}
+
+func TestContactQueuesNotification(t *testing.T) {
+	s := newTestServer(t)
+
+	req := models.ContactRequest{Name: "Jane", Email: "jane@example.com", Subject: "Turbo", Message: "Hi", FormToken: s.formToken()}
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+
+	req.Website = "http://spam.example"
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+
+	emails, err := s.store.ClaimEmails(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	if len(emails) != 1 {
+		t.Fatalf("Expected one notification and none for spam, got %+v", emails)
+	}
+	if emails[0].To != testAdminEmail || emails[0].ReplyTo != "jane@example.com" || !strings.Contains(emails[0].Subject, "Turbo") {
+		t.Fatalf("Unexpected notification %+v", emails[0])
+	}
+}
+
+func TestContactAutoReplyNeedsCaptcha(t *testing.T) {
+	s := newTestServer(t)
+	s.contact.Emails.AutoReply = true
+
+	req := models.ContactRequest{Name: "Jane", Email: "jane@example.com", Message: "Hi", FormToken: s.formToken()}
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+
+	s.contact.Captcha = spam.Fake{Token: "pass"}
+	req.FormToken, req.CaptchaToken = s.formToken(), "pass"
+	expectStatus(t, s.request("POST", "/api/contact", req, ""), http.StatusCreated)
+
+	emails, err := s.store.ClaimEmails(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to read outbox: %v", err)
+	}
+	var replies int
+	for _, email := range emails {
+		if email.To == "jane@example.com" {
+			replies++
+		}
+	}
+	if len(emails) != 3 || replies != 1 {
+		t.Fatalf("Expected two notifications and one auto-reply after the CAPTCHA, got %+v", emails)
+	}
+}
+
+func TestContactInbox(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.store.SetClock(func() time.Time { return now })
+
+	var ids []int
+	for _, sub := range []models.ContactSubmission{
+		{Name: "Jane", Email: "jane@example.com", Subject: "Turbo", Message: "What turbo is that?"},
+		{Name: "Bob", Email: "bob@example.com", Message: "Great photos of the car"},
+		{Name: "Spammer", Email: "spam@example.com", Message: "Buy now", IsSpam: true, SpamReason: "honeypot field filled in"},
+		{Name: "Alice", Email: "alice@example.com", Subject: "Job", Message: "Are you hiring?"},
+	} {
+		sub := sub
+		if err := s.store.CreateContactSubmission(ctx, &sub, nil); err != nil {
+			t.Fatalf("Failed to create submission: %v", err)
+		}
+		ids = append(ids, sub.ID)
+		now = now.Add(24 * time.Hour)
+	}
+	jane, bob, spammer, alice := ids[0], ids[1], ids[2], ids[3]
+
+	list := func(query string) []models.ContactSubmission {
+		t.Helper()
+		w := s.admin("GET", "/api/contact"+query, nil)
+		expectStatus(t, w, http.StatusOK)
+		var submissions []models.ContactSubmission
+		decode(t, w, &submissions)
+		return submissions
+	}
+	names := func(submissions []models.ContactSubmission) string {
+		var names []string
+		for _, sub := range submissions {
+			names = append(names, sub.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	unread := func() int {
+		t.Helper()
+		w := s.admin("GET", "/api/contact/unread-count", nil)
+		expectStatus(t, w, http.StatusOK)
+		var resp struct {
+			Unread int `json:"unread"`
+		}
+		decode(t, w, &resp)
+		return resp.Unread
+	}
+
+	if got := names(list("")); got != "Alice,Bob,Jane" {
+		t.Fatalf("Expected inbox newest first without spam, got %s", got)
+	}
+	if got := unread(); got != 3 {
+		t.Fatalf("Expected 3 unread, got %d", got)
+	}
+
+	w := s.admin("PATCH", fmt.Sprintf("/api/contact/%d", jane), map[string]bool{"is_read": true, "is_starred": true})
+	expectStatus(t, w, http.StatusOK)
+	var updated models.ContactSubmission
+	decode(t, w, &updated)
+	if !updated.IsRead || !updated.IsStarred || updated.IsArchived {
+		t.Fatalf("Unexpected update result %+v", updated)
+	}
+	expectStatus(t, s.admin("PATCH", fmt.Sprintf("/api/contact/%d", bob), map[string]bool{"is_archived": true}), http.StatusOK)
+	expectStatus(t, s.admin("PATCH", fmt.Sprintf("/api/contact/%d", spammer), map[string]bool{"is_spam": false}), http.StatusOK)
+
+	if got := unread(); got != 2 {
+		t.Fatalf("Expected 2 unread after reading, archiving and rescuing spam, got %d", got)
+	}
+	if got := names(list("?filter=archived")); got != "Bob" {
+		t.Fatalf("Expected Bob in the archive, got %s", got)
+	}
+	if got := names(list("?filter=spam")); got != "" {
+		t.Fatalf("Expected no spam left, got %s", got)
+	}
+	if got := names(list("?starred=true")); got != "Jane" {
+		t.Fatalf("Expected Jane starred, got %s", got)
+	}
+	if got := names(list("?read=false")); got != "Alice,Spammer" {
+		t.Fatalf("Expected unread Alice and Spammer, got %s", got)
+	}
+	if got := names(list("?filter=all&since=2024-03-02&until=2024-03-03")); got != "Spammer,Bob" {
+		t.Fatalf("Expected submissions from 2 and 3 March, got %s", got)
+	}
+	if got := names(list("?filter=all&q=TURBO")); got != "Jane" {
+		t.Fatalf("Expected search to find Jane, got %s", got)
+	}
+
+	w = s.admin("GET", "/api/contact?filter=all&per_page=2&page=2", nil)
+	expectStatus(t, w, http.StatusOK)
+	var page []models.ContactSubmission
+	decode(t, w, &page)
+	if names(page) != "Bob,Jane" || w.Header().Get(TotalCountHeader) != "4" {
+		t.Fatalf("Unexpected second page %s of %s", names(page), w.Header().Get(TotalCountHeader))
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, `page=1&per_page=2>; rel="prev"`) || strings.Contains(link, `rel="next"`) {
+		t.Fatalf("Unexpected Link header %q", link)
+	}
+
+	for _, query := range []string{"?page=0", "?per_page=1000", "?read=maybe", "?since=yesterday", "?filter=unknown"} {
+		expectStatus(t, s.admin("GET", "/api/contact"+query, nil), http.StatusBadRequest)
+	}
+	expectStatus(t, s.admin("PATCH", fmt.Sprintf("/api/contact/%d", alice), map[string]bool{}), http.StatusBadRequest)
+	expectStatus(t, s.admin("PATCH", "/api/contact/999", map[string]bool{"is_read": true}), http.StatusNotFound)
+}
+
+func TestContactReplies(t *testing.T) {
+	s := newTestServer(t)
+
+	sub := models.ContactSubmission{Name: "Jane", Email: "jane@example.com", Message: "Hi"}
+	if err := s.store.CreateContactSubmission(context.Background(), &sub, nil); err != nil {
+		t.Fatalf("Failed to create submission: %v", err)
+	}
+	path := fmt.Sprintf("/api/contact/%d", sub.ID)
+
+	expectStatus(t, s.admin("POST", path+"/replies", map[string]string{"note": " "}), http.StatusBadRequest)
+	expectStatus(t, s.admin("POST", "/api/contact/999/replies", map[string]string{"note": "Hi"}), http.StatusNotFound)
+
+	w := s.admin("POST", path+"/replies", map[string]string{"note": "Replied by email with build details"})
+	expectStatus(t, w, http.StatusCreated)
+	var reply models.ContactReply
+	decode(t, w, &reply)
+	if reply.ID == 0 || reply.Author != "Admin" {
+		t.Fatalf("Unexpected reply %+v", reply)
+	}
+
+	w = s.admin("GET", path, nil)
+	expectStatus(t, w, http.StatusOK)
+	var got models.ContactSubmission
+	decode(t, w, &got)
+	if got.ReplyCount != 1 || got.LastRepliedAt == nil || len(got.Replies) != 1 ||
+		got.Replies[0].Note != "Replied by email with build details" {
+		t.Fatalf("Unexpected submission with replies %+v", got)
+	}
+
+	var listed []models.ContactSubmission
+	decode(t, s.admin("GET", "/api/contact", nil), &listed)
+	if len(listed) != 1 || listed[0].ReplyCount != 1 || listed[0].Replies != nil {
+		t.Fatalf("Expected list to summarize replies, got %+v", listed)
+	}
+}
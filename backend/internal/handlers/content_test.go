@@ -0,0 +1,535 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+)
+
+func TestAboutContentCRUD(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.request("POST", "/api/about", models.AboutContent{Title: "Hello", Content: "World"}, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = s.admin("POST", "/api/about", models.AboutContent{Title: "Hello", Content: "World", Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var created models.AboutContent
+	decode(t, w, &created)
+	if created.ID == 0 {
+		t.Fatal("Expected created content to have an ID")
+	}
+
+	path := fmt.Sprintf("/api/about/%d", created.ID)
+	w = s.admin("PUT", path, models.AboutContent{Title: "Hi", Content: "There", Publication: published})
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.request("GET", "/api/about", nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var contents []models.AboutContent
+	decode(t, w, &contents)
+	if len(contents) != 1 || contents[0].Title != "Hi" || contents[0].Content != "There" {
+		t.Fatalf("Unexpected about content: %+v", contents)
+	}
+
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = s.admin("PUT", path, models.AboutContent{Title: "Gone"})
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = s.admin("PUT", "/api/about/abc", models.AboutContent{Title: "Bad"})
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestResumeSectionCRUD(t *testing.T) {
+	s := newTestServer(t)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := s.admin("POST", "/api/resume", models.ResumeSection{SectionType: "experience", Title: "Engineer", StartDate: &start, DisplayOrder: 2, Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var later models.ResumeSection
+	decode(t, w, &later)
+
+	w = s.admin("POST", "/api/resume", models.ResumeSection{SectionType: "education", Title: "University", DisplayOrder: 1, Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+
+	w = s.request("GET", "/api/resume", nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var sections []models.ResumeSection
+	decode(t, w, &sections)
+	if len(sections) != 2 || sections[0].Title != "University" {
+		t.Fatalf("Expected sections ordered by display order, got %+v", sections)
+	}
+	if sections[1].StartDate == nil || !sections[1].StartDate.Equal(start) {
+		t.Fatalf("Expected start date %v, got %v", start, sections[1].StartDate)
+	}
+
+	path := fmt.Sprintf("/api/resume/%d", later.ID)
+	w = s.admin("PUT", path, models.ResumeSection{SectionType: "experience", Title: "Senior Engineer", DisplayOrder: 2, Publication: published})
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.admin("PUT", path, models.ResumeSection{SectionType: "experience", Title: "Gone"})
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestCarBuildEntryCRUD(t *testing.T) {
+	s := newTestServer(t)
+	cost := 249.99
+
+	entry := models.CarBuildEntry{
+		Title:       "Coilovers",
+		Date:        time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+		Category:    "suspension",
+		Cost:        &cost,
+		ImageURLs:   []string{"/api/image/1"},
+		Publication: published,
+	}
+	w := s.admin("POST", "/api/carbuild", entry)
+	expectStatus(t, w, http.StatusCreated)
+	var created models.CarBuildEntry
+	decode(t, w, &created)
+
+	w = s.request("GET", "/api/carbuild", nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var entries []models.CarBuildEntry
+	decode(t, w, &entries)
+	if len(entries) != 1 || entries[0].Cost == nil || *entries[0].Cost != cost || len(entries[0].ImageURLs) != 1 {
+		t.Fatalf("Unexpected car build entries: %+v", entries)
+	}
+
+	path := fmt.Sprintf("/api/carbuild/%d", created.ID)
+	entry.Title = "Coilovers and sway bars"
+	w = s.admin("PUT", path, entry)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestCarBuildListQuery(t *testing.T) {
+	s := newTestServer(t)
+	price := func(f float64) *float64 { return &f }
+	for _, entry := range []models.CarBuildEntry{
+		{Title: "Coilovers", Date: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), Category: "suspension", Cost: price(1200), Publication: published},
+		{Title: "Sway bars", Date: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), Category: "suspension", Cost: price(300), Publication: published},
+		{Title: "Oil change", Date: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC), Category: "maintenance", Cost: price(60), Publication: published},
+		{Title: "Wash", Date: time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), Category: "maintenance", Publication: published},
+	} {
+		expectStatus(t, s.admin("POST", "/api/carbuild", entry), http.StatusCreated)
+	}
+
+	titles := func(query string) string {
+		t.Helper()
+		w := s.request("GET", "/api/carbuild"+query, nil, "")
+		expectStatus(t, w, http.StatusOK)
+		var entries []models.CarBuildEntry
+		decode(t, w, &entries)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Title)
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct{ query, want string }{
+		{"", "Wash,Oil change,Sway bars,Coilovers"},
+		{"?category=suspension", "Sway bars,Coilovers"},
+		{"?since=2023-06-01&until=2023-06-15", "Oil change,Sway bars"},
+		{"?min_cost=100&max_cost=1000", "Sway bars"},
+		{"?sort=cost", "Oil change,Sway bars,Coilovers,Wash"},
+		{"?sort=-cost&limit=2", "Wash,Coilovers"},
+		{"?sort=title&limit=2&offset=1", "Oil change,Sway bars"},
+	}
+	for _, tt := range tests {
+		if got := titles(tt.query); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.query, tt.want, got)
+		}
+	}
+
+	w := s.request("GET", "/api/carbuild?limit=1", nil, "")
+	if w.Header().Get(TotalCountHeader) != "4" || !strings.Contains(w.Header().Get("Link"), `offset=3>; rel="last"`) {
+		t.Errorf("Unexpected page headers %v", w.Header())
+	}
+
+	for _, query := range []string{"?sort=description", "?min_cost=cheap", "?since=yesterday", "?limit=1000"} {
+		expectStatus(t, s.request("GET", "/api/carbuild"+query, nil, ""), http.StatusBadRequest)
+	}
+}
+
+func TestResumeSectionTypeFilter(t *testing.T) {
+	s := newTestServer(t)
+	for _, section := range []models.ResumeSection{
+		{SectionType: "experience", Title: "Engineer", Publication: published},
+		{SectionType: "education", Title: "University", Publication: published},
+		{SectionType: "experience", Title: "Intern", DisplayOrder: 1, Publication: published},
+	} {
+		expectStatus(t, s.admin("POST", "/api/resume", section), http.StatusCreated)
+	}
+
+	w := s.request("GET", "/api/resume?section_type=experience&sort=-title", nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var sections []models.ResumeSection
+	decode(t, w, &sections)
+	if len(sections) != 2 || sections[0].Title != "Intern" || sections[1].Title != "Engineer" {
+		t.Fatalf("Unexpected sections %+v", sections)
+	}
+	if w.Header().Get(TotalCountHeader) != "2" {
+		t.Errorf("Expected a total of 2, got %s", w.Header().Get(TotalCountHeader))
+	}
+}
+
+func TestContactSubmissions(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.request("POST", "/api/contact", models.ContactRequest{Name: "Jane", Email: "jane@example.com", Message: "Hi", FormToken: s.formToken()}, "")
+	expectStatus(t, w, http.StatusCreated)
+
+	w = s.request("GET", "/api/contact", nil, "")
+	expectStatus(t, w, http.StatusUnauthorized)
+
+	w = s.admin("GET", "/api/contact", nil)
+	expectStatus(t, w, http.StatusOK)
+	var submissions []models.ContactSubmission
+	decode(t, w, &submissions)
+	if len(submissions) != 1 || submissions[0].Name != "Jane" || submissions[0].IsRead {
+		t.Fatalf("Unexpected submissions: %+v", submissions)
+	}
+
+	path := fmt.Sprintf("/api/contact/%d", submissions[0].ID)
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusNoContent)
+
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestContentValidation(t *testing.T) {
+	s := newTestServer(t)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(-1, 0, 0)
+	negative := -5.0
+	long := strings.Repeat("x", 256)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   interface{}
+		fields []string
+	}{
+		{"about without title", "/api/about", models.AboutContent{Content: "Hi"}, []string{"title"}},
+		{"about long title", "/api/about", models.AboutContent{Title: long}, []string{"title"}},
+		{"resume without type or title", "/api/resume", models.ResumeSection{}, []string{"section_type", "title"}},
+		{"resume unknown type", "/api/resume", models.ResumeSection{SectionType: "hobbies", Title: "Chess"}, []string{"section_type"}},
+		{"resume end before start", "/api/resume", models.ResumeSection{SectionType: "education", Title: "School", StartDate: &start, EndDate: &end}, []string{"end_date"}},
+		{"carbuild without title or date", "/api/carbuild", models.CarBuildEntry{}, []string{"title", "date"}},
+		{"carbuild negative cost", "/api/carbuild", models.CarBuildEntry{Title: "Refund", Date: start, Cost: &negative}, []string{"cost"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.admin("POST", tt.path, tt.body)
+			expectStatus(t, w, http.StatusBadRequest)
+			if got := w.Header().Get("Content-Type"); got != problem.ContentType {
+				t.Errorf("Expected %s, got %s", problem.ContentType, got)
+			}
+			var p problem.Problem
+			decode(t, w, &p)
+			if p.Code != problem.ValidationFailed.Name || len(p.Fields) != len(tt.fields) {
+				t.Fatalf("Expected errors for %v, got %+v", tt.fields, p)
+			}
+			for _, field := range tt.fields {
+				if p.Fields[field] == "" {
+					t.Errorf("Expected an error for %s, got %v", field, p.Fields)
+				}
+			}
+		})
+	}
+
+	// Section types are matched case-insensitively and stored in lower case
+	w := s.admin("POST", "/api/resume", models.ResumeSection{SectionType: " Experience ", Title: "Engineer"})
+	expectStatus(t, w, http.StatusCreated)
+	var section models.ResumeSection
+	decode(t, w, &section)
+	if section.SectionType != "experience" {
+		t.Errorf("Expected section type experience, got %q", section.SectionType)
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		method, path string
+		admin        bool
+		code         problem.Code
+	}{
+		{"DELETE", "/api/carbuild/999", true, problem.NotFound},
+		{"PUT", "/api/about/abc", true, problem.InvalidID},
+		{"POST", "/api/about", false, problem.Unauthorized},
+		{"POST", "/api/login", false, problem.InvalidBody},
+		{"GET", "/api/carbuild?sort=nope", false, problem.InvalidQuery},
+		{"GET", "/api/nowhere", false, problem.NotFound},
+	}
+
+	for _, tt := range tests {
+		var w *httptest.ResponseRecorder
+		if tt.admin {
+			w = s.admin(tt.method, tt.path, models.AboutContent{Title: "Hi"})
+		} else {
+			w = s.request(tt.method, tt.path, nil, "")
+		}
+		expectStatus(t, w, tt.code.Status)
+		var p problem.Problem
+		decode(t, w, &p)
+		if p.Code != tt.code.Name || p.Status != tt.code.Status || p.Title == "" || p.Detail == "" {
+			t.Errorf("%s %s: expected code %s, got %+v", tt.method, tt.path, tt.code.Name, p)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	s := newTestServer(t)
+	cost := 450.0
+	w := s.admin("POST", "/api/carbuild", models.CarBuildEntry{Title: "Brakes", Description: "Pads and rotors", Category: "brakes", Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Cost: &cost, Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var entry models.CarBuildEntry
+	decode(t, w, &entry)
+	path := fmt.Sprintf("/api/carbuild/%d", entry.ID)
+
+	patch := func(body, contentType, ifMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest("PATCH", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return s.serve(r, s.token)
+	}
+
+	w = s.request("GET", path, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != `"v1"` {
+		t.Fatalf(`Expected ETag "v1", got %s`, got)
+	}
+
+	// Omitted fields are kept and null removes a value
+	w = patch(`{"title": "Big brakes", "cost": null}`, mergePatchMediaType, "")
+	expectStatus(t, w, http.StatusOK)
+	entry = models.CarBuildEntry{}
+	decode(t, w, &entry)
+	if entry.Title != "Big brakes" || entry.Description != "Pads and rotors" || entry.Category != "brakes" || entry.Cost != nil {
+		t.Errorf("Unexpected patched entry %+v", entry)
+	}
+	if entry.Version != 2 || w.Header().Get("ETag") != `"v2"` {
+		t.Errorf("Expected version 2, got %d and ETag %s", entry.Version, w.Header().Get("ETag"))
+	}
+
+	expectStatus(t, patch(`{"title": "x"}`, "text/plain", ""), http.StatusUnsupportedMediaType)
+	expectStatus(t, patch(`["title"]`, mergePatchMediaType, ""), http.StatusBadRequest)
+	expectStatus(t, patch(`{"title": 5}`, mergePatchMediaType, ""), http.StatusBadRequest)
+	expectStatus(t, patch(`{"title": null}`, mergePatchMediaType, ""), http.StatusBadRequest)
+
+	// A stale If-Match is refused by PATCH and PUT alike
+	w = patch(`{"title": "Stale"}`, mergePatchMediaType, `"v1"`)
+	expectStatus(t, w, http.StatusPreconditionFailed)
+	if got := w.Header().Get("ETag"); got != `"v2"` {
+		t.Errorf(`Expected current ETag "v2", got %s`, got)
+	}
+	r := httptest.NewRequest("PUT", path, strings.NewReader(`{"title": "Stale", "date": "2023-03-01T00:00:00Z"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("If-Match", `"v1"`)
+	expectStatus(t, s.serve(r, s.token), http.StatusPreconditionFailed)
+
+	w = patch(`{"category": "stopping"}`, "application/json", `"v2"`)
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != `"v3"` {
+		t.Errorf(`Expected ETag "v3", got %s`, got)
+	}
+
+	// PUT without If-Match still overwrites unconditionally
+	w = s.admin("PUT", path, models.CarBuildEntry{Title: "Brakes", Date: entry.Date, Publication: published})
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != `"v4"` {
+		t.Errorf(`Expected ETag "v4", got %s`, got)
+	}
+	w = s.request("GET", path, nil, "")
+	entry = models.CarBuildEntry{}
+	decode(t, w, &entry)
+	if entry.Version != 4 || entry.Category != "" {
+		t.Errorf("Expected an overwrite at version 4, got %+v", entry)
+	}
+
+	expectStatus(t, s.request("PATCH", path, map[string]string{"title": "x"}, ""), http.StatusUnauthorized)
+	expectStatus(t, s.admin("PATCH", "/api/carbuild/999", map[string]string{"title": "x"}), http.StatusNotFound)
+}
+
+func TestMergePatchAboutAndResume(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.admin("POST", "/api/about", models.AboutContent{Title: "About", Content: "Hello", ImageURL: "/me.jpg", Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var about models.AboutContent
+	decode(t, w, &about)
+	w = s.admin("PATCH", fmt.Sprintf("/api/about/%d", about.ID), map[string]interface{}{"content": "Hi there", "image_url": nil})
+	expectStatus(t, w, http.StatusOK)
+	about = models.AboutContent{}
+	decode(t, w, &about)
+	if about.Title != "About" || about.Content != "Hi there" || about.ImageURL != "" || about.Version != 2 {
+		t.Errorf("Unexpected patched about content %+v", about)
+	}
+
+	w = s.admin("POST", "/api/resume", models.ResumeSection{SectionType: "experience", Title: "Engineer", Subtitle: "Acme", Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var section models.ResumeSection
+	decode(t, w, &section)
+	path := fmt.Sprintf("/api/resume/%d", section.ID)
+	w = s.admin("PATCH", path, map[string]interface{}{"section_type": "hobbies"})
+	expectStatus(t, w, http.StatusBadRequest)
+	w = s.admin("PATCH", path, map[string]interface{}{"display_order": 3})
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &section)
+	if section.Title != "Engineer" || section.Subtitle != "Acme" || section.DisplayOrder != 3 {
+		t.Errorf("Unexpected patched resume section %+v", section)
+	}
+
+	w = s.request("GET", path, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != versionETag(section.Version) {
+		t.Errorf("Expected ETag %s, got %s", versionETag(section.Version), got)
+	}
+	expectStatus(t, s.request("GET", "/api/resume/999", nil, ""), http.StatusNotFound)
+}
+
+func TestPublishingWorkflow(t *testing.T) {
+	s := newTestServer(t)
+	past := time.Now().Add(-time.Hour).UTC()
+	future := time.Now().Add(time.Hour).UTC()
+	date := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	ids := make(map[string]int)
+	for title, p := range map[string]models.Publication{
+		"Draft":    {},
+		"Due":      {Status: models.StatusScheduled, PublishAt: &past},
+		"Upcoming": {Status: models.StatusScheduled, PublishAt: &future},
+		"Live":     {Status: " Published "},
+		"Archived": {Status: models.StatusArchived},
+	} {
+		w := s.admin("POST", "/api/carbuild", models.CarBuildEntry{Title: title, Date: date, Publication: p})
+		expectStatus(t, w, http.StatusCreated)
+		var entry models.CarBuildEntry
+		decode(t, w, &entry)
+		ids[title] = entry.ID
+		if title == "Draft" && entry.Status != models.StatusDraft {
+			t.Errorf("Expected new content to default to draft, got %q", entry.Status)
+		}
+		if title == "Live" && (entry.Status != models.StatusPublished || entry.PublishAt == nil) {
+			t.Errorf("Expected published content to be stamped with a publish time, got %+v", entry.Publication)
+		}
+	}
+
+	titles := func(path, token string) string {
+		t.Helper()
+		w := s.request("GET", path, nil, token)
+		expectStatus(t, w, http.StatusOK)
+		var entries []models.CarBuildEntry
+		decode(t, w, &entries)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Title)
+		}
+		return strings.Join(names, ",")
+	}
+	if got := titles("/api/carbuild?sort=title", ""); got != "Due,Live" {
+		t.Errorf("Expected only live entries in public, got %s", got)
+	}
+	if got := titles("/api/admin/carbuild?sort=title", s.token); got != "Archived,Draft,Due,Live,Upcoming" {
+		t.Errorf("Expected admins to see every entry, got %s", got)
+	}
+	if got := titles("/api/admin/carbuild?status=scheduled&sort=title", s.token); got != "Due,Upcoming" {
+		t.Errorf("Expected the scheduled entries, got %s", got)
+	}
+	expectStatus(t, s.admin("GET", "/api/admin/carbuild?status=hidden", nil), http.StatusBadRequest)
+	expectStatus(t, s.request("GET", "/api/admin/carbuild", nil, ""), http.StatusUnauthorized)
+
+	for title, want := range map[string]int{"Draft": http.StatusNotFound, "Upcoming": http.StatusNotFound, "Archived": http.StatusNotFound, "Due": http.StatusOK} {
+		expectStatus(t, s.request("GET", fmt.Sprintf("/api/carbuild/%d", ids[title]), nil, ""), want)
+		expectStatus(t, s.admin("GET", fmt.Sprintf("/api/admin/carbuild/%d", ids[title]), nil), http.StatusOK)
+	}
+
+	// Publishing a draft makes it public
+	path := fmt.Sprintf("/api/carbuild/%d", ids["Draft"])
+	expectStatus(t, s.admin("PATCH", path, map[string]string{"status": models.StatusPublished}), http.StatusOK)
+	expectStatus(t, s.request("GET", path, nil, ""), http.StatusOK)
+
+	for _, tt := range []struct {
+		body  map[string]interface{}
+		field string
+	}{
+		{map[string]interface{}{"title": "About", "status": "hidden"}, "status"},
+		{map[string]interface{}{"title": "About", "status": "scheduled"}, "publish_at"},
+	} {
+		w := s.admin("POST", "/api/about", tt.body)
+		expectStatus(t, w, http.StatusBadRequest)
+		var p problem.Problem
+		decode(t, w, &p)
+		if _, ok := p.Fields[tt.field]; !ok || len(p.Fields) != 1 {
+			t.Errorf("Expected an error for %s, got %+v", tt.field, p.Fields)
+		}
+	}
+}
+
+func TestMarkdownContent(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.upload("gallery", testJPEG(t, 16, 16))
+	alt := "Engine bay"
+	expectStatus(t, s.admin("PATCH", fmt.Sprintf("/api/gallery/image/%d", ids[0]), models.GalleryImageUpdate{AltText: &alt}), http.StatusOK)
+
+	source := fmt.Sprintf("# Swap\n\n![](image:%d)\n\n<script>alert(1)</script> [x](javascript:alert(1))", ids[0])
+	w := s.admin("POST", "/api/about", models.AboutContent{Title: "About", Content: source, ContentHTML: "<b>forged</b>", Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var created models.AboutContent
+	decode(t, w, &created)
+	if created.Content != source {
+		t.Errorf("Expected the Markdown source back, got %q", created.Content)
+	}
+
+	w = s.request("GET", fmt.Sprintf("/api/about/%d", created.ID), nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var content models.AboutContent
+	decode(t, w, &content)
+	html := content.ContentHTML
+	for _, want := range []string{"<h1>Swap</h1>", fmt.Sprintf(`<img src="/api/image/%d" alt="Engine bay">`, ids[0]), "&lt;script&gt;"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("Expected %q in %q", want, html)
+		}
+	}
+	for _, unwanted := range []string{"<script", "javascript:", "forged"} {
+		if strings.Contains(html, unwanted) {
+			t.Errorf("Expected no %q in %q", unwanted, html)
+		}
+	}
+
+	date := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	expectStatus(t, s.admin("POST", "/api/carbuild", models.CarBuildEntry{Title: "Brakes", Description: "**Big** brakes", Date: date, Publication: published}), http.StatusCreated)
+	w = s.request("GET", "/api/carbuild", nil, "")
+	var entries []models.CarBuildEntry
+	decode(t, w, &entries)
+	if len(entries) != 1 || entries[0].DescriptionHTML != "<p><strong>Big</strong> brakes</p>\n" {
+		t.Errorf("Expected rendered descriptions in lists, got %+v", entries)
+	}
+}
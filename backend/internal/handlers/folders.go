@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+	"github.com/gorilla/mux"
+)
+
+// folderSlugPattern keeps slugs safe for URLs and storage keys
+var folderSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type FolderHandler struct {
+	folders store.FolderStore
+}
+
+func NewFolderHandler(folders store.FolderStore) *FolderHandler {
+	return &FolderHandler{folders: folders}
+}
+
+// folderExists reports whether images can be stored in or listed from a folder.
+// When publicOnly is set, private folders are treated as missing.
+func folderExists(ctx context.Context, folders store.FolderStore, slug string, publicOnly bool) bool {
+	if slug == "" || len(slug) > 50 {
+		return false
+	}
+	folder, err := folders.GetFolder(ctx, slug)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logging.FromContext(ctx).Error("error looking up folder", "folder", slug, "error", err)
+		}
+		return false
+	}
+	return folder.Visibility == "public" || !publicOnly
+}
+
+func (h *FolderHandler) listFolders(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	folders, err := h.folders.ListFolders(r.Context(), publicOnly)
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folders)
+}
+
+// GetFolders lists the public folders
+func (h *FolderHandler) GetFolders(w http.ResponseWriter, r *http.Request) {
+	h.listFolders(w, r, true)
+}
+
+// ListFolders lists every folder, including private ones
+func (h *FolderHandler) ListFolders(w http.ResponseWriter, r *http.Request) {
+	h.listFolders(w, r, false)
+}
+
+// validateFolder checks a folder from a create or update request, fills in
+// defaults and returns an error message for each invalid field
+func validateFolder(folder *models.Folder) map[string]string {
+	fields := make(map[string]string)
+	if !folderSlugPattern.MatchString(folder.Slug) || len(folder.Slug) > 50 {
+		fields["slug"] = "Slug must be lowercase letters, digits and dashes (max 50 characters)"
+	}
+	if folder.Title == "" {
+		fields["title"] = "Title is required"
+	}
+	if folder.Visibility == "" {
+		folder.Visibility = "public"
+	}
+	if folder.Visibility != "public" && folder.Visibility != "private" {
+		fields["visibility"] = "Visibility must be public or private"
+	}
+	return fields
+}
+
+// folderWriteError maps store errors from a folder write to a response
+func folderWriteError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		problem.Write(w, problem.Conflict, "A folder with that slug already exists")
+	case errors.Is(err, store.ErrInvalidReference):
+		problem.Validation(w, map[string]string{"cover_image_id": "Cover image not found"})
+	case errors.Is(err, store.ErrNotFound):
+		problem.Write(w, problem.NotFound, "Folder not found")
+	default:
+		logging.FromContext(r.Context()).Error("error "+action+" folder", "error", err)
+		problem.Write(w, problem.Internal, "Error "+action+" folder")
+	}
+}
+
+func (h *FolderHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	var folder models.Folder
+	if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+
+	if fields := validateFolder(&folder); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+
+	if err := h.folders.CreateFolder(r.Context(), &folder); err != nil {
+		folderWriteError(w, r, err, "creating")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(folder)
+}
+
+// UpdateFolder replaces a folder's fields. Changing the slug moves its images
+// along with it.
+func (h *FolderHandler) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	var folder models.Folder
+	if err := json.NewDecoder(r.Body).Decode(&folder); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+
+	if fields := validateFolder(&folder); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+	folder.ID = id
+
+	if err := h.folders.UpdateFolder(r.Context(), &folder); err != nil {
+		folderWriteError(w, r, err, "updating")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(folder)
+}
+
+// DeleteFolder removes an empty folder. Folders that still hold images are
+// rejected so nothing is orphaned.
+func (h *FolderHandler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	err = h.folders.DeleteFolder(r.Context(), id)
+	if errors.Is(err, store.ErrInUse) {
+		problem.Write(w, problem.Conflict, "Folder still contains images")
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Folder not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error deleting folder")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Folder deleted successfully"})
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+func TestValidateFolder(t *testing.T) {
+	tests := []struct {
+		name   string
+		folder models.Folder
+		field  string
+	}{
+		{"valid", models.Folder{Slug: "track-days", Title: "Track Days"}, ""},
+		{"private", models.Folder{Slug: "drafts", Title: "Drafts", Visibility: "private"}, ""},
+		{"missing slug", models.Folder{Title: "Track Days"}, "slug"},
+		{"uppercase slug", models.Folder{Slug: "Track", Title: "Track"}, "slug"},
+		{"path in slug", models.Folder{Slug: "../etc", Title: "Etc"}, "slug"},
+		{"trailing dash", models.Folder{Slug: "track-", Title: "Track"}, "slug"},
+		{"missing title", models.Folder{Slug: "track"}, "title"},
+		{"bad visibility", models.Folder{Slug: "track", Title: "Track", Visibility: "unlisted"}, "visibility"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := tt.folder
+			fields := validateFolder(&folder)
+			if tt.field == "" && len(fields) > 0 {
+				t.Fatalf("validateFolder() = %v, want no errors", fields)
+			}
+			if tt.field != "" && (len(fields) != 1 || fields[tt.field] == "") {
+				t.Fatalf("validateFolder() = %v, want an error for %s", fields, tt.field)
+			}
+			if len(fields) == 0 && folder.Visibility == "" {
+				t.Error("Expected visibility to default to public")
+			}
+		})
+	}
+}
+
+func TestFolderCRUD(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.admin("POST", "/api/gallery/folders", models.Folder{Slug: "track-days", Title: "Track Days"})
+	expectStatus(t, w, http.StatusCreated)
+	var folder models.Folder
+	decode(t, w, &folder)
+	if folder.Visibility != "public" {
+		t.Fatalf("Expected default visibility public, got %q", folder.Visibility)
+	}
+
+	w = s.admin("POST", "/api/gallery/folders", models.Folder{Slug: "track-days", Title: "Again"})
+	expectStatus(t, w, http.StatusConflict)
+
+	s.upload("track-days", testJPEG(t, 16, 16))
+
+	path := fmt.Sprintf("/api/gallery/folders/%d", folder.ID)
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusConflict)
+
+	// Renaming the slug carries the images along
+	w = s.admin("PUT", path, models.Folder{Slug: "track", Title: "Track"})
+	expectStatus(t, w, http.StatusOK)
+	if list := listImages(t, s, "/api/gallery/images?folder=track&v=2"); len(list.Images) != 1 {
+		t.Fatalf("Expected image to follow the renamed folder, got %+v", list.Images)
+	}
+
+	w = s.admin("PUT", "/api/gallery/folders/999", models.Folder{Slug: "nowhere", Title: "Nowhere"})
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = s.admin("POST", "/api/gallery/folders", models.Folder{Slug: "empty", Title: "Empty"})
+	expectStatus(t, w, http.StatusCreated)
+	var empty models.Folder
+	decode(t, w, &empty)
+	w = s.admin("DELETE", fmt.Sprintf("/api/gallery/folders/%d", empty.ID), nil)
+	expectStatus(t, w, http.StatusOK)
+}
+
+func TestPrivateFolderHiddenFromPublic(t *testing.T) {
+	s := newTestServer(t)
+
+	w := s.admin("POST", "/api/gallery/folders", models.Folder{Slug: "drafts", Title: "Drafts", Visibility: "private"})
+	expectStatus(t, w, http.StatusCreated)
+	ids := s.upload("drafts", testJPEG(t, 16, 16))
+
+	w = s.request("GET", "/api/folders", nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var public []models.Folder
+	decode(t, w, &public)
+	for _, f := range public {
+		if f.Slug == "drafts" {
+			t.Fatal("Expected private folder to be hidden from the public list")
+		}
+	}
+
+	w = s.request("GET", "/api/images?folder=drafts", nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var urls []string
+	decode(t, w, &urls)
+	if len(urls) != 0 {
+		t.Fatalf("Expected no public images for a private folder, got %v", urls)
+	}
+
+	if list := listImages(t, s, "/api/gallery/images?folder=drafts&v=2"); len(list.Images) != 1 {
+		t.Fatalf("Expected admin to see the private image, got %+v", list.Images)
+	}
+
+	path := fmt.Sprintf("/api/image/%d", ids[0])
+	expectStatus(t, s.request("GET", path, nil, ""), http.StatusNotFound)
+	expectStatus(t, s.request("GET", path, nil, "not-a-token"), http.StatusNotFound)
+	w = s.admin("GET", path, nil)
+	expectStatus(t, w, http.StatusOK)
+	if cc := w.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private") {
+		t.Errorf("Expected a private image to stay out of shared caches, got %q", cc)
+	}
+
+	source := fmt.Sprintf("![](image:%d)", ids[0])
+	w = s.admin("POST", "/api/about", models.AboutContent{Title: "About", Content: source, Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var created models.AboutContent
+	decode(t, w, &created)
+	if !strings.Contains(created.ContentHTML, "<img") {
+		t.Errorf("Expected admins to see the private image embedded, got %q", created.ContentHTML)
+	}
+	w = s.request("GET", fmt.Sprintf("/api/about/%d", created.ID), nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var content models.AboutContent
+	decode(t, w, &content)
+	if strings.Contains(content.ContentHTML, "<img") {
+		t.Errorf("Expected the private image to be left out for the public, got %q", content.ContentHTML)
+	}
+}
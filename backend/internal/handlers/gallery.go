@@ -1,36 +1,46 @@
 package handlers
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"strconv"
-
-	"github.com/Jakeito/TestWebsite/backend/internal/database"
+	"strings"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/imaging"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/storage"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
 	"github.com/gorilla/mux"
 )
 
 type GalleryHandler struct {
-	db *database.DB
+	images  store.ImageStore
+	folders store.FolderStore
+	blobs   storage.Backend
 }
 
-func NewGalleryHandler(db *database.DB) *GalleryHandler {
-	return &GalleryHandler{db: db}
+func NewGalleryHandler(images store.ImageStore, folders store.FolderStore, blobs storage.Backend) *GalleryHandler {
+	return &GalleryHandler{images: images, folders: folders, blobs: blobs}
 }
 
-// UploadImage handles image upload to database
+// UploadImage handles image upload to the storage backend
 func (h *GalleryHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
-	// Set response header early
-	w.Header().Set("Content-Type", "application/json")
+	logger := logging.FromContext(r.Context())
 
 	// Parse multipart form (max 100MB total)
 	if err := r.ParseMultipartForm(100 << 20); err != nil {
-		log.Printf("Error parsing multipart form: %v", err)
-		http.Error(w, fmt.Sprintf("Files too large or invalid: %v", err), http.StatusBadRequest)
+		logger.Warn("invalid multipart form", "error", err)
+		problem.Write(w, problem.BadRequest, fmt.Sprintf("Files too large or invalid: %v", err))
 		return
 	}
 
@@ -39,19 +49,12 @@ func (h *GalleryHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 		folder = "gallery"
 	}
 
-	log.Printf("Upload request for folder: %s", folder)
+	logger.Debug("upload request", "folder", folder)
 
 	// Validate folder
-	allowedFolders := map[string]bool{
-		"gallery":  true,
-		"about":    true,
-		"carbuild": true,
-		"hero":     true,
-	}
-
-	if !allowedFolders[folder] {
-		log.Printf("Invalid folder: %s", folder)
-		http.Error(w, "Invalid folder", http.StatusBadRequest)
+	if !folderExists(r.Context(), h.folders, folder, false) {
+		logger.Warn("upload to invalid folder", "folder", folder)
+		problem.Write(w, problem.BadRequest, "Invalid folder")
 		return
 	}
 
@@ -67,29 +70,30 @@ func (h *GalleryHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(fileHeaders) == 0 {
-		log.Printf("No files found in request. Available fields: %v", r.MultipartForm.File)
-		http.Error(w, "No files provided", http.StatusBadRequest)
+		logger.Warn("upload without files", "fields", len(r.MultipartForm.File))
+		problem.Write(w, problem.BadRequest, "No files provided")
 		return
 	}
 
-	log.Printf("Processing %d files for folder %s", len(fileHeaders), folder)
+	logger.Debug("processing upload", "files", len(fileHeaders), "folder", folder)
 
 	var uploadedImages []map[string]interface{}
 
 	for _, fileHeader := range fileHeaders {
-		log.Printf("Processing file: %s (size: %d)", fileHeader.Filename, fileHeader.Size)
+		logger.Debug("processing file", "filename", fileHeader.Filename, "size", fileHeader.Size)
 
 		file, err := fileHeader.Open()
 		if err != nil {
-			log.Printf("Error opening file %s: %v", fileHeader.Filename, err)
+			logger.Error("error opening upload", "filename", fileHeader.Filename, "error", err)
+			metrics.ImageUploads.Inc(metrics.ResultFailure)
 			continue
 		}
-		defer file.Close()
-
 		// Read file data
 		fileData, err := io.ReadAll(file)
+		file.Close()
 		if err != nil {
-			log.Printf("Error reading file %s: %v", fileHeader.Filename, err)
+			logger.Error("error reading upload", "filename", fileHeader.Filename, "error", err)
+			metrics.ImageUploads.Inc(metrics.ResultFailure)
 			continue
 		}
 
@@ -99,21 +103,19 @@ func (h *GalleryHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 			contentType = "image/jpeg"
 		}
 
-		log.Printf("Inserting file %s with content type %s", fileHeader.Filename, contentType)
+		logger.Debug("storing image", "filename", fileHeader.Filename, "content_type", contentType)
 
-		// Insert into database
-		var id int
-		err = h.db.QueryRow(
-			"INSERT INTO gallery_images (folder, filename, image_data, content_type) VALUES ($1, $2, $3, $4) RETURNING id",
-			folder, fileHeader.Filename, fileData, contentType,
-		).Scan(&id)
+		// Store image and insert into database
+		id, err := store.SaveImage(r.Context(), h.images, h.blobs, folder, fileHeader.Filename, contentType, fileData)
 
 		if err != nil {
-			log.Printf("Error inserting image %s into database: %v", fileHeader.Filename, err)
+			logger.Error("error storing image", "filename", fileHeader.Filename, "error", err)
+			metrics.ImageUploads.Inc(metrics.ResultFailure)
 			continue
 		}
 
-		log.Printf("Successfully uploaded file %s with ID %d", fileHeader.Filename, id)
+		logger.Info("image uploaded", "filename", fileHeader.Filename, "image_id", id, "folder", folder)
+		metrics.ImageUploads.Inc(metrics.ResultSuccess)
 
 		uploadedImages = append(uploadedImages, map[string]interface{}{
 			"id":       id,
@@ -123,6 +125,7 @@ func (h *GalleryHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"uploaded": len(uploadedImages),
 		"images":   uploadedImages,
@@ -130,83 +133,424 @@ func (h *GalleryHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 }
 
 
-// GetImage serves an image by ID
+// servedImage is the stored rendition chosen to answer an image request
+type servedImage struct {
+	variantID   int // 0 for the original
+	key         string
+	contentType string
+	hash        string
+	modTime     time.Time
+}
+
+// byteCounter counts the image bytes actually sent, which ranges and
+// revalidation reduce
+type byteCounter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (c *byteCounter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *byteCounter) Write(b []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// record adds the bytes to the folder's total unless the response was an error
+func (c *byteCounter) record(folder string) {
+	if c.status < http.StatusBadRequest {
+		metrics.ImageBytesServed.Add(float64(c.n), folder)
+	}
+}
+
+// GetImage serves an image by ID. The optional w and format query parameters,
+// or the Accept header, select a resized variant instead of the original.
+// Responses carry an ETag and Last-Modified so clients can revalidate, and
+// support HEAD and byte ranges.
 func (h *GalleryHandler) GetImage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid image ID")
+		return
+	}
+
+	width := 0
+	if ws := r.URL.Query().Get("w"); ws != "" {
+		width, err = strconv.Atoi(ws)
+		if err != nil || width <= 0 {
+			problem.Write(w, problem.InvalidQuery, "Invalid width")
+			return
+		}
+	}
+	format := r.URL.Query().Get("format")
+
+	file, err := h.images.GetImageFile(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Image not found")
+		return
+	}
+
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error retrieving image")
+		return
+	}
+
+	// Images in private folders look missing to everyone but admins
+	public := folderExists(r.Context(), h.folders, file.Folder, true)
+	if !public && !isAdmin(r.Context()) {
+		problem.Write(w, problem.NotFound, "Image not found")
+		return
+	}
+
+	counter := &byteCounter{ResponseWriter: w}
+	defer counter.record(file.Folder)
+	w = counter
+
+	// Rows not yet moved by migrate-images still carry their bytes inline
+	if file.Key == "" {
+		h.serveLegacyImage(w, r, id, file.ContentType, file.CreatedAt, public)
+		return
+	}
+
+	img := servedImage{
+		key:         file.Key,
+		contentType: file.ContentType,
+		hash:        file.Hash,
+		modTime:     file.CreatedAt,
+	}
+	if width > 0 || format != "" || r.Header.Get("Accept") != "" {
+		img, err = h.selectVariant(r.Context(), id, img, file.Width, width, format, r.Header.Get("Accept"))
+		if err != nil {
+			problem.Write(w, problem.Internal, "Error retrieving image")
+			return
+		}
+	}
+
+	setImageHeaders(w, img.contentType, img.hash, public)
+
+	// Answer revalidation from the database without touching storage
+	if img.hash != "" && etagMatches(r.Header.Get("If-None-Match"), imageETag(img.hash)) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, info, err := h.blobs.Get(r.Context(), img.key)
+	if errors.Is(err, storage.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Image not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error reading image from storage", "image_id", id, "error", err)
+		problem.Write(w, problem.Internal, "Error retrieving image")
 		return
 	}
+	defer body.Close()
+
+	if !info.ModTime.IsZero() {
+		img.modTime = info.ModTime
+	}
+
+	content, seekable := body.(io.ReadSeeker)
+	if !seekable || img.hash == "" {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("error reading image from storage", "image_id", id, "error", err)
+			problem.Write(w, problem.Internal, "Error retrieving image")
+			return
+		}
+
+		// Images stored before hashes were kept get one on first request
+		if img.hash == "" {
+			img.hash = store.ContentHash(data)
+			w.Header().Set("ETag", imageETag(img.hash))
+			if err := h.images.SaveContentHash(r.Context(), id, img.variantID, img.hash); err != nil {
+				logging.FromContext(r.Context()).Error("error saving image hash", "image_id", id, "error", err)
+			}
+		}
+		content = bytes.NewReader(data)
+	}
+
+	http.ServeContent(w, r, "", img.modTime, content)
+}
+
+// selectVariant returns the rendition that best matches the requested width
+// and format, which may be the original
+func (h *GalleryHandler) selectVariant(ctx context.Context, id int, original servedImage, originalWidth, width int, format, accept string) (servedImage, error) {
+	variants, err := h.images.ListVariants(ctx, id)
+	if err != nil {
+		return servedImage{}, err
+	}
 
-	var imageData []byte
-	var contentType string
+	served := map[int]servedImage{0: original}
+	candidates := make([]imaging.Candidate, 0, len(variants))
+	for _, v := range variants {
+		served[v.ID] = servedImage{
+			variantID:   v.ID,
+			key:         v.Key,
+			contentType: v.ContentType,
+			hash:        v.Hash,
+			modTime:     v.CreatedAt,
+		}
+		candidates = append(candidates, imaging.Candidate{
+			ID:          v.ID,
+			Format:      v.Format,
+			ContentType: v.ContentType,
+			Width:       v.Width,
+		})
+	}
 
-	err = h.db.QueryRow(
-		"SELECT image_data, content_type FROM gallery_images WHERE id = $1",
-		id,
-	).Scan(&imageData, &contentType)
+	chosen := imaging.Select(imaging.Candidate{
+		Format:      imaging.FormatFromContentType(original.contentType),
+		ContentType: original.contentType,
+		Width:       originalWidth,
+	}, candidates, width, format, accept)
+	return served[chosen.ID], nil
+}
 
-	if err == sql.ErrNoRows {
-		http.Error(w, "Image not found", http.StatusNotFound)
+func (h *GalleryHandler) serveLegacyImage(w http.ResponseWriter, r *http.Request, id int, contentType string, createdAt time.Time, public bool) {
+	imageData, err := h.images.LegacyImageData(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Image not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error retrieving image")
 		return
 	}
 
+	// Inline bytes predate sanitizing on upload, so strip EXIF/GPS here until
+	// migrate-images moves them. Images that can't be sanitized aren't served.
+	imageData, _, err = imaging.Sanitize(imageData)
 	if err != nil {
-		http.Error(w, "Error retrieving image", http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("error sanitizing legacy image", "image_id", id, "error", err)
+		problem.Write(w, problem.Internal, "Error retrieving image")
 		return
 	}
 
+	setImageHeaders(w, contentType, store.ContentHash(imageData), public)
+	http.ServeContent(w, r, "", createdAt, bytes.NewReader(imageData))
+}
+
+// setImageHeaders sets the headers shared by every image response. Images
+// may be cached for a day, after which clients revalidate with the ETag.
+// Images that aren't public are kept out of shared caches.
+func setImageHeaders(w http.ResponseWriter, contentType, hash string, public bool) {
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Cache-Control", "public, max-age=31536000")
-	w.Write(imageData)
+	if public {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	} else {
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+	}
+	w.Header().Set("Vary", "Accept")
+	if hash != "" {
+		w.Header().Set("ETag", imageETag(hash))
+	}
+}
+
+// imageETag builds a strong ETag from a content hash
+func imageETag(hash string) string {
+	if len(hash) > 32 {
+		hash = hash[:32]
+	}
+	return `"` + hash + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// imageListMediaType selects the version 2 list shape through the Accept header
+const imageListMediaType = "application/vnd.testwebsite.images.v2+json"
+
+// imageListVersion returns the response version requested with ?v=2 or the
+// versioned media type. Version 1, a bare array of URLs, stays the default so
+// existing clients keep working.
+func imageListVersion(r *http.Request) int {
+	if v := r.URL.Query().Get("v"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 2 {
+			return 2
+		}
+		return 1
+	}
+	if strings.Contains(r.Header.Get("Accept"), imageListMediaType) {
+		return 2
+	}
+	return 1
+}
+
+// writeImageList encodes a folder's images in the requested response version
+func writeImageList(w http.ResponseWriter, r *http.Request, folder string, images []models.GalleryImage) {
+	w.Header().Set("Vary", "Accept")
+
+	if imageListVersion(r) < 2 {
+		urls := make([]string, 0, len(images))
+		for _, image := range images {
+			urls = append(urls, image.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(urls)
+		return
+	}
+
+	if images == nil {
+		images = []models.GalleryImage{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.GalleryImageList{
+		Version: 2,
+		Folder:  folder,
+		Images:  images,
+	})
 }
 
-// ListImages returns all images for a folder
+// ListImages returns all images for a folder along with their captions and
+// camera metadata
 func (h *GalleryHandler) ListImages(w http.ResponseWriter, r *http.Request) {
 	folder := r.URL.Query().Get("folder")
 	if folder == "" {
 		folder = "gallery"
 	}
 
-	allowedFolders := map[string]bool{
-		"gallery":  true,
-		"about":    true,
-		"carbuild": true,
-		"hero":     true,
+	q := parseListQuery(r, store.ImageSorts, 0)
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
 	}
 
-	if !allowedFolders[folder] {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode([]string{})
+	if !folderExists(r.Context(), h.folders, folder, false) {
+		writeImageList(w, r, folder, nil)
 		return
 	}
 
-	rows, err := h.db.Query(
-		"SELECT id, filename FROM gallery_images WHERE folder = $1 ORDER BY display_order, created_at DESC",
-		folder,
-	)
+	images, total, err := h.images.ListImages(r.Context(), folder, q.opts)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode([]string{})
+		logging.FromContext(r.Context()).Error("error listing images", "folder", folder, "error", err)
+		problem.Write(w, problem.Internal, "Error fetching images")
 		return
 	}
-	defer rows.Close()
 
-	var images []string
-	for rows.Next() {
-		var id int
-		var filename string
-		if err := rows.Scan(&id, &filename); err != nil {
-			continue
-		}
-		images = append(images, fmt.Sprintf("/api/image/%d", id))
+	q.writeHeaders(w, total)
+	writeImageList(w, r, folder, images)
+}
+
+// UpdateImage edits an image's caption, alt text, folder or display order.
+// Moving an image to another folder without an explicit order places it last.
+func (h *GalleryHandler) UpdateImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid image ID")
+		return
+	}
+
+	var update models.GalleryImageUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+
+	if update.Caption == nil && update.AltText == nil && update.Folder == nil && update.DisplayOrder == nil {
+		problem.Write(w, problem.BadRequest, "No fields to update")
+		return
+	}
+	fields := make(map[string]string)
+	if update.Caption != nil && len(*update.Caption) > 2000 {
+		fields["caption"] = "Caption must be at most 2000 characters"
+	}
+	if update.AltText != nil && len(*update.AltText) > 500 {
+		fields["alt_text"] = "Alt text must be at most 500 characters"
+	}
+	if update.DisplayOrder != nil && *update.DisplayOrder < 0 {
+		fields["display_order"] = "Display order must not be negative"
+	}
+	if update.Folder != nil && !folderExists(r.Context(), h.folders, *update.Folder, false) {
+		fields["folder"] = "Folder does not exist"
+	}
+	if len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+
+	image, err := h.images.UpdateImage(r.Context(), id, update)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Image not found")
+		return
+	}
+	if errors.Is(err, store.ErrInvalidReference) {
+		problem.Validation(w, map[string]string{"folder": "Folder does not exist"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error updating image", "image_id", id, "error", err)
+		problem.Write(w, problem.Internal, "Error updating image")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(image)
+}
+
+// ReorderImages sets the display order of a folder from a list of image IDs
+func (h *GalleryHandler) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	var req models.GalleryOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+
+	fields := make(map[string]string)
+	if !folderExists(r.Context(), h.folders, req.Folder, false) {
+		fields["folder"] = "Folder does not exist"
+	}
+	if len(req.ImageIDs) == 0 {
+		fields["image_ids"] = "Image IDs are required"
+	}
+	if len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+
+	err := h.images.ReorderImages(r.Context(), req.Folder, req.ImageIDs)
+	if errors.Is(err, store.ErrImageNotInFolder) {
+		problem.Validation(w, map[string]string{"image_ids": "Every image must be in the folder and listed once"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("error reordering images", "folder", req.Folder, "error", err)
+		problem.Write(w, problem.Internal, "Error reordering images")
+		return
+	}
+
+	images, _, err := h.images.ListImages(r.Context(), req.Folder, store.ListOptions{})
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error fetching images")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(images)
+	json.NewEncoder(w).Encode(models.GalleryImageList{
+		Version: 2,
+		Folder:  req.Folder,
+		Images:  images,
+	})
 }
 
 // DeleteImage deletes an image by ID
@@ -216,13 +560,17 @@ func (h *GalleryHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
 	
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid image ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid image ID")
 		return
 	}
 
-	_, err = h.db.Exec("DELETE FROM gallery_images WHERE id = $1", id)
+	err = store.DeleteImage(r.Context(), h.images, h.blobs, id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Image not found")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error deleting image", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error deleting image")
 		return
 	}
 
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/storage"
+)
+
+func listImages(t *testing.T, s *testServer, path string) models.GalleryImageList {
+	t.Helper()
+	w := s.admin("GET", path, nil)
+	expectStatus(t, w, http.StatusOK)
+	var list models.GalleryImageList
+	decode(t, w, &list)
+	return list
+}
+
+func TestGalleryUploadAndServe(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.upload("gallery", testJPEG(t, 64, 48))
+
+	w := s.request("GET", "/api/images?folder=gallery", nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var urls []string
+	decode(t, w, &urls)
+	if len(urls) != 1 || urls[0] != fmt.Sprintf("/api/image/%d", ids[0]) {
+		t.Fatalf("Unexpected v1 image list: %v", urls)
+	}
+
+	list := listImages(t, s, "/api/images?folder=gallery&v=2")
+	if list.Version != 2 || len(list.Images) != 1 {
+		t.Fatalf("Unexpected v2 image list: %+v", list)
+	}
+	if img := list.Images[0]; img.Width == nil || *img.Width != 64 || img.Height == nil || *img.Height != 48 {
+		t.Fatalf("Expected 64x48 dimensions, got %+v", img)
+	}
+
+	path := fmt.Sprintf("/api/image/%d", ids[0])
+	w = s.request("GET", path, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Body.Len() == 0 {
+		t.Fatalf("Expected an ETag and body, got %q with %d bytes", etag, w.Body.Len())
+	}
+
+	r := httptest.NewRequest("GET", path, nil)
+	r.Header.Set("If-None-Match", etag)
+	w = s.serve(r, "")
+	expectStatus(t, w, http.StatusNotModified)
+
+	w = s.request("HEAD", path, nil, "")
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.request("GET", "/api/image/999", nil, "")
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestGalleryUpdateAndReorder(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.upload("gallery", testJPEG(t, 16, 16), testJPEG(t, 16, 16), testJPEG(t, 16, 16))
+
+	caption := "Sunset at the track"
+	w := s.admin("PATCH", fmt.Sprintf("/api/gallery/image/%d", ids[0]), models.GalleryImageUpdate{Caption: &caption})
+	expectStatus(t, w, http.StatusOK)
+	var updated models.GalleryImage
+	decode(t, w, &updated)
+	if updated.Caption != caption {
+		t.Fatalf("Expected caption %q, got %q", caption, updated.Caption)
+	}
+
+	w = s.admin("PATCH", fmt.Sprintf("/api/gallery/image/%d", ids[0]), models.GalleryImageUpdate{})
+	expectStatus(t, w, http.StatusBadRequest)
+
+	w = s.admin("PATCH", "/api/gallery/image/999", models.GalleryImageUpdate{Caption: &caption})
+	expectStatus(t, w, http.StatusNotFound)
+
+	order := []int{ids[2], ids[0], ids[1]}
+	w = s.admin("PUT", "/api/gallery/order", models.GalleryOrderRequest{Folder: "gallery", ImageIDs: order})
+	expectStatus(t, w, http.StatusOK)
+	var list models.GalleryImageList
+	decode(t, w, &list)
+	for i, img := range list.Images {
+		if img.ID != order[i] {
+			t.Fatalf("Expected order %v, got image %d at %d", order, img.ID, i)
+		}
+	}
+
+	w = s.admin("PUT", "/api/gallery/order", models.GalleryOrderRequest{Folder: "about", ImageIDs: order})
+	expectStatus(t, w, http.StatusBadRequest)
+
+	// Moving an image without an order puts it at the end of its new folder
+	s.upload("about", testJPEG(t, 16, 16))
+	folder := "about"
+	w = s.admin("PATCH", fmt.Sprintf("/api/gallery/image/%d", ids[1]), models.GalleryImageUpdate{Folder: &folder})
+	expectStatus(t, w, http.StatusOK)
+
+	about := listImages(t, s, "/api/gallery/images?folder=about&v=2")
+	if len(about.Images) != 2 || about.Images[1].ID != ids[1] {
+		t.Fatalf("Expected moved image last in about, got %+v", about.Images)
+	}
+
+	missing := "missing"
+	w = s.admin("PATCH", fmt.Sprintf("/api/gallery/image/%d", ids[1]), models.GalleryImageUpdate{Folder: &missing})
+	expectStatus(t, w, http.StatusBadRequest)
+}
+
+func TestGalleryDeleteImage(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.upload("gallery", testJPEG(t, 16, 16))
+	path := fmt.Sprintf("/api/gallery/image/%d", ids[0])
+
+	w := s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusOK)
+
+	w = s.admin("DELETE", path, nil)
+	expectStatus(t, w, http.StatusNotFound)
+
+	w = s.request("GET", fmt.Sprintf("/api/image/%d", ids[0]), nil, "")
+	expectStatus(t, w, http.StatusNotFound)
+
+	if list := listImages(t, s, "/api/gallery/images?folder=gallery&v=2"); len(list.Images) != 0 {
+		t.Fatalf("Expected no images after delete, got %+v", list.Images)
+	}
+}
+
+// missingBlobs wraps storage.ErrNotFound the way a backend adding context
+// would
+type missingBlobs struct {
+	storage.Backend
+}
+
+func (missingBlobs) Get(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	return nil, nil, fmt.Errorf("reading %s: %w", key, storage.ErrNotFound)
+}
+
+func TestGalleryMissingObject(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.upload("gallery", testJPEG(t, 16, 16))
+
+	handler := NewGalleryHandler(s.store, s.store, missingBlobs{s.blobs})
+	r := httptest.NewRequest("GET", fmt.Sprintf("/api/image/%d", ids[0]), nil)
+	w := httptest.NewRecorder()
+	handler.GetImage(w, mux.SetURLVars(r, map[string]string{"id": strconv.Itoa(ids[0])}))
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestGalleryServesLegacyImage(t *testing.T) {
+	s := newTestServer(t)
+	data := testJPEG(t, 8, 8)
+	// An EXIF segment right after the start of image marker
+	exif := []byte{0xFF, 0xE1, 0x00, 0x0A, 'E', 'x', 'i', 'f', 0, 0, 'G', 'P'}
+	withExif := append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+	id := s.store.AddLegacyImage("gallery", "old.jpg", "image/jpeg", withExif)
+
+	w := s.request("GET", fmt.Sprintf("/api/image/%d", id), nil, "")
+	expectStatus(t, w, http.StatusOK)
+	if w.Body.Len() != len(data) {
+		t.Fatalf("Expected %d bytes, got %d", len(data), w.Body.Len())
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("Exif")) {
+		t.Fatal("Expected the EXIF segment to be stripped")
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" {
+		t.Fatalf("Expected image/jpeg, got %q", ct)
+	}
+}
+
+func TestGalleryMetrics(t *testing.T) {
+	s := newTestServer(t)
+	uploads := metrics.ImageUploads.Value(metrics.ResultSuccess)
+	served := metrics.ImageBytesServed.Value("about")
+
+	ids := s.upload("about", testJPEG(t, 16, 16))
+	if got := metrics.ImageUploads.Value(metrics.ResultSuccess) - uploads; got != 1 {
+		t.Fatalf("Expected 1 successful upload, got %v", got)
+	}
+
+	w := s.request("GET", fmt.Sprintf("/api/image/%d", ids[0]), nil, "")
+	expectStatus(t, w, http.StatusOK)
+	if got := metrics.ImageBytesServed.Value("about") - served; got != float64(w.Body.Len()) {
+		t.Fatalf("Expected %d bytes served, got %v", w.Body.Len(), got)
+	}
+}
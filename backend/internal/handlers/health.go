@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+// HealthCheck is one dependency checked by the readiness probe
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// NewHealthHandler returns a handler whose readiness probe runs every check
+// in parallel, each limited to timeout
+func NewHealthHandler(timeout time.Duration, checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks, timeout: timeout}
+}
+
+// SetDraining marks the instance as shutting down. Readiness fails from then
+// on so the load balancer stops sending new traffic while in-flight requests
+// finish.
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness reports that the process is up and serving requests. It checks
+// no dependencies, so a database outage doesn't get the process restarted.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, models.HealthStatus{Status: "ok"})
+}
+
+// Readiness reports whether the instance can serve traffic: the database
+// answers, migrations are applied and image storage is reachable. Failure
+// details are logged rather than returned since the endpoint is public.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, models.HealthStatus{Status: "draining"})
+		return
+	}
+
+	status := models.HealthStatus{Status: "ok", Components: make(map[string]models.ComponentHealth, len(h.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			result := h.run(r.Context(), check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			status.Components[check.Name] = result
+			if result.Status != "ok" {
+				status.Status = "unavailable"
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if status.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, status)
+}
+
+func (h *HealthHandler) run(ctx context.Context, check HealthCheck) models.ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := models.ComponentHealth{
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "fail"
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			result.Status = "timeout"
+		}
+		logging.FromContext(ctx).Warn("readiness check failed", "component", check.Name, "error", err)
+	}
+	return result
+}
+
+func writeHealth(w http.ResponseWriter, code int, status models.HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
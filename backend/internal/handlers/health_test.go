@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+func healthRequest(t *testing.T, handler http.HandlerFunc) (int, models.HealthStatus) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", "/readyz", nil))
+	var status models.HealthStatus
+	decode(t, w, &status)
+	return w.Code, status
+}
+
+func TestReadiness(t *testing.T) {
+	ok := HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }}
+	failing := HealthCheck{Name: "storage", Check: func(ctx context.Context) error { return errors.New("bucket missing") }}
+	slow := HealthCheck{Name: "migrations", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	code, status := healthRequest(t, NewHealthHandler(time.Second, ok).Readiness)
+	if code != http.StatusOK || status.Status != "ok" || status.Components["database"].Status != "ok" {
+		t.Fatalf("Expected ready, got %d %+v", code, status)
+	}
+
+	code, status = healthRequest(t, NewHealthHandler(20*time.Millisecond, ok, failing, slow).Readiness)
+	if code != http.StatusServiceUnavailable || status.Status != "unavailable" {
+		t.Fatalf("Expected unavailable, got %d %+v", code, status)
+	}
+	want := map[string]string{"database": "ok", "storage": "fail", "migrations": "timeout"}
+	for name, s := range want {
+		if status.Components[name].Status != s {
+			t.Errorf("Expected %s to be %s, got %+v", name, s, status.Components[name])
+		}
+	}
+}
+
+func TestReadinessDraining(t *testing.T) {
+	h := NewHealthHandler(time.Second)
+
+	if code, _ := healthRequest(t, h.Readiness); code != http.StatusOK {
+		t.Fatalf("Expected ready before draining, got %d", code)
+	}
+
+	h.SetDraining()
+	if code, status := healthRequest(t, h.Readiness); code != http.StatusServiceUnavailable || status.Status != "draining" {
+		t.Fatalf("Expected draining, got %d %+v", code, status)
+	}
+
+	// The process is still alive while it drains
+	if code, status := healthRequest(t, h.Liveness); code != http.StatusOK || status.Status != "ok" {
+		t.Fatalf("Expected live, got %d %+v", code, status)
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/auth"
+	"github.com/Jakeito/TestWebsite/backend/internal/mailer"
+	"github.com/Jakeito/TestWebsite/backend/internal/maintenance"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/preview"
+	"github.com/Jakeito/TestWebsite/backend/internal/spam"
+	"github.com/Jakeito/TestWebsite/backend/internal/storage"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+	"github.com/gorilla/mux"
+)
+
+const (
+	testSecret        = "test-secret"
+	testAdminEmail    = "admin@example.com"
+	testAdminPassword = "changeme"
+)
+
+// published makes test content visible on the public endpoints
+var published = models.Publication{Status: models.StatusPublished}
+
+// testServer wires the handlers to an in-memory store with the same routes
+// cmd/server serves from Postgres
+type testServer struct {
+	t      *testing.T
+	router *mux.Router
+	store  *store.Memory
+	blobs  storage.Backend
+	token  string
+	// contact is exposed so tests can swap in stricter spam checks
+	contact *ContactHandler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	mem := store.NewMemory()
+	blobs, err := storage.NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+
+	hash, err := auth.HashPassword(testAdminPassword)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	admin := &models.User{Email: testAdminEmail, PasswordHash: hash, Username: "Admin", IsAdmin: true}
+	if err := mem.CreateUser(context.Background(), admin); err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+
+	authHandler := NewAuthHandler(mem, mem, testSecret)
+	aboutHandler := NewAboutHandler(mem, mem)
+	resumeHandler := NewResumeHandler(mem, mem)
+	carBuildHandler := NewCarBuildHandler(mem, mem)
+	partHandler := NewPartHandler(mem, mem)
+	maintenanceHandler := NewMaintenanceHandler(mem, maintenance.Window{Miles: 500, Days: 14})
+	revisionHandler := NewRevisionHandler(mem, mem, mem, mem, mem)
+	previewHandler := NewPreviewHandler(preview.NewTokens(testSecret, time.Hour), mem, mem, mem, mem)
+	contactHandler := NewContactHandler(mem, spam.NewFormTokens(testSecret, 0, time.Hour, mem), spam.Disabled{}, spam.Filter{MaxLinks: 2},
+		mailer.ContactEmails{SiteName: "TestWebsite", NotifyTo: testAdminEmail})
+	imageHandler := NewImageHandler(mem, mem)
+	galleryHandler := NewGalleryHandler(mem, mem, blobs)
+	folderHandler := NewFolderHandler(mem)
+
+	r := NewRouter(API{
+		Auth:        authHandler,
+		About:       aboutHandler,
+		Resume:      resumeHandler,
+		CarBuild:    carBuildHandler,
+		Parts:       partHandler,
+		Maintenance: maintenanceHandler,
+		Revisions:   revisionHandler,
+		Preview:     previewHandler,
+		Contact:     contactHandler,
+		Images:      imageHandler,
+		Gallery:     galleryHandler,
+		Folders:     folderHandler,
+		Health:      NewHealthHandler(time.Second),
+		JWTSecret:   testSecret,
+		Sessions:    mem,
+	})
+
+	s := &testServer{t: t, router: r, store: mem, blobs: blobs, contact: contactHandler}
+	s.token = s.login(testAdminEmail, testAdminPassword).Token
+	return s
+}
+
+func (s *testServer) login(email, password string) models.LoginResponse {
+	s.t.Helper()
+
+	w := s.request("POST", "/api/login", models.LoginRequest{Email: email, Password: password}, "")
+	if w.Code != http.StatusOK {
+		s.t.Fatalf("Login failed with status %d: %s", w.Code, w.Body.String())
+	}
+	var resp models.LoginResponse
+	decode(s.t, w, &resp)
+	return resp
+}
+
+// request sends a JSON request, authenticated when token is non-empty
+func (s *testServer) request(method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("Failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	r := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return s.serve(r, token)
+}
+
+// admin sends a JSON request as the admin user
+func (s *testServer) admin(method, path string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+	return s.request(method, path, body, s.token)
+}
+
+func (s *testServer) serve(r *http.Request, token string) *httptest.ResponseRecorder {
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+// upload posts images to a folder and returns the uploaded IDs
+func (s *testServer) upload(folder string, files ...[]byte) []int {
+	s.t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("folder", folder)
+	for i, data := range files {
+		header := make(map[string][]string)
+		header["Content-Disposition"] = []string{`form-data; name="images"; filename="photo` + string(rune('a'+i)) + `.jpg"`}
+		header["Content-Type"] = []string{"image/jpeg"}
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			s.t.Fatalf("Failed to create part: %v", err)
+		}
+		part.Write(data)
+	}
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/api/gallery/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := s.serve(r, s.token)
+	if w.Code != http.StatusOK {
+		s.t.Fatalf("Upload failed with status %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Uploaded int `json:"uploaded"`
+		Images   []struct {
+			ID int `json:"id"`
+		} `json:"images"`
+	}
+	decode(s.t, w, &resp)
+	if resp.Uploaded != len(files) {
+		s.t.Fatalf("Uploaded %d of %d files", resp.Uploaded, len(files))
+	}
+
+	ids := make([]int, len(resp.Images))
+	for i, img := range resp.Images {
+		ids[i] = img.ID
+	}
+	return ids
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", w.Body.String(), err)
+	}
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("Expected status %d, got %d: %s", want, w.Code, w.Body.String())
+	}
+}
+
+// testJPEG encodes a small solid-colour JPEG
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: 120, B: uint8(y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// formToken fetches a contact form token as the frontend does on load
+func (s *testServer) formToken() string {
+	s.t.Helper()
+
+	w := s.request("GET", "/api/contact/token", nil, "")
+	expectStatus(s.t, w, http.StatusOK)
+	var resp struct {
+		Token string `json:"token"`
+	}
+	decode(s.t, w, &resp)
+	return resp.Token
+}
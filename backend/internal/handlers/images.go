@@ -1,65 +1,48 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 
-	"github.com/Jakeito/TestWebsite/backend/internal/database"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
 )
 
 type ImageHandler struct {
-	db *database.DB
+	images  store.ImageStore
+	folders store.FolderStore
 }
 
-func NewImageHandler(db *database.DB) *ImageHandler {
-	return &ImageHandler{db: db}
+func NewImageHandler(images store.ImageStore, folders store.FolderStore) *ImageHandler {
+	return &ImageHandler{images: images, folders: folders}
 }
 
+// GetImages lists a folder's images. By default the response is an array of
+// image URLs; ?v=2 returns objects with captions, alt text and dimensions.
 func (h *ImageHandler) GetImages(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	
 	folder := r.URL.Query().Get("folder")
 	if folder == "" {
 		folder = "gallery"
 	}
-
-	// Validate folder name to prevent injection
-	allowedFolders := map[string]bool{
-		"gallery":  true,
-		"about":    true,
-		"carbuild": true,
-		"hero":     true,
+	q := parseListQuery(r, store.ImageSorts, 0)
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
 	}
 
-	if !allowedFolders[folder] {
-		json.NewEncoder(w).Encode([]string{})
+	// Private and unknown folders look the same to the public
+	if !folderExists(r.Context(), h.folders, folder, true) {
+		writeImageList(w, r, folder, nil)
 		return
 	}
 
-	// Query database for images
-	rows, err := h.db.Query(
-		"SELECT id FROM gallery_images WHERE folder = $1 ORDER BY display_order, created_at DESC",
-		folder,
-	)
+	images, total, err := h.images.ListImages(r.Context(), folder, q.opts)
 	if err != nil {
-		json.NewEncoder(w).Encode([]string{})
+		logging.FromContext(r.Context()).Error("error listing images", "folder", folder, "error", err)
+		writeImageList(w, r, folder, nil)
 		return
 	}
-	defer rows.Close()
-
-	var images []string
-	for rows.Next() {
-		var id int
-		if err := rows.Scan(&id); err != nil {
-			continue
-		}
-		images = append(images, fmt.Sprintf("/api/image/%d", id))
-	}
-
-	if images == nil {
-		images = []string{}
-	}
 
-	json.NewEncoder(w).Encode(images)
+	q.writeHeaders(w, total)
+	writeImageList(w, r, folder, images)
 }
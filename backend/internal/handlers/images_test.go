@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+func TestImageListVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		want   int
+	}{
+		{"default", "/api/images", "", 1},
+		{"query v2", "/api/images?v=2", "", 2},
+		{"query v1", "/api/images?v=1", imageListMediaType, 1},
+		{"invalid query", "/api/images?v=abc", "", 1},
+		{"media type", "/api/images", imageListMediaType, 2},
+		{"plain json", "/api/images", "application/json", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			if got := imageListVersion(r); got != tt.want {
+				t.Errorf("imageListVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteImageList(t *testing.T) {
+	images := []models.GalleryImage{
+		{ID: 1, URL: "/api/image/1", Caption: "First", AltText: "A car"},
+		{ID: 2, URL: "/api/image/2"},
+	}
+
+	w := httptest.NewRecorder()
+	writeImageList(w, httptest.NewRequest("GET", "/api/images", nil), "gallery", images)
+
+	var urls []string
+	if err := json.NewDecoder(w.Body).Decode(&urls); err != nil {
+		t.Fatalf("Failed to decode v1 response: %v", err)
+	}
+	if len(urls) != 2 || urls[0] != "/api/image/1" || urls[1] != "/api/image/2" {
+		t.Errorf("Unexpected v1 response: %v", urls)
+	}
+
+	w = httptest.NewRecorder()
+	writeImageList(w, httptest.NewRequest("GET", "/api/images?v=2", nil), "gallery", images)
+
+	var list models.GalleryImageList
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode v2 response: %v", err)
+	}
+	if list.Version != 2 || list.Folder != "gallery" || len(list.Images) != 2 {
+		t.Fatalf("Unexpected v2 response: %+v", list)
+	}
+	if list.Images[0].Caption != "First" || list.Images[0].AltText != "A car" {
+		t.Errorf("Caption and alt text not returned: %+v", list.Images[0])
+	}
+
+	w = httptest.NewRecorder()
+	writeImageList(w, httptest.NewRequest("GET", "/api/images?v=2", nil), "missing", nil)
+	if body := w.Body.String(); body != "{\"version\":2,\"folder\":\"missing\",\"images\":[]}\n" {
+		t.Errorf("Empty v2 response = %q", body)
+	}
+}
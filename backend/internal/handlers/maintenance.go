@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/maintenance"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// Longest maintenance intervals, which keep due dates and mileages sane
+const (
+	maxIntervalMiles  = 1000000
+	maxIntervalMonths = 120
+)
+
+// MaintenanceHandler serves the car's maintenance schedule and what is due,
+// as of the latest mileage in the build log
+type MaintenanceHandler struct {
+	Store store.MaintenanceStore
+	// Window is how close to due a job counts as upcoming
+	Window maintenance.Window
+}
+
+func NewMaintenanceHandler(schedules store.MaintenanceStore, window maintenance.Window) *MaintenanceHandler {
+	return &MaintenanceHandler{Store: schedules, Window: window}
+}
+
+// report writes the maintenance report, leaving out jobs that aren't due
+// when dueOnly is set
+func (h *MaintenanceHandler) report(w http.ResponseWriter, r *http.Request, dueOnly bool) {
+	report, err := maintenance.Report(r.Context(), h.Store, h.Window, time.Now())
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+	if dueOnly {
+		items := []models.MaintenanceDue{}
+		for _, item := range report.Items {
+			if item.Status != models.MaintenanceOK {
+				items = append(items, item)
+			}
+		}
+		report.Items = items
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetMaintenance reports when every scheduled job is next due, most pressing
+// first
+func (h *MaintenanceHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	h.report(w, r, false)
+}
+
+// GetMaintenanceDue reports only the upcoming and overdue jobs
+func (h *MaintenanceHandler) GetMaintenanceDue(w http.ResponseWriter, r *http.Request) {
+	h.report(w, r, true)
+}
+
+// validateMaintenanceSchedule trims the last done date to a day and returns
+// an error message for each invalid field
+func validateMaintenanceSchedule(schedule *models.MaintenanceSchedule) map[string]string {
+	fields := make(map[string]string)
+	checkText(fields, "name", "Name", schedule.Name, true, maxTitleLength)
+	checkText(fields, "description", "Description", schedule.Description, false, 2000)
+	switch {
+	case schedule.IntervalMiles == nil && schedule.IntervalMonths == nil:
+		fields["interval_miles"] = "An interval in miles or months is required"
+	case schedule.IntervalMiles != nil && (*schedule.IntervalMiles < 1 || *schedule.IntervalMiles > maxIntervalMiles):
+		fields["interval_miles"] = "Interval must be between 1 and " + strconv.Itoa(maxIntervalMiles) + " miles"
+	}
+	if schedule.IntervalMonths != nil && (*schedule.IntervalMonths < 1 || *schedule.IntervalMonths > maxIntervalMonths) {
+		fields["interval_months"] = "Interval must be between 1 and " + strconv.Itoa(maxIntervalMonths) + " months"
+	}
+	if schedule.LastDoneMileage != nil && *schedule.LastDoneMileage < 0 {
+		fields["last_done_mileage"] = "Mileage must not be negative"
+	}
+	if schedule.LastDoneAt != nil {
+		date := time.Date(schedule.LastDoneAt.Year(), schedule.LastDoneAt.Month(), schedule.LastDoneAt.Day(), 0, 0, 0, 0, time.UTC)
+		schedule.LastDoneAt = &date
+	}
+	return fields
+}
+
+// readMaintenanceSchedule decodes and validates the schedule in a create or
+// update request, writing the error response if it can't
+func readMaintenanceSchedule(w http.ResponseWriter, r *http.Request) (*models.MaintenanceSchedule, bool) {
+	var schedule models.MaintenanceSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return nil, false
+	}
+	if fields := validateMaintenanceSchedule(&schedule); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return nil, false
+	}
+	return &schedule, true
+}
+
+// latestMileage returns the latest mileage reading, or nil before the first
+func (h *MaintenanceHandler) latestMileage(ctx context.Context) (*models.MileageReading, error) {
+	reading, err := h.Store.LatestMileage(ctx)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return reading, err
+}
+
+// writeDue writes when schedule is next due, with the given status code
+func (h *MaintenanceHandler) writeDue(w http.ResponseWriter, r *http.Request, status int, schedule *models.MaintenanceSchedule) {
+	reading, err := h.latestMileage(r.Context())
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(maintenance.Due(*schedule, reading, h.Window, time.Now()))
+}
+
+// maintenanceWriteError maps store errors from a schedule write to a response
+func maintenanceWriteError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Maintenance schedule not found")
+		return
+	}
+	logging.FromContext(r.Context()).Error("error "+action+" maintenance schedule", "error", err)
+	problem.Write(w, problem.Internal, "Error "+action+" maintenance schedule")
+}
+
+// CreateMaintenanceSchedule adds a job to the schedule. Unless told when it
+// was last done, it counts from the latest mileage and today.
+func (h *MaintenanceHandler) CreateMaintenanceSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, ok := readMaintenanceSchedule(w, r)
+	if !ok {
+		return
+	}
+
+	if schedule.LastDoneMileage == nil && schedule.IntervalMiles != nil {
+		reading, err := h.latestMileage(r.Context())
+		if err != nil {
+			problem.Write(w, problem.Internal, "Database error")
+			return
+		}
+		if reading != nil {
+			schedule.LastDoneMileage = &reading.Mileage
+		}
+	}
+	if schedule.LastDoneAt == nil {
+		now := time.Now().UTC()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		schedule.LastDoneAt = &today
+	}
+
+	if err := h.Store.CreateMaintenanceSchedule(r.Context(), schedule); err != nil {
+		maintenanceWriteError(w, r, err, "creating")
+		return
+	}
+	h.writeDue(w, r, http.StatusCreated, schedule)
+}
+
+// UpdateMaintenanceSchedule replaces a job's fields
+func (h *MaintenanceHandler) UpdateMaintenanceSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	schedule, ok := readMaintenanceSchedule(w, r)
+	if !ok {
+		return
+	}
+	schedule.ID = id
+
+	if err := h.Store.UpdateMaintenanceSchedule(r.Context(), schedule); err != nil {
+		maintenanceWriteError(w, r, err, "updating")
+		return
+	}
+	h.writeDue(w, r, http.StatusOK, schedule)
+}
+
+// CompleteMaintenance marks a job done at the mileage and date in the body,
+// which default to the latest mileage and today, so it is scheduled again
+// from there
+func (h *MaintenanceHandler) CompleteMaintenance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	var done struct {
+		Mileage *int       `json:"mileage"`
+		Date    *time.Time `json:"date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&done); err != nil && !errors.Is(err, io.EOF) {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+
+	schedule, err := h.Store.GetMaintenanceSchedule(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Maintenance schedule not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	if done.Mileage == nil {
+		reading, err := h.latestMileage(r.Context())
+		if err != nil {
+			problem.Write(w, problem.Internal, "Database error")
+			return
+		}
+		if reading != nil {
+			done.Mileage = &reading.Mileage
+		}
+	}
+	if done.Date == nil {
+		now := time.Now().UTC()
+		done.Date = &now
+	}
+	schedule.LastDoneMileage, schedule.LastDoneAt = done.Mileage, done.Date
+	if fields := validateMaintenanceSchedule(schedule); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+
+	if err := h.Store.UpdateMaintenanceSchedule(r.Context(), schedule); err != nil {
+		maintenanceWriteError(w, r, err, "updating")
+		return
+	}
+	h.writeDue(w, r, http.StatusOK, schedule)
+}
+
+func (h *MaintenanceHandler) DeleteMaintenanceSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	err = h.Store.DeleteMaintenanceSchedule(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Maintenance schedule not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error deleting maintenance schedule")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Maintenance schedule deleted successfully"})
+}
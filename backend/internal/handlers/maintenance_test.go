@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+)
+
+func TestMaintenance(t *testing.T) {
+	s := newTestServer(t)
+	intPtr := func(n int) *int { return &n }
+	fluidChanged := time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, entry := range []models.CarBuildEntry{
+		{Title: "Bought it", Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Mileage: intPtr(78000)},
+		{Title: "Track day", Date: time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC), Mileage: intPtr(84600)},
+		{Title: "New wheels", Date: time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)},
+	} {
+		expectStatus(t, s.admin("POST", "/api/carbuild", entry), http.StatusCreated)
+	}
+	w := s.admin("POST", "/api/carbuild", models.CarBuildEntry{Title: "Oops", Date: time.Now(), Mileage: intPtr(-1)})
+	expectStatus(t, w, http.StatusBadRequest)
+	var p problem.Problem
+	decode(t, w, &p)
+	if p.Fields["mileage"] == "" {
+		t.Errorf("Expected an error for mileage, got %+v", p.Fields)
+	}
+
+	w = s.admin("POST", "/api/maintenance", models.MaintenanceSchedule{Name: "Oil change", IntervalMiles: intPtr(5000)})
+	expectStatus(t, w, http.StatusCreated)
+	var oil models.MaintenanceDue
+	decode(t, w, &oil)
+	if *oil.LastDoneMileage != 84600 || oil.LastDoneAt == nil || oil.Status != models.MaintenanceOK || *oil.MilesRemaining != 5000 {
+		t.Errorf("Expected oil to count from the latest mileage, got %+v", oil)
+	}
+	for _, schedule := range []models.MaintenanceSchedule{
+		{Name: "Spark plugs", IntervalMiles: intPtr(30000), LastDoneMileage: intPtr(55000)},
+		{Name: "Brake fluid", IntervalMonths: intPtr(24), LastDoneAt: &fluidChanged},
+	} {
+		expectStatus(t, s.admin("POST", "/api/maintenance", schedule), http.StatusCreated)
+	}
+	w = s.admin("POST", "/api/maintenance", models.MaintenanceSchedule{Name: "Wax", IntervalMonths: intPtr(0)})
+	expectStatus(t, w, http.StatusBadRequest)
+	p = problem.Problem{}
+	decode(t, w, &p)
+	if p.Fields["interval_months"] == "" {
+		t.Errorf("Expected an error for interval_months, got %+v", p.Fields)
+	}
+	expectStatus(t, s.admin("POST", "/api/maintenance", models.MaintenanceSchedule{Name: "Wax"}), http.StatusBadRequest)
+	expectStatus(t, s.request("GET", "/api/maintenance", nil, ""), http.StatusUnauthorized)
+
+	report := func(path string) models.MaintenanceReport {
+		t.Helper()
+		w := s.admin("GET", path, nil)
+		expectStatus(t, w, http.StatusOK)
+		var report models.MaintenanceReport
+		decode(t, w, &report)
+		return report
+	}
+	statuses := func(report models.MaintenanceReport) string {
+		var got []string
+		for _, item := range report.Items {
+			got = append(got, item.Name+" "+item.Status)
+		}
+		return fmt.Sprint(got)
+	}
+	all := report("/api/maintenance")
+	if all.Mileage == nil || all.Mileage.Mileage != 84600 || all.Mileage.Date.Day() != 30 {
+		t.Errorf("Expected the track day reading, got %+v", all.Mileage)
+	}
+	if got := statuses(all); got != "[Brake fluid overdue Spark plugs upcoming Oil change ok]" {
+		t.Errorf("Unexpected report %s", got)
+	}
+	if got := statuses(report("/api/maintenance/due")); got != "[Brake fluid overdue Spark plugs upcoming]" {
+		t.Errorf("Unexpected due items %s", got)
+	}
+
+	path := fmt.Sprintf("/api/maintenance/%d", all.Items[1].ID)
+	w = s.admin("POST", path+"/done", nil)
+	expectStatus(t, w, http.StatusOK)
+	var plugs models.MaintenanceDue
+	decode(t, w, &plugs)
+	if *plugs.LastDoneMileage != 84600 || *plugs.DueMileage != 114600 || plugs.Status != models.MaintenanceOK {
+		t.Errorf("Expected the plugs to be done at the latest mileage, got %+v", plugs)
+	}
+	w = s.admin("POST", path+"/done", map[string]interface{}{"mileage": 84000, "date": "2024-05-01T00:00:00Z"})
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &plugs)
+	if *plugs.LastDoneMileage != 84000 || plugs.LastDoneAt.Month() != time.May {
+		t.Errorf("Expected the given mileage and date, got %+v", plugs)
+	}
+	expectStatus(t, s.admin("POST", "/api/maintenance/999/done", nil), http.StatusNotFound)
+
+	w = s.admin("PUT", path, models.MaintenanceSchedule{Name: "Spark plugs", IntervalMiles: intPtr(500), LastDoneMileage: intPtr(84000)})
+	expectStatus(t, w, http.StatusOK)
+	decode(t, w, &plugs)
+	if plugs.Status != models.MaintenanceOverdue || *plugs.MilesRemaining != -100 {
+		t.Errorf("Expected the plugs to be overdue, got %+v", plugs)
+	}
+	expectStatus(t, s.admin("PUT", "/api/maintenance/999", models.MaintenanceSchedule{Name: "Wax", IntervalMonths: intPtr(3)}), http.StatusNotFound)
+
+	expectStatus(t, s.admin("DELETE", path, nil), http.StatusOK)
+	expectStatus(t, s.admin("DELETE", path, nil), http.StatusNotFound)
+	if got := statuses(report("/api/maintenance/due")); got != "[Brake fluid overdue]" {
+		t.Errorf("Unexpected due items after delete %s", got)
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/markdown"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// markdownField is a Markdown source and where the HTML it renders to goes
+type markdownField struct {
+	source string
+	html   *string
+}
+
+// renderContent fills in the rendered HTML of about content, resume sections
+// and car build entries, given a pointer to one or a slice of them. Other
+// values are left alone.
+func renderContent(ctx context.Context, images store.ImageStore, content interface{}) {
+	var fields []markdownField
+	switch c := content.(type) {
+	case *models.AboutContent:
+		fields = append(fields, markdownField{c.Content, &c.ContentHTML})
+	case []models.AboutContent:
+		for i := range c {
+			fields = append(fields, markdownField{c[i].Content, &c[i].ContentHTML})
+		}
+	case *models.ResumeSection:
+		fields = append(fields, markdownField{c.Description, &c.DescriptionHTML})
+	case []models.ResumeSection:
+		for i := range c {
+			fields = append(fields, markdownField{c[i].Description, &c[i].DescriptionHTML})
+		}
+	case *models.CarBuildEntry:
+		fields = append(fields, markdownField{c.Description, &c.DescriptionHTML})
+	case []models.CarBuildEntry:
+		for i := range c {
+			fields = append(fields, markdownField{c[i].Description, &c[i].DescriptionHTML})
+		}
+	}
+	renderMarkdown(ctx, images, fields)
+}
+
+// renderMarkdown renders fields, looking up the gallery images they embed in
+// one query. Images in private folders are only resolved for admins. If the
+// lookup fails the images are left out rather than failing the request.
+func renderMarkdown(ctx context.Context, images store.ImageStore, fields []markdownField) {
+	var ids []int
+	for _, field := range fields {
+		ids = append(ids, markdown.ImageIDs(field.source)...)
+	}
+
+	var alts map[int]string
+	if len(ids) > 0 {
+		var err error
+		if alts, err = images.ImageAltTexts(ctx, ids, !isAdmin(ctx)); err != nil {
+			logging.FromContext(ctx).Error("error looking up embedded images", "error", err)
+		}
+	}
+	resolve := func(id int) (string, bool) {
+		alt, ok := alts[id]
+		return alt, ok
+	}
+
+	for _, field := range fields {
+		*field.html = markdown.Render(field.source, resolve)
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// partStatuses are the states a part moves through
+var partStatuses = []string{models.PartOrdered, models.PartInstalled, models.PartReturned}
+
+// PartHandler serves the parts inventory of the car build and what it has
+// cost so far
+type PartHandler struct {
+	Store    store.PartStore
+	CarBuild store.CarBuildStore
+}
+
+func NewPartHandler(parts store.PartStore, carBuild store.CarBuildStore) *PartHandler {
+	return &PartHandler{Store: parts, CarBuild: carBuild}
+}
+
+// partFilter reads the filters shared by the part list and spend endpoints:
+// entry_id, status, category, vendor, since and until
+func partFilter(q *listQuery) store.PartFilter {
+	filter := store.PartFilter{
+		Status:      q.oneOf("status", "", partStatuses...),
+		Category:    q.string("category"),
+		Vendor:      q.string("vendor"),
+		ListOptions: q.opts,
+	}
+	if id := q.int("entry_id", 0, 1, math.MaxInt32); id != 0 {
+		filter.EntryID = &id
+	}
+	filter.Since, filter.Until = q.dateRange()
+	return filter
+}
+
+// ListParts lists parts, newest purchase first unless sorted otherwise
+func (h *PartHandler) ListParts(w http.ResponseWriter, r *http.Request) {
+	q := parseListQuery(r, store.PartSorts, 0)
+	filter := partFilter(q)
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	parts, total, err := h.Store.ListParts(r.Context(), filter)
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+	if parts == nil {
+		parts = []models.Part{}
+	}
+
+	q.writeHeaders(w, total)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(parts)
+}
+
+// GetPartSpend totals the spend on parts, broken down by the group in the
+// path. It takes the same filters as ListParts.
+func (h *PartHandler) GetPartSpend(w http.ResponseWriter, r *http.Request) {
+	q := parseListQuery(r, nil, 0)
+	filter := partFilter(q)
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	spend, err := h.Store.PartSpend(r.Context(), filter, mux.Vars(r)["group"])
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spend)
+}
+
+func (h *PartHandler) GetPart(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	part, err := h.Store.GetPart(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Part not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(part)
+}
+
+// validatePart normalizes the status, quantity and purchase date and returns
+// an error message for each invalid field
+func validatePart(part *models.Part) map[string]string {
+	fields := make(map[string]string)
+	checkText(fields, "name", "Name", part.Name, true, maxTitleLength)
+	checkText(fields, "brand", "Brand", part.Brand, false, 100)
+	checkText(fields, "part_number", "Part number", part.PartNumber, false, 100)
+	checkText(fields, "vendor", "Vendor", part.Vendor, false, 100)
+	checkText(fields, "category", "Category", part.Category, false, 100)
+	if part.Price < 0 {
+		fields["price"] = "Price must not be negative"
+	} else if part.Price > maxCarBuildCost {
+		fields["price"] = "Price is too large"
+	}
+	if part.Quantity == 0 {
+		part.Quantity = 1
+	} else if part.Quantity < 0 {
+		fields["quantity"] = "Quantity must be positive"
+	}
+	if part.PurchaseDate != nil {
+		date := time.Date(part.PurchaseDate.Year(), part.PurchaseDate.Month(), part.PurchaseDate.Day(), 0, 0, 0, 0, time.UTC)
+		part.PurchaseDate = &date
+	}
+
+	part.Status = strings.ToLower(strings.TrimSpace(part.Status))
+	if part.Status == "" {
+		part.Status = models.PartOrdered
+	} else if !slices.Contains(partStatuses, part.Status) {
+		fields["status"] = "Status must be one of " + strings.Join(partStatuses, ", ")
+	}
+	return fields
+}
+
+// linkEntry checks the build entry a part is linked to, and gives the part the
+// entry's category when it has none of its own
+func (h *PartHandler) linkEntry(ctx context.Context, part *models.Part, fields map[string]string) error {
+	if part.EntryID == nil {
+		return nil
+	}
+	entry, err := h.CarBuild.GetCarBuildEntry(ctx, *part.EntryID)
+	if errors.Is(err, store.ErrNotFound) {
+		fields["entry_id"] = "Build entry not found"
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if part.Category == "" {
+		part.Category = entry.Category
+	}
+	return nil
+}
+
+// readPart decodes and validates the part in a create or update request,
+// writing the error response if it can't
+func (h *PartHandler) readPart(w http.ResponseWriter, r *http.Request) (*models.Part, bool) {
+	var part models.Part
+	if err := json.NewDecoder(r.Body).Decode(&part); err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return nil, false
+	}
+
+	fields := validatePart(&part)
+	if err := h.linkEntry(r.Context(), &part, fields); err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return nil, false
+	}
+	if len(fields) > 0 {
+		problem.Validation(w, fields)
+		return nil, false
+	}
+	return &part, true
+}
+
+// partWriteError maps store errors from a part write to a response
+func partWriteError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	switch {
+	case errors.Is(err, store.ErrInvalidReference):
+		problem.Validation(w, map[string]string{"entry_id": "Build entry not found"})
+	case errors.Is(err, store.ErrNotFound):
+		problem.Write(w, problem.NotFound, "Part not found")
+	default:
+		logging.FromContext(r.Context()).Error("error "+action+" part", "error", err)
+		problem.Write(w, problem.Internal, "Error "+action+" part")
+	}
+}
+
+// CreatePart adds a part. Parts linked to a build entry take its category
+// unless given one.
+func (h *PartHandler) CreatePart(w http.ResponseWriter, r *http.Request) {
+	part, ok := h.readPart(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Store.CreatePart(r.Context(), part); err != nil {
+		partWriteError(w, r, err, "creating")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(part)
+}
+
+// UpdatePart replaces a part's fields
+func (h *PartHandler) UpdatePart(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	part, ok := h.readPart(w, r)
+	if !ok {
+		return
+	}
+	part.ID = id
+
+	if err := h.Store.UpdatePart(r.Context(), part); err != nil {
+		partWriteError(w, r, err, "updating")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(part)
+}
+
+func (h *PartHandler) DeletePart(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	err = h.Store.DeletePart(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Part not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error deleting part")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Part deleted successfully"})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+)
+
+func TestValidatePart(t *testing.T) {
+	tests := []struct {
+		name  string
+		part  models.Part
+		field string
+	}{
+		{"valid", models.Part{Name: "Coilovers", Price: 1200, Quantity: 1}, ""},
+		{"defaults", models.Part{Name: "Lug nuts"}, ""},
+		{"missing name", models.Part{Price: 10}, "name"},
+		{"negative price", models.Part{Name: "Pads", Price: -1}, "price"},
+		{"huge price", models.Part{Name: "Pads", Price: 1e9}, "price"},
+		{"negative quantity", models.Part{Name: "Pads", Quantity: -2}, "quantity"},
+		{"bad status", models.Part{Name: "Pads", Status: "lost"}, "status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			part := tt.part
+			fields := validatePart(&part)
+			if tt.field == "" && len(fields) > 0 {
+				t.Fatalf("validatePart() = %v, want no errors", fields)
+			}
+			if tt.field != "" && (len(fields) != 1 || fields[tt.field] == "") {
+				t.Fatalf("validatePart() = %v, want an error for %s", fields, tt.field)
+			}
+			if len(fields) == 0 && (part.Quantity < 1 || part.Status != models.PartOrdered) {
+				t.Errorf("Expected quantity and status defaults, got %+v", part)
+			}
+		})
+	}
+}
+
+func TestPartCRUD(t *testing.T) {
+	s := newTestServer(t)
+	date := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	w := s.admin("POST", "/api/carbuild", models.CarBuildEntry{Title: "Brakes", Category: "brakes", Date: date})
+	expectStatus(t, w, http.StatusCreated)
+	var entry models.CarBuildEntry
+	decode(t, w, &entry)
+
+	purchased := time.Date(2024, 1, 10, 15, 30, 0, 0, time.UTC)
+	w = s.admin("POST", "/api/parts", models.Part{Name: "Pads", Brand: "Hawk", Price: 89.5, EntryID: &entry.ID, PurchaseDate: &purchased})
+	expectStatus(t, w, http.StatusCreated)
+	var part models.Part
+	decode(t, w, &part)
+	if part.Category != "brakes" || part.Quantity != 1 || part.Status != models.PartOrdered || !part.PurchaseDate.Equal(purchased.Truncate(24*time.Hour)) {
+		t.Errorf("Expected the entry's category and defaults, got %+v", part)
+	}
+	expectStatus(t, s.request("POST", "/api/parts", models.Part{Name: "Pads"}, ""), http.StatusUnauthorized)
+
+	missing := 999
+	w = s.admin("POST", "/api/parts", models.Part{Name: "Rotors", EntryID: &missing, Price: -5})
+	expectStatus(t, w, http.StatusBadRequest)
+	var p problem.Problem
+	decode(t, w, &p)
+	if p.Fields["entry_id"] == "" || p.Fields["price"] == "" {
+		t.Errorf("Expected errors for entry_id and price, got %+v", p.Fields)
+	}
+
+	path := fmt.Sprintf("/api/parts/%d", part.ID)
+	w = s.admin("PUT", path, models.Part{Name: "Pads", Price: 89.5, Quantity: 2, Category: "brakes", Status: "Installed"})
+	expectStatus(t, w, http.StatusOK)
+	w = s.request("GET", path, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	part = models.Part{}
+	decode(t, w, &part)
+	if part.Quantity != 2 || part.Status != models.PartInstalled || part.EntryID != nil || part.Brand != "" {
+		t.Errorf("Expected the part to be replaced, got %+v", part)
+	}
+	expectStatus(t, s.admin("PUT", "/api/parts/999", models.Part{Name: "Pads"}), http.StatusNotFound)
+
+	w = s.request("GET", "/api/parts?status=installed", nil, "")
+	var parts []models.Part
+	decode(t, w, &parts)
+	if len(parts) != 1 || w.Header().Get(TotalCountHeader) != "1" {
+		t.Errorf("Expected the installed part, got %+v", parts)
+	}
+	expectStatus(t, s.request("GET", "/api/parts?status=lost", nil, ""), http.StatusBadRequest)
+
+	expectStatus(t, s.admin("DELETE", path, nil), http.StatusOK)
+	expectStatus(t, s.request("GET", path, nil, ""), http.StatusNotFound)
+	expectStatus(t, s.admin("DELETE", path, nil), http.StatusNotFound)
+}
+
+func TestPartSpend(t *testing.T) {
+	s := newTestServer(t)
+	day := func(month, d int) *time.Time {
+		date := time.Date(2024, time.Month(month), d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	for _, part := range []models.Part{
+		{Name: "Turbo", Category: "engine", Vendor: "FastParts", Price: 1500, PurchaseDate: day(2, 3)},
+		{Name: "Spark plugs", Category: "engine", Vendor: "AutoShop", Price: 12.5, Quantity: 4, PurchaseDate: day(1, 20)},
+		{Name: "Pads", Category: "brakes", Vendor: "AutoShop", Price: 89.99, PurchaseDate: day(1, 5), Status: models.PartInstalled},
+		{Name: "Wrong pads", Category: "brakes", Vendor: "AutoShop", Price: 75, PurchaseDate: day(1, 5), Status: models.PartReturned},
+		{Name: "Stickers", Price: 10.01},
+	} {
+		expectStatus(t, s.admin("POST", "/api/parts", part), http.StatusCreated)
+	}
+
+	spend := func(path string) models.PartSpend {
+		t.Helper()
+		w := s.request("GET", path, nil, "")
+		expectStatus(t, w, http.StatusOK)
+		var spend models.PartSpend
+		decode(t, w, &spend)
+		return spend
+	}
+	tests := []struct {
+		path  string
+		total float64
+		want  []models.SpendTotal
+	}{
+		{"/api/parts/spend/category", 1650, []models.SpendTotal{
+			{Key: "engine", Total: 1550, Parts: 2}, {Key: "brakes", Total: 89.99, Parts: 1}, {Key: "", Total: 10.01, Parts: 1},
+		}},
+		{"/api/parts/spend/month", 1650, []models.SpendTotal{
+			{Key: "2024-01", Total: 139.99, Parts: 2}, {Key: "2024-02", Total: 1500, Parts: 1}, {Key: "", Total: 10.01, Parts: 1},
+		}},
+		{"/api/parts/spend/vendor?since=2024-01-10", 1550, []models.SpendTotal{
+			{Key: "FastParts", Total: 1500, Parts: 1}, {Key: "AutoShop", Total: 50, Parts: 1},
+		}},
+	}
+	for _, tt := range tests {
+		got := spend(tt.path)
+		if got.Total != tt.total || fmt.Sprint(got.Totals) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: expected %v in total as %v, got %v as %v", tt.path, tt.total, tt.want, got.Total, got.Totals)
+		}
+	}
+	expectStatus(t, s.request("GET", "/api/parts/spend/brand", nil, ""), http.StatusNotFound)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+)
+
+// mergePatchMediaType is the media type of JSON Merge Patch (RFC 7386) bodies.
+// Plain application/json is accepted too.
+const mergePatchMediaType = "application/merge-patch+json"
+
+// Largest PATCH body read, in bytes
+const maxPatchSize = 1 << 20
+
+// versionETag builds the strong ETag of a versioned record
+func versionETag(version int) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// checkIfMatch responds 412 and returns false when an If-Match header is
+// present and names neither * nor the record's current version. If-Match uses
+// strong comparison, so weak tags never match.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	etag := versionETag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	w.Header().Set("ETag", etag)
+	writeStale(w)
+	return false
+}
+
+// ifMatchVersion returns the version named by an If-Match header, for writes
+// that leave the check to the store. It is 0 when the header is absent or *,
+// and ok is false when the header names no version at all.
+func ifMatchVersion(r *http.Request) (version int, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	if _, err := fmt.Sscanf(header, `"v%d"`, &version); err != nil || versionETag(version) != header {
+		return 0, false
+	}
+	return version, true
+}
+
+// writeStale responds 412 to a write based on an outdated version
+func writeStale(w http.ResponseWriter) {
+	problem.Write(w, problem.PreconditionFailed, "The record has changed since it was read; reload it and try again")
+}
+
+// readMergePatch reads a JSON Merge Patch body, which must be a JSON object.
+// It writes the error response and returns false if the body is unusable.
+func readMergePatch(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil ||
+		(mediaType != mergePatchMediaType && mediaType != "application/json") {
+		problem.Write(w, problem.UnsupportedMediaType, "PATCH bodies must be "+mergePatchMediaType)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return nil, false
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		problem.Write(w, problem.InvalidBody, "Patch must be a JSON object")
+		return nil, false
+	}
+	return patch, true
+}
+
+// errInvalidPatch is returned when a patched document no longer fits the model
+var errInvalidPatch = errors.New("patch does not match the record's fields")
+
+// applyMergePatch returns a copy of current with patch applied as described
+// in RFC 7386: members set to null are removed, objects merge recursively and
+// anything else replaces the current value.
+func applyMergePatch[T any](current *T, patch map[string]interface{}) (*T, error) {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	data, err = json.Marshal(mergeObject(doc, patch))
+	if err != nil {
+		return nil, err
+	}
+	var patched T
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return nil, errInvalidPatch
+	}
+	return &patched, nil
+}
+
+func mergeObject(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = make(map[string]interface{})
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if object, ok := value.(map[string]interface{}); ok {
+			existing, _ := target[key].(map[string]interface{})
+			target[key] = mergeObject(existing, object)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
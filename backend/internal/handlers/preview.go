@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/preview"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// PreviewHandler shares content before it is published through signed links.
+// Routes name the content type in the "type" path variable.
+type PreviewHandler struct {
+	Tokens   *preview.Tokens
+	About    store.AboutStore
+	Resume   store.ResumeStore
+	CarBuild store.CarBuildStore
+	Images   store.ImageStore
+}
+
+func NewPreviewHandler(tokens *preview.Tokens, about store.AboutStore, resume store.ResumeStore, carBuild store.CarBuildStore, images store.ImageStore) *PreviewHandler {
+	return &PreviewHandler{Tokens: tokens, About: about, Resume: resume, CarBuild: carBuild, Images: images}
+}
+
+// content loads a record of any status by type and ID
+func (h *PreviewHandler) content(ctx context.Context, contentType string, id int) (interface{}, error) {
+	switch contentType {
+	case models.AboutContentType:
+		return h.About.GetAboutContent(ctx, id)
+	case models.ResumeContentType:
+		return h.Resume.GetResumeSection(ctx, id)
+	case models.CarBuildContentType:
+		return h.CarBuild.GetCarBuildEntry(ctx, id)
+	}
+	return nil, store.ErrNotFound
+}
+
+// CreatePreviewLink issues a link to one record that works without logging in
+func (h *PreviewHandler) CreatePreviewLink(w http.ResponseWriter, r *http.Request) {
+	contentType := mux.Vars(r)["type"]
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	_, err = h.content(r.Context(), contentType, id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Content not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	token, expires := h.Tokens.Issue(contentType, id)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.PreviewLink{
+		Token:     token,
+		URL:       fmt.Sprintf("/api/preview/%s/%d?token=%s", contentType, id, url.QueryEscape(token)),
+		ExpiresAt: expires,
+	})
+}
+
+// GetPreview returns one record whatever its status to holders of a valid
+// preview token. Previews are kept out of caches and search engines.
+func (h *PreviewHandler) GetPreview(w http.ResponseWriter, r *http.Request) {
+	contentType := mux.Vars(r)["type"]
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	switch err := h.Tokens.Check(r.URL.Query().Get("token"), contentType, id); {
+	case errors.Is(err, preview.ErrExpired):
+		problem.Write(w, problem.InvalidToken, "Preview link has expired")
+		return
+	case err != nil:
+		problem.Write(w, problem.InvalidToken, "Invalid preview link")
+		return
+	}
+
+	content, err := h.content(r.Context(), contentType, id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Content not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+
+	renderContent(r.Context(), h.Images, content)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
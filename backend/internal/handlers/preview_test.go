@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+func TestPreviewLinks(t *testing.T) {
+	s := newTestServer(t)
+	w := s.admin("POST", "/api/about", models.AboutContent{Title: "Coming soon", Content: "Draft"})
+	expectStatus(t, w, http.StatusCreated)
+	var about models.AboutContent
+	decode(t, w, &about)
+	expectStatus(t, s.request("GET", fmt.Sprintf("/api/about/%d", about.ID), nil, ""), http.StatusNotFound)
+
+	expectStatus(t, s.request("POST", fmt.Sprintf("/api/about/%d/preview", about.ID), nil, ""), http.StatusUnauthorized)
+	expectStatus(t, s.admin("POST", "/api/about/999/preview", nil), http.StatusNotFound)
+	w = s.admin("POST", fmt.Sprintf("/api/about/%d/preview", about.ID), nil)
+	expectStatus(t, w, http.StatusCreated)
+	var link models.PreviewLink
+	decode(t, w, &link)
+	if link.Token == "" || link.ExpiresAt.IsZero() {
+		t.Fatalf("Unexpected preview link %+v", link)
+	}
+
+	w = s.request("GET", link.URL, nil, "")
+	expectStatus(t, w, http.StatusOK)
+	var previewed models.AboutContent
+	decode(t, w, &previewed)
+	if previewed.Title != "Coming soon" || previewed.Status != models.StatusDraft {
+		t.Errorf("Expected the draft, got %+v", previewed)
+	}
+	if w.Header().Get("Cache-Control") != "no-store" || w.Header().Get("X-Robots-Tag") != "noindex" {
+		t.Errorf("Expected previews to be kept out of caches and indexes, got %v", w.Header())
+	}
+
+	// The token only opens the record it was issued for
+	for _, path := range []string{
+		fmt.Sprintf("/api/preview/about/%d", about.ID),
+		fmt.Sprintf("/api/preview/about/%d?token=forged", about.ID),
+		fmt.Sprintf("/api/preview/resume/%d?token=%s", about.ID, link.Token),
+		fmt.Sprintf("/api/preview/about/%d?token=%s", about.ID+1, link.Token),
+	} {
+		expectStatus(t, s.request("GET", path, nil, ""), http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// Page sizes for paginated lists
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+// TotalCountHeader carries the number of items across all pages
+const TotalCountHeader = "X-Total-Count"
+
+// listQuery reads the query parameters shared by list endpoints. A page is
+// requested with limit and offset, or with page and per_page; sort names a
+// field, with a leading - for descending order. Filters are read with the
+// typed helpers. The first invalid parameter is kept in err, so a handler
+// reads everything and checks once.
+type listQuery struct {
+	r      *http.Request
+	values url.Values
+	opts   store.ListOptions
+	// paged is set when the page was requested with page and per_page
+	paged bool
+	err   error
+}
+
+// parseListQuery reads the pagination and sort parameters of r. sorts lists
+// the fields the endpoint can be sorted on; defaultLimit is the page size when
+// none is requested, or 0 to return every item.
+func parseListQuery(r *http.Request, sorts []string, defaultLimit int) *listQuery {
+	q := &listQuery{r: r, values: r.URL.Query()}
+
+	if q.values.Has("page") || q.values.Has("per_page") {
+		q.paged = true
+		perPage := defaultLimit
+		if perPage == 0 {
+			perPage = defaultPerPage
+		}
+		perPage = q.int("per_page", perPage, 1, maxPerPage)
+		page := q.int("page", 1, 1, math.MaxInt32)
+		q.opts.Limit, q.opts.Offset = perPage, (page-1)*perPage
+	} else {
+		q.opts.Limit = q.int("limit", defaultLimit, 1, maxPerPage)
+		q.opts.Offset = q.int("offset", 0, 0, math.MaxInt32)
+	}
+
+	if value := q.values.Get("sort"); value != "" {
+		field, desc := strings.CutPrefix(value, "-")
+		if !slices.Contains(sorts, field) {
+			q.fail(fmt.Errorf("invalid sort %q, expected one of %s", value, strings.Join(sorts, ", ")))
+		}
+		q.opts.Sort, q.opts.Desc = field, desc
+	}
+	return q
+}
+
+// fail records err unless an earlier parameter already failed
+func (q *listQuery) fail(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+// int reads an integer parameter between lo and hi, or returns def if it is
+// absent
+func (q *listQuery) int(name string, def, lo, hi int) int {
+	value := q.values.Get(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		if hi == math.MaxInt32 {
+			q.fail(fmt.Errorf("%s must be a whole number of at least %d", name, lo))
+		} else {
+			q.fail(fmt.Errorf("%s must be between %d and %d", name, lo, hi))
+		}
+		return def
+	}
+	return n
+}
+
+// string reads a parameter with surrounding space removed
+func (q *listQuery) string(name string) string {
+	return strings.TrimSpace(q.values.Get(name))
+}
+
+// oneOf reads a parameter that must be one of allowed, or returns def if it is
+// absent
+func (q *listQuery) oneOf(name, def string, allowed ...string) string {
+	value := q.values.Get(name)
+	if value == "" {
+		return def
+	}
+	if !slices.Contains(allowed, value) {
+		q.fail(fmt.Errorf("invalid %s %q", name, value))
+		return def
+	}
+	return value
+}
+
+// bool reads an optional true/false parameter
+func (q *listQuery) bool(name string) *bool {
+	value := q.values.Get(name)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		q.fail(fmt.Errorf("invalid %s %q", name, value))
+		return nil
+	}
+	return &b
+}
+
+// float reads an optional number parameter
+func (q *listQuery) float(name string) *float64 {
+	value := q.values.Get(name)
+	if value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		q.fail(fmt.Errorf("invalid %s %q", name, value))
+		return nil
+	}
+	return &f
+}
+
+// time reads an optional date (2006-01-02) or RFC 3339 time parameter. With
+// endOfDay set, a date-only value means the start of the following day, so an
+// exclusive bound includes the whole date.
+func (q *listQuery) time(name string, endOfDay bool) *time.Time {
+	value := q.values.Get(name)
+	if value == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		q.fail(fmt.Errorf("invalid %s %q, expected a date or RFC 3339 time", name, value))
+		return nil
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t
+}
+
+// dateRange reads the since and until parameters; until is exclusive
+func (q *listQuery) dateRange() (since, until *time.Time) {
+	return q.time("since", false), q.time("until", true)
+}
+
+// statusFilter selects live content for the public; admins may narrow a list
+// to one status with the status parameter
+func (q *listQuery) statusFilter(publicOnly bool) store.StatusFilter {
+	if publicOnly {
+		return store.StatusFilter{Live: true}
+	}
+	return store.StatusFilter{Status: q.oneOf("status", "", publicationStatuses...)}
+}
+
+// writeHeaders sets X-Total-Count and, for a limited page, a Link header
+// pointing at the neighbouring pages in the same style the request used
+func (q *listQuery) writeHeaders(w http.ResponseWriter, total int) {
+	w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+
+	limit, offset := q.opts.Limit, q.opts.Offset
+	if limit == 0 {
+		return
+	}
+	last := 0
+	if total > 0 {
+		last = (total - 1) / limit * limit
+	}
+	link := func(offset int, rel string) string {
+		query := q.r.URL.Query()
+		if q.paged {
+			query.Set("page", strconv.Itoa(offset/limit+1))
+			query.Set("per_page", strconv.Itoa(limit))
+		} else {
+			query.Set("limit", strconv.Itoa(limit))
+			query.Set("offset", strconv.Itoa(offset))
+		}
+		u := url.URL{Path: q.r.URL.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=%q", u.String(), rel)
+	}
+
+	links := []string{link(0, "first")}
+	if offset > 0 {
+		links = append(links, link(max(min(offset-limit, last), 0), "prev"))
+	}
+	if offset+limit < total {
+		links = append(links, link(offset+limit, "next"))
+	}
+	links = append(links, link(last, "last"))
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
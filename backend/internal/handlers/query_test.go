@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+func TestParseListQuery(t *testing.T) {
+	sorts := []string{"date", "title"}
+	tests := []struct {
+		query   string
+		want    store.ListOptions
+		wantErr bool
+	}{
+		{"", store.ListOptions{Limit: 10}, false},
+		{"limit=5&offset=20", store.ListOptions{Limit: 5, Offset: 20}, false},
+		{"page=3&per_page=4", store.ListOptions{Limit: 4, Offset: 8}, false},
+		{"page=2", store.ListOptions{Limit: 10, Offset: 10}, false},
+		{"sort=title", store.ListOptions{Sort: "title", Limit: 10}, false},
+		{"sort=-date", store.ListOptions{Sort: "date", Desc: true, Limit: 10}, false},
+		{"sort=cost", store.ListOptions{}, true},
+		{"limit=0", store.ListOptions{}, true},
+		{"limit=201", store.ListOptions{}, true},
+		{"offset=-1", store.ListOptions{}, true},
+		{"page=0", store.ListOptions{}, true},
+		{"per_page=abc", store.ListOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		q := parseListQuery(httptest.NewRequest("GET", "/api/items?"+tt.query, nil), sorts, 10)
+		if tt.wantErr {
+			if q.err == nil {
+				t.Errorf("%q: expected an error", tt.query)
+			}
+			continue
+		}
+		if q.err != nil {
+			t.Errorf("%q: unexpected error %v", tt.query, q.err)
+		} else if q.opts != tt.want {
+			t.Errorf("%q: expected %+v, got %+v", tt.query, tt.want, q.opts)
+		}
+	}
+}
+
+func TestListQueryFilters(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/items?category=+Engine+&min_cost=9.5&since=2024-01-01&until=2024-01-31&filter=all", nil)
+	q := parseListQuery(r, nil, 0)
+	if got := q.string("category"); got != "Engine" {
+		t.Errorf("Expected trimmed category, got %q", got)
+	}
+	if got := q.float("min_cost"); got == nil || *got != 9.5 {
+		t.Errorf("Expected min_cost 9.5, got %v", got)
+	}
+	if got := q.float("max_cost"); got != nil {
+		t.Errorf("Expected no max_cost, got %v", *got)
+	}
+	since, until := q.dateRange()
+	if since == nil || since.Format("2006-01-02") != "2024-01-01" {
+		t.Errorf("Unexpected since %v", since)
+	}
+	if until == nil || until.Format("2006-01-02") != "2024-02-01" {
+		t.Errorf("Expected until to include the whole day, got %v", until)
+	}
+	if got := q.oneOf("filter", "inbox", "inbox", "all"); got != "all" {
+		t.Errorf("Expected filter all, got %q", got)
+	}
+	if q.err != nil {
+		t.Fatalf("Unexpected error %v", q.err)
+	}
+
+	r = httptest.NewRequest("GET", "/api/items?min_cost=NaN&read=maybe", nil)
+	q = parseListQuery(r, nil, 0)
+	q.float("min_cost")
+	q.bool("read")
+	if q.err == nil || q.err.Error() != `invalid min_cost "NaN"` {
+		t.Errorf("Expected the first invalid parameter to be reported, got %v", q.err)
+	}
+}
+
+func TestListQueryHeaders(t *testing.T) {
+	tests := []struct {
+		query string
+		total int
+		link  string
+	}{
+		{"", 7, ""},
+		{"limit=3", 7, `</api/items?limit=3&offset=0>; rel="first", </api/items?limit=3&offset=3>; rel="next", </api/items?limit=3&offset=6>; rel="last"`},
+		{"limit=3&offset=3", 7, `</api/items?limit=3&offset=0>; rel="first", </api/items?limit=3&offset=0>; rel="prev", </api/items?limit=3&offset=6>; rel="next", </api/items?limit=3&offset=6>; rel="last"`},
+		{"page=3&per_page=3", 7, `</api/items?page=1&per_page=3>; rel="first", </api/items?page=2&per_page=3>; rel="prev", </api/items?page=3&per_page=3>; rel="last"`},
+		{"page=9&per_page=3", 7, `</api/items?page=1&per_page=3>; rel="first", </api/items?page=3&per_page=3>; rel="prev", </api/items?page=3&per_page=3>; rel="last"`},
+		{"limit=3", 0, `</api/items?limit=3&offset=0>; rel="first", </api/items?limit=3&offset=0>; rel="last"`},
+	}
+
+	for _, tt := range tests {
+		q := parseListQuery(httptest.NewRequest("GET", "/api/items?"+tt.query, nil), nil, 0)
+		w := httptest.NewRecorder()
+		q.writeHeaders(w, tt.total)
+		if got := w.Header().Get("Link"); got != tt.link {
+			t.Errorf("%q: expected Link %s, got %s", tt.query, tt.link, got)
+		}
+		if got := w.Header().Get(TotalCountHeader); got == "" {
+			t.Errorf("%q: expected %s to be set", tt.query, TotalCountHeader)
+		}
+	}
+}
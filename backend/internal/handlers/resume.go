@@ -1,88 +1,151 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/Jakeito/TestWebsite/backend/internal/database"
 	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
 )
 
 type ResumeHandler struct {
-	DB *database.DB
+	Store store.ResumeStore
+	// Images resolves the gallery images embedded in the Markdown
+	Images store.ImageStore
 }
 
-func NewResumeHandler(db *database.DB) *ResumeHandler {
-	return &ResumeHandler{DB: db}
+func NewResumeHandler(resume store.ResumeStore, images store.ImageStore) *ResumeHandler {
+	return &ResumeHandler{Store: resume, Images: images}
 }
 
-func (h *ResumeHandler) GetResumeSections(w http.ResponseWriter, r *http.Request) {
-	rows, err := h.DB.Query(
-		`SELECT id, section_type, title, subtitle, description, start_date, end_date, 
-		display_order, created_at, updated_at FROM resume_sections ORDER BY display_order, start_date DESC`,
-	)
+// listResumeSections lists resume sections in display order unless sorted
+// otherwise; section_type narrows them to one type. When publicOnly is set
+// only live sections are listed; otherwise status narrows them to one status.
+func (h *ResumeHandler) listResumeSections(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	q := parseListQuery(r, store.ResumeSorts, 0)
+	filter := store.ResumeFilter{
+		SectionType:  q.string("section_type"),
+		StatusFilter: q.statusFilter(publicOnly),
+		ListOptions:  q.opts,
+	}
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	sections, total, err := h.Store.ListResumeSections(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var sections []models.ResumeSection
-	for rows.Next() {
-		var section models.ResumeSection
-		var subtitle, description sql.NullString
-		var startDate, endDate sql.NullTime
-		
-		if err := rows.Scan(
-			&section.ID, &section.SectionType, &section.Title, &subtitle, &description,
-			&startDate, &endDate, &section.DisplayOrder, &section.CreatedAt, &section.UpdatedAt,
-		); err != nil {
-			http.Error(w, "Error scanning row", http.StatusInternalServerError)
-			return
-		}
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
 
-		if subtitle.Valid {
-			section.Subtitle = subtitle.String
-		}
-		if description.Valid {
-			section.Description = description.String
-		}
-		if startDate.Valid {
-			section.StartDate = &startDate.Time
-		}
-		if endDate.Valid {
-			section.EndDate = &endDate.Time
-		}
+	renderContent(r.Context(), h.Images, sections)
+	q.writeHeaders(w, total)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sections)
+}
+
+// GetResumeSections lists the resume sections the public may see
+func (h *ResumeHandler) GetResumeSections(w http.ResponseWriter, r *http.Request) {
+	h.listResumeSections(w, r, true)
+}
+
+// ListResumeSections lists resume sections in every status
+func (h *ResumeHandler) ListResumeSections(w http.ResponseWriter, r *http.Request) {
+	h.listResumeSections(w, r, false)
+}
+
+// resumeSectionTypes are the groups the resume page shows sections under
+var resumeSectionTypes = []string{"experience", "education", "skills", "projects", "certifications"}
+
+// validateResumeSection normalizes the section type and status, drops any
+// rendered HTML sent back with the section and returns an error message for
+// each invalid field
+func validateResumeSection(section *models.ResumeSection) map[string]string {
+	fields := make(map[string]string)
+	section.DescriptionHTML = ""
+
+	section.SectionType = strings.ToLower(strings.TrimSpace(section.SectionType))
+	if section.SectionType == "" {
+		fields["section_type"] = "Section type is required"
+	} else if !slices.Contains(resumeSectionTypes, section.SectionType) {
+		fields["section_type"] = "Section type must be one of " + strings.Join(resumeSectionTypes, ", ")
+	}
+
+	checkText(fields, "title", "Title", section.Title, true, maxTitleLength)
+	checkText(fields, "subtitle", "Subtitle", section.Subtitle, false, maxTitleLength)
+	if section.StartDate != nil && section.EndDate != nil && section.EndDate.Before(*section.StartDate) {
+		fields["end_date"] = "End date must not be before the start date"
+	}
+	if section.DisplayOrder < 0 {
+		fields["display_order"] = "Display order must not be negative"
+	}
+	checkPublication(fields, &section.Publication)
+	return fields
+}
+
+// showResumeSection returns one resume section with its ETag. When publicOnly
+// is set, a section that isn't live is treated as missing.
+func (h *ResumeHandler) showResumeSection(w http.ResponseWriter, r *http.Request, publicOnly bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
 
-		sections = append(sections, section)
+	section, err := h.Store.GetResumeSection(r.Context(), id)
+	if err == nil && publicOnly && !section.Live(time.Now()) {
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Resume section not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
 	}
 
+	renderContent(r.Context(), h.Images, section)
+	w.Header().Set("ETag", versionETag(section.Version))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(sections)
+	json.NewEncoder(w).Encode(section)
+}
+
+// GetResumeSection returns one live resume section
+func (h *ResumeHandler) GetResumeSection(w http.ResponseWriter, r *http.Request) {
+	h.showResumeSection(w, r, true)
+}
+
+// ShowResumeSection returns one resume section in any status
+func (h *ResumeHandler) ShowResumeSection(w http.ResponseWriter, r *http.Request) {
+	h.showResumeSection(w, r, false)
 }
 
 func (h *ResumeHandler) CreateResumeSection(w http.ResponseWriter, r *http.Request) {
 	var section models.ResumeSection
 	if err := json.NewDecoder(r.Body).Decode(&section); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
+		return
+	}
+	if fields := validateResumeSection(&section); len(fields) > 0 {
+		problem.Validation(w, fields)
 		return
 	}
 
-	err := h.DB.QueryRow(
-		`INSERT INTO resume_sections (section_type, title, subtitle, description, start_date, end_date, display_order)
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`,
-		section.SectionType, section.Title, nullString(section.Subtitle), nullString(section.Description),
-		nullTime(section.StartDate), nullTime(section.EndDate), section.DisplayOrder,
-	).Scan(&section.ID, &section.CreatedAt, &section.UpdatedAt)
-
-	if err != nil {
-		http.Error(w, "Error creating resume section", http.StatusInternalServerError)
+	if err := h.Store.CreateResumeSection(r.Context(), &section, currentUserID(r)); err != nil {
+		problem.Write(w, problem.Internal, "Error creating resume section")
 		return
 	}
 
+	renderContent(r.Context(), h.Images, &section)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(section)
@@ -92,43 +155,129 @@ func (h *ResumeHandler) UpdateResumeSection(w http.ResponseWriter, r *http.Reque
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid ID")
 		return
 	}
 
 	var section models.ResumeSection
 	if err := json.NewDecoder(r.Body).Decode(&section); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidBody, "Invalid request body")
 		return
 	}
+	if fields := validateResumeSection(&section); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+	section.ID, section.Version = id, 0
 
-	_, err = h.DB.Exec(
-		`UPDATE resume_sections SET section_type = $1, title = $2, subtitle = $3, description = $4,
-		start_date = $5, end_date = $6, display_order = $7, updated_at = CURRENT_TIMESTAMP WHERE id = $8`,
-		section.SectionType, section.Title, nullString(section.Subtitle), nullString(section.Description),
-		nullTime(section.StartDate), nullTime(section.EndDate), section.DisplayOrder, id,
-	)
+	// With If-Match the update only applies to the version the client read
+	if r.Header.Get("If-Match") != "" {
+		current, err := h.Store.GetResumeSection(r.Context(), id)
+		if errors.Is(err, store.ErrNotFound) {
+			problem.Write(w, problem.NotFound, "Resume section not found")
+			return
+		}
+		if err != nil {
+			problem.Write(w, problem.Internal, "Database error")
+			return
+		}
+		if !checkIfMatch(w, r, current.Version) {
+			return
+		}
+		section.Version = current.Version
+	}
 
+	err = h.Store.UpdateResumeSection(r.Context(), &section, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Resume section not found")
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeStale(w)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error updating resume section", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error updating resume section")
 		return
 	}
 
+	w.Header().Set("ETag", versionETag(section.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Resume section updated successfully"})
 }
 
+// PatchResumeSection applies a JSON Merge Patch. The write is conditional on
+// the version the patch was applied to, so concurrent edits get 412.
+func (h *ResumeHandler) PatchResumeSection(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	current, err := h.Store.GetResumeSection(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Resume section not found")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+	if !checkIfMatch(w, r, current.Version) {
+		return
+	}
+
+	section, err := applyMergePatch(current, patch)
+	if err != nil {
+		problem.Write(w, problem.InvalidBody, err.Error())
+		return
+	}
+	section.ID, section.Version = id, current.Version
+	if fields := validateResumeSection(section); len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+
+	err = h.Store.UpdateResumeSection(r.Context(), section, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Resume section not found")
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeStale(w)
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error updating resume section")
+		return
+	}
+
+	renderContent(r.Context(), h.Images, section)
+	w.Header().Set("ETag", versionETag(section.Version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(section)
+}
+
 func (h *ResumeHandler) DeleteResumeSection(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		problem.Write(w, problem.InvalidID, "Invalid ID")
 		return
 	}
 
-	_, err = h.DB.Exec("DELETE FROM resume_sections WHERE id = $1", id)
+	err = h.Store.DeleteResumeSection(r.Context(), id, currentUserID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Resume section not found")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error deleting resume section", http.StatusInternalServerError)
+		problem.Write(w, problem.Internal, "Error deleting resume section")
 		return
 	}
 
@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/Jakeito/TestWebsite/backend/internal/auth"
+	"github.com/Jakeito/TestWebsite/backend/internal/middleware"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// RevisionHandler serves the history of about content, resume sections and
+// car build entries, and the trash their deletes go to. Routes name the
+// content type in the "type" path variable.
+type RevisionHandler struct {
+	Revisions store.RevisionStore
+	About     store.AboutStore
+	Resume    store.ResumeStore
+	CarBuild  store.CarBuildStore
+	Images    store.ImageStore
+}
+
+func NewRevisionHandler(revisions store.RevisionStore, about store.AboutStore, resume store.ResumeStore, carBuild store.CarBuildStore, images store.ImageStore) *RevisionHandler {
+	return &RevisionHandler{Revisions: revisions, About: about, Resume: resume, CarBuild: carBuild, Images: images}
+}
+
+// errUnknownContentType is returned for a type without revision history
+var errUnknownContentType = errors.New("unknown content type")
+
+// Snapshot fields that change with every revision and are left out of diffs
+var revisionMetadata = map[string]bool{"id": true, "version": true, "created_at": true, "updated_at": true}
+
+// currentUserID returns the ID of the authenticated user, or nil
+func currentUserID(r *http.Request) *int {
+	if claims, ok := r.Context().Value(middleware.UserContextKey).(*auth.Claims); ok {
+		return &claims.UserID
+	}
+	return nil
+}
+
+// isAdmin reports whether the request behind ctx was made by an admin
+func isAdmin(ctx context.Context) bool {
+	claims, ok := ctx.Value(middleware.UserContextKey).(*auth.Claims)
+	return ok && claims.IsAdmin
+}
+
+// ListRevisions lists the revisions of a record newest first, without their
+// snapshots. Revisions of deleted records are still listed.
+func (h *RevisionHandler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+	q := parseListQuery(r, nil, defaultPerPage)
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	revisions, total, err := h.Revisions.ListRevisions(r.Context(), mux.Vars(r)["type"], id, q.opts)
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+	if revisions == nil {
+		revisions = []models.ContentRevision{}
+	}
+
+	q.writeHeaders(w, total)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// revision loads the revision named by the id and version path variables,
+// writing the error response if it can't
+func (h *RevisionHandler) revision(w http.ResponseWriter, r *http.Request, version int) (*models.ContentRevision, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return nil, false
+	}
+
+	revision, err := h.Revisions.GetRevision(r.Context(), mux.Vars(r)["type"], id, version)
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Revision not found")
+		return nil, false
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return nil, false
+	}
+	return revision, true
+}
+
+// GetRevision returns one revision with the snapshot of the record it left
+func (h *RevisionHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid version")
+		return
+	}
+	revision, ok := h.revision(w, r, version)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revision)
+}
+
+// DiffRevisions lists the fields that differ between the revisions named by
+// the from and to query parameters
+func (h *RevisionHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	q := parseListQuery(r, nil, 0)
+	fromVersion := q.int("from", 0, 1, math.MaxInt32)
+	toVersion := q.int("to", 0, 1, math.MaxInt32)
+	if q.err == nil && (fromVersion == 0 || toVersion == 0) {
+		q.fail(errors.New("from and to are required"))
+	}
+	if q.err != nil {
+		problem.Write(w, problem.InvalidQuery, q.err.Error())
+		return
+	}
+
+	from, ok := h.revision(w, r, fromVersion)
+	if !ok {
+		return
+	}
+	to, ok := h.revision(w, r, toVersion)
+	if !ok {
+		return
+	}
+	changes, err := diffSnapshots(from.Snapshot, to.Snapshot)
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error reading revisions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.RevisionDiff{
+		ContentType: from.ContentType,
+		ContentID:   from.ContentID,
+		From:        from.Version,
+		To:          to.Version,
+		Changes:     changes,
+	})
+}
+
+// diffSnapshots compares two snapshots field by field, in field name order.
+// A field missing from one side shows as null there.
+func diffSnapshots(from, to json.RawMessage) ([]models.RevisionChange, error) {
+	var a, b map[string]interface{}
+	if err := json.Unmarshal(from, &a); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(to, &b); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]bool)
+	for field := range a {
+		fields[field] = true
+	}
+	for field := range b {
+		fields[field] = true
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		if !revisionMetadata[field] {
+			names = append(names, field)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []models.RevisionChange{}
+	for _, field := range names {
+		if !reflect.DeepEqual(a[field], b[field]) {
+			changes = append(changes, models.RevisionChange{Field: field, From: a[field], To: b[field]})
+		}
+	}
+	return changes, nil
+}
+
+// RestoreRevision rolls a record back to the content of one of its revisions.
+// The rollback is recorded as a new revision, so it can itself be undone. An
+// If-Match header makes it conditional, as for updates.
+func (h *RevisionHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid version")
+		return
+	}
+	expected, ok := ifMatchVersion(r)
+	if !ok {
+		writeStale(w)
+		return
+	}
+	revision, ok := h.revision(w, r, version)
+	if !ok {
+		return
+	}
+
+	content, newVersion, fields, err := h.rollback(r.Context(), revision, expected, currentUserID(r))
+	if len(fields) > 0 {
+		problem.Validation(w, fields)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Record not found; restore it from the trash first")
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		writeStale(w)
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error restoring revision")
+		return
+	}
+
+	renderContent(r.Context(), h.Images, content)
+	w.Header().Set("ETag", versionETag(newVersion))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
+
+// rollback overwrites a record with a revision's snapshot, as long as the
+// record is at version or version is 0. The snapshot is validated again in
+// case the rules have changed since it was taken.
+func (h *RevisionHandler) rollback(ctx context.Context, revision *models.ContentRevision, version int, author *int) (interface{}, int, map[string]string, error) {
+	switch revision.ContentType {
+	case models.AboutContentType:
+		var content models.AboutContent
+		if err := json.Unmarshal(revision.Snapshot, &content); err != nil {
+			return nil, 0, nil, err
+		}
+		content.ID, content.Version = revision.ContentID, version
+		if fields := validateAboutContent(&content); len(fields) > 0 {
+			return nil, 0, fields, nil
+		}
+		err := h.About.UpdateAboutContent(ctx, &content, author)
+		return &content, content.Version, nil, err
+	case models.ResumeContentType:
+		var section models.ResumeSection
+		if err := json.Unmarshal(revision.Snapshot, &section); err != nil {
+			return nil, 0, nil, err
+		}
+		section.ID, section.Version = revision.ContentID, version
+		if fields := validateResumeSection(&section); len(fields) > 0 {
+			return nil, 0, fields, nil
+		}
+		err := h.Resume.UpdateResumeSection(ctx, &section, author)
+		return &section, section.Version, nil, err
+	case models.CarBuildContentType:
+		var entry models.CarBuildEntry
+		if err := json.Unmarshal(revision.Snapshot, &entry); err != nil {
+			return nil, 0, nil, err
+		}
+		entry.ID, entry.Version = revision.ContentID, version
+		if fields := validateCarBuildEntry(&entry); len(fields) > 0 {
+			return nil, 0, fields, nil
+		}
+		err := h.CarBuild.UpdateCarBuildEntry(ctx, &entry, author)
+		return &entry, entry.Version, nil, err
+	}
+	return nil, 0, nil, errUnknownContentType
+}
+
+// ListTrash lists deleted records of every type, most recently deleted first
+func (h *RevisionHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := h.Revisions.ListTrash(r.Context())
+	if err != nil {
+		problem.Write(w, problem.Internal, "Database error")
+		return
+	}
+	if items == nil {
+		items = []models.TrashItem{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// RestoreFromTrash undeletes a record and returns it
+func (h *RevisionHandler) RestoreFromTrash(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		problem.Write(w, problem.InvalidID, "Invalid ID")
+		return
+	}
+
+	var content interface{}
+	var version int
+	author := currentUserID(r)
+	switch mux.Vars(r)["type"] {
+	case models.AboutContentType:
+		var restored *models.AboutContent
+		if restored, err = h.About.RestoreAboutContent(r.Context(), id, author); err == nil {
+			content, version = restored, restored.Version
+		}
+	case models.ResumeContentType:
+		var restored *models.ResumeSection
+		if restored, err = h.Resume.RestoreResumeSection(r.Context(), id, author); err == nil {
+			content, version = restored, restored.Version
+		}
+	case models.CarBuildContentType:
+		var restored *models.CarBuildEntry
+		if restored, err = h.CarBuild.RestoreCarBuildEntry(r.Context(), id, author); err == nil {
+			content, version = restored, restored.Version
+		}
+	default:
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		problem.Write(w, problem.NotFound, "Not in the trash")
+		return
+	}
+	if err != nil {
+		problem.Write(w, problem.Internal, "Error restoring from the trash")
+		return
+	}
+
+	renderContent(r.Context(), h.Images, content)
+	w.Header().Set("ETag", versionETag(version))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
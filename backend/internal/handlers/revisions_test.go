@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+func TestRevisionHistory(t *testing.T) {
+	s := newTestServer(t)
+	date := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	w := s.admin("POST", "/api/carbuild", models.CarBuildEntry{Title: "Brakes", Description: "Pads", Category: "brakes", Date: date})
+	expectStatus(t, w, http.StatusCreated)
+	var entry models.CarBuildEntry
+	decode(t, w, &entry)
+	path := fmt.Sprintf("/api/carbuild/%d", entry.ID)
+
+	expectStatus(t, s.admin("PATCH", path, map[string]string{"title": "Big brakes", "description": "Pads and rotors"}), http.StatusOK)
+	expectStatus(t, s.admin("DELETE", path, nil), http.StatusOK)
+
+	w = s.admin("GET", path+"/revisions", nil)
+	expectStatus(t, w, http.StatusOK)
+	var revisions []models.ContentRevision
+	decode(t, w, &revisions)
+	if len(revisions) != 3 {
+		t.Fatalf("Expected 3 revisions, got %d", len(revisions))
+	}
+	for i, want := range []struct {
+		version int
+		action  string
+	}{{3, models.RevisionDelete}, {2, models.RevisionUpdate}, {1, models.RevisionCreate}} {
+		got := revisions[i]
+		if got.Version != want.version || got.Action != want.action || got.Author != "Admin" || got.Snapshot != nil {
+			t.Errorf("Revision %d: expected version %d %s by Admin, got %+v", i, want.version, want.action, got)
+		}
+	}
+	expectStatus(t, s.request("GET", path+"/revisions", nil, ""), http.StatusUnauthorized)
+
+	w = s.admin("GET", path+"/revisions/1", nil)
+	expectStatus(t, w, http.StatusOK)
+	var revision models.ContentRevision
+	decode(t, w, &revision)
+	var snapshot models.CarBuildEntry
+	if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil || snapshot.Title != "Brakes" {
+		t.Errorf("Expected the original entry in the snapshot, got %s (%v)", revision.Snapshot, err)
+	}
+	expectStatus(t, s.admin("GET", path+"/revisions/9", nil), http.StatusNotFound)
+
+	w = s.admin("GET", path+"/revisions/diff?from=1&to=2", nil)
+	expectStatus(t, w, http.StatusOK)
+	var diff models.RevisionDiff
+	decode(t, w, &diff)
+	if len(diff.Changes) != 2 || diff.Changes[0].Field != "description" || diff.Changes[1].Field != "title" ||
+		diff.Changes[1].From != "Brakes" || diff.Changes[1].To != "Big brakes" {
+		t.Errorf("Unexpected diff %+v", diff)
+	}
+	expectStatus(t, s.admin("GET", path+"/revisions/diff?from=1", nil), http.StatusBadRequest)
+	expectStatus(t, s.admin("GET", path+"/revisions/diff?from=1&to=8", nil), http.StatusNotFound)
+
+	// Deleted entries are hidden, and can only be rolled back once restored
+	expectStatus(t, s.request("GET", path, nil, ""), http.StatusNotFound)
+	expectStatus(t, s.admin("DELETE", path, nil), http.StatusNotFound)
+	expectStatus(t, s.admin("POST", path+"/revisions/1/restore", nil), http.StatusNotFound)
+}
+
+func TestTrash(t *testing.T) {
+	s := newTestServer(t)
+	w := s.admin("POST", "/api/about", models.AboutContent{Title: "About", Content: "Hello", Publication: published})
+	expectStatus(t, w, http.StatusCreated)
+	var about models.AboutContent
+	decode(t, w, &about)
+	w = s.admin("POST", "/api/resume", models.ResumeSection{SectionType: "skills", Title: "Go"})
+	expectStatus(t, w, http.StatusCreated)
+	var section models.ResumeSection
+	decode(t, w, &section)
+
+	expectStatus(t, s.admin("DELETE", fmt.Sprintf("/api/about/%d", about.ID), nil), http.StatusOK)
+	expectStatus(t, s.admin("DELETE", fmt.Sprintf("/api/resume/%d", section.ID), nil), http.StatusOK)
+
+	w = s.request("GET", "/api/about", nil, "")
+	var contents []models.AboutContent
+	decode(t, w, &contents)
+	if len(contents) != 0 || w.Header().Get(TotalCountHeader) != "0" {
+		t.Errorf("Expected deleted content to be hidden, got %+v", contents)
+	}
+
+	w = s.admin("GET", "/api/trash", nil)
+	expectStatus(t, w, http.StatusOK)
+	var items []models.TrashItem
+	decode(t, w, &items)
+	if len(items) != 2 || items[0].ContentType != models.ResumeContentType || items[1].Title != "About" || items[1].Version != 2 {
+		t.Fatalf("Unexpected trash %+v", items)
+	}
+
+	w = s.admin("POST", fmt.Sprintf("/api/trash/about/%d/restore", about.ID), nil)
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("ETag"); got != `"v3"` {
+		t.Errorf(`Expected ETag "v3", got %s`, got)
+	}
+	expectStatus(t, s.request("GET", fmt.Sprintf("/api/about/%d", about.ID), nil, ""), http.StatusOK)
+	expectStatus(t, s.admin("POST", fmt.Sprintf("/api/trash/about/%d/restore", about.ID), nil), http.StatusNotFound)
+	expectStatus(t, s.admin("POST", fmt.Sprintf("/api/trash/carbuild/%d/restore", section.ID), nil), http.StatusNotFound)
+
+	w = s.admin("GET", "/api/trash", nil)
+	decode(t, w, &items)
+	if len(items) != 1 || items[0].ID != section.ID {
+		t.Errorf("Expected only the resume section left in the trash, got %+v", items)
+	}
+}
+
+func TestRestoreRevision(t *testing.T) {
+	s := newTestServer(t)
+	w := s.admin("POST", "/api/about", models.AboutContent{Title: "About", Content: "First draft", ImageURL: "/me.jpg"})
+	expectStatus(t, w, http.StatusCreated)
+	var about models.AboutContent
+	decode(t, w, &about)
+	path := fmt.Sprintf("/api/about/%d", about.ID)
+	expectStatus(t, s.admin("PUT", path, models.AboutContent{Title: "About me", Content: "Second draft"}), http.StatusOK)
+
+	restore := func(ifMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path+"/revisions/1/restore", nil)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return s.serve(r, s.token)
+	}
+	expectStatus(t, restore(`"v1"`), http.StatusPreconditionFailed)
+	expectStatus(t, restore(`W/"v2"`), http.StatusPreconditionFailed)
+
+	w = restore(`"v2"`)
+	expectStatus(t, w, http.StatusOK)
+	about = models.AboutContent{}
+	decode(t, w, &about)
+	if about.Title != "About" || about.Content != "First draft" || about.ImageURL != "/me.jpg" || about.Version != 3 {
+		t.Errorf("Expected the first revision back at version 3, got %+v", about)
+	}
+
+	w = s.admin("GET", path+"/revisions?limit=1", nil)
+	var revisions []models.ContentRevision
+	decode(t, w, &revisions)
+	if len(revisions) != 1 || revisions[0].Version != 3 || revisions[0].Action != models.RevisionUpdate {
+		t.Errorf("Expected the rollback to be recorded as an update, got %+v", revisions)
+	}
+	if got := w.Header().Get(TotalCountHeader); got != "3" {
+		t.Errorf("Expected 3 revisions in total, got %s", got)
+	}
+}
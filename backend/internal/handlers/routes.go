@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/Jakeito/TestWebsite/backend/internal/middleware"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+)
+
+// API is everything NewRouter wires into routes. Optional fields may be left
+// nil: their routes are not registered, or their rate limits not applied.
+type API struct {
+	Auth        *AuthHandler
+	About       *AboutHandler
+	Resume      *ResumeHandler
+	CarBuild    *CarBuildHandler
+	Parts       *PartHandler
+	Maintenance *MaintenanceHandler
+	Revisions   *RevisionHandler
+	Preview     *PreviewHandler
+	Contact     *ContactHandler
+	Images      *ImageHandler
+	Gallery     *GalleryHandler
+	Folders     *FolderHandler
+	Health      *HealthHandler
+
+	// JWTSecret and Sessions authenticate admin requests
+	JWTSecret string
+	Sessions  middleware.SessionChecker
+
+	// Per-client rate limits: API covers every route but the health probes,
+	// the others are applied on top of it
+	LimitAPI, LimitLogin, LimitRefresh, LimitContact func(http.Handler) http.Handler
+
+	// Reseed clears and reseeds the gallery (optional)
+	Reseed http.Handler
+	// Metrics serves Prometheus metrics (optional)
+	Metrics http.Handler
+}
+
+// NewRouter registers every route of the API. Each request's route is
+// recorded for the logging and metrics middleware.
+func NewRouter(api API) *mux.Router {
+	root := mux.NewRouter()
+	root.Use(middleware.RecordRoute)
+	root.NotFoundHandler = problem.Handler(problem.NotFound, "No such endpoint")
+	root.MethodNotAllowedHandler = problem.Handler(problem.MethodNotAllowed, "Method not allowed")
+
+	// Liveness and readiness probes skip the API rate limit, so health checks
+	// from the load balancer are never throttled
+	root.HandleFunc("/healthz", api.Health.Liveness).Methods("GET", "HEAD")
+	root.HandleFunc("/readyz", api.Health.Readiness).Methods("GET", "HEAD")
+
+	// Every other route is rate limited per client
+	r := root.NewRoute().Subrouter()
+	if api.LimitAPI != nil {
+		r.Use(api.LimitAPI)
+	}
+	limit := func(mw func(http.Handler) http.Handler, h http.HandlerFunc) http.Handler {
+		if mw == nil {
+			return h
+		}
+		return mw(h)
+	}
+
+	// Serve static files from public directory
+	r.PathPrefix("/public/").Handler(http.StripPrefix("/public/", http.FileServer(http.Dir("./public"))))
+
+	// Health check / root endpoint
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ok","message":"TestWebsite Backend API"}`))
+	}).Methods("GET")
+
+	// Public routes
+	r.Handle("/api/login", limit(api.LimitLogin, api.Auth.Login)).Methods("POST")
+	r.Handle("/api/token/refresh", limit(api.LimitRefresh, api.Auth.RefreshToken)).Methods("POST")
+	r.HandleFunc("/api/about", api.About.GetAboutContent).Methods("GET")
+	r.HandleFunc("/api/about/{id}", api.About.GetAboutContentByID).Methods("GET")
+	r.HandleFunc("/api/resume", api.Resume.GetResumeSections).Methods("GET")
+	r.HandleFunc("/api/resume/{id}", api.Resume.GetResumeSection).Methods("GET")
+	r.HandleFunc("/api/carbuild", api.CarBuild.GetCarBuildEntries).Methods("GET")
+	r.HandleFunc("/api/carbuild/{id}", api.CarBuild.GetCarBuildEntry).Methods("GET")
+	r.HandleFunc("/api/parts", api.Parts.ListParts).Methods("GET")
+	r.HandleFunc("/api/parts/spend/{group:category|month|vendor}", api.Parts.GetPartSpend).Methods("GET")
+	r.HandleFunc("/api/parts/{id}", api.Parts.GetPart).Methods("GET")
+	r.HandleFunc("/api/preview/{type:about|resume|carbuild}/{id}", api.Preview.GetPreview).Methods("GET")
+	r.Handle("/api/contact", limit(api.LimitContact, api.Contact.SubmitContact)).Methods("POST")
+	r.HandleFunc("/api/contact/token", api.Contact.GetFormToken).Methods("GET")
+	r.HandleFunc("/api/images", api.Images.GetImages).Methods("GET")
+	r.HandleFunc("/api/folders", api.Folders.GetFolders).Methods("GET")
+	r.Handle("/api/image/{id}", middleware.OptionalAuthMiddleware(api.JWTSecret, api.Sessions)(http.HandlerFunc(api.Gallery.GetImage))).Methods("GET", "HEAD")
+
+	// Protected routes (require authentication)
+	authRouter := r.PathPrefix("/api").Subrouter()
+	authRouter.Use(middleware.AuthMiddleware(api.JWTSecret, api.Sessions))
+
+	authRouter.HandleFunc("/logout", api.Auth.Logout).Methods("POST")
+
+	// Admin-only routes
+	adminRouter := authRouter.PathPrefix("").Subrouter()
+	adminRouter.Use(middleware.AdminMiddleware)
+
+	// Admin routes for about content
+	adminRouter.HandleFunc("/admin/about", api.About.ListAboutContent).Methods("GET")
+	adminRouter.HandleFunc("/admin/about/{id}", api.About.ShowAboutContent).Methods("GET")
+	adminRouter.HandleFunc("/about", api.About.CreateAboutContent).Methods("POST")
+	adminRouter.HandleFunc("/about/{id}", api.About.UpdateAboutContent).Methods("PUT")
+	adminRouter.HandleFunc("/about/{id}", api.About.PatchAboutContent).Methods("PATCH")
+	adminRouter.HandleFunc("/about/{id}", api.About.DeleteAboutContent).Methods("DELETE")
+
+	// Admin routes for resume
+	adminRouter.HandleFunc("/admin/resume", api.Resume.ListResumeSections).Methods("GET")
+	adminRouter.HandleFunc("/admin/resume/{id}", api.Resume.ShowResumeSection).Methods("GET")
+	adminRouter.HandleFunc("/resume", api.Resume.CreateResumeSection).Methods("POST")
+	adminRouter.HandleFunc("/resume/{id}", api.Resume.UpdateResumeSection).Methods("PUT")
+	adminRouter.HandleFunc("/resume/{id}", api.Resume.PatchResumeSection).Methods("PATCH")
+	adminRouter.HandleFunc("/resume/{id}", api.Resume.DeleteResumeSection).Methods("DELETE")
+
+	// Admin routes for car build
+	adminRouter.HandleFunc("/admin/carbuild", api.CarBuild.ListCarBuildEntries).Methods("GET")
+	adminRouter.HandleFunc("/admin/carbuild/{id}", api.CarBuild.ShowCarBuildEntry).Methods("GET")
+	adminRouter.HandleFunc("/carbuild", api.CarBuild.CreateCarBuildEntry).Methods("POST")
+	adminRouter.HandleFunc("/carbuild/{id}", api.CarBuild.UpdateCarBuildEntry).Methods("PUT")
+	adminRouter.HandleFunc("/carbuild/{id}", api.CarBuild.PatchCarBuildEntry).Methods("PATCH")
+	adminRouter.HandleFunc("/carbuild/{id}", api.CarBuild.DeleteCarBuildEntry).Methods("DELETE")
+	adminRouter.HandleFunc("/parts", api.Parts.CreatePart).Methods("POST")
+	adminRouter.HandleFunc("/parts/{id}", api.Parts.UpdatePart).Methods("PUT")
+	adminRouter.HandleFunc("/parts/{id}", api.Parts.DeletePart).Methods("DELETE")
+
+	// Admin routes for car maintenance
+	adminRouter.HandleFunc("/maintenance", api.Maintenance.GetMaintenance).Methods("GET")
+	adminRouter.HandleFunc("/maintenance/due", api.Maintenance.GetMaintenanceDue).Methods("GET")
+	adminRouter.HandleFunc("/maintenance", api.Maintenance.CreateMaintenanceSchedule).Methods("POST")
+	adminRouter.HandleFunc("/maintenance/{id}", api.Maintenance.UpdateMaintenanceSchedule).Methods("PUT")
+	adminRouter.HandleFunc("/maintenance/{id}", api.Maintenance.DeleteMaintenanceSchedule).Methods("DELETE")
+	adminRouter.HandleFunc("/maintenance/{id}/done", api.Maintenance.CompleteMaintenance).Methods("POST")
+
+	// Admin routes for revision history and the trash
+	adminRouter.HandleFunc("/{type:about|resume|carbuild}/{id}/revisions", api.Revisions.ListRevisions).Methods("GET")
+	adminRouter.HandleFunc("/{type:about|resume|carbuild}/{id}/revisions/diff", api.Revisions.DiffRevisions).Methods("GET")
+	adminRouter.HandleFunc("/{type:about|resume|carbuild}/{id}/revisions/{version}", api.Revisions.GetRevision).Methods("GET")
+	adminRouter.HandleFunc("/{type:about|resume|carbuild}/{id}/revisions/{version}/restore", api.Revisions.RestoreRevision).Methods("POST")
+	adminRouter.HandleFunc("/{type:about|resume|carbuild}/{id}/preview", api.Preview.CreatePreviewLink).Methods("POST")
+	adminRouter.HandleFunc("/trash", api.Revisions.ListTrash).Methods("GET")
+	adminRouter.HandleFunc("/trash/{type:about|resume|carbuild}/{id}/restore", api.Revisions.RestoreFromTrash).Methods("POST")
+
+	// Admin routes for contact submissions
+	adminRouter.HandleFunc("/contact", api.Contact.GetContactSubmissions).Methods("GET")
+	adminRouter.HandleFunc("/contact/unread-count", api.Contact.GetUnreadCount).Methods("GET")
+	adminRouter.HandleFunc("/contact/{id}", api.Contact.GetContactSubmission).Methods("GET")
+	adminRouter.HandleFunc("/contact/{id}", api.Contact.UpdateContactSubmission).Methods("PATCH")
+	adminRouter.HandleFunc("/contact/{id}", api.Contact.DeleteContactSubmission).Methods("DELETE")
+	adminRouter.HandleFunc("/contact/{id}/replies", api.Contact.CreateContactReply).Methods("POST")
+
+	// Admin routes for user management
+	adminRouter.HandleFunc("/users", api.Auth.CreateUser).Methods("POST")
+
+	// Admin routes for gallery images
+	adminRouter.HandleFunc("/gallery/upload", api.Gallery.UploadImage).Methods("POST")
+	adminRouter.HandleFunc("/gallery/images", api.Gallery.ListImages).Methods("GET")
+	adminRouter.HandleFunc("/gallery/image/{id}", api.Gallery.UpdateImage).Methods("PATCH")
+	adminRouter.HandleFunc("/gallery/image/{id}", api.Gallery.DeleteImage).Methods("DELETE")
+	adminRouter.HandleFunc("/gallery/order", api.Gallery.ReorderImages).Methods("PUT")
+	adminRouter.HandleFunc("/gallery/folders", api.Folders.ListFolders).Methods("GET")
+	adminRouter.HandleFunc("/gallery/folders", api.Folders.CreateFolder).Methods("POST")
+	adminRouter.HandleFunc("/gallery/folders/{id}", api.Folders.UpdateFolder).Methods("PUT")
+	adminRouter.HandleFunc("/gallery/folders/{id}", api.Folders.DeleteFolder).Methods("DELETE")
+
+	// Admin route to rebuild the gallery from public/images
+	if api.Reseed != nil {
+		adminRouter.Handle("/gallery/reseed", api.Reseed).Methods("POST")
+	}
+
+	// Metrics for Prometheus
+	if api.Metrics != nil {
+		r.Handle("/metrics", api.Metrics).Methods("GET")
+	}
+
+	return root
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+// Maximum length of short text fields stored as VARCHAR(255)
+const maxTitleLength = 255
+
+// checkText records a message in fields when a text field is missing but
+// required, or longer than max characters
+func checkText(fields map[string]string, key, label, value string, required bool, max int) {
+	switch {
+	case value == "" && required:
+		fields[key] = label + " is required"
+	case utf8.RuneCountInString(value) > max:
+		fields[key] = fmt.Sprintf("%s must be at most %d characters", label, max)
+	}
+}
+
+// publicationStatuses are the states content moves through
+var publicationStatuses = []string{models.StatusDraft, models.StatusScheduled, models.StatusPublished, models.StatusArchived}
+
+// checkPublication normalizes a content status, which defaults to draft, and
+// records a message in fields when it is invalid. Scheduled content needs a
+// publish_at; published content without one is stamped with the current time.
+func checkPublication(fields map[string]string, p *models.Publication) {
+	p.Status = strings.ToLower(strings.TrimSpace(p.Status))
+	if p.Status == "" {
+		p.Status = models.StatusDraft
+	}
+	switch {
+	case !slices.Contains(publicationStatuses, p.Status):
+		fields["status"] = "Status must be one of " + strings.Join(publicationStatuses, ", ")
+	case p.Status == models.StatusScheduled && p.PublishAt == nil:
+		fields["publish_at"] = "Publish time is required for scheduled content"
+	case p.Status == models.StatusPublished && p.PublishAt == nil:
+		now := time.Now().UTC()
+		p.PublishAt = &now
+	}
+}
@@ -0,0 +1,229 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Metadata holds the EXIF fields worth keeping from a photo
+type Metadata struct {
+	Orientation  int
+	TakenAt      *time.Time
+	CameraMake   string
+	CameraModel  string
+	LensModel    string
+	ExposureTime string
+	FNumber      *float64
+	ISO          *int
+	FocalLength  *float64
+	HasGPS       bool
+}
+
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagExposureTime     = 0x829A
+	tagFNumber          = 0x829D
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagOffsetTimeOrig   = 0x9011
+	tagFocalLength      = 0x920A
+	tagLensModel        = 0xA434
+	exifDateTimeLayout  = "2006:01:02 15:04:05"
+	maxIFDEntries       = 1000
+	tiffTypeASCII       = 2
+	tiffTypeShort       = 3
+	tiffTypeLong        = 4
+	tiffTypeRational    = 5
+)
+
+var errInvalidExif = errors.New("invalid exif data")
+
+// tiffTypeSizes maps TIFF field types to their size in bytes
+var tiffTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ParseExif reads the fields of Metadata from the payload of a JPEG APP1
+// segment, including the leading "Exif\x00\x00" identifier
+func ParseExif(payload []byte) (*Metadata, error) {
+	if !strings.HasPrefix(string(payload), "Exif\x00\x00") {
+		return nil, errInvalidExif
+	}
+	data := payload[6:]
+	if len(data) < 8 {
+		return nil, errInvalidExif
+	}
+
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errInvalidExif
+	}
+	if t.order.Uint16(data[2:4]) != 42 {
+		return nil, errInvalidExif
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{Orientation: 1}
+	meta.CameraMake = t.ascii(ifd0[tagMake])
+	meta.CameraModel = t.ascii(ifd0[tagModel])
+	if o, ok := t.uintValue(ifd0[tagOrientation]); ok && o >= 1 && o <= 8 {
+		meta.Orientation = int(o)
+	}
+	_, meta.HasGPS = ifd0[tagGPSIFD]
+
+	if offset, ok := t.uintValue(ifd0[tagExifIFD]); ok {
+		exif, err := t.readIFD(offset)
+		if err != nil {
+			return meta, fmt.Errorf("error reading exif ifd: %w", err)
+		}
+
+		meta.LensModel = t.ascii(exif[tagLensModel])
+		meta.TakenAt = parseExifTime(t.ascii(exif[tagDateTimeOriginal]), t.ascii(exif[tagOffsetTimeOrig]))
+		if num, den, ok := t.rational(exif[tagExposureTime]); ok {
+			meta.ExposureTime = formatExposure(num, den)
+		}
+		if num, den, ok := t.rational(exif[tagFNumber]); ok {
+			f := roundTo(float64(num)/float64(den), 1)
+			meta.FNumber = &f
+		}
+		if num, den, ok := t.rational(exif[tagFocalLength]); ok {
+			f := roundTo(float64(num)/float64(den), 1)
+			meta.FocalLength = &f
+		}
+		if iso, ok := t.uintValue(exif[tagISO]); ok {
+			v := int(iso)
+			meta.ISO = &v
+		}
+	}
+
+	return meta, nil
+}
+
+func (t *tiffReader) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	if int64(offset)+2 > int64(len(t.data)) {
+		return nil, errInvalidExif
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	if count > maxIFDEntries || int64(offset)+2+int64(count)*12 > int64(len(t.data)) {
+		return nil, errInvalidExif
+	}
+
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count; i++ {
+		e := t.data[int(offset)+2+i*12:]
+		tag := t.order.Uint16(e[0:2])
+		typ := t.order.Uint16(e[2:4])
+		n := t.order.Uint32(e[4:8])
+
+		size, ok := tiffTypeSizes[typ]
+		if !ok {
+			continue
+		}
+		total := int64(size) * int64(n)
+
+		// Values of four bytes or less are stored inline
+		var value []byte
+		if total <= 4 {
+			value = e[8 : 8+total]
+		} else {
+			start := int64(t.order.Uint32(e[8:12]))
+			if start+total > int64(len(t.data)) {
+				continue
+			}
+			value = t.data[start : start+total]
+		}
+		entries[tag] = ifdEntry{typ: typ, count: n, value: value}
+	}
+	return entries, nil
+}
+
+func (t *tiffReader) ascii(e ifdEntry) string {
+	if e.typ != tiffTypeASCII {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+}
+
+func (t *tiffReader) uintValue(e ifdEntry) (uint32, bool) {
+	switch {
+	case e.typ == tiffTypeShort && len(e.value) >= 2:
+		return uint32(t.order.Uint16(e.value)), true
+	case e.typ == tiffTypeLong && len(e.value) >= 4:
+		return t.order.Uint32(e.value), true
+	default:
+		return 0, false
+	}
+}
+
+func (t *tiffReader) rational(e ifdEntry) (uint32, uint32, bool) {
+	if e.typ != tiffTypeRational || len(e.value) < 8 {
+		return 0, 0, false
+	}
+	num, den := t.order.Uint32(e.value[0:4]), t.order.Uint32(e.value[4:8])
+	if den == 0 {
+		return 0, 0, false
+	}
+	return num, den, true
+}
+
+// parseExifTime parses an EXIF timestamp, applying the offset tag if the
+// camera recorded one
+func parseExifTime(value, offset string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	if offset != "" {
+		if t, err := time.Parse(exifDateTimeLayout+"-07:00", value+offset); err == nil {
+			return &t
+		}
+	}
+
+	t, err := time.Parse(exifDateTimeLayout, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// formatExposure renders a shutter speed the way cameras display it, e.g. "1/250" or "2"
+func formatExposure(num, den uint32) string {
+	if num == 0 {
+		return "0"
+	}
+	if num >= den {
+		return fmt.Sprintf("%g", roundTo(float64(num)/float64(den), 1))
+	}
+	return fmt.Sprintf("1/%d", int(math.Round(float64(den)/float64(num))))
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
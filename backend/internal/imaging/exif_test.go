@@ -0,0 +1,309 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+type testTag struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// buildExif assembles a big-endian APP1 EXIF payload with an IFD0 and an
+// EXIF sub-IFD
+func buildExif(ifd0, exif []testTag) []byte {
+	be := binary.BigEndian
+	var data bytes.Buffer
+	data.WriteString("MM")
+	binary.Write(&data, be, uint16(42))
+	binary.Write(&data, be, uint32(8))
+
+	ifdSize := func(tags []testTag) int { return 2 + len(tags)*12 + 4 }
+	exifOffset := uint32(8 + ifdSize(ifd0))
+	extraOffset := exifOffset + uint32(ifdSize(exif))
+
+	var extra bytes.Buffer
+	writeIFD := func(tags []testTag) {
+		binary.Write(&data, be, uint16(len(tags)))
+		for _, t := range tags {
+			value := t.value
+			if t.tag == tagExifIFD {
+				value = be.AppendUint32(nil, exifOffset)
+			}
+			binary.Write(&data, be, t.tag)
+			binary.Write(&data, be, t.typ)
+			binary.Write(&data, be, t.count)
+			if len(value) <= 4 {
+				data.Write(append(value, make([]byte, 4-len(value))...))
+			} else {
+				binary.Write(&data, be, extraOffset+uint32(extra.Len()))
+				extra.Write(value)
+			}
+		}
+		binary.Write(&data, be, uint32(0))
+	}
+	writeIFD(ifd0)
+	writeIFD(exif)
+	data.Write(extra.Bytes())
+
+	return append([]byte("Exif\x00\x00"), data.Bytes()...)
+}
+
+func asciiTag(tag uint16, s string) testTag {
+	return testTag{tag: tag, typ: tiffTypeASCII, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func rationalTag(tag uint16, num, den uint32) testTag {
+	v := binary.BigEndian.AppendUint32(nil, num)
+	return testTag{tag: tag, typ: tiffTypeRational, count: 1, value: binary.BigEndian.AppendUint32(v, den)}
+}
+
+func testExif(orientation uint16) []byte {
+	return buildExif(
+		[]testTag{
+			asciiTag(tagMake, "Canon"),
+			asciiTag(tagModel, "EOS R6"),
+			{tag: tagOrientation, typ: tiffTypeShort, count: 1, value: binary.BigEndian.AppendUint16(nil, orientation)},
+			{tag: tagExifIFD, typ: tiffTypeLong, count: 1},
+			{tag: tagGPSIFD, typ: tiffTypeLong, count: 1, value: []byte{0, 0, 0, 0}},
+		},
+		[]testTag{
+			rationalTag(tagExposureTime, 1, 250),
+			rationalTag(tagFNumber, 28, 10),
+			{tag: tagISO, typ: tiffTypeShort, count: 1, value: binary.BigEndian.AppendUint16(nil, 400)},
+			asciiTag(tagDateTimeOriginal, "2024:05:18 14:03:22"),
+			asciiTag(tagOffsetTimeOrig, "-05:00"),
+			rationalTag(tagFocalLength, 50, 1),
+			asciiTag(tagLensModel, "RF 50mm F1.8 STM"),
+		},
+	)
+}
+
+// testJPEG encodes a w x h JPEG and inserts exif as an APP1 segment after SOI
+func testJPEG(t *testing.T, w, h int, exif []byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	// Mark the top-left corner so orientation can be checked
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{A: 255}
+			if x < w/2 && y < h/2 {
+				c.R = 255
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	encoded := buf.Bytes()
+
+	segment := []byte{0xFF, markerAPP1, byte((len(exif) + 2) >> 8), byte(len(exif) + 2)}
+	out := append([]byte{}, encoded[:2]...)
+	out = append(out, segment...)
+	out = append(out, exif...)
+	return append(out, encoded[2:]...)
+}
+
+func TestParseExif(t *testing.T) {
+	meta, err := ParseExif(testExif(6))
+	if err != nil {
+		t.Fatalf("ParseExif failed: %v", err)
+	}
+
+	if meta.CameraMake != "Canon" || meta.CameraModel != "EOS R6" {
+		t.Errorf("Unexpected camera %q %q", meta.CameraMake, meta.CameraModel)
+	}
+	if meta.LensModel != "RF 50mm F1.8 STM" {
+		t.Errorf("Unexpected lens %q", meta.LensModel)
+	}
+	if meta.Orientation != 6 {
+		t.Errorf("Expected orientation 6, got %d", meta.Orientation)
+	}
+	if !meta.HasGPS {
+		t.Error("Expected GPS to be detected")
+	}
+	if meta.ExposureTime != "1/250" {
+		t.Errorf("Expected exposure 1/250, got %s", meta.ExposureTime)
+	}
+	if meta.FNumber == nil || *meta.FNumber != 2.8 {
+		t.Errorf("Expected f/2.8, got %v", meta.FNumber)
+	}
+	if meta.ISO == nil || *meta.ISO != 400 {
+		t.Errorf("Expected ISO 400, got %v", meta.ISO)
+	}
+	if meta.FocalLength == nil || *meta.FocalLength != 50 {
+		t.Errorf("Expected 50mm, got %v", meta.FocalLength)
+	}
+	if meta.TakenAt == nil || meta.TakenAt.Format("2006-01-02T15:04:05-07:00") != "2024-05-18T14:03:22-05:00" {
+		t.Errorf("Unexpected capture time %v", meta.TakenAt)
+	}
+}
+
+func TestParseExifRejectsGarbage(t *testing.T) {
+	inputs := [][]byte{
+		nil,
+		[]byte("Exif\x00\x00"),
+		[]byte("Exif\x00\x00MM\x00\x2a\xff\xff\xff\xff"),
+		[]byte("not exif at all"),
+	}
+	for _, input := range inputs {
+		if _, err := ParseExif(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestSanitizeStripsExifAndRotates(t *testing.T) {
+	data := testJPEG(t, 40, 20, testExif(6))
+
+	clean, meta, err := Sanitize(data)
+	if err != nil {
+		t.Fatalf("Sanitize failed: %v", err)
+	}
+	if meta == nil || meta.CameraMake != "Canon" {
+		t.Fatalf("Expected metadata, got %+v", meta)
+	}
+	if bytes.Contains(clean, []byte("Exif\x00\x00")) || bytes.Contains(clean, []byte("Canon")) {
+		t.Error("Expected EXIF to be removed")
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(clean))
+	if err != nil {
+		t.Fatalf("Sanitized image is not a valid JPEG: %v", err)
+	}
+	if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 40 {
+		t.Fatalf("Expected rotated 20x40 image, got %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	// Rotating 90 degrees clockwise moves the red top-left quadrant to the top right
+	r, _, _, _ := img.At(15, 5).RGBA()
+	if r>>8 < 200 {
+		t.Errorf("Expected red top-right corner after rotation, got r=%d", r>>8)
+	}
+}
+
+func TestSanitizeWithoutRotationKeepsPixels(t *testing.T) {
+	data := testJPEG(t, 40, 20, testExif(1))
+
+	clean, _, err := Sanitize(data)
+	if err != nil {
+		t.Fatalf("Sanitize failed: %v", err)
+	}
+
+	// Only the APP1 segment is removed, the image data is untouched
+	if len(data)-len(clean) != len(testExif(1))+4 {
+		t.Errorf("Expected only the EXIF segment to be removed, size %d -> %d", len(data), len(clean))
+	}
+}
+
+func TestSanitizeIgnoresOtherFormats(t *testing.T) {
+	data := []byte("GIF89a rest")
+	clean, meta, err := Sanitize(data)
+	if err != nil || meta != nil || !bytes.Equal(clean, data) {
+		t.Errorf("Expected other input to pass through unchanged")
+	}
+}
+
+// pngChunk encodes a PNG chunk with its length and CRC
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(append(chunk, chunkType...), data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func TestSanitizeStripsPNGMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(40, 20)); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	// Insert metadata chunks after the IHDR chunk, which ends at byte 33
+	encoded := buf.Bytes()
+	data := append([]byte{}, encoded[:33]...)
+	data = append(data, pngChunk("eXIf", testExif(6)[len("Exif\x00\x00"):])...)
+	data = append(data, pngChunk("tEXt", []byte("Author\x00Jane Doe"))...)
+	data = append(data, pngChunk("iTXt", []byte("GPS\x00\x00\x00\x00\x0051.5,-0.1"))...)
+	data = append(data, pngChunk("zTXt", []byte("Comment\x00\x00x"))...)
+	data = append(data, encoded[33:]...)
+
+	clean, meta, err := Sanitize(data)
+	if err != nil {
+		t.Fatalf("Sanitize failed: %v", err)
+	}
+	if meta == nil || meta.CameraMake != "Canon" {
+		t.Fatalf("Expected metadata, got %+v", meta)
+	}
+	for _, chunk := range []string{"eXIf", "tEXt", "iTXt", "zTXt", "Jane Doe", "Canon"} {
+		if bytes.Contains(clean, []byte(chunk)) {
+			t.Errorf("Expected %q to be removed", chunk)
+		}
+	}
+
+	img, err := png.Decode(bytes.NewReader(clean))
+	if err != nil {
+		t.Fatalf("Sanitized image is not a valid PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 40 {
+		t.Errorf("Expected rotated 20x40 image, got %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	if _, _, err := Sanitize(data[:40]); err == nil {
+		t.Error("Expected an error for a truncated PNG")
+	}
+}
+
+func TestSanitizeRejectsHugeImages(t *testing.T) {
+	data := testJPEG(t, 8, 8, testExif(1))
+	// Claim 60000x60000 pixels in the start of frame segment
+	sof := bytes.Index(data, []byte{0xFF, 0xC0})
+	if sof < 0 {
+		t.Fatal("No start of frame segment")
+	}
+	data[sof+5], data[sof+6], data[sof+7], data[sof+8] = 0xEA, 0x60, 0xEA, 0x60
+
+	if _, _, err := Sanitize(data); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		x, y        int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+
+	for _, tt := range tests {
+		dst := Orient(src, tt.orientation)
+		if dst.Bounds().Dx() != tt.w || dst.Bounds().Dy() != tt.h {
+			t.Errorf("Orientation %d: expected %dx%d, got %dx%d", tt.orientation, tt.w, tt.h, dst.Bounds().Dx(), dst.Bounds().Dy())
+			continue
+		}
+		if dst.RGBAAt(tt.x, tt.y).R != 255 {
+			t.Errorf("Orientation %d: expected marked pixel at (%d,%d)", tt.orientation, tt.x, tt.y)
+		}
+	}
+}
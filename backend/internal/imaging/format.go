@@ -0,0 +1,55 @@
+package imaging
+
+import (
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Format is an output encoding for generated variants. Only the standard
+// library's JPEG and PNG encoders are used; there are no WebP or AVIF
+// variants.
+type Format struct {
+	Name        string
+	ContentType string
+	Encode      func(w io.Writer, img image.Image) error
+}
+
+// formatPreference ranks formats from most to least preferred when a client
+// accepts several. Formats missing from the list rank last.
+var formatPreference = []string{"jpeg", "png"}
+
+var (
+	jpegFormat = Format{
+		Name:        "jpeg",
+		ContentType: "image/jpeg",
+		Encode: func(w io.Writer, img image.Image) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: 82})
+		},
+	}
+	pngFormat = Format{
+		Name:        "png",
+		ContentType: "image/png",
+		Encode:      png.Encode,
+	}
+)
+
+// variantFormat returns the format to encode variants of an image decoded as
+// source in. JPEGs stay JPEG; anything else, which may be transparent, is
+// kept lossless as PNG.
+func variantFormat(source string) Format {
+	if source == "jpeg" {
+		return jpegFormat
+	}
+	return pngFormat
+}
+
+func formatRank(name string) int {
+	for i, n := range formatPreference {
+		if n == name {
+			return i
+		}
+	}
+	return len(formatPreference)
+}
@@ -0,0 +1,105 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// MaxPixels is the most pixels an image may have to be decoded. Dimensions
+// are read from the header first, so a small file claiming a huge size is
+// rejected before its pixels are allocated.
+const MaxPixels = 40_000_000
+
+// ErrTooLarge is returned for images with more than MaxPixels pixels
+var ErrTooLarge = errors.New("image too large")
+
+// checkSize reads an image's header and rejects it if it is too large to
+// decode
+func checkSize(r io.Reader) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("error decoding image: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	return nil
+}
+
+// Variant is a named size generated for every stored image
+type Variant struct {
+	Name     string
+	MaxWidth int
+}
+
+// Variants lists the sizes generated on upload, smallest first
+var Variants = []Variant{
+	{Name: "thumbnail", MaxWidth: 320},
+	{Name: "medium", MaxWidth: 960},
+	{Name: "large", MaxWidth: 1920},
+}
+
+// Output is one encoded variant of an image
+type Output struct {
+	Variant     string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Result holds the dimensions of the original image and its generated variants
+type Result struct {
+	Width    int
+	Height   int
+	Variants []Output
+}
+
+// Process decodes an image and renders every variant narrower than the
+// original, in the original's own format (PNG for GIFs). Variants that would
+// upscale the original are skipped.
+func Process(r io.Reader) (*Result, error) {
+	// Keep the header bytes checkSize reads so the decode can replay them
+	var header bytes.Buffer
+	if err := checkSize(io.TeeReader(r, &header)); err != nil {
+		return nil, err
+	}
+	src, source, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding image: %w", err)
+	}
+
+	// Convert once up front instead of once per variant
+	rgba := toRGBA(src)
+	result := &Result{Width: rgba.Bounds().Dx(), Height: rgba.Bounds().Dy()}
+	format := variantFormat(source)
+
+	for _, variant := range Variants {
+		if variant.MaxWidth >= result.Width {
+			continue
+		}
+
+		resized := Resize(rgba, variant.MaxWidth)
+		var buf bytes.Buffer
+		if err := format.Encode(&buf, resized); err != nil {
+			return nil, fmt.Errorf("error encoding %s %s: %w", variant.Name, format.Name, err)
+		}
+		result.Variants = append(result.Variants, Output{
+			Variant:     variant.Name,
+			Format:      format.Name,
+			ContentType: format.ContentType,
+			Width:       resized.Bounds().Dx(),
+			Height:      resized.Bounds().Dy(),
+			Data:        buf.Bytes(),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func TestResize(t *testing.T) {
+	resized := Resize(testImage(1000, 500), 320)
+
+	if resized.Bounds().Dx() != 320 || resized.Bounds().Dy() != 160 {
+		t.Errorf("Expected 320x160, got %dx%d", resized.Bounds().Dx(), resized.Bounds().Dy())
+	}
+
+	// Resizing never upscales
+	same := Resize(testImage(100, 50), 320)
+	if same.Bounds().Dx() != 100 || same.Bounds().Dy() != 50 {
+		t.Errorf("Expected 100x50, got %dx%d", same.Bounds().Dx(), same.Bounds().Dy())
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 0, G: 0, B: 0, A: 255})
+	img.Set(1, 0, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+
+	got := Resize(img, 1).RGBAAt(0, 0)
+	expected := color.RGBA{R: 100, G: 50, B: 25, A: 255}
+	if got != expected {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(1200, 800)); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+
+	result, err := Process(&buf)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if result.Width != 1200 || result.Height != 800 {
+		t.Errorf("Expected original 1200x800, got %dx%d", result.Width, result.Height)
+	}
+
+	// The large variant would upscale a 1200px image, so only two are made.
+	// A PNG may be transparent, so its variants stay PNG.
+	names := map[string]bool{}
+	for _, v := range result.Variants {
+		names[v.Variant] = true
+		if v.Format != "png" || v.ContentType != "image/png" {
+			t.Errorf("Unexpected format %s (%s)", v.Format, v.ContentType)
+		}
+		if _, err := png.Decode(bytes.NewReader(v.Data)); err != nil {
+			t.Errorf("Variant %s is not a valid PNG: %v", v.Variant, err)
+		}
+	}
+	if !names["thumbnail"] || !names["medium"] || names["large"] {
+		t.Errorf("Unexpected variants: %v", names)
+	}
+
+	buf.Reset()
+	if err := jpeg.Encode(&buf, testImage(400, 300), nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	result, err = Process(&buf)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(result.Variants) != 1 || result.Variants[0].Format != "jpeg" {
+		t.Errorf("Expected one JPEG variant of a JPEG, got %+v", result.Variants)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(result.Variants[0].Data)); err != nil {
+		t.Errorf("Variant is not a valid JPEG: %v", err)
+	}
+}
+
+func TestProcessRejectsNonImages(t *testing.T) {
+	if _, err := Process(bytes.NewReader([]byte("not an image"))); err == nil {
+		t.Error("Expected error for non-image input")
+	}
+}
+
+func TestProcessRejectsHugeImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, testImage(2, 2), nil); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	// Claim a 65535x65535 screen in the header
+	data := buf.Bytes()
+	data[6], data[7], data[8], data[9] = 0xFF, 0xFF, 0xFF, 0xFF
+
+	if _, err := Process(bytes.NewReader(data)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Candidate is a stored rendition of an image that can be served
+type Candidate struct {
+	ID          int // 0 for the original upload
+	Format      string
+	ContentType string
+	Width       int
+}
+
+// FormatFromContentType maps a MIME type to a format name
+func FormatFromContentType(contentType string) string {
+	switch strings.ToLower(contentType) {
+	case "image/jpeg", "image/jpg":
+		return "jpeg"
+	case "image/png":
+		return "png"
+	case "image/gif":
+		return "gif"
+	case "image/webp":
+		return "webp"
+	case "image/avif":
+		return "avif"
+	default:
+		return ""
+	}
+}
+
+// Select picks the rendition to serve. An explicit format wins over the
+// Accept header; among acceptable renditions it prefers the narrowest one at
+// least width pixels wide (the widest when width is 0 or nothing is wide
+// enough), breaking ties by format preference. original is returned when no
+// candidate is acceptable.
+func Select(original Candidate, candidates []Candidate, width int, format, accept string) Candidate {
+	all := append([]Candidate{original}, candidates...)
+
+	var acceptable []Candidate
+	for _, c := range all {
+		if format != "" {
+			if c.Format == format {
+				acceptable = append(acceptable, c)
+			}
+		} else if accepts(accept, c.ContentType) {
+			acceptable = append(acceptable, c)
+		}
+	}
+	if len(acceptable) == 0 {
+		return original
+	}
+
+	best := acceptable[0]
+	for _, c := range acceptable[1:] {
+		if better(c, best, width) {
+			best = c
+		}
+	}
+	return best
+}
+
+func better(c, best Candidate, width int) bool {
+	if c.Width != best.Width {
+		cFits, bestFits := width > 0 && c.Width >= width, width > 0 && best.Width >= width
+		switch {
+		case cFits && bestFits:
+			return c.Width < best.Width
+		case cFits != bestFits:
+			return cFits
+		default:
+			return c.Width > best.Width
+		}
+	}
+	return formatRank(c.Format) < formatRank(best.Format)
+}
+
+// accepts reports whether an Accept header allows contentType. An empty
+// header accepts anything. The most specific matching range decides, so
+// "image/webp;q=0, */*" rejects WebP (RFC 9110 section 12.5.1).
+func accepts(accept, contentType string) bool {
+	if strings.TrimSpace(accept) == "" {
+		return true
+	}
+
+	major := strings.SplitN(contentType, "/", 2)[0]
+	best, q := 0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		var specificity int
+		switch strings.ToLower(strings.TrimSpace(fields[0])) {
+		case contentType:
+			specificity = 3
+		case major + "/*":
+			specificity = 2
+		case "*/*":
+			specificity = 1
+		default:
+			continue
+		}
+		if specificity < best {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					weight = v
+				}
+			}
+		}
+		// Repeats of the same range count if any of them allows it
+		if specificity > best || weight > q {
+			best, q = specificity, weight
+		}
+	}
+	return q > 0
+}
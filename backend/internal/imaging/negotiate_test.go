@@ -0,0 +1,74 @@
+package imaging
+
+import "testing"
+
+func TestSelect(t *testing.T) {
+	original := Candidate{ID: 0, Format: "jpeg", ContentType: "image/jpeg", Width: 4000}
+	variants := []Candidate{
+		{ID: 1, Format: "jpeg", ContentType: "image/jpeg", Width: 320},
+		{ID: 2, Format: "png", ContentType: "image/png", Width: 320},
+		{ID: 3, Format: "jpeg", ContentType: "image/jpeg", Width: 960},
+		{ID: 4, Format: "png", ContentType: "image/png", Width: 960},
+		{ID: 5, Format: "jpeg", ContentType: "image/jpeg", Width: 1920},
+	}
+
+	tests := []struct {
+		name     string
+		width    int
+		format   string
+		accept   string
+		expected int
+	}{
+		{"no hints serves original", 0, "", "", 0},
+		{"width picks smallest that fits", 500, "", "", 3},
+		{"jpeg only client", 500, "", "image/jpeg", 3},
+		{"browser accept prefers jpeg", 300, "", "image/avif,image/webp,*/*", 1},
+		{"png only client", 300, "", "image/png", 2},
+		{"explicit format", 300, "png", "image/jpeg,*/*", 2},
+		{"too wide falls back to widest", 5000, "", "image/jpeg", 0},
+		{"unavailable format falls back to original", 300, "avif", "", 0},
+		{"q=0 rejects format", 900, "", "image/jpeg;q=0, image/png", 4},
+		{"q=0 beats a later wildcard", 900, "", "image/jpeg;q=0, */*", 4},
+		{"q=0 beats a type wildcard", 300, "", "image/*, image/png;q=0", 1},
+		{"wildcard q=0 with exact type", 300, "", "*/*;q=0, image/png", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Select(original, variants, tt.width, tt.format, tt.accept)
+			if got.ID != tt.expected {
+				t.Errorf("Expected candidate %d, got %d", tt.expected, got.ID)
+			}
+		})
+	}
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	if got := FormatFromContentType("image/JPEG"); got != "jpeg" {
+		t.Errorf("Expected jpeg, got %s", got)
+	}
+	if got := FormatFromContentType("text/plain"); got != "" {
+		t.Errorf("Expected empty format, got %s", got)
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept      string
+		contentType string
+		expected    bool
+	}{
+		{"", "image/webp", true},
+		{"image/webp;q=0, */*", "image/webp", false},
+		{"image/webp;q=0, */*", "image/png", true},
+		{"*/*, image/*;q=0", "image/png", false},
+		{"image/*;q=0, image/png;q=0.5", "image/png", true},
+		{"text/html", "image/png", false},
+	}
+
+	for _, tt := range tests {
+		if got := accepts(tt.accept, tt.contentType); got != tt.expected {
+			t.Errorf("accepts(%q, %q) = %v, expected %v", tt.accept, tt.contentType, got, tt.expected)
+		}
+	}
+}
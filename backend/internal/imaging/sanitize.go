@@ -0,0 +1,231 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+var (
+	errInvalidJPEG = errors.New("invalid jpeg data")
+	errInvalidPNG  = errors.New("invalid png data")
+)
+
+const (
+	markerSOS   = 0xDA
+	markerAPP1  = 0xE1
+	markerAPP13 = 0xED
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// pngMetadataChunks are the PNG chunks dropped by Sanitize: EXIF and the
+// text chunks, which can carry GPS coordinates, authors and comments
+var pngMetadataChunks = map[string]bool{"eXIf": true, "tEXt": true, "iTXt": true, "zTXt": true}
+
+// Sanitize prepares an uploaded image for serving. For JPEGs it removes the
+// APP1 (EXIF/XMP) and APP13 (IPTC) segments, and for PNGs the eXIf and text
+// chunks, which carry GPS coordinates and camera serial numbers. The EXIF
+// orientation is baked into the pixels so the image still displays upright
+// without it. Other formats are returned unchanged. Images of any format with
+// more than MaxPixels pixels are rejected. The returned metadata is nil when
+// the image had no EXIF data.
+func Sanitize(data []byte) ([]byte, *Metadata, error) {
+	if err := checkSize(bytes.NewReader(data)); errors.Is(err, ErrTooLarge) {
+		return nil, nil, err
+	}
+
+	var stripped, exif []byte
+	var err error
+	switch {
+	case isJPEG(data):
+		stripped, exif, err = stripJPEGMetadata(data)
+	case isPNG(data):
+		stripped, exif, err = stripPNGMetadata(data)
+	default:
+		return data, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var meta *Metadata
+	if exif != nil {
+		// Unreadable EXIF is dropped along with everything else
+		meta, _ = ParseExif(exif)
+	}
+
+	if meta == nil || meta.Orientation <= 1 {
+		return stripped, meta, nil
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error decoding image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, Orient(img, meta.Orientation))
+	} else {
+		err = jpeg.Encode(&buf, Orient(img, meta.Orientation), &jpeg.Options{Quality: 92})
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding image: %w", err)
+	}
+
+	return buf.Bytes(), meta, nil
+}
+
+func isPNG(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(pngSignature))
+}
+
+// stripPNGMetadata copies a PNG without its metadata chunks and returns the
+// payload of its eXIf chunk, prefixed like a JPEG EXIF segment
+func stripPNGMetadata(data []byte) ([]byte, []byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+
+	var exif []byte
+	i := len(pngSignature)
+	for {
+		// Length, type, data and CRC
+		if i+8 > len(data) {
+			return nil, nil, errInvalidPNG
+		}
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) || end < i {
+			return nil, nil, errInvalidPNG
+		}
+		chunkType := string(data[i+4 : i+8])
+
+		switch {
+		case chunkType == "eXIf":
+			if exif == nil {
+				exif = append([]byte("Exif\x00\x00"), data[i+8:i+8+length]...)
+			}
+		case pngMetadataChunks[chunkType]:
+		default:
+			out = append(out, data[i:end]...)
+		}
+		if chunkType == "IEND" {
+			return out, exif, nil
+		}
+		i = end
+	}
+}
+
+func isJPEG(data []byte) bool {
+	return len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF
+}
+
+// stripJPEGMetadata copies a JPEG without its APP1 and APP13 segments and
+// returns the payload of the first EXIF segment it removed
+func stripJPEGMetadata(data []byte) ([]byte, []byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+
+	var exif []byte
+	i := 2
+	for {
+		if i >= len(data) {
+			return nil, nil, errInvalidJPEG
+		}
+		if data[i] != 0xFF {
+			return nil, nil, errInvalidJPEG
+		}
+		// Skip fill bytes between segments
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+		if i >= len(data) {
+			return nil, nil, errInvalidJPEG
+		}
+		marker := data[i]
+		i++
+
+		// Markers without a length field
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8) {
+			out = append(out, 0xFF, marker)
+			continue
+		}
+		if marker == 0xD9 {
+			out = append(out, 0xFF, marker)
+			return out, exif, nil
+		}
+
+		if i+2 > len(data) {
+			return nil, nil, errInvalidJPEG
+		}
+		length := int(data[i])<<8 | int(data[i+1])
+		if length < 2 || i+length > len(data) {
+			return nil, nil, errInvalidJPEG
+		}
+		segment := data[i : i+length]
+		payload := segment[2:]
+
+		// Everything from the start of scan onwards is image data
+		if marker == markerSOS {
+			out = append(out, 0xFF, marker)
+			out = append(out, data[i:]...)
+			return out, exif, nil
+		}
+
+		switch marker {
+		case markerAPP1:
+			if exif == nil && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				exif = payload
+			}
+		case markerAPP13:
+		default:
+			out = append(out, 0xFF, marker)
+			out = append(out, segment...)
+		}
+		i += length
+	}
+}
+
+// Orient applies an EXIF orientation (1-8) so the pixels are upright
+func Orient(src image.Image, orientation int) *image.RGBA {
+	rgba := toRGBA(src)
+	if orientation < 2 || orientation > 8 {
+		return rgba
+	}
+
+	w, h := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirror horizontal
+				sx, sy = w-1-x, y
+			case 3: // rotate 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirror vertical
+				sx, sy = x, h-1-y
+			case 5: // transpose
+				sx, sy = y, x
+			case 6: // rotate 90 clockwise
+				sx, sy = y, h-1-x
+			case 7: // transverse
+				sx, sy = w-1-y, h-1-x
+			case 8: // rotate 90 counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], rgba.Pix[rgba.PixOffset(sx, sy):rgba.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
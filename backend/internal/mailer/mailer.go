@@ -0,0 +1,66 @@
+// Package mailer sends email. Mail is not sent from request handlers: they
+// queue it in the outbox (store.OutboxStore) and a Worker delivers it with
+// retries, so a slow or unreachable mail server never fails a request.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/config"
+)
+
+// Message is a plain text email. The sender address belongs to the Mailer.
+type Message struct {
+	To      string
+	ReplyTo string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers a message or reports why it could not
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by cfg.MailDriver
+func New(cfg *config.Config, logger *slog.Logger) (Mailer, error) {
+	switch cfg.MailDriver {
+	case "", "log":
+		return NewLogMailer(logger), nil
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		})
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+	}
+}
+
+// LogMailer logs messages instead of sending them, for development and for
+// deployments without a mail server
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+func NewLogMailer(logger *slog.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Info("email not sent, mail driver is log",
+		"to", msg.To, "reply_to", msg.ReplyTo, "subject", msg.Subject)
+	m.logger.Debug("email body", "to", msg.To, "body", msg.Body)
+	return nil
+}
+
+// headerValue drops line breaks so user input cannot inject headers
+func headerValue(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,152 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// smtpSink is a minimal SMTP server that records the messages it receives
+type smtpSink struct {
+	listener net.Listener
+	messages chan sinkMessage
+}
+
+type sinkMessage struct {
+	from, to, data string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &smtpSink{listener: l, messages: make(chan sinkMessage, 10)}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) handle(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP sink")
+
+	var msg sinkMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 8BITMIME")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.from = envelopeAddress(line)
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.to = envelopeAddress(line)
+			tp.PrintfLine("250 OK")
+		case cmd == "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.messages <- msg
+			tp.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+// envelopeAddress extracts the <address> of a MAIL FROM or RCPT TO command
+func envelopeAddress(line string) string {
+	_, rest, _ := strings.Cut(line, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
+
+func (s *smtpSink) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	sink := newSMTPSink(t)
+	m, err := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: sink.port(), From: "Site <noreply@example.com>"})
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+
+	err = m.Send(context.Background(), Message{
+		To:      "admin@example.com",
+		ReplyTo: "jane@example.com\r\nBcc: victim@example.com",
+		Subject: "Héllo",
+		Body:    "Line one\nLine two",
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	msg := <-sink.messages
+	if msg.from != "noreply@example.com" || msg.to != "admin@example.com" {
+		t.Fatalf("Unexpected envelope %q -> %q", msg.from, msg.to)
+	}
+
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(msg.data)))
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("Failed to parse headers: %v", err)
+	}
+	if got := header.Get("Subject"); got != "=?utf-8?q?H=C3=A9llo?=" {
+		t.Errorf("Unexpected subject %q", got)
+	}
+	if got := header.Get("From"); got != `"Site" <noreply@example.com>` {
+		t.Errorf("Unexpected from %q", got)
+	}
+	if header.Get("Bcc") != "" || strings.Contains(header.Get("Reply-To"), "\n") {
+		t.Errorf("Header injection through Reply-To: %v", header)
+	}
+	if !strings.Contains(msg.data, "Line one\nLine two") {
+		t.Errorf("Unexpected body %q", msg.data)
+	}
+}
+
+func TestSMTPMailerUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	l.Close()
+
+	m, err := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: port, From: "noreply@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+	if err := m.Send(context.Background(), Message{To: "admin@example.com", Subject: "Hi", Body: "Hi"}); err == nil {
+		t.Fatal("Expected an error when the server is down")
+	}
+}
@@ -0,0 +1,83 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+// Templates are plain text. The first line is the subject and the rest is
+// the body.
+//
+//go:embed templates/*.txt
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.txt"))
+
+// ContactEmails renders the emails sent when someone submits the contact form
+type ContactEmails struct {
+	SiteName string
+	// NotifyTo receives a copy of each submission; empty disables it
+	NotifyTo string
+	// AutoReply sends the submitter a confirmation. It repeats nothing they
+	// wrote, so the form can't be used to send arbitrary text to any address.
+	AutoReply bool
+}
+
+// Render returns the notification and auto-reply for a submission, as
+// configured
+func (c ContactEmails) Render(submission *models.ContactSubmission) ([]Message, error) {
+	data := struct {
+		SiteName   string
+		Submission *models.ContactSubmission
+	}{c.SiteName, submission}
+
+	var messages []Message
+	if c.NotifyTo != "" {
+		msg, err := render("contact_notification.txt", data)
+		if err != nil {
+			return nil, err
+		}
+		msg.To = c.NotifyTo
+		msg.ReplyTo = submission.Email
+		messages = append(messages, msg)
+	}
+	if c.AutoReply {
+		msg, err := render("contact_auto_reply.txt", data)
+		if err != nil {
+			return nil, err
+		}
+		msg.To = submission.Email
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// MaintenanceEmail renders the reminder sent when maintenance comes due
+type MaintenanceEmail struct {
+	SiteName string
+	To       string
+}
+
+// Render returns the reminder for the items in report
+func (e MaintenanceEmail) Render(report *models.MaintenanceReport) (Message, error) {
+	msg, err := render("maintenance_due.txt", struct {
+		SiteName string
+		Report   *models.MaintenanceReport
+	}{e.SiteName, report})
+	msg.To = e.To
+	return msg, err
+}
+
+func render(name string, data interface{}) (Message, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return Message{}, fmt.Errorf("error rendering %s: %w", name, err)
+	}
+	subject, body, _ := strings.Cut(buf.String(), "\n")
+	return Message{Subject: strings.TrimSpace(subject), Body: body}, nil
+}
@@ -0,0 +1,82 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+func TestContactEmailsRender(t *testing.T) {
+	submission := &models.ContactSubmission{
+		Name:    "Jane",
+		Email:   "jane@example.com",
+		Subject: "Turbo",
+		Message: "What turbo is that?",
+	}
+
+	messages, err := ContactEmails{SiteName: "TestWebsite", NotifyTo: "admin@example.com", AutoReply: true}.Render(submission)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("Expected notification and auto-reply, got %+v", messages)
+	}
+
+	notify := messages[0]
+	if notify.To != "admin@example.com" || notify.ReplyTo != "jane@example.com" {
+		t.Errorf("Unexpected notification addresses %q, %q", notify.To, notify.ReplyTo)
+	}
+	if notify.Subject != "[TestWebsite] Contact form: Jane - Turbo" {
+		t.Errorf("Unexpected notification subject %q", notify.Subject)
+	}
+	if !strings.Contains(notify.Body, "What turbo is that?") {
+		t.Errorf("Expected the message in the notification, got %q", notify.Body)
+	}
+
+	reply := messages[1]
+	if reply.To != "jane@example.com" || !strings.Contains(reply.Body, "TestWebsite") {
+		t.Errorf("Unexpected auto-reply %+v", reply)
+	}
+	// The submitter's address is not verified, so nothing they wrote is sent to it
+	for _, echoed := range []string{"Jane", "Turbo", "What turbo is that?"} {
+		if strings.Contains(reply.Subject+reply.Body, echoed) {
+			t.Errorf("Expected the auto-reply not to repeat %q, got %+v", echoed, reply)
+		}
+	}
+
+	messages, err = ContactEmails{}.Render(submission)
+	if err != nil || len(messages) != 0 {
+		t.Fatalf("Expected no emails when disabled, got %+v, %v", messages, err)
+	}
+}
+
+func TestMaintenanceEmailRender(t *testing.T) {
+	miles, due := 80000, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	report := &models.MaintenanceReport{
+		Mileage: &models.MileageReading{Mileage: 80250, Date: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+		Items: []models.MaintenanceDue{
+			{MaintenanceSchedule: models.MaintenanceSchedule{Name: "Oil change"}, Status: models.MaintenanceOverdue, DueMileage: &miles},
+			{MaintenanceSchedule: models.MaintenanceSchedule{Name: "Brake fluid"}, Status: models.MaintenanceUpcoming, DueDate: &due},
+		},
+	}
+
+	msg, err := MaintenanceEmail{SiteName: "TestWebsite", To: "admin@example.com"}.Render(report)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if msg.To != "admin@example.com" || msg.Subject != "[TestWebsite] Maintenance due: 2 jobs" {
+		t.Errorf("Unexpected reminder %q to %q", msg.Subject, msg.To)
+	}
+	for _, want := range []string{"80250 miles on 20 May 2024", "Oil change is overdue, due at 80000 miles", "Brake fluid is upcoming, due by 1 Jun 2024"} {
+		if !strings.Contains(msg.Body, want) {
+			t.Errorf("Expected %q in the reminder, got %q", want, msg.Body)
+		}
+	}
+
+	report.Items = report.Items[:1]
+	if msg, _ = (MaintenanceEmail{SiteName: "TestWebsite"}).Render(report); msg.Subject != "[TestWebsite] Maintenance due: Oil change" {
+		t.Errorf("Unexpected subject %q", msg.Subject)
+	}
+}
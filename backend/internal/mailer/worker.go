@@ -0,0 +1,131 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// Retry schedule for failed sends: RetryBase doubling up to RetryMax, giving
+// up after MaxAttempts (about a day of retries)
+const (
+	RetryBase   = 30 * time.Second
+	RetryMax    = 2 * time.Hour
+	MaxAttempts = 20
+)
+
+// Backoff returns how long to wait after the given number of failed attempts,
+// or zero once the email should be given up on
+func Backoff(attempts int) time.Duration {
+	if attempts >= MaxAttempts {
+		return 0
+	}
+	d := RetryBase
+	for i := 1; i < attempts && d < RetryMax; i++ {
+		d *= 2
+	}
+	if d > RetryMax {
+		d = RetryMax
+	}
+	return d
+}
+
+// Worker delivers queued emails from the outbox
+type Worker struct {
+	outbox store.OutboxStore
+	mailer Mailer
+	logger *slog.Logger
+
+	// Interval between polls of the outbox
+	Interval time.Duration
+	// BatchSize is the most emails claimed per poll
+	BatchSize int
+	// SendTimeout bounds each delivery. Claimed emails stay hidden from
+	// other workers long enough for the whole batch to time out.
+	SendTimeout time.Duration
+}
+
+func NewWorker(outbox store.OutboxStore, mailer Mailer, logger *slog.Logger) *Worker {
+	return &Worker{
+		outbox:      outbox,
+		mailer:      mailer,
+		logger:      logger,
+		Interval:    10 * time.Second,
+		BatchSize:   20,
+		SendTimeout: 30 * time.Second,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("error processing email outbox", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the emails that are due and returns how many were sent
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	emails, err := w.outbox.ClaimEmails(ctx, w.BatchSize, w.SendTimeout*time.Duration(w.BatchSize+1))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range emails {
+		if ctx.Err() != nil {
+			// Unsent claims become due again once their lease runs out
+			return sent, ctx.Err()
+		}
+		if w.send(ctx, email) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func (w *Worker) send(ctx context.Context, email store.OutboxEmail) bool {
+	sendCtx, cancel := context.WithTimeout(ctx, w.SendTimeout)
+	defer cancel()
+
+	err := w.mailer.Send(sendCtx, Message{
+		To:      email.To,
+		ReplyTo: email.ReplyTo,
+		Subject: email.Subject,
+		Body:    email.Body,
+	})
+	if err == nil {
+		metrics.Emails.Inc("sent")
+		if err := w.outbox.MarkEmailSent(ctx, email.ID); err != nil {
+			w.logger.Error("error marking email sent", "email_id", email.ID, "error", err)
+		}
+		return true
+	}
+
+	retryIn := Backoff(email.Attempts + 1)
+	if retryIn == 0 {
+		metrics.Emails.Inc("failed")
+		w.logger.Error("giving up on email", "email_id", email.ID, "to", email.To,
+			"attempts", email.Attempts+1, "error", err)
+	} else {
+		metrics.Emails.Inc("retry")
+		w.logger.Warn("error sending email, will retry", "email_id", email.ID, "to", email.To,
+			"attempts", email.Attempts+1, "retry_in", retryIn, "error", err)
+	}
+	if err := w.outbox.MarkEmailFailed(ctx, email.ID, retryIn, err.Error()); err != nil {
+		w.logger.Error("error recording email failure", "email_id", email.ID, "error", err)
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// flakyMailer fails its next failures sends, then delivers
+type flakyMailer struct {
+	failures int
+	sent     []Message
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg Message) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("connection refused")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{9, RetryMax},
+		{MaxAttempts - 1, RetryMax},
+		{MaxAttempts, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v; want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWorkerRetries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mem := store.NewMemory()
+	mem.SetClock(func() time.Time { return now })
+
+	err := mem.CreateContactSubmission(ctx, &models.ContactSubmission{Name: "Jane"}, []store.OutboxEmail{
+		{To: "admin@example.com", Subject: "New message", Body: "Hi"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to queue email: %v", err)
+	}
+
+	m := &flakyMailer{failures: 2}
+	w := NewWorker(mem, m, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	attempts := []struct {
+		wait time.Duration
+		sent int
+	}{
+		{0, 0},
+		{RetryBase, 0},
+		{2 * RetryBase, 1},
+	}
+	for i, a := range attempts {
+		now = now.Add(a.wait)
+		if sent, err := w.RunOnce(ctx); err != nil || sent != a.sent {
+			t.Fatalf("Attempt %d: sent %d, error %v; want %d sent", i+1, sent, err, a.sent)
+		}
+		// Nothing is due again until the backoff has passed
+		if sent, _ := w.RunOnce(ctx); sent != 0 {
+			t.Fatalf("Attempt %d: expected nothing due, sent %d", i+1, sent)
+		}
+	}
+
+	if len(m.sent) != 1 || m.sent[0].To != "admin@example.com" {
+		t.Fatalf("Expected one delivered email, got %+v", m.sent)
+	}
+	now = now.Add(24 * time.Hour)
+	if emails, _ := mem.ClaimEmails(ctx, 10, time.Minute); len(emails) != 0 {
+		t.Fatalf("Expected sent email to leave the outbox, got %+v", emails)
+	}
+}
+
+func TestWorkerGivesUp(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mem := store.NewMemory()
+	mem.SetClock(func() time.Time { return now })
+
+	mem.CreateContactSubmission(ctx, &models.ContactSubmission{Name: "Jane"}, []store.OutboxEmail{
+		{To: "admin@example.com", Subject: "New message", Body: "Hi"},
+	})
+
+	m := &flakyMailer{failures: MaxAttempts + 5}
+	w := NewWorker(mem, m, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for i := 0; i < MaxAttempts+5; i++ {
+		w.RunOnce(ctx)
+		now = now.Add(RetryMax)
+	}
+
+	if remaining := m.failures; remaining != 5 {
+		t.Fatalf("Expected %d attempts before giving up, made %d", MaxAttempts, MaxAttempts+5-remaining)
+	}
+}
@@ -0,0 +1,91 @@
+package maintenance
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// Checker sends reminders as jobs come due. Each job is notified once when it
+// becomes upcoming and again when it becomes overdue; marking it done or
+// editing it starts over.
+type Checker struct {
+	store    store.MaintenanceStore
+	notifier Notifier
+	logger   *slog.Logger
+	now      func() time.Time
+
+	// Window is how close to due a job counts as upcoming
+	Window Window
+	// Interval between checks
+	Interval time.Duration
+}
+
+func NewChecker(maintenance store.MaintenanceStore, notifier Notifier, logger *slog.Logger) *Checker {
+	return &Checker{
+		store:    maintenance,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+		Window:   Window{Miles: 500, Days: 14},
+		Interval: time.Hour,
+	}
+}
+
+// Run checks for due jobs until ctx is cancelled
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Error("error checking maintenance", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce notifies the jobs whose status has changed to upcoming or overdue
+// since they were last notified, and returns how many there were. Their new
+// statuses are recorded with the reminder emails in one write, so an email is
+// queued once or, if the write fails, retried on the next check.
+func (c *Checker) RunOnce(ctx context.Context) (int, error) {
+	report, err := Report(ctx, c.store, c.Window, c.now())
+	if err != nil {
+		return 0, err
+	}
+
+	statuses := make(map[int]string)
+	due := &models.MaintenanceReport{Mileage: report.Mileage}
+	for _, item := range report.Items {
+		switch {
+		case item.Status == models.MaintenanceOK && item.NotifiedStatus != "":
+			// No longer due, say after the window was narrowed
+			statuses[item.ID] = ""
+		case item.Status != models.MaintenanceOK && item.Status != item.NotifiedStatus:
+			statuses[item.ID] = item.Status
+			due.Items = append(due.Items, item)
+		}
+	}
+	if len(statuses) == 0 {
+		return 0, nil
+	}
+
+	var emails []store.OutboxEmail
+	if len(due.Items) > 0 {
+		if emails, err = c.notifier.Notify(ctx, due); err != nil {
+			return 0, err
+		}
+	}
+	if err := c.store.SetMaintenanceNotified(ctx, statuses, emails); err != nil {
+		return 0, err
+	}
+	return len(due.Items), nil
+}
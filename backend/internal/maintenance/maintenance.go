@@ -0,0 +1,103 @@
+// Package maintenance works out when the car's scheduled maintenance is next
+// due, from the latest mileage in the build log, and sends reminders as jobs
+// come due.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// Window is how close to coming due a job counts as upcoming
+type Window struct {
+	Miles int
+	Days  int
+}
+
+// statusRank orders statuses from most to least pressing
+var statusRank = map[string]int{
+	models.MaintenanceOverdue:  0,
+	models.MaintenanceUpcoming: 1,
+	models.MaintenanceOK:       2,
+}
+
+// Due works out when schedule next comes due as of reading, which is nil
+// before the first one, and the day of now. Miles count from the mileage the
+// job was last done at, or from zero if it never was; months count from when
+// it was last done, or from when the schedule was made. A job is overdue once
+// either is passed and upcoming once either is within window.
+func Due(schedule models.MaintenanceSchedule, reading *models.MileageReading, window Window, now time.Time) models.MaintenanceDue {
+	due := models.MaintenanceDue{MaintenanceSchedule: schedule, Status: models.MaintenanceOK}
+	overdue, upcoming := false, false
+
+	if schedule.IntervalMiles != nil {
+		mileage := *schedule.IntervalMiles
+		if schedule.LastDoneMileage != nil {
+			mileage += *schedule.LastDoneMileage
+		}
+		due.DueMileage = &mileage
+		if reading != nil {
+			remaining := mileage - reading.Mileage
+			due.MilesRemaining = &remaining
+			overdue = overdue || remaining < 0
+			upcoming = upcoming || remaining <= window.Miles
+		}
+	}
+
+	if schedule.IntervalMonths != nil {
+		from := day(schedule.CreatedAt)
+		if schedule.LastDoneAt != nil {
+			from = day(*schedule.LastDoneAt)
+		}
+		date := from.AddDate(0, *schedule.IntervalMonths, 0)
+		remaining := int(date.Sub(day(now)).Hours() / 24)
+		due.DueDate = &date
+		due.DaysRemaining = &remaining
+		overdue = overdue || remaining < 0
+		upcoming = upcoming || remaining <= window.Days
+	}
+
+	switch {
+	case overdue:
+		due.Status = models.MaintenanceOverdue
+	case upcoming:
+		due.Status = models.MaintenanceUpcoming
+	}
+	return due
+}
+
+// Report works out when every scheduled job is next due, overdue jobs first,
+// then upcoming ones, each by name
+func Report(ctx context.Context, maintenance store.MaintenanceStore, window Window, now time.Time) (*models.MaintenanceReport, error) {
+	reading, err := maintenance.LatestMileage(ctx)
+	if errors.Is(err, store.ErrNotFound) {
+		reading, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := maintenance.ListMaintenanceSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.MaintenanceReport{Mileage: reading, Items: []models.MaintenanceDue{}}
+	for _, schedule := range schedules {
+		report.Items = append(report.Items, Due(schedule, reading, window, now))
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		return statusRank[report.Items[i].Status] < statusRank[report.Items[j].Status]
+	})
+	return report, nil
+}
+
+// day returns midnight UTC on the day of t
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,178 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/mailer"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+func intPtr(n int) *int { return &n }
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2024, 6, 1, 18, 30, 0, 0, time.UTC)
+	reading := &models.MileageReading{Mileage: 84800, Date: *date(2024, 5, 28)}
+	window := Window{Miles: 500, Days: 14}
+
+	tests := []struct {
+		name     string
+		schedule models.MaintenanceSchedule
+		reading  *models.MileageReading
+		status   string
+		miles    string
+		days     string
+	}{
+		{"miles upcoming", models.MaintenanceSchedule{IntervalMiles: intPtr(5000), LastDoneMileage: intPtr(80000)}, reading, models.MaintenanceUpcoming, "200", "-"},
+		{"miles ok", models.MaintenanceSchedule{IntervalMiles: intPtr(5000), LastDoneMileage: intPtr(82000)}, reading, models.MaintenanceOK, "2200", "-"},
+		{"miles overdue", models.MaintenanceSchedule{IntervalMiles: intPtr(3000), LastDoneMileage: intPtr(80000)}, reading, models.MaintenanceOverdue, "-1800", "-"},
+		{"never done", models.MaintenanceSchedule{IntervalMiles: intPtr(30000)}, reading, models.MaintenanceOverdue, "-54800", "-"},
+		{"no reading", models.MaintenanceSchedule{IntervalMiles: intPtr(5000), LastDoneMileage: intPtr(80000)}, nil, models.MaintenanceOK, "-", "-"},
+		{"months ok", models.MaintenanceSchedule{IntervalMonths: intPtr(6), LastDoneAt: date(2024, 3, 1)}, reading, models.MaintenanceOK, "-", "92"},
+		{"months upcoming", models.MaintenanceSchedule{IntervalMonths: intPtr(6), LastDoneAt: date(2023, 12, 10)}, reading, models.MaintenanceUpcoming, "-", "9"},
+		{"due today", models.MaintenanceSchedule{IntervalMonths: intPtr(12), LastDoneAt: date(2023, 6, 1)}, reading, models.MaintenanceUpcoming, "-", "0"},
+		{"months overdue", models.MaintenanceSchedule{IntervalMonths: intPtr(6), LastDoneAt: date(2023, 11, 1)}, reading, models.MaintenanceOverdue, "-", "-31"},
+		{"from creation", models.MaintenanceSchedule{IntervalMonths: intPtr(1), CreatedAt: time.Date(2024, 5, 20, 9, 0, 0, 0, time.UTC)}, nil, models.MaintenanceOK, "-", "19"},
+		{"whichever first", models.MaintenanceSchedule{IntervalMiles: intPtr(5000), LastDoneMileage: intPtr(82000), IntervalMonths: intPtr(6), LastDoneAt: date(2023, 11, 1)}, reading, models.MaintenanceOverdue, "2200", "-31"},
+	}
+
+	show := func(n *int) string {
+		if n == nil {
+			return "-"
+		}
+		return fmt.Sprint(*n)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := Due(tt.schedule, tt.reading, window, now)
+			if due.Status != tt.status || show(due.MilesRemaining) != tt.miles || show(due.DaysRemaining) != tt.days {
+				t.Errorf("Due() = %s with %s miles and %s days left; want %s with %s and %s",
+					due.Status, show(due.MilesRemaining), show(due.DaysRemaining), tt.status, tt.miles, tt.days)
+			}
+		})
+	}
+}
+
+// recordingNotifier keeps the jobs it was told about
+type recordingNotifier struct {
+	err      error
+	notified [][]string
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, report *models.MaintenanceReport) ([]store.OutboxEmail, error) {
+	if n.err != nil {
+		return nil, n.err
+	}
+	var names []string
+	for _, item := range report.Items {
+		names = append(names, item.Name+" "+item.Status)
+	}
+	n.notified = append(n.notified, names)
+	return nil, nil
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+	mem := store.NewMemory()
+	notifier := &recordingNotifier{}
+	checker := NewChecker(mem, notifier, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	checker.now = func() time.Time { return now }
+
+	check := func(want string) {
+		t.Helper()
+		notifier.notified = nil
+		n, err := checker.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+		if got := fmt.Sprint(notifier.notified); got != want || (want == "[]" && n != 0) {
+			t.Errorf("Expected %s notified, got %s (%d)", want, got, n)
+		}
+	}
+
+	oil := &models.MaintenanceSchedule{Name: "Oil change", IntervalMiles: intPtr(5000), LastDoneMileage: intPtr(80000)}
+	coolant := &models.MaintenanceSchedule{Name: "Coolant", IntervalMonths: intPtr(24), LastDoneAt: date(2023, 1, 1)}
+	for _, schedule := range []*models.MaintenanceSchedule{oil, coolant} {
+		if err := mem.CreateMaintenanceSchedule(ctx, schedule); err != nil {
+			t.Fatalf("Failed to create schedule: %v", err)
+		}
+	}
+	check("[]")
+
+	entry := &models.CarBuildEntry{Title: "Track day", Date: *date(2024, 5, 30), Mileage: intPtr(84600)}
+	if err := mem.CreateCarBuildEntry(ctx, entry, nil); err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	check("[[Oil change upcoming]]")
+	check("[]")
+
+	entry.Mileage = intPtr(85100)
+	if err := mem.UpdateCarBuildEntry(ctx, entry, nil); err != nil {
+		t.Fatalf("Failed to update entry: %v", err)
+	}
+	now = time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC)
+	check("[[Oil change overdue Coolant upcoming]]")
+
+	// A failed notification is retried on the next check
+	now = time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	notifier.err = errors.New("mail server down")
+	if _, err := checker.RunOnce(ctx); err == nil {
+		t.Error("Expected the notifier's error")
+	}
+	notifier.err = nil
+	check("[[Coolant overdue]]")
+
+	// Marking the job done starts over
+	oil.LastDoneMileage = intPtr(85100)
+	if err := mem.UpdateMaintenanceSchedule(ctx, oil); err != nil {
+		t.Fatalf("Failed to update schedule: %v", err)
+	}
+	check("[]")
+}
+
+func TestCheckerQueuesEmail(t *testing.T) {
+	ctx := context.Background()
+	mem := store.NewMemory()
+	notifier := &EmailNotifier{Email: mailer.MaintenanceEmail{SiteName: "TestWebsite", To: "owner@example.com"}}
+	checker := NewChecker(mem, notifier, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	checker.now = func() time.Time { return time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	coolant := &models.MaintenanceSchedule{Name: "Coolant", IntervalMonths: intPtr(24), LastDoneAt: date(2022, 1, 1)}
+	if err := mem.CreateMaintenanceSchedule(ctx, coolant); err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+
+	// The reminder is queued with the notified status, so checking again
+	// doesn't queue it twice
+	for i := 0; i < 2; i++ {
+		if _, err := checker.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce failed: %v", err)
+		}
+	}
+	emails, err := mem.ClaimEmails(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to claim emails: %v", err)
+	}
+	if len(emails) != 1 || emails[0].To != "owner@example.com" {
+		t.Errorf("Expected one reminder to the owner, got %+v", emails)
+	}
+	schedule, err := mem.GetMaintenanceSchedule(ctx, coolant.ID)
+	if err != nil {
+		t.Fatalf("Failed to get schedule: %v", err)
+	}
+	if schedule.NotifiedStatus != models.MaintenanceOverdue {
+		t.Errorf("Expected the overdue reminder to be recorded, got %q", schedule.NotifiedStatus)
+	}
+}
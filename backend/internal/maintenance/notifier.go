@@ -0,0 +1,63 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/mailer"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+	"github.com/Jakeito/TestWebsite/backend/internal/store"
+)
+
+// Notifier tells the owner about jobs that have come due. The report holds
+// only the jobs to remind them of. Notify returns the emails to send, which
+// the checker queues in the same transaction that records the jobs as
+// notified; notifiers that don't send email act directly and return none.
+type Notifier interface {
+	Notify(ctx context.Context, report *models.MaintenanceReport) ([]store.OutboxEmail, error)
+}
+
+// NewNotifier returns the notifier for kind, which is "log", "email" or
+// "off". It returns nil for "off" (or empty).
+func NewNotifier(kind string, email mailer.MaintenanceEmail, logger *slog.Logger) (Notifier, error) {
+	switch kind {
+	case "", "off":
+		return nil, nil
+	case "log":
+		return LogNotifier{Logger: logger}, nil
+	case "email":
+		if email.To == "" {
+			return nil, fmt.Errorf("maintenance notifier %q needs an address", kind)
+		}
+		return &EmailNotifier{Email: email}, nil
+	default:
+		return nil, fmt.Errorf("unknown maintenance notifier %q", kind)
+	}
+}
+
+// LogNotifier logs a warning for each job
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+func (n LogNotifier) Notify(ctx context.Context, report *models.MaintenanceReport) ([]store.OutboxEmail, error) {
+	for _, item := range report.Items {
+		n.Logger.Warn("maintenance due", "schedule_id", item.ID, "name", item.Name, "status", item.Status)
+	}
+	return nil, nil
+}
+
+// EmailNotifier sends one reminder email covering every job through the
+// outbox; the mail worker delivers it
+type EmailNotifier struct {
+	Email mailer.MaintenanceEmail
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, report *models.MaintenanceReport) ([]store.OutboxEmail, error) {
+	msg, err := n.Email.Render(report)
+	if err != nil {
+		return nil, err
+	}
+	return []store.OutboxEmail{{To: msg.To, Subject: msg.Subject, Body: msg.Body}}, nil
+}
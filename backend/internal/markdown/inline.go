@@ -0,0 +1,462 @@
+package markdown
+
+import (
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func escape(s string) string {
+	return html.EscapeString(s)
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// inline renders the inline content of a block. Inside link text, nested
+// links are written as plain text.
+func (r *renderer) inline(s string, inLink bool) {
+	var text strings.Builder
+	flush := func() {
+		r.out.WriteString(escape(text.String()))
+		text.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			flush()
+			r.out.WriteString("<br>\n")
+			i += 2
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+		case c == '`':
+			n := runLength(s, i)
+			if end := findCodeSpanEnd(s, i+n, n); end >= 0 {
+				flush()
+				r.codeSpan(s[i+n : end])
+				i = end + n
+			} else {
+				text.WriteString(s[i : i+n])
+				i += n
+			}
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if next, ok := r.linkOrImage(s, i+1, true, inLink, flush); ok {
+				i = next
+			} else {
+				text.WriteString("![")
+				i += 2
+			}
+		case c == '[':
+			if next, ok := r.linkOrImage(s, i, false, inLink, flush); ok {
+				i = next
+			} else {
+				text.WriteByte('[')
+				i++
+			}
+		case c == '<':
+			if next, ok := r.autolink(s, i, inLink, flush); ok {
+				i = next
+			} else {
+				text.WriteByte('<')
+				i++
+			}
+		case c == '*' || c == '_' || (c == '~' && strings.HasPrefix(s[i:], "~~")):
+			if next, ok := r.emphasis(s, i, inLink, flush); ok {
+				i = next
+			} else {
+				n := runLength(s, i)
+				text.WriteString(s[i : i+n])
+				i += n
+			}
+		default:
+			text.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+}
+
+// runLength counts the characters equal to s[i] starting at i
+func runLength(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// findCodeSpanEnd returns the index of a backtick run of exactly n closing a
+// code span whose content starts at from, or -1
+func findCodeSpanEnd(s string, from, n int) int {
+	for i := from; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := runLength(s, i)
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+func (r *renderer) codeSpan(code string) {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+		code = code[1 : len(code)-1]
+	}
+	r.out.WriteString("<code>" + escape(code) + "</code>")
+}
+
+// emphasis renders *em*, **strong**, ***both*** or ~~deleted~~ text opened by
+// the delimiter run at s[i], returning the index after the closing run. The
+// run must be followed by text and closed by an equal run preceded by text;
+// underscores also can't open or close inside a word.
+func (r *renderer) emphasis(s string, i int, inLink bool, flush func()) (int, bool) {
+	c := s[i]
+	n := runLength(s, i)
+	if c == '~' && n != 2 || n > 3 {
+		return 0, false
+	}
+	if !canOpen(s, i, n) {
+		return 0, false
+	}
+
+	for j := i + n; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			run := runLength(s, j)
+			if end := findCodeSpanEnd(s, j+run, run); end >= 0 {
+				j = end + run
+			} else {
+				j += run
+			}
+			continue
+		case c:
+		default:
+			j++
+			continue
+		}
+		run := runLength(s, j)
+		if run == n && canClose(s, j, n) {
+			flush()
+			open, close := emphasisTags(c, n)
+			r.out.WriteString(open)
+			r.inline(s[i+n:j], inLink)
+			r.out.WriteString(close)
+			return j + n, true
+		}
+		j += run
+	}
+	return 0, false
+}
+
+func emphasisTags(c byte, n int) (string, string) {
+	switch {
+	case c == '~':
+		return "<del>", "</del>"
+	case n == 1:
+		return "<em>", "</em>"
+	case n == 2:
+		return "<strong>", "</strong>"
+	}
+	return "<em><strong>", "</strong></em>"
+}
+
+func canOpen(s string, i, n int) bool {
+	if i+n >= len(s) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(s[i+n:])
+	if unicode.IsSpace(next) {
+		return false
+	}
+	if s[i] == '_' && i > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(s[:i])
+		return !unicode.IsLetter(prev) && !unicode.IsDigit(prev)
+	}
+	return true
+}
+
+func canClose(s string, j, n int) bool {
+	prev, _ := utf8.DecodeLastRuneInString(s[:j])
+	if unicode.IsSpace(prev) {
+		return false
+	}
+	if s[j] == '_' && j+n < len(s) {
+		next, _ := utf8.DecodeRuneInString(s[j+n:])
+		return !unicode.IsLetter(next) && !unicode.IsDigit(next)
+	}
+	return true
+}
+
+// linkOrImage renders [text](destination "title") or ![alt](destination)
+// with its [ at s[open], returning the index after the closing parenthesis
+func (r *renderer) linkOrImage(s string, open int, image, inLink bool, flush func()) (int, bool) {
+	closeBracket := matchingBracket(s, open)
+	if closeBracket < 0 || closeBracket+1 >= len(s) || s[closeBracket+1] != '(' {
+		return 0, false
+	}
+	dest, title, next, ok := parseDestination(s, closeBracket+2)
+	if !ok {
+		return 0, false
+	}
+	label := s[open+1 : closeBracket]
+
+	flush()
+	if image {
+		r.image(label, dest, title)
+		return next, true
+	}
+	href, safe := safeURL(dest)
+	if !safe || inLink {
+		r.inline(label, inLink)
+		return next, true
+	}
+	r.out.WriteString(`<a href="` + escape(href) + `"`)
+	if title != "" {
+		r.out.WriteString(` title="` + escape(title) + `"`)
+	}
+	if isExternal(href) {
+		r.out.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	r.out.WriteString(">")
+	r.inline(label, true)
+	r.out.WriteString("</a>")
+	return next, true
+}
+
+// image writes an <img>. Gallery images without alt text of their own take
+// the gallery's; images that can't be shown are replaced by their alt text.
+func (r *renderer) image(label, dest, title string) {
+	alt := plainText(label)
+	src, ok := "", false
+	if id, isGallery := galleryImageID(dest); isGallery {
+		var galleryAlt string
+		if r.images != nil {
+			galleryAlt, ok = r.images(id)
+		}
+		if alt == "" {
+			alt = galleryAlt
+		}
+		src = ImagePath + strconv.Itoa(id)
+	} else {
+		src, ok = safeURL(dest)
+	}
+	if !ok {
+		r.out.WriteString(escape(alt))
+		return
+	}
+
+	r.out.WriteString(`<img src="` + escape(src) + `" alt="` + escape(alt) + `"`)
+	if title != "" {
+		r.out.WriteString(` title="` + escape(title) + `"`)
+	}
+	r.out.WriteString(">")
+}
+
+// galleryImageID reads a destination of the form image:42
+func galleryImageID(dest string) (int, bool) {
+	rest, ok := strings.CutPrefix(strings.ToLower(dest), "image:")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(rest)
+	if err != nil || id <= 0 || strconv.Itoa(id) != rest {
+		return 0, false
+	}
+	return id, true
+}
+
+// plainText strips backslash escapes and emphasis markers from image alt text
+func plainText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == '*' || s[i] == '_' || s[i] == '`':
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// matchingBracket returns the index of the ] closing the [ at s[open], or -1
+func matchingBracket(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			run := runLength(s, i)
+			if end := findCodeSpanEnd(s, i+run, run); end >= 0 {
+				i = end + run - 1
+			} else {
+				i += run - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseDestination reads a link destination and optional title starting after
+// the ( at s[start-1], returning the index after the closing )
+func parseDestination(s string, start int) (dest, title string, next int, ok bool) {
+	i := skipSpace(s, start)
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexAny(s[i+1:], ">\n")
+		if end < 0 || s[i+1+end] != '>' {
+			return "", "", 0, false
+		}
+		dest = s[i+1 : i+1+end]
+		i += end + 2
+	} else {
+		begin, depth := i, 0
+		for ; i < len(s); i++ {
+			c := s[i]
+			if c == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+				i++
+				continue
+			}
+			if c == ' ' || c == '\n' || c < 0x20 {
+				break
+			}
+			if c == '(' {
+				depth++
+			}
+			if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		dest = s[begin:i]
+	}
+
+	i = skipSpace(s, i)
+	if i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		closer := s[i]
+		if closer == '(' {
+			closer = ')'
+		}
+		end := strings.IndexByte(s[i+1:], closer)
+		if end < 0 {
+			return "", "", 0, false
+		}
+		title = s[i+1 : i+1+end]
+		i = skipSpace(s, i+end+2)
+	}
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return unescapePunct(dest), unescapePunct(title), i + 1, true
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func unescapePunct(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// autolink renders <https://example.com> or <someone@example.com> with its <
+// at s[i]
+func (r *renderer) autolink(s string, i int, inLink bool, flush func()) (int, bool) {
+	end := strings.IndexAny(s[i+1:], "<> \n")
+	if end < 0 || s[i+1+end] != '>' {
+		return 0, false
+	}
+	target := s[i+1 : i+1+end]
+	href := target
+	if strings.Contains(target, "@") && !strings.Contains(target, ":") {
+		href = "mailto:" + target
+	}
+	if !strings.Contains(href, ":") {
+		return 0, false
+	}
+	href, safe := safeURL(href)
+	if !safe {
+		return 0, false
+	}
+
+	flush()
+	if inLink {
+		r.out.WriteString(escape(target))
+		return i + end + 2, true
+	}
+	r.out.WriteString(`<a href="` + escape(href) + `"`)
+	if isExternal(href) {
+		r.out.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	r.out.WriteString(">" + escape(target) + "</a>")
+	return i + end + 2, true
+}
+
+// Schemes links and images may use; anything else, such as javascript: or
+// data:, is dropped
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// safeURL returns the URL to write for a link destination, and false when it
+// must not be written. Relative URLs are allowed; image:42 points at the
+// gallery image.
+func safeURL(dest string) (string, bool) {
+	dest = strings.TrimSpace(dest)
+	for _, c := range dest {
+		if c < 0x20 || c == 0x7f || unicode.IsSpace(c) {
+			return "", false
+		}
+	}
+	if id, ok := galleryImageID(dest); ok {
+		return ImagePath + strconv.Itoa(id), true
+	}
+
+	// A scheme is whatever precedes the first colon, unless a path, query or
+	// fragment starts first
+	colon := strings.IndexByte(dest, ':')
+	if colon < 0 || (strings.IndexAny(dest, "/?#") >= 0 && strings.IndexAny(dest, "/?#") < colon) {
+		return dest, true
+	}
+	return dest, safeSchemes[strings.ToLower(dest[:colon])]
+}
+
+func isExternal(href string) bool {
+	lower := strings.ToLower(href)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "//")
+}
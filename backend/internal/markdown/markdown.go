@@ -0,0 +1,338 @@
+// Package markdown renders the Markdown stored in content fields to HTML that
+// is safe to insert into a page.
+//
+// The supported syntax is the common subset of CommonMark: ATX and setext
+// headings, paragraphs, block quotes, nested lists, fenced code blocks,
+// thematic breaks, emphasis, strong emphasis, ~~strikethrough~~, code spans,
+// links, images and autolinks. Raw HTML in the source is escaped rather than
+// passed through, so the output only ever contains the tags the renderer
+// writes itself. Links and images are limited to http, https, mailto and
+// relative URLs, and images may name a gallery image as image:42.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ImagePath is the URL gallery images embedded as image:ID resolve to
+const ImagePath = "/api/image/"
+
+// Images resolves gallery images embedded as image:ID. It returns the image's
+// alt text, and false when there is no such image.
+type Images func(id int) (alt string, ok bool)
+
+// imageRefPattern finds the gallery images a source may embed
+var imageRefPattern = regexp.MustCompile(`image:(\d+)`)
+
+// ImageIDs returns the IDs of the gallery images src may embed, so they can be
+// looked up together before rendering. It can include IDs that turn out not
+// to be in an image.
+func ImageIDs(src string) []int {
+	var ids []int
+	for _, match := range imageRefPattern.FindAllStringSubmatch(src, -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Render converts src to HTML. images may be nil, in which case embedded
+// gallery images are left out.
+func Render(src string, images Images) string {
+	if strings.TrimSpace(src) == "" {
+		return ""
+	}
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	src = strings.ReplaceAll(src, "\x00", "�")
+
+	r := &renderer{images: images}
+	r.blocks(strings.Split(src, "\n"), false, 0)
+	return r.out.String()
+}
+
+// Deepest nesting of block quotes and lists rendered as such
+const maxDepth = 16
+
+type renderer struct {
+	out    strings.Builder
+	images Images
+	// endsInline is set while the last block written was a paragraph
+	// without <p> tags
+	endsInline bool
+}
+
+var (
+	atxHeadingPattern = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	thematicPattern   = regexp.MustCompile(`^ {0,3}(?:(?:\*[ ]*){3,}|(?:-[ ]*){3,}|(?:_[ ]*){3,})$`)
+	fencePattern      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ ]*([^`]*)$")
+	quotePattern      = regexp.MustCompile(`^ {0,3}>[ ]?`)
+	listItemPattern   = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ ]+|$)`)
+	setextPattern     = regexp.MustCompile(`^ {0,3}(=+|-+)[ ]*$`)
+	languagePattern   = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+)
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// startsBlock reports whether line begins a block that interrupts a paragraph
+func startsBlock(line string) bool {
+	return atxHeadingPattern.MatchString(line) || thematicPattern.MatchString(line) ||
+		fencePattern.MatchString(line) || quotePattern.MatchString(line) || listItemPattern.MatchString(line)
+}
+
+// blocks renders a sequence of lines as block elements. In a tight list item
+// paragraphs are written without <p> tags.
+func (r *renderer) blocks(lines []string, tight bool, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if !isBlank(line) {
+			r.endsInline = false
+		}
+		switch {
+		case isBlank(line):
+			i++
+		case fencePattern.MatchString(line):
+			i = r.fencedCode(lines, i)
+		case atxHeadingPattern.MatchString(line):
+			m := atxHeadingPattern.FindStringSubmatch(line)
+			r.heading(len(m[1]), m[2])
+			i++
+		case thematicPattern.MatchString(line):
+			r.out.WriteString("<hr>\n")
+			i++
+		case quotePattern.MatchString(line) && depth < maxDepth:
+			i = r.blockQuote(lines, i, depth)
+		case listItemPattern.MatchString(line) && depth < maxDepth:
+			i = r.list(lines, i, depth)
+		default:
+			i = r.paragraph(lines, i, tight)
+		}
+	}
+}
+
+func (r *renderer) heading(level int, text string) {
+	tag := "h" + strconv.Itoa(level)
+	r.out.WriteString("<" + tag + ">")
+	r.inline(strings.TrimSpace(text), false)
+	r.out.WriteString("</" + tag + ">\n")
+}
+
+// fencedCode renders the code block opened at lines[start] and returns the
+// index of the line after it. An unclosed fence runs to the end.
+func (r *renderer) fencedCode(lines []string, start int) int {
+	m := fencePattern.FindStringSubmatch(lines[start])
+	indent, fence := len(m[1]), m[2]
+	language := strings.Fields(m[3])
+
+	r.out.WriteString("<pre><code")
+	if len(language) > 0 && languagePattern.MatchString(language[0]) {
+		r.out.WriteString(` class="language-` + escape(language[0]) + `"`)
+	}
+	r.out.WriteString(">")
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) < 4 && strings.HasPrefix(trimmed, fence[:1]) &&
+			strings.TrimRight(trimmed, fence[:1]+" ") == "" && len(strings.TrimRight(trimmed, " ")) >= len(fence) {
+			i++
+			break
+		}
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		r.out.WriteString(escape(line) + "\n")
+	}
+	r.out.WriteString("</code></pre>\n")
+	return i
+}
+
+// blockQuote renders the quote starting at lines[start], made of consecutive
+// lines beginning with >, and returns the index of the line after it
+func (r *renderer) blockQuote(lines []string, start, depth int) int {
+	var inner []string
+	i := start
+	for ; i < len(lines) && quotePattern.MatchString(lines[i]); i++ {
+		inner = append(inner, quotePattern.ReplaceAllString(lines[i], ""))
+	}
+	r.out.WriteString("<blockquote>\n")
+	r.blocks(inner, false, depth+1)
+	r.out.WriteString("</blockquote>\n")
+	return i
+}
+
+type listItem struct {
+	lines []string
+	// blankAfter is set when a blank line separates the item from the next
+	blankAfter bool
+}
+
+// list renders the list starting at lines[start] and returns the index of the
+// line after it. Items continue on lines indented past their marker; a list
+// with blank lines between or inside its items is loose and gets paragraphs.
+func (r *renderer) list(lines []string, start, depth int) int {
+	first := listItemPattern.FindStringSubmatch(lines[start])
+	marker := first[2]
+	ordered := marker[0] >= '0' && marker[0] <= '9'
+	delimiter := marker[len(marker)-1:]
+
+	var items []*listItem
+	loose := false
+	i := start
+	for i < len(lines) {
+		m := listItemPattern.FindStringSubmatch(lines[i])
+		if m == nil || !sameList(m[2], ordered, delimiter) {
+			break
+		}
+		// Content lines up with the first character after the marker, unless
+		// the marker is followed by nothing or by more than four spaces
+		contentIndent := len(m[0])
+		if len(m[3]) == 0 || len(m[3]) > 4 {
+			contentIndent = len(m[1]) + len(m[2]) + 1
+		}
+		item := &listItem{lines: []string{lines[i][len(m[0]):]}}
+		items = append(items, item)
+		i++
+
+		// Continuation lines, which may include blank lines
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				// The item goes on if the next non-blank line is indented enough
+				j := i
+				for j < len(lines) && isBlank(lines[j]) {
+					j++
+				}
+				if j < len(lines) && indentation(lines[j]) >= contentIndent {
+					for ; i < j; i++ {
+						item.lines = append(item.lines, "")
+					}
+					continue
+				}
+				item.blankAfter = true
+				i = j
+				break
+			}
+			if indentation(line) >= contentIndent {
+				item.lines = append(item.lines, line[contentIndent:])
+				i++
+				continue
+			}
+			// A lazy continuation of the item's paragraph
+			if !startsBlock(line) && !isBlank(item.lines[len(item.lines)-1]) {
+				item.lines = append(item.lines, line)
+				i++
+				continue
+			}
+			break
+		}
+		if item.blankAfter && i < len(lines) {
+			if m := listItemPattern.FindStringSubmatch(lines[i]); m != nil && sameList(m[2], ordered, delimiter) {
+				loose = true
+			}
+		}
+		for _, line := range item.lines[1:] {
+			if isBlank(line) {
+				loose = true
+			}
+		}
+	}
+
+	switch {
+	case !ordered:
+		r.out.WriteString("<ul>\n")
+	case strings.TrimRight(marker, ".)") != "1":
+		n, _ := strconv.Atoi(strings.TrimRight(marker, ".)"))
+		r.out.WriteString(`<ol start="` + strconv.Itoa(n) + `">` + "\n")
+	default:
+		r.out.WriteString("<ol>\n")
+	}
+	for _, item := range items {
+		inner := &renderer{images: r.images}
+		inner.blocks(item.lines, !loose, depth+1)
+		html := inner.out.String()
+		if loose {
+			html = "\n" + html
+		} else if inner.endsInline {
+			html = strings.TrimSuffix(html, "\n")
+		}
+		r.out.WriteString("<li>" + html + "</li>\n")
+	}
+	if ordered {
+		r.out.WriteString("</ol>\n")
+	} else {
+		r.out.WriteString("</ul>\n")
+	}
+
+	return i
+}
+
+// sameList reports whether marker continues a list of the given kind
+func sameList(marker string, ordered bool, delimiter string) bool {
+	isOrdered := marker[0] >= '0' && marker[0] <= '9'
+	if isOrdered != ordered {
+		return false
+	}
+	if ordered {
+		return strings.HasSuffix(marker, delimiter)
+	}
+	return marker == delimiter
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// paragraph renders the paragraph starting at lines[start] and returns the
+// index of the line after it. A paragraph underlined with = or - becomes a
+// heading.
+func (r *renderer) paragraph(lines []string, start int, tight bool) int {
+	var text []string
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if len(text) > 0 && setextPattern.MatchString(line) {
+			level := 2
+			if strings.Contains(line, "=") {
+				level = 1
+			}
+			r.heading(level, strings.Join(text, "\n"))
+			return i + 1
+		}
+		if len(text) > 0 && startsBlock(line) {
+			break
+		}
+		text = append(text, line)
+	}
+
+	// Two trailing spaces make a hard line break
+	for n := range text {
+		line := strings.TrimLeft(text[n], " ")
+		if n < len(text)-1 && strings.HasSuffix(line, "  ") {
+			line = strings.TrimRight(line, " ") + "\\"
+		} else {
+			line = strings.TrimRight(line, " ")
+		}
+		text[n] = line
+	}
+	if !tight {
+		r.out.WriteString("<p>")
+	}
+	r.inline(strings.Join(text, "\n"), false)
+	if !tight {
+		r.out.WriteString("</p>")
+	}
+	r.out.WriteString("\n")
+	r.endsInline = tight
+	return i
+}
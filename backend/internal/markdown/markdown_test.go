@@ -0,0 +1,99 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"empty", "  \n", ""},
+		{"paragraphs", "One\ntwo\n\nThree", "<p>One\ntwo</p>\n<p>Three</p>\n"},
+		{"headings", "# One #\n### Three\nSetext\n---", "<h1>One</h1>\n<h3>Three</h3>\n<h2>Setext</h2>\n"},
+		{"emphasis", "*em* _em_ **strong** __strong__ ***both*** ~~del~~", "<p><em>em</em> <em>em</em> <strong>strong</strong> <strong>strong</strong> <em><strong>both</strong></em> <del>del</del></p>\n"},
+		{"intraword underscores", "snake_case_name and 2 * 3 * 4", "<p>snake_case_name and 2 * 3 * 4</p>\n"},
+		{"nested emphasis", "**a *b* c**", "<p><strong>a <em>b</em> c</strong></p>\n"},
+		{"code span", "Use `a < b` or `` x`y ``", "<p>Use <code>a &lt; b</code> or <code>x`y</code></p>\n"},
+		{"escapes", `\*not em\* and \[not a link\]`, "<p>*not em* and [not a link]</p>\n"},
+		{"hard breaks", "one  \ntwo\\\nthree", "<p>one<br>\ntwo<br>\nthree</p>\n"},
+		{"fenced code", "```js\nif (a < b) {}\n```", "<pre><code class=\"language-js\">if (a &lt; b) {}\n</code></pre>\n"},
+		{"block quote", "> quoted\n> **text**", "<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>\n"},
+		{"thematic break", "a\n\n* * *", "<p>a</p>\n<hr>\n"},
+		{"tight list", "- a\n- b\n  - c\n- d", "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n<li>d</li>\n</ul>\n"},
+		{"loose list", "1. a\n\n2. b", "<ol>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ol>\n"},
+		{"ordered start", "3) a\n4) b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"lazy continuation", "- a\ncontinued", "<ul>\n<li>a\ncontinued</li>\n</ul>\n"},
+		{"links", `[site](https://example.com "Title") [page](/resume) [mail](mailto:me@example.com)`,
+			`<p><a href="https://example.com" title="Title" rel="nofollow noopener noreferrer">site</a> <a href="/resume">page</a> <a href="mailto:me@example.com">mail</a></p>` + "\n"},
+		{"autolinks", "<https://example.com/a?b=1&c=2> <me@example.com>",
+			`<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">https://example.com/a?b=1&amp;c=2</a> <a href="mailto:me@example.com">me@example.com</a></p>` + "\n"},
+		{"images", `![Turbo](https://example.com/t.jpg "Boost")`, `<p><img src="https://example.com/t.jpg" alt="Turbo" title="Boost"></p>` + "\n"},
+	}
+
+	for _, tt := range tests {
+		if got := Render(tt.src, nil); got != tt.want {
+			t.Errorf("%s: Render(%q) =\n%s\nwant\n%s", tt.name, tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{`<iframe src="https://example.com"></iframe>`, "<p>&lt;iframe src=&#34;https://example.com&#34;&gt;&lt;/iframe&gt;</p>\n"},
+		{`<img src=x onerror=alert(1)>`, "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"[click](javascript:alert(1))", "<p>click</p>\n"},
+		{"[click](JavaScript:alert(1))", "<p>click</p>\n"},
+		{"[click](vbscript:msgbox)", "<p>click</p>\n"},
+		{"<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+		{"![pic](data:image/svg+xml;base64,PHN2Zz4=)", "<p>pic</p>\n"},
+		{`[x](/a" onmouseover="alert(1))`, "<p>[x](/a&#34; onmouseover=&#34;alert(1))</p>\n"},
+		{`[x](</a" onclick="b>)`, "<p>x</p>\n"},
+		{"```\"><script>\n```", "<pre><code></code></pre>\n"},
+	}
+
+	for _, tt := range tests {
+		if got := Render(tt.src, nil); got != tt.want {
+			t.Errorf("Render(%q) = %s; want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestRenderGalleryImages(t *testing.T) {
+	images := func(id int) (string, bool) {
+		if id == 42 {
+			return `A "blue" car`, true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		src, want string
+	}{
+		{"![](image:42)", `<p><img src="/api/image/42" alt="A &#34;blue&#34; car"></p>` + "\n"},
+		{"![Before paint](image:42)", `<p><img src="/api/image/42" alt="Before paint"></p>` + "\n"},
+		{"![Missing](image:7)", "<p>Missing</p>\n"},
+		{"![](image:abc)", "<p></p>\n"},
+		{"[full size](image:42)", `<p><a href="/api/image/42">full size</a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		if got := Render(tt.src, images); got != tt.want {
+			t.Errorf("Render(%q) = %s; want %s", tt.src, got, tt.want)
+		}
+	}
+
+	if got := Render("![](image:42)", nil); got != "<p></p>\n" {
+		t.Errorf("Expected gallery images to be left out without a resolver, got %s", got)
+	}
+}
+
+func TestImageIDs(t *testing.T) {
+	got := ImageIDs("![](image:42) text ![x](image:7 \"t\") [link](image:42)")
+	if want := []int{42, 7, 42}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ImageIDs = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,375 @@
+// Package metrics implements the small subset of Prometheus instrumentation
+// the server needs: counters, gauges and histograms with labels, exposed in
+// the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds suited to HTTP handlers
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector writes one metric family in the text format
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds the metrics exposed by Handler
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.collectors[c.name()]; exists {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	r.collectors[c.name()] = c
+}
+
+// Write writes every registered metric, sorted by name
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		r.mu.Lock()
+		c := r.collectors[name]
+		r.mu.Unlock()
+		c.write(w)
+	}
+}
+
+// Handler serves the registry in the Prometheus text exposition format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// desc is the name, help text and label names shared by a metric family
+type desc struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (d desc) name() string { return d.metricName }
+
+func (d desc) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.metricName, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.metricName, kind)
+}
+
+// key joins label values into a map key, checking the count matches
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.metricName, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// line formats a sample's name and labels, with an optional extra label such
+// as a histogram's le
+func (d desc) line(suffix string, values []string, extraName, extraValue string) string {
+	var b strings.Builder
+	b.WriteString(d.metricName)
+	b.WriteString(suffix)
+
+	pairs := len(d.labels)
+	if extraName != "" {
+		pairs++
+	}
+	if pairs == 0 {
+		return b.String()
+	}
+
+	b.WriteByte('{')
+	for i, label := range d.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", label, escapeLabel(values[i]))
+	}
+	if extraName != "" {
+		if len(d.labels) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// sample holds the label values and value of one series
+type sample struct {
+	values []string
+	value  float64
+}
+
+// vec is a set of float series keyed by label values
+type vec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*sample
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{desc: desc{metricName: name, help: help, labels: labels}, series: make(map[string]*sample)}
+}
+
+func (v *vec) update(values []string, fn func(float64) float64) {
+	key := v.key(values)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &sample{values: append([]string(nil), values...)}
+		v.series[key] = s
+	}
+	s.value = fn(s.value)
+}
+
+func (v *vec) get(values []string) float64 {
+	key := v.key(values)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (v *vec) writeSamples(w io.Writer, kind string) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	samples := make([]sample, len(keys))
+	for i, key := range keys {
+		samples[i] = *v.series[key]
+	}
+	v.mu.Unlock()
+
+	v.header(w, kind)
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s %s\n", v.line("", s.values, "", ""), formatFloat(s.value))
+	}
+}
+
+// Counter is a monotonically increasing value per label set
+type Counter struct {
+	vec
+}
+
+// NewCounter registers a counter with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{vec: newVec(name, help, labels)}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the series for the label values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta, which must not be negative, to the series for the label values
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter " + c.metricName + " cannot decrease")
+	}
+	c.update(values, func(v float64) float64 { return v + delta })
+}
+
+// Value returns the current value of a series
+func (c *Counter) Value(values ...string) float64 {
+	return c.get(values)
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.writeSamples(w, "counter")
+}
+
+// Gauge is a value per label set that can go up and down
+type Gauge struct {
+	vec
+}
+
+// NewGauge registers a gauge with the given label names
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{vec: newVec(name, help, labels)}
+	r.register(g)
+	return g
+}
+
+// Set replaces the value of the series for the label values
+func (g *Gauge) Set(value float64, values ...string) {
+	g.update(values, func(float64) float64 { return value })
+}
+
+// Value returns the current value of a series
+func (g *Gauge) Value(values ...string) float64 {
+	return g.get(values)
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.writeSamples(w, "gauge")
+}
+
+// funcMetric is an unlabelled metric whose value is read at scrape time
+type funcMetric struct {
+	desc
+	kind string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge whose value is computed on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help}, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is read on every scrape,
+// for cumulative totals kept elsewhere
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcMetric{desc: desc{metricName: name, help: help}, kind: "counter", fn: fn})
+}
+
+func (f *funcMetric) write(w io.Writer) {
+	f.header(w, f.kind)
+	fmt.Fprintf(w, "%s %s\n", f.metricName, formatFloat(f.fn()))
+}
+
+// Histogram counts observations into cumulative buckets per label set
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	values []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram. Buckets are upper bounds in increasing
+// order; the +Inf bucket is implied.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: histogram " + name + " buckets must be sorted")
+	}
+	h := &Histogram{
+		desc:    desc{metricName: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records one value in the series for the label values
+func (h *Histogram) Observe(value float64, values ...string) {
+	key := h.key(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{values: append([]string(nil), values...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count returns the number of observations in a series
+func (h *Histogram) Count(values ...string) uint64 {
+	key := h.key(values)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.series))
+	for key := range h.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	series := make([]histogramSeries, len(keys))
+	for i, key := range keys {
+		s := *h.series[key]
+		s.counts = append([]uint64(nil), s.counts...)
+		series[i] = s
+	}
+	h.mu.Unlock()
+
+	h.header(w, "histogram")
+	for _, s := range series {
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s %d\n", h.line("_bucket", s.values, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s %d\n", h.line("_bucket", s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s %s\n", h.line("_sum", s.values, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s %d\n", h.line("_count", s.values, "", ""), s.count)
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
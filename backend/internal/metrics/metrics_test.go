@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCounterExposition(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests\nserved.", "route", "status")
+
+	c.Inc("/api/image/{id}", "200")
+	c.Add(2, "/api/image/{id}", "200")
+	c.Inc(`/say/"hi"\`, "404")
+
+	if got := c.Value("/api/image/{id}", "200"); got != 3 {
+		t.Fatalf("Expected 3, got %v", got)
+	}
+
+	var b strings.Builder
+	r.Write(&b)
+	want := `# HELP requests_total Requests\nserved.
+# TYPE requests_total counter
+requests_total{route="/api/image/{id}",status="200"} 3
+requests_total{route="/say/\"hi\"\\",status="404"} 1
+`
+	if b.String() != want {
+		t.Fatalf("Unexpected exposition:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(3, "/a")
+
+	if got := h.Count("/a"); got != 4 {
+		t.Fatalf("Expected 4 observations, got %d", got)
+	}
+
+	var b strings.Builder
+	r.Write(&b)
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 3.65
+latency_seconds_count{route="/a"} 4
+`
+	if b.String() != want {
+		t.Fatalf("Unexpected exposition:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestGaugesAndHandler(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("seed_seconds", "Seed time.")
+	g.Set(1.5)
+	g.Set(2.5)
+	RegisterDBStats(r, func() sql.DBStats {
+		return sql.DBStats{OpenConnections: 4, InUse: 1, Idle: 3, WaitDuration: 1500 * time.Millisecond}
+	})
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Unexpected content type %q", ct)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		"seed_seconds 2.5\n",
+		"# TYPE testwebsite_db_open_connections gauge\n",
+		"testwebsite_db_open_connections 4\n",
+		"testwebsite_db_idle_connections 3\n",
+		"# TYPE testwebsite_db_wait_duration_seconds_total counter\n",
+		"testwebsite_db_wait_duration_seconds_total 1.5\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestLabelCountMismatchPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("things_total", "Things.", "kind")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for missing label values")
+		}
+	}()
+	c.Inc()
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("dup", "First.")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for a duplicate metric")
+		}
+	}()
+	r.NewGauge("dup", "Second.")
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"database/sql"
+	"runtime"
+)
+
+// Default is the registry served at /metrics
+var Default = NewRegistry()
+
+// Label values for the result of uploads and logins
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultLocked  = "locked"
+)
+
+var (
+	HTTPRequests = Default.NewCounter("testwebsite_http_requests_total",
+		"HTTP requests by method, route template and status.", "method", "route", "status")
+	HTTPRequestDuration = Default.NewHistogram("testwebsite_http_request_duration_seconds",
+		"HTTP request latency by method, route template and status.", DefaultBuckets, "method", "route", "status")
+
+	ImageBytesServed = Default.NewCounter("testwebsite_image_bytes_served_total",
+		"Image bytes written to clients by folder.", "folder")
+	ImageUploads = Default.NewCounter("testwebsite_image_uploads_total",
+		"Uploaded image files by result.", "result")
+
+	Logins = Default.NewCounter("testwebsite_logins_total",
+		"Login attempts by result.", "result")
+	ContactSubmissions = Default.NewCounter("testwebsite_contact_submissions_total",
+		"Accepted contact form submissions by destination, inbox or spam.", "folder")
+	Emails = Default.NewCounter("testwebsite_emails_total",
+		"Outbox delivery attempts by result: sent, retry or failed.", "result")
+	RateLimited = Default.NewCounter("testwebsite_rate_limited_total",
+		"Requests rejected by rate limiting, by limiter.", "limiter")
+
+	MigrationDuration = Default.NewGauge("testwebsite_migration_duration_seconds",
+		"Time taken to apply migrations at startup.")
+	MigrationsApplied = Default.NewGauge("testwebsite_migrations_applied",
+		"Number of migrations applied at startup.")
+	SeedDuration = Default.NewGauge("testwebsite_seed_duration_seconds",
+		"Time taken to seed gallery images at startup.")
+)
+
+func init() {
+	Default.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// RegisterDBStats exposes connection pool statistics read from stats, which
+// is normally (*sql.DB).Stats
+func RegisterDBStats(r *Registry, stats func() sql.DBStats) {
+	r.NewGaugeFunc("testwebsite_db_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(stats().MaxOpenConnections) })
+	r.NewGaugeFunc("testwebsite_db_open_connections", "Established connections, both in use and idle.",
+		func() float64 { return float64(stats().OpenConnections) })
+	r.NewGaugeFunc("testwebsite_db_in_use_connections", "Connections currently in use.",
+		func() float64 { return float64(stats().InUse) })
+	r.NewGaugeFunc("testwebsite_db_idle_connections", "Idle connections.",
+		func() float64 { return float64(stats().Idle) })
+	r.NewCounterFunc("testwebsite_db_wait_count_total", "Total number of connections waited for.",
+		func() float64 { return float64(stats().WaitCount) })
+	r.NewCounterFunc("testwebsite_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		func() float64 { return stats().WaitDuration.Seconds() })
+	r.NewCounterFunc("testwebsite_db_max_idle_closed_total", "Connections closed due to the idle limit.",
+		func() float64 { return float64(stats().MaxIdleClosed) })
+	r.NewCounterFunc("testwebsite_db_max_lifetime_closed_total", "Connections closed due to the maximum lifetime.",
+		func() float64 { return float64(stats().MaxLifetimeClosed) })
+}
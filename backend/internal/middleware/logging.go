@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+)
+
+// RequestIDHeader carries the request ID in both directions. A well-formed ID
+// sent by a proxy or client is kept so logs can be correlated across hops.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+const requestIDContextKey contextKey = "request_id"
+const accessLogContextKey contextKey = "access_log"
+
+// accessLog collects what inner handlers learn about a request, such as the
+// authenticated user, for the access log line written once it completes
+type accessLog struct {
+	userID int
+}
+
+// LoggingMiddleware assigns every request an ID, exposes a logger tagged with
+// it through the request context, and writes one structured access log line
+// per request. The route template noted by RecordRoute is logged so that
+// paths with IDs are grouped together.
+func LoggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			reqLogger := logger.With("request_id", requestID)
+			entry := &accessLog{}
+			ctx := context.WithValue(r.Context(), requestIDContextKey, requestID)
+			ctx = context.WithValue(ctx, accessLogContextKey, entry)
+			ctx = logging.WithLogger(ctx, reqLogger)
+			r, route := withRoute(r.WithContext(ctx))
+
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", *route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.Status()),
+				slog.Int64("bytes", rec.bytes),
+				slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_addr", r.RemoteAddr),
+			}
+			if entry.userID != 0 {
+				attrs = append(attrs, slog.Int("user_id", entry.userID))
+			}
+
+			level := slog.LevelInfo
+			if rec.Status() >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			reqLogger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
+
+// RequestID returns the ID assigned to the request by LoggingMiddleware
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// withUser records the authenticated user for the access log and tags the
+// request logger with it
+func withUser(ctx context.Context, userID int) context.Context {
+	if entry, ok := ctx.Value(accessLogContextKey).(*accessLog); ok {
+		entry.userID = userID
+	}
+	return logging.WithLogger(ctx, logging.FromContext(ctx).With("user_id", userID))
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder captures the status code and body size written by the
+// wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Status returns the response status, which is 200 if the handler never
+// wrote a header
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
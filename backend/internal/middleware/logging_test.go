@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/auth"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+func decodeLogLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggingMiddlewareRequestID(t *testing.T) {
+	var seen string
+	handler := LoggingMiddleware(slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"propagated", "edge-1234.abc", true},
+		{"invalid characters", "bad id\n", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(RequestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("Expected response ID %q to match context ID %q", got, seen)
+			}
+			if (got == tt.incoming) != tt.keep {
+				t.Fatalf("Incoming ID %q, response ID %q, keep %v", tt.incoming, got, tt.keep)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	secret := "test-secret"
+
+	router := mux.NewRouter()
+	router.Use(RecordRoute)
+	protected := router.PathPrefix("/api").Subrouter()
+	protected.Use(AuthMiddleware(secret, fakeSessions{"active": true}))
+	protected.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("handling item")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})
+
+	handler := LoggingMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)))(router)
+
+	token, err := auth.GenerateToken(42, "test@example.com", false, "active", secret)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/items/7", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := decodeLogLines(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected a handler line and an access line, got %d", len(entries))
+	}
+
+	handlerLine, access := entries[0], entries[1]
+	if handlerLine["request_id"] != "req-1" || handlerLine["user_id"] != float64(42) {
+		t.Fatalf("Expected handler log to carry request and user IDs, got %v", handlerLine)
+	}
+
+	want := map[string]interface{}{
+		"msg":        "request",
+		"request_id": "req-1",
+		"method":     "GET",
+		"route":      "/api/items/{id}",
+		"path":       "/api/items/7",
+		"status":     float64(http.StatusTeapot),
+		"bytes":      float64(len("short and stout")),
+		"user_id":    float64(42),
+	}
+	for key, value := range want {
+		if access[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, access[key])
+		}
+	}
+	if _, ok := access["duration_ms"]; !ok {
+		t.Error("Expected duration_ms in access log")
+	}
+}
+
+func TestLoggingMiddlewareUnmatchedRoute(t *testing.T) {
+	var buf bytes.Buffer
+	router := mux.NewRouter()
+	handler := LoggingMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)))(router)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	entries := decodeLogLines(t, &buf)
+	if len(entries) != 1 || entries[0]["route"] != "" || entries[0]["status"] != float64(http.StatusNotFound) {
+		t.Fatalf("Unexpected access log: %v", entries)
+	}
+	if _, ok := entries[0]["user_id"]; ok {
+		t.Fatal("Expected no user_id for an anonymous request")
+	}
+}
+
+func TestLoggingMiddlewareSharesRouteWithMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	matches := 0
+	router := mux.NewRouter()
+	router.Use(RecordRoute)
+	router.HandleFunc("/api/gadgets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).MatcherFunc(func(r *http.Request, m *mux.RouteMatch) bool {
+		matches++
+		return true
+	})
+	handler := LoggingMiddleware(slog.New(slog.NewJSONHandler(&buf, nil)))(MetricsMiddleware()(router))
+
+	before := metrics.HTTPRequests.Value("GET", "/api/gadgets/{id}", "204")
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/gadgets/3", nil))
+
+	if matches != 1 {
+		t.Errorf("Expected the router to match the request once, got %d", matches)
+	}
+	if got := metrics.HTTPRequests.Value("GET", "/api/gadgets/{id}", "204") - before; got != 1 {
+		t.Errorf("Expected 1 request counted for the route, got %v", got)
+	}
+	entries := decodeLogLines(t, &buf)
+	if len(entries) != 1 || entries[0]["route"] != "/api/gadgets/{id}" {
+		t.Fatalf("Unexpected access log: %v", entries)
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+	"github.com/gorilla/mux"
+)
+
+// unmatchedRoute labels requests that match no route, so arbitrary paths
+// can't create new series
+const unmatchedRoute = "unmatched"
+
+// otherMethod labels requests with a non-standard method, for the same reason
+const otherMethod = "other"
+
+const routeContextKey contextKey = "route"
+
+// MetricsMiddleware counts requests and records their latency by method,
+// route template and status. The template is noted by RecordRoute inside the
+// router; requests that never reach a route, such as CORS preflights and
+// unknown paths, are counted as unmatched.
+func MetricsMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			r, route := withRoute(r)
+			next.ServeHTTP(rec, r)
+
+			template := *route
+			if template == "" {
+				template = unmatchedRoute
+			}
+			method := methodLabel(r.Method)
+			status := strconv.Itoa(rec.Status())
+			metrics.HTTPRequests.Inc(method, template, status)
+			metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), method, template, status)
+		})
+	}
+}
+
+// withRoute returns r with somewhere for RecordRoute to note the matched
+// route template, reusing the one an outer middleware already added, and
+// that template once the router has run
+func withRoute(r *http.Request) (*http.Request, *string) {
+	if route, ok := r.Context().Value(routeContextKey).(*string); ok {
+		return r, route
+	}
+	route := new(string)
+	return r.WithContext(context.WithValue(r.Context(), routeContextKey, route)), route
+}
+
+// RecordRoute notes the template of the matched route for LoggingMiddleware
+// and MetricsMiddleware. Register it with Router.Use so it reuses the
+// router's match.
+func RecordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeContextKey).(*string); ok {
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					*route = template
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// methodLabel returns method if it is a standard HTTP method, or "other"
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return otherMethod
+	}
+}
+
+// MetricsAuth only lets requests carrying the bearer token through. Scrapers
+// use a static token rather than a user session.
+func MetricsAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				problem.Write(w, problem.Unauthorized, "Invalid metrics token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
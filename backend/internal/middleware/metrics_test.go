@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(RecordRoute)
+	router.HandleFunc("/api/widgets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}).Methods("GET", "BREW")
+	handler := MetricsMiddleware()(router)
+
+	before := metrics.HTTPRequests.Value("GET", "/api/widgets/{id}", "202")
+	beforeOther := metrics.HTTPRequests.Value(otherMethod, "/api/widgets/{id}", "202")
+	beforeUnmatched := metrics.HTTPRequests.Value("GET", unmatchedRoute, "404")
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/widgets/1", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/widgets/2", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/api/widgets/3", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nowhere", nil))
+
+	if got := metrics.HTTPRequests.Value("GET", "/api/widgets/{id}", "202") - before; got != 2 {
+		t.Errorf("Expected 2 requests counted for the route, got %v", got)
+	}
+	if got := metrics.HTTPRequests.Value(otherMethod, "/api/widgets/{id}", "202") - beforeOther; got != 1 {
+		t.Errorf("Expected 1 request with a non-standard method, got %v", got)
+	}
+	if metrics.HTTPRequests.Value("BREW", "/api/widgets/{id}", "202") != 0 {
+		t.Error("Expected non-standard methods not to get their own series")
+	}
+	if got := metrics.HTTPRequests.Value("GET", unmatchedRoute, "404") - beforeUnmatched; got != 1 {
+		t.Errorf("Expected 1 unmatched request, got %v", got)
+	}
+	if metrics.HTTPRequestDuration.Count("GET", "/api/widgets/{id}", "202") < 2 {
+		t.Error("Expected latency observations for the route")
+	}
+}
+
+func TestMetricsAuth(t *testing.T) {
+	handler := MetricsAuth("scrape-token")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		expected int
+	}{
+		{"valid token", "Bearer scrape-token", http.StatusOK},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	MetricsAuth("")(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an empty token to reject everything, got %d", rec.Code)
+	}
+}
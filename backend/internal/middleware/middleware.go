@@ -6,34 +6,86 @@ import (
 	"strings"
 
 	"github.com/Jakeito/TestWebsite/backend/internal/auth"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
 )
 
 type contextKey string
 
 const UserContextKey contextKey = "user"
 
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// SessionChecker reports whether the session behind an access token has been
+// revoked (logout, refresh token reuse) before the token itself expires.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+func AuthMiddleware(jwtSecret string, sessions SessionChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				problem.Write(w, problem.Unauthorized, "Authorization header required")
 				return
 			}
 
 			bearerToken := strings.Split(authHeader, " ")
 			if len(bearerToken) != 2 || bearerToken[0] != "Bearer" {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				problem.Write(w, problem.Unauthorized, "Invalid authorization header format")
 				return
 			}
 
 			claims, err := auth.ValidateToken(bearerToken[1], jwtSecret)
 			if err != nil {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				problem.Write(w, problem.InvalidToken, "Invalid or expired token")
+				return
+			}
+
+			if claims.SessionID == "" {
+				problem.Write(w, problem.InvalidToken, "Invalid or expired token")
+				return
+			}
+
+			active, err := sessions.IsSessionActive(r.Context(), claims.SessionID)
+			if err != nil {
+				problem.Write(w, problem.Internal, "Error checking session")
+				return
+			}
+			if !active {
+				problem.Write(w, problem.InvalidToken, "Session has been revoked")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			ctx = withUser(ctx, claims.UserID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalAuthMiddleware attaches the caller's claims like AuthMiddleware
+// when the request carries a valid token for an active session, and otherwise
+// lets it through anonymously, for public routes that show admins more.
+func OptionalAuthMiddleware(jwtSecret string, sessions SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := auth.ValidateToken(token, jwtSecret)
+			if err != nil || claims.SessionID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if active, err := sessions.IsSessionActive(r.Context(), claims.SessionID); err != nil || !active {
+				next.ServeHTTP(w, r)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			ctx = withUser(ctx, claims.UserID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -43,17 +95,10 @@ func AdminMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		claims, ok := r.Context().Value(UserContextKey).(*auth.Claims)
 		if !ok || !claims.IsAdmin {
-			http.Error(w, "Admin access required", http.StatusForbidden)
+			problem.Write(w, problem.Forbidden, "Admin access required")
 			return
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
-
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Simple logging - in production, use a proper logger
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/auth"
+)
+
+type fakeSessions map[string]bool
+
+func (f fakeSessions) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return f[sessionID], nil
+}
+
+func TestAuthMiddlewareSessions(t *testing.T) {
+	secret := "test-secret"
+	sessions := fakeSessions{"active": true, "revoked": false}
+
+	handler := AuthMiddleware(secret, sessions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(UserContextKey).(*auth.Claims); !ok {
+			t.Error("Expected claims in request context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name      string
+		sessionID string
+		expected  int
+	}{
+		{"active session", "active", http.StatusOK},
+		{"revoked session", "revoked", http.StatusUnauthorized},
+		{"unknown session", "unknown", http.StatusUnauthorized},
+		{"missing session", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := auth.GenerateToken(1, "test@example.com", true, tt.sessionID, secret)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/contact", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareMissingHeader(t *testing.T) {
+	handler := AuthMiddleware("test-secret", fakeSessions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called without a token")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/contact", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	secret := "test-secret"
+	sessions := fakeSessions{"active": true}
+
+	var claims *auth.Claims
+	handler := OptionalAuthMiddleware(secret, sessions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = r.Context().Value(UserContextKey).(*auth.Claims)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	active, _ := auth.GenerateToken(1, "test@example.com", true, "active", secret)
+	revoked, _ := auth.GenerateToken(1, "test@example.com", true, "revoked", secret)
+	tests := []struct {
+		name       string
+		header     string
+		wantClaims bool
+	}{
+		{"no header", "", false},
+		{"active session", "Bearer " + active, true},
+		{"revoked session", "Bearer " + revoked, false},
+		{"invalid token", "Bearer nope", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/image/1", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if (claims != nil) != tt.wantClaims {
+				t.Errorf("Expected claims %v, got %+v", tt.wantClaims, claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/metrics"
+	"github.com/Jakeito/TestWebsite/backend/internal/problem"
+)
+
+// Rate allows Events requests per Per, with bursts of up to Events. The zero
+// Rate disables limiting.
+type Rate struct {
+	Events int
+	Per    time.Duration
+}
+
+// ParseRate parses a rate such as "10/1m" or "5/30s". An empty string or
+// "off" returns the zero Rate.
+func ParseRate(s string) (Rate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Rate{}, nil
+	}
+
+	events, per, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(events)
+	if !ok || err != nil || n <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: expected events/duration such as 10/1m", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: expected events/duration such as 10/1m", s)
+	}
+	return Rate{Events: n, Per: d}, nil
+}
+
+// bucket is a token bucket refilled continuously at the limiter's rate
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter keeps a token bucket per key, such as a client IP
+type RateLimiter struct {
+	name      string
+	rate      Rate
+	now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter returns a limiter for rate. The name labels its metrics.
+func NewRateLimiter(name string, rate Rate) *RateLimiter {
+	return &RateLimiter{name: name, rate: rate, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token for key. When none is left it reports how long until
+// the next one is available.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	if l.rate.Events == 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	perToken := l.rate.Per / time.Duration(l.rate.Events)
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.rate.Events), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.rate.Events), b.tokens+float64(now.Sub(b.last))/float64(perToken))
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) * float64(perToken))
+}
+
+// sweep drops buckets that have refilled completely, which are no different
+// from new ones; callers hold mu
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.rate.Per {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.rate.Per {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit rejects requests from clients that have used up their tokens with
+// 429 and a Retry-After header
+func RateLimit(l *RateLimiter, clientIP *ClientIPResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.rate.Events == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retry := l.Allow(clientIP.ClientIP(r)); !ok {
+				metrics.RateLimited.Inc(l.name)
+				TooManyRequests(w, retry, problem.RateLimited, "Too many requests, try again later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TooManyRequests writes a 429 problem telling the client when to retry
+func TooManyRequests(w http.ResponseWriter, retryAfter time.Duration, code problem.Code, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	problem.Write(w, code, message)
+}
+
+// ClientIPResolver finds the client address of a request. X-Forwarded-For is
+// only believed when the request comes from a trusted proxy, and then only up
+// to the first hop that isn't trusted, so clients can't pick their own key.
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIPResolver trusts the given proxy addresses or CIDR ranges
+func NewClientIPResolver(trustedProxies []string) (*ClientIPResolver, error) {
+	c := &ClientIPResolver{}
+	for _, proxy := range trustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		c.trusted = append(c.trusted, network)
+	}
+	return c, nil
+}
+
+func (c *ClientIPResolver) isTrusted(ip net.IP) bool {
+	for _, network := range c.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address requests from this client should be keyed by
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !c.isTrusted(ip) {
+		return host
+	}
+
+	// Walk the chain from the nearest hop back towards the client
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !c.isTrusted(hop) {
+			break
+		}
+	}
+	return ip.String()
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Rate
+		wantErr bool
+	}{
+		{"10/1m", Rate{Events: 10, Per: time.Minute}, false},
+		{" 5/30s ", Rate{Events: 5, Per: 30 * time.Second}, false},
+		{"", Rate{}, false},
+		{"off", Rate{}, false},
+		{"10", Rate{}, true},
+		{"0/1m", Rate{}, true},
+		{"ten/1m", Rate{}, true},
+		{"10/soon", Rate{}, true},
+		{"10/-1m", Rate{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRate(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRate(%q) = %v, %v; want %v, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter("test", Rate{Events: 3, Per: 30 * time.Second})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("1.2.3.4"); !ok {
+			t.Fatalf("Expected request %d within the burst to be allowed", i+1)
+		}
+	}
+
+	ok, retry := l.Allow("1.2.3.4")
+	if ok || retry != 10*time.Second {
+		t.Fatalf("Expected rejection with a 10s retry, got %v %v", ok, retry)
+	}
+
+	// Other clients have their own bucket
+	if ok, _ := l.Allow("5.6.7.8"); !ok {
+		t.Fatal("Expected a different client to be allowed")
+	}
+
+	now = now.Add(10 * time.Second)
+	if ok, _ := l.Allow("1.2.3.4"); !ok {
+		t.Fatal("Expected one token to have refilled")
+	}
+	if ok, _ := l.Allow("1.2.3.4"); ok {
+		t.Fatal("Expected the refilled token to be used up")
+	}
+
+	// Idle buckets are dropped once they would be full again
+	now = now.Add(time.Minute)
+	l.Allow("9.9.9.9")
+	if _, exists := l.buckets["5.6.7.8"]; exists {
+		t.Fatal("Expected idle bucket to be swept")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	resolver, _ := NewClientIPResolver(nil)
+	handler := RateLimit(NewRateLimiter("test", Rate{Events: 1, Per: time.Minute}), resolver)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/api/login", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected first request allowed, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("Expected 429 with Retry-After 60, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewClientIPResolver([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Failed to create resolver: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		want      string
+	}{
+		{"direct client", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted proxy is ignored", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed hops before the client", "10.1.2.3:5000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.1.2.3:5000", []string{"198.51.100.1, 192.0.2.1", "10.9.9.9"}, "198.51.100.1"},
+		{"trusted proxy without header", "192.0.2.1:5000", nil, "192.0.2.1"},
+		{"garbage header", "10.1.2.3:5000", []string{"not-an-ip"}, "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := resolver.ClientIP(req); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := NewClientIPResolver([]string{"not a network"}); err == nil {
+		t.Error("Expected an error for an invalid proxy")
+	}
+}
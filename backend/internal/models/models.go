@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	ID           int       `json:"id"`
@@ -10,51 +13,346 @@ type User struct {
 	IsAdmin      bool      `json:"is_admin"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	// FailedLogins counts consecutive failed logins; LockedFor is how much of
+	// the resulting lockout remains when the user was loaded
+	FailedLogins int           `json:"-"`
+	LockedFor    time.Duration `json:"-"`
 }
 
+// Content statuses. Scheduled content goes live by itself at its PublishAt.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Publication is the publishing state shared by the content types
+type Publication struct {
+	Status string `json:"status"`
+	// PublishAt is when scheduled content goes live, or when published
+	// content went live
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+}
+
+// Live reports whether the public may see the content at now: it is
+// published, or scheduled for now or earlier
+func (p Publication) Live(now time.Time) bool {
+	switch p.Status {
+	case StatusPublished:
+		return true
+	case StatusScheduled:
+		return p.PublishAt != nil && !p.PublishAt.After(now)
+	}
+	return false
+}
+
+// AboutContent.Content, ResumeSection.Description and CarBuildEntry.Description
+// hold Markdown. Responses add the sanitized HTML it renders to, which is not
+// stored.
 type AboutContent struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	ImageURL  string    `json:"image_url,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	ContentHTML string    `json:"content_html,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Publication
 }
 
 type ResumeSection struct {
+	ID              int        `json:"id"`
+	SectionType     string     `json:"section_type"`
+	Title           string     `json:"title"`
+	Subtitle        string     `json:"subtitle,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	DescriptionHTML string     `json:"description_html,omitempty"`
+	StartDate       *time.Time `json:"start_date,omitempty"`
+	EndDate         *time.Time `json:"end_date,omitempty"`
+	DisplayOrder    int        `json:"display_order"`
+	Version         int        `json:"version"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Publication
+}
+
+type CarBuildEntry struct {
+	ID              int       `json:"id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	DescriptionHTML string    `json:"description_html,omitempty"`
+	Date            time.Time `json:"date"`
+	Category        string    `json:"category,omitempty"`
+	Cost            *float64  `json:"cost,omitempty"`
+	Mileage         *int      `json:"mileage,omitempty"`
+	ImageURLs       []string  `json:"image_urls,omitempty"`
+	DisplayOrder    int       `json:"display_order"`
+	Version         int       `json:"version"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Publication
+}
+
+// Part statuses. Returned parts don't count towards spend.
+const (
+	PartOrdered   = "ordered"
+	PartInstalled = "installed"
+	PartReturned  = "returned"
+)
+
+// Part is a part bought for the car build, optionally linked to the build
+// entry it went into. Price is per unit.
+type Part struct {
 	ID           int        `json:"id"`
-	SectionType  string     `json:"section_type"`
-	Title        string     `json:"title"`
-	Subtitle     string     `json:"subtitle,omitempty"`
-	Description  string     `json:"description,omitempty"`
-	StartDate    *time.Time `json:"start_date,omitempty"`
-	EndDate      *time.Time `json:"end_date,omitempty"`
+	EntryID      *int       `json:"entry_id,omitempty"`
+	Name         string     `json:"name"`
+	Brand        string     `json:"brand,omitempty"`
+	PartNumber   string     `json:"part_number,omitempty"`
+	Vendor       string     `json:"vendor,omitempty"`
+	Category     string     `json:"category,omitempty"`
+	Price        float64    `json:"price"`
+	Quantity     int        `json:"quantity"`
+	PurchaseDate *time.Time `json:"purchase_date,omitempty"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// PartSpend totals what was spent on parts, and breaks it down by Group:
+// "category", "month" or "vendor"
+type PartSpend struct {
+	Group  string       `json:"group"`
+	Total  float64      `json:"total"`
+	Totals []SpendTotal `json:"totals"`
+}
+
+// SpendTotal is the spend on the parts sharing one Key, such as a vendor or a
+// month written as 2024-01. The key is empty for parts without a value.
+type SpendTotal struct {
+	Key   string  `json:"key"`
+	Total float64 `json:"total"`
+	Parts int     `json:"parts"`
+}
+
+// Maintenance statuses, from least to most pressing
+const (
+	MaintenanceOK       = "ok"
+	MaintenanceUpcoming = "upcoming"
+	MaintenanceOverdue  = "overdue"
+)
+
+// MaintenanceSchedule is a job that comes due every IntervalMiles or
+// IntervalMonths, whichever is reached first. At least one is set.
+type MaintenanceSchedule struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description,omitempty"`
+	IntervalMiles   *int       `json:"interval_miles,omitempty"`
+	IntervalMonths  *int       `json:"interval_months,omitempty"`
+	LastDoneMileage *int       `json:"last_done_mileage,omitempty"`
+	LastDoneAt      *time.Time `json:"last_done_at,omitempty"`
+	// NotifiedStatus is the status a reminder was last sent for
+	NotifiedStatus string    `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// MileageReading is an odometer reading taken from a build entry
+type MileageReading struct {
+	Mileage int       `json:"mileage"`
+	Date    time.Time `json:"date"`
+	EntryID int       `json:"entry_id"`
+}
+
+// MaintenanceDue is when a scheduled job is next due. Remaining miles and
+// days go negative once it is overdue; each is left out when the schedule
+// or the mileage doesn't give one.
+type MaintenanceDue struct {
+	MaintenanceSchedule
+	Status         string     `json:"status"`
+	DueMileage     *int       `json:"due_mileage,omitempty"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	MilesRemaining *int       `json:"miles_remaining,omitempty"`
+	DaysRemaining  *int       `json:"days_remaining,omitempty"`
+}
+
+// MaintenanceReport is the state of every scheduled job, most pressing first,
+// as of the latest mileage reading. Mileage is nil before any reading.
+type MaintenanceReport struct {
+	Mileage *MileageReading  `json:"mileage"`
+	Items   []MaintenanceDue `json:"items"`
+}
+
+// Content types with revision history, as they appear in API paths
+const (
+	AboutContentType    = "about"
+	ResumeContentType   = "resume"
+	CarBuildContentType = "carbuild"
+)
+
+// Revision actions
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+// ContentRevision is a snapshot of a record taken after one change to it.
+// Each change bumps the record's version, so versions number its revisions.
+type ContentRevision struct {
+	ID          int             `json:"id"`
+	ContentType string          `json:"content_type"`
+	ContentID   int             `json:"content_id"`
+	Version     int             `json:"version"`
+	Action      string          `json:"action"`
+	AuthorID    *int            `json:"author_id,omitempty"`
+	Author      string          `json:"author,omitempty"`
+	Snapshot    json.RawMessage `json:"snapshot,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// RevisionChange is one field that differs between two revisions
+type RevisionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// RevisionDiff lists the fields that differ between two revisions of a record
+type RevisionDiff struct {
+	ContentType string           `json:"content_type"`
+	ContentID   int              `json:"content_id"`
+	From        int              `json:"from"`
+	To          int              `json:"to"`
+	Changes     []RevisionChange `json:"changes"`
+}
+
+// TrashItem is a soft-deleted record that can still be restored
+type TrashItem struct {
+	ContentType string    `json:"content_type"`
+	ID          int       `json:"id"`
+	Title       string    `json:"title"`
+	Version     int       `json:"version"`
+	DeletedAt   time.Time `json:"deleted_at"`
+}
+
+// PreviewLink lets someone without an account read unpublished content until
+// ExpiresAt. URL is relative to the API host.
+type PreviewLink struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ContactSubmission struct {
+	ID            int            `json:"id"`
+	Name          string         `json:"name"`
+	Email         string         `json:"email"`
+	Subject       string         `json:"subject,omitempty"`
+	Message       string         `json:"message"`
+	IsRead        bool           `json:"is_read"`
+	IsArchived    bool           `json:"is_archived"`
+	IsStarred     bool           `json:"is_starred"`
+	IsSpam        bool           `json:"is_spam"`
+	SpamReason    string         `json:"spam_reason,omitempty"`
+	ReplyCount    int            `json:"reply_count"`
+	LastRepliedAt *time.Time     `json:"last_replied_at,omitempty"`
+	Replies       []ContactReply `json:"replies,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// ContactSubmissionUpdate is a partial update; nil fields are left unchanged
+type ContactSubmissionUpdate struct {
+	IsRead     *bool `json:"is_read"`
+	IsArchived *bool `json:"is_archived"`
+	IsStarred  *bool `json:"is_starred"`
+	IsSpam     *bool `json:"is_spam"`
+}
+
+// ContactReply records how an admin answered a submission
+type ContactReply struct {
+	ID           int       `json:"id"`
+	SubmissionID int       `json:"submission_id"`
+	AuthorID     *int      `json:"author_id,omitempty"`
+	Author       string    `json:"author,omitempty"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ContactRequest is the body of a public contact form submission
+type ContactRequest struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	// Website is a honeypot hidden from people; bots tend to fill it in
+	Website string `json:"website"`
+	// FormToken comes from GET /api/contact/token when the form loads
+	FormToken    string `json:"form_token"`
+	CaptchaToken string `json:"captcha_token"`
+}
+
+type GalleryImage struct {
+	ID           int        `json:"id"`
+	URL          string     `json:"url"`
+	Folder       string     `json:"folder"`
+	Filename     string     `json:"filename"`
+	ContentType  string     `json:"content_type"`
+	Caption      string     `json:"caption"`
+	AltText      string     `json:"alt_text"`
 	DisplayOrder int        `json:"display_order"`
+	Width        *int       `json:"width,omitempty"`
+	Height       *int       `json:"height,omitempty"`
+	TakenAt      *time.Time `json:"taken_at,omitempty"`
+	CameraMake   string     `json:"camera_make,omitempty"`
+	CameraModel  string     `json:"camera_model,omitempty"`
+	LensModel    string     `json:"lens_model,omitempty"`
+	ExposureTime string     `json:"exposure_time,omitempty"`
+	FNumber      *float64   `json:"f_number,omitempty"`
+	ISO          *int       `json:"iso,omitempty"`
+	FocalLength  *float64   `json:"focal_length,omitempty"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
-type CarBuildEntry struct {
+// Folder is an album that gallery images are grouped into. Private folders
+// are only listed to admins and are hidden from the public image endpoints.
+type Folder struct {
 	ID           int       `json:"id"`
+	Slug         string    `json:"slug"`
 	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	Date         time.Time `json:"date"`
-	Category     string    `json:"category,omitempty"`
-	Cost         *float64  `json:"cost,omitempty"`
-	ImageURLs    []string  `json:"image_urls,omitempty"`
-	DisplayOrder int       `json:"display_order"`
+	Description  string    `json:"description,omitempty"`
+	CoverImageID *int      `json:"cover_image_id,omitempty"`
+	CoverURL     string    `json:"cover_url,omitempty"`
+	Visibility   string    `json:"visibility"`
+	SortOrder    int       `json:"sort_order"`
+	ImageCount   int       `json:"image_count"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-type ContactSubmission struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Subject   string    `json:"subject,omitempty"`
-	Message   string    `json:"message"`
-	IsRead    bool      `json:"is_read"`
-	CreatedAt time.Time `json:"created_at"`
+// GalleryImageUpdate is a partial update; nil fields are left unchanged
+type GalleryImageUpdate struct {
+	Caption      *string `json:"caption"`
+	AltText      *string `json:"alt_text"`
+	Folder       *string `json:"folder"`
+	DisplayOrder *int    `json:"display_order"`
+}
+
+type GalleryOrderRequest struct {
+	Folder   string `json:"folder"`
+	ImageIDs []int  `json:"image_ids"`
+}
+
+// GalleryImageList is the version 2 response shape of the image list endpoints
+type GalleryImageList struct {
+	Version int            `json:"version"`
+	Folder  string         `json:"folder"`
+	Images  []GalleryImage `json:"images"`
 }
 
 type LoginRequest struct {
@@ -63,6 +361,26 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         User   `json:"user"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// HealthStatus is the body of the health endpoints. Status is "ok" or
+// "unavailable".
+type HealthStatus struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+// ComponentHealth is the result of checking one dependency. Status is "ok",
+// "fail" or "timeout".
+type ComponentHealth struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
 }
@@ -0,0 +1,53 @@
+package preview
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokens(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tokens := NewTokens("secret", time.Hour)
+	tokens.SetClock(func() time.Time { return now })
+
+	token, expires := tokens.Issue("about", 7)
+	if !expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected the token to expire in an hour, got %v", expires)
+	}
+	if err := tokens.Check(token, "about", 7); err != nil {
+		t.Fatalf("Expected token to be accepted, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := tokens.Check(token, "about", 7); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Expected the token to have expired, got %v", err)
+	}
+}
+
+func TestTokensRejectOtherRecords(t *testing.T) {
+	tokens := NewTokens("secret", time.Hour)
+	other := NewTokens("other-secret", time.Hour)
+	token, _ := tokens.Issue("resume", 3)
+	forged, _ := other.Issue("resume", 3)
+
+	tests := []struct {
+		token       string
+		contentType string
+		id          int
+	}{
+		{"", "resume", 3},
+		{"garbage", "resume", 3},
+		{"a.b", "resume", 3},
+		{forged, "resume", 3},
+		{token + "x", "resume", 3},
+		{token, "resume", 4},
+		{token, "carbuild", 3},
+	}
+
+	for _, tt := range tests {
+		if err := tokens.Check(tt.token, tt.contentType, tt.id); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Check(%q, %q, %d) = %v; want %v", tt.token, tt.contentType, tt.id, err, ErrInvalid)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Package problem writes API errors as RFC 7807 problem details. Every
+// problem carries a stable code that clients can switch on; the title and
+// detail are meant for people and may change.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// Code is a stable, machine-readable error code and the status it is sent with
+type Code struct {
+	Name   string
+	Status int
+}
+
+var (
+	// BadRequest covers malformed requests without a more specific code
+	BadRequest = Code{"bad_request", http.StatusBadRequest}
+	// InvalidID means a path ID is not a number
+	InvalidID = Code{"invalid_id", http.StatusBadRequest}
+	// InvalidBody means the request body could not be decoded
+	InvalidBody = Code{"invalid_body", http.StatusBadRequest}
+	// InvalidQuery means a query parameter is malformed or out of range
+	InvalidQuery = Code{"invalid_query", http.StatusBadRequest}
+	// ValidationFailed lists the invalid fields of a well-formed body
+	ValidationFailed = Code{"validation_failed", http.StatusBadRequest}
+	// CaptchaFailed means the CAPTCHA response was missing or rejected
+	CaptchaFailed = Code{"captcha_failed", http.StatusBadRequest}
+
+	Unauthorized = Code{"unauthorized", http.StatusUnauthorized}
+	// InvalidCredentials means the email or password is wrong
+	InvalidCredentials = Code{"invalid_credentials", http.StatusUnauthorized}
+	// InvalidToken means an access or refresh token is invalid, expired or
+	// belongs to a revoked session
+	InvalidToken = Code{"invalid_token", http.StatusUnauthorized}
+	Forbidden    = Code{"forbidden", http.StatusForbidden}
+	NotFound     = Code{"not_found", http.StatusNotFound}
+	// MethodNotAllowed means the path exists but not for this method
+	MethodNotAllowed = Code{"method_not_allowed", http.StatusMethodNotAllowed}
+	// Conflict means the change clashes with existing data, such as a
+	// duplicate email or slug
+	Conflict = Code{"conflict", http.StatusConflict}
+	// PreconditionFailed means If-Match named an outdated version, or the
+	// record changed while the request was being handled
+	PreconditionFailed = Code{"precondition_failed", http.StatusPreconditionFailed}
+	// UnsupportedMediaType means the body's content type is not accepted
+	UnsupportedMediaType = Code{"unsupported_media_type", http.StatusUnsupportedMediaType}
+	// RateLimited comes with a Retry-After header
+	RateLimited = Code{"rate_limited", http.StatusTooManyRequests}
+	// AccountLocked means too many failed logins; it also sets Retry-After
+	AccountLocked = Code{"account_locked", http.StatusTooManyRequests}
+	Internal      = Code{"internal_error", http.StatusInternalServerError}
+	// Unavailable means a dependency, such as the CAPTCHA provider, failed
+	Unavailable = Code{"unavailable", http.StatusServiceUnavailable}
+)
+
+// Problem is the body of an error response. Fields is an extension member
+// holding a message per invalid field for validation_failed.
+type Problem struct {
+	Type   string            `json:"type"`
+	Title  string            `json:"title"`
+	Status int               `json:"status"`
+	Code   string            `json:"code"`
+	Detail string            `json:"detail,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// New returns the problem for code with a human-readable detail. Codes are
+// not separately documented types, so the type is about:blank and the title
+// is the status text, as RFC 7807 recommends.
+func New(code Code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code.Status),
+		Status: code.Status,
+		Code:   code.Name,
+		Detail: detail,
+	}
+}
+
+// Write sends the problem as the response
+func (p *Problem) Write(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", ContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Write sends a problem with the given code and detail. It replaces
+// http.Error, so any content type already set is overwritten.
+func Write(w http.ResponseWriter, code Code, detail string) {
+	New(code, detail).Write(w)
+}
+
+// Validation sends a validation_failed problem with a message for each
+// invalid field
+func Validation(w http.ResponseWriter, fields map[string]string) {
+	p := New(ValidationFailed, "Validation failed")
+	p.Fields = fields
+	p.Write(w)
+}
+
+// Handler always responds with the given problem, for use as a router's
+// not found and method not allowed handlers
+func Handler(code Code, detail string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Write(w, code, detail)
+	})
+}
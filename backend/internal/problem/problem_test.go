@@ -0,0 +1,46 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "application/json")
+	Write(w, NotFound, "Image not found")
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Expected content type %s, got %s", ContentType, got)
+	}
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{Type: "about:blank", Title: "Not Found", Status: 404, Code: "not_found", Detail: "Image not found"}
+	if p.Type != want.Type || p.Title != want.Title || p.Status != want.Status || p.Code != want.Code || p.Detail != want.Detail || p.Fields != nil {
+		t.Errorf("Expected %+v, got %+v", want, p)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	w := httptest.NewRecorder()
+	Validation(w, map[string]string{"title": "Title is required"})
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Code != ValidationFailed.Name || p.Fields["title"] != "Title is required" {
+		t.Errorf("Unexpected problem %+v", p)
+	}
+}
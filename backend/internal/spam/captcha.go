@@ -0,0 +1,119 @@
+package spam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrCaptchaFailed is returned when a CAPTCHA response is missing or rejected
+var ErrCaptchaFailed = errors.New("captcha verification failed")
+
+// Verifier checks the response token a CAPTCHA widget produced in the browser
+type Verifier interface {
+	// Verify returns ErrCaptchaFailed when the token is not accepted, or
+	// another error when the provider could not be reached
+	Verify(ctx context.Context, token string) error
+	// Enabled reports whether submissions need a CAPTCHA token at all
+	Enabled() bool
+}
+
+// Siteverify endpoints of the supported providers. They share one protocol.
+var siteverifyURLs = map[string]string{
+	"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+	"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+}
+
+// NewVerifier returns the verifier for provider, which is "none" (or empty),
+// "turnstile", "hcaptcha" or "recaptcha"
+func NewVerifier(provider, secret string) (Verifier, error) {
+	if provider == "" || provider == "none" {
+		return Disabled{}, nil
+	}
+	endpoint, ok := siteverifyURLs[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown captcha provider %q", provider)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("captcha provider %q needs a secret", provider)
+	}
+	return NewSiteverify(endpoint, secret), nil
+}
+
+// Disabled accepts every submission without a CAPTCHA
+type Disabled struct{}
+
+func (Disabled) Verify(ctx context.Context, token string) error { return nil }
+func (Disabled) Enabled() bool                                  { return false }
+
+// Fake accepts exactly one token. It stands in for a real provider in tests
+// and local development.
+type Fake struct {
+	Token string
+}
+
+func (f Fake) Verify(ctx context.Context, token string) error {
+	if token == "" || token != f.Token {
+		return ErrCaptchaFailed
+	}
+	return nil
+}
+
+func (Fake) Enabled() bool { return true }
+
+// Siteverify checks tokens against a provider's siteverify endpoint
+type Siteverify struct {
+	endpoint string
+	secret   string
+	client   *http.Client
+}
+
+func NewSiteverify(endpoint, secret string) *Siteverify {
+	return &Siteverify{
+		endpoint: endpoint,
+		secret:   secret,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *Siteverify) Enabled() bool { return true }
+
+func (s *Siteverify) Verify(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrCaptchaFailed
+	}
+
+	form := url.Values{"secret": {s.secret}, "response": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha siteverify: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha siteverify: unexpected status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("captcha siteverify: %w", err)
+	}
+	if !result.Success {
+		return ErrCaptchaFailed
+	}
+	return nil
+}
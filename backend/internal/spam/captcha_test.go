@@ -0,0 +1,49 @@
+package spam
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSiteverify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		ok := r.PostForm.Get("secret") == "secret" && r.PostForm.Get("response") == "good"
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": ok})
+	}))
+	defer srv.Close()
+
+	v := NewSiteverify(srv.URL, "secret")
+	ctx := context.Background()
+
+	if err := v.Verify(ctx, "good"); err != nil {
+		t.Fatalf("Expected token to verify, got %v", err)
+	}
+	if err := v.Verify(ctx, "bad"); !errors.Is(err, ErrCaptchaFailed) {
+		t.Fatalf("Expected rejected token to fail, got %v", err)
+	}
+	if err := v.Verify(ctx, ""); !errors.Is(err, ErrCaptchaFailed) {
+		t.Fatalf("Expected empty token to fail, got %v", err)
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	if v, err := NewVerifier("", ""); err != nil || v.Enabled() {
+		t.Fatalf("Expected disabled verifier, got %v, %v", v, err)
+	}
+	if v, err := NewVerifier("turnstile", "secret"); err != nil || !v.Enabled() {
+		t.Fatalf("Expected turnstile verifier, got %v, %v", v, err)
+	}
+	if _, err := NewVerifier("turnstile", ""); err == nil {
+		t.Fatal("Expected an error without a secret")
+	}
+	if _, err := NewVerifier("unknown", "secret"); err == nil {
+		t.Fatal("Expected an error for an unknown provider")
+	}
+}
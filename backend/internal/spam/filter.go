@@ -0,0 +1,38 @@
+package spam
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// Filter flags messages by their content. Flagged messages are still kept so
+// an admin can rescue false positives.
+type Filter struct {
+	// MaxLinks is the most links a message may contain; negative disables
+	// the check
+	MaxLinks int
+	// Blocklist holds terms matched case-insensitively anywhere in the text
+	Blocklist []string
+}
+
+// Check returns why the text looks like spam, or "" when it does not
+func (f Filter) Check(text ...string) string {
+	joined := strings.Join(text, "\n")
+
+	if f.MaxLinks >= 0 {
+		if links := len(linkPattern.FindAllStringIndex(joined, -1)); links > f.MaxLinks {
+			return fmt.Sprintf("too many links (%d)", links)
+		}
+	}
+
+	lower := strings.ToLower(joined)
+	for _, term := range f.Blocklist {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" && strings.Contains(lower, term) {
+			return fmt.Sprintf("blocked term %q", term)
+		}
+	}
+	return ""
+}
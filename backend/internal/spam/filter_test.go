@@ -0,0 +1,28 @@
+package spam
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	f := Filter{MaxLinks: 2, Blocklist: []string{"Casino", " ", "seo services"}}
+
+	tests := []struct {
+		text []string
+		spam bool
+	}{
+		{[]string{"Jane", "Love the car build, what turbo is that?"}, false},
+		{[]string{"Jane", "See https://example.com and www.example.org"}, false},
+		{[]string{"Jane", "http://a.example http://b.example HTTPS://c.example"}, true},
+		{[]string{"Best CASINO bonus", "hello"}, true},
+		{[]string{"Jane", "We offer SEO Services for your site"}, true},
+	}
+
+	for _, tt := range tests {
+		if reason := f.Check(tt.text...); (reason != "") != tt.spam {
+			t.Errorf("Check(%q) = %q; want spam %v", tt.text, reason, tt.spam)
+		}
+	}
+
+	if reason := (Filter{MaxLinks: -1}).Check("http://a http://b http://c"); reason != "" {
+		t.Errorf("Expected link check to be disabled, got %q", reason)
+	}
+}
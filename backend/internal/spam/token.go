@@ -0,0 +1,128 @@
+// Package spam keeps bots out of the public contact form: signed form tokens
+// that enforce a minimum time to submit, CAPTCHA verification and content
+// heuristics.
+package spam
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTokenMissing = errors.New("missing form token")
+	ErrTokenInvalid = errors.New("invalid form token")
+	ErrTokenTooFast = errors.New("submitted too fast")
+	ErrTokenExpired = errors.New("expired form token")
+	ErrTokenUsed    = errors.New("form token already used")
+)
+
+// Rejected reports whether err is Check turning down the token itself, rather
+// than failing to check it
+func Rejected(err error) bool {
+	for _, reason := range []error{ErrTokenMissing, ErrTokenInvalid, ErrTokenTooFast, ErrTokenExpired, ErrTokenUsed} {
+		if errors.Is(err, reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bytes of randomness in each token, so every token can only be used once
+const nonceSize = 16
+
+// NonceStore remembers which tokens have been used
+type NonceStore interface {
+	// UseFormNonce records nonce as used until expires, and reports whether
+	// it was still unused
+	UseFormNonce(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// FormTokens issues and checks tokens that record when a form was loaded. A
+// person takes a few seconds to fill in the form; a bot posting straight
+// away, replaying a stale token or reusing one, does not pass Check.
+type FormTokens struct {
+	secret []byte
+	minAge time.Duration
+	maxAge time.Duration
+	nonces NonceStore
+	now    func() time.Time
+}
+
+// NewFormTokens signs tokens with secret. Check accepts tokens between minAge
+// and maxAge old, once each; nonces remembers the used ones.
+func NewFormTokens(secret string, minAge, maxAge time.Duration, nonces NonceStore) *FormTokens {
+	return &FormTokens{secret: []byte(secret), minAge: minAge, maxAge: maxAge, nonces: nonces, now: time.Now}
+}
+
+// SetClock replaces the clock used to issue and check tokens
+func (f *FormTokens) SetClock(now func() time.Time) {
+	f.now = now
+}
+
+// Issue returns a token stamped with the current time and a random nonce
+func (f *FormTokens) Issue() (string, error) {
+	var payload [8 + nonceSize]byte
+	binary.BigEndian.PutUint64(payload[:8], uint64(f.now().UnixMilli()))
+	if _, err := rand.Read(payload[8:]); err != nil {
+		return "", err
+	}
+	return encode(payload[:]) + "." + encode(f.sign(payload[:])), nil
+}
+
+// Check reports why token is unacceptable, or nil when it is valid. A valid
+// token is used up, so checking it again returns ErrTokenUsed. Errors other
+// than the Err* values come from the NonceStore.
+func (f *FormTokens) Check(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrTokenMissing
+	}
+	payloadPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil || len(payload) != 8+nonceSize {
+		return ErrTokenInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, f.sign(payload)) {
+		return ErrTokenInvalid
+	}
+
+	issued := time.UnixMilli(int64(binary.BigEndian.Uint64(payload[:8])))
+	age := f.now().Sub(issued)
+	switch {
+	case age < f.minAge:
+		return ErrTokenTooFast
+	case age > f.maxAge:
+		return ErrTokenExpired
+	}
+
+	// Past maxAge the token is expired anyway, so the nonce can be forgotten
+	unused, err := f.nonces.UseFormNonce(ctx, encode(payload[8:]), issued.Add(f.maxAge))
+	if err != nil {
+		return err
+	}
+	if !unused {
+		return ErrTokenUsed
+	}
+	return nil
+}
+
+func (f *FormTokens) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte("contact-form:"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
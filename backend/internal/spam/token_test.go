@@ -0,0 +1,93 @@
+package spam
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// usedNonces is a NonceStore that never forgets
+type usedNonces map[string]bool
+
+func (u usedNonces) UseFormNonce(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	if u[nonce] {
+		return false, nil
+	}
+	u[nonce] = true
+	return true, nil
+}
+
+func issue(t *testing.T, tokens *FormTokens) string {
+	t.Helper()
+	token, err := tokens.Issue()
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	return token
+}
+
+func TestFormTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tokens := NewFormTokens("secret", 3*time.Second, time.Hour, usedNonces{})
+	tokens.SetClock(func() time.Time { return now })
+
+	token := issue(t, tokens)
+	if err := tokens.Check(ctx, token); !errors.Is(err, ErrTokenTooFast) {
+		t.Fatalf("Expected an immediate submission to be too fast, got %v", err)
+	}
+
+	now = now.Add(5 * time.Second)
+	if err := tokens.Check(ctx, token); err != nil {
+		t.Fatalf("Expected token to be accepted, got %v", err)
+	}
+	if err := tokens.Check(ctx, token); !errors.Is(err, ErrTokenUsed) {
+		t.Fatalf("Expected a reused token to be rejected, got %v", err)
+	}
+
+	other := issue(t, tokens)
+	now = now.Add(2 * time.Hour)
+	if err := tokens.Check(ctx, other); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Expected an old token to be expired, got %v", err)
+	}
+}
+
+func TestFormTokensRejectForgeries(t *testing.T) {
+	tokens := NewFormTokens("secret", 0, time.Hour, usedNonces{})
+	other := NewFormTokens("other-secret", 0, time.Hour, usedNonces{})
+
+	tests := []struct {
+		token string
+		want  error
+	}{
+		{"", ErrTokenMissing},
+		{"garbage", ErrTokenInvalid},
+		{"a.b", ErrTokenInvalid},
+		{issue(t, other), ErrTokenInvalid},
+		{issue(t, tokens) + "x", ErrTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		if err := tokens.Check(context.Background(), tt.token); !errors.Is(err, tt.want) {
+			t.Errorf("Check(%q) = %v; want %v", tt.token, err, tt.want)
+		}
+	}
+}
+
+func TestFormTokensNonceStoreError(t *testing.T) {
+	tokens := NewFormTokens("secret", 0, time.Hour, failingNonces{})
+	token := issue(t, tokens)
+	if err := tokens.Check(context.Background(), token); err == nil || Rejected(err) {
+		t.Fatalf("Expected the store error to be passed on, got %v", err)
+	}
+	if err := tokens.Check(context.Background(), ""); !Rejected(err) {
+		t.Fatalf("Expected a missing token to be rejected, got %v", err)
+	}
+}
+
+type failingNonces struct{}
+
+func (failingNonces) UseFormNonce(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	return false, errors.New("database down")
+}
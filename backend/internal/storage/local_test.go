@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/config"
+)
+
+func TestLocalBackendRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	data := "fake image bytes"
+	if err := backend.Put(ctx, "gallery/abc-car.jpg", strings.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	info, err := backend.Stat(ctx, "gallery/abc-car.jpg")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size != int64(len(data)) {
+		t.Errorf("Expected size %d, got %d", len(data), info.Size)
+	}
+	if info.ContentType != "image/jpeg" {
+		t.Errorf("Expected content type image/jpeg, got %s", info.ContentType)
+	}
+
+	rc, _, err := backend.Get(ctx, "gallery/abc-car.jpg")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != data {
+		t.Errorf("Expected %q, got %q", data, got)
+	}
+
+	if err := backend.Delete(ctx, "gallery/abc-car.jpg"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := backend.Stat(ctx, "gallery/abc-car.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+
+	// Deleting a missing object is not an error
+	if err := backend.Delete(ctx, "gallery/abc-car.jpg"); err != nil {
+		t.Errorf("Delete of missing object failed: %v", err)
+	}
+}
+
+func TestLocalBackendKeysStayInRoot(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	backend, err := NewLocalBackend(root)
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	p, err := backend.path("../../etc/passwd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(p, root) {
+		t.Errorf("Expected path under %s, got %s", root, p)
+	}
+
+	if err := backend.Put(ctx, "", strings.NewReader("x"), 1, ""); err == nil {
+		t.Error("Expected error for empty key")
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	key, err := NewKey("gallery", "../My Car (1).JPG")
+	if err != nil {
+		t.Fatalf("NewKey failed: %v", err)
+	}
+
+	if !strings.HasPrefix(key, "gallery/") {
+		t.Errorf("Expected key in gallery folder, got %s", key)
+	}
+	if !strings.HasSuffix(key, "-My_Car__1_.JPG") {
+		t.Errorf("Expected sanitized filename, got %s", key)
+	}
+}
+
+func TestDurable(t *testing.T) {
+	tests := []struct {
+		cfg      config.Config
+		expected bool
+	}{
+		{config.Config{StorageBackend: "local", StorageLocalDurable: true}, true},
+		{config.Config{StorageBackend: "local"}, false},
+		{config.Config{StorageBackend: "s3"}, true},
+	}
+	for _, tt := range tests {
+		if got := Durable(&tt.cfg); got != tt.expected {
+			t.Errorf("Durable(%s) = %v, expected %v", tt.cfg.StorageBackend, got, tt.expected)
+		}
+	}
+}
+
+func TestLocalBackendPing(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "images")
+	backend, err := NewLocalBackend(root)
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	if err := backend.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Ping(context.Background()); err == nil {
+		t.Fatal("Expected Ping to fail once the directory is gone")
+	}
+}
@@ -0,0 +1,375 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config configures an S3-compatible backend (AWS S3, MinIO, R2, ...)
+type S3Config struct {
+	Endpoint     string
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	UsePathStyle bool
+}
+
+// S3Backend talks to an S3-compatible object store using Signature V4
+type S3Backend struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Backend(cfg S3Config) (*S3Backend, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+
+	return &S3Backend{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+func (b *S3Backend) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := b.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	// The body is streamed, so it is not hashed up front
+	resp, err := b.do(req, "UNSIGNED-PAYLOAD")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3 put %s: unexpected status %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	req, err := b.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := b.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("s3 get %s: unexpected status %d", key, resp.StatusCode)
+	}
+
+	info := objectInfo(key, resp)
+	return &s3Object{ctx: ctx, backend: b, key: key, size: info.Size, body: resp.Body}, info, nil
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	req, err := b.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("s3 delete %s: unexpected status %d", key, resp.StatusCode)
+	}
+}
+
+func (b *S3Backend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	req, err := b.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("s3 stat %s: unexpected status %d", key, resp.StatusCode)
+	}
+
+	return objectInfo(key, resp), nil
+}
+
+// Ping checks the bucket exists and the credentials can reach it
+func (b *S3Backend) Ping(ctx context.Context) error {
+	req, err := b.request(ctx, http.MethodHead, b.bucketPath(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3 bucket %s: unexpected status %d", b.cfg.Bucket, resp.StatusCode)
+	}
+	return nil
+}
+
+// s3Object streams an object body and supports seeking by reopening the
+// object with a ranged GET from the new offset on the next Read
+type s3Object struct {
+	ctx     context.Context
+	backend *S3Backend
+	key     string
+	size    int64
+	body    io.ReadCloser
+	bodyPos int64
+	offset  int64
+}
+
+func (o *s3Object) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+
+	if o.body == nil || o.bodyPos != o.offset {
+		if o.body != nil {
+			o.body.Close()
+			o.body = nil
+		}
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := o.body.Read(p)
+	o.bodyPos += int64(n)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *s3Object) open() error {
+	req, err := o.backend.newRequest(o.ctx, http.MethodGet, o.key, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", o.offset))
+
+	resp, err := o.backend.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return fmt.Errorf("s3 get %s: unexpected status %d for range request", o.key, resp.StatusCode)
+	}
+
+	o.body = resp.Body
+	o.bodyPos = o.offset
+	return nil
+}
+
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = o.offset + offset
+	case io.SeekEnd:
+		if o.size < 0 {
+			return 0, errors.New("s3 object size unknown")
+		}
+		abs = o.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	o.offset = abs
+	return abs, nil
+}
+
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	return o.body.Close()
+}
+
+func objectInfo(key string, resp *http.Response) *ObjectInfo {
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64); err == nil {
+		info.Size = size
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info
+}
+
+// bucketPath returns the escaped request path of the bucket itself
+func (b *S3Backend) bucketPath() string {
+	prefix := strings.TrimSuffix(b.endpoint.EscapedPath(), "/")
+	if b.cfg.UsePathStyle {
+		return prefix + "/" + uriEncode(b.cfg.Bucket, true)
+	}
+	return prefix + "/"
+}
+
+// objectPath returns the escaped request path for a key
+func (b *S3Backend) objectPath(key string) string {
+	return strings.TrimSuffix(b.bucketPath(), "/") + "/" + uriEncode(strings.TrimPrefix(key, "/"), false)
+}
+
+func (b *S3Backend) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	if key == "" {
+		return nil, errors.New("storage key is required")
+	}
+	return b.request(ctx, method, b.objectPath(key), body)
+}
+
+// request builds a request for an escaped path, addressing the bucket by
+// path or by virtual host as configured
+func (b *S3Backend) request(ctx context.Context, method, escaped string, body io.Reader) (*http.Request, error) {
+	host := b.endpoint.Host
+	if !b.cfg.UsePathStyle {
+		host = b.cfg.Bucket + "." + host
+	}
+
+	u := &url.URL{Scheme: b.endpoint.Scheme, Host: host}
+	u.Path, _ = url.PathUnescape(escaped)
+	u.RawPath = escaped
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+func (b *S3Backend) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	b.sign(req, payloadHash)
+	return b.client.Do(req)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req
+func (b *S3Backend) sign(req *http.Request, payloadHash string) {
+	t := b.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + b.cfg.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.cfg.SecretKey), date)
+	key = hmacSHA256(key, b.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode escapes s the way SigV4 canonical requests expect: everything
+// except unreserved characters is percent-encoded, and "/" is kept unless
+// encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
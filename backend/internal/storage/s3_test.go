@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a tiny in-memory stand-in for an S3-compatible server such as MinIO
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := r.URL.Path
+	if r.Method == http.MethodHead && key == "/images" {
+		// The bucket itself
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+		f.types[key] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		status := http.StatusOK
+		var start int
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			data = data[start:]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3BackendRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	backend, err := NewS3Backend(S3Config{
+		Endpoint:     server.URL,
+		Bucket:       "images",
+		AccessKey:    "access",
+		SecretKey:    "secret",
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	data := "fake image bytes"
+	if err := backend.Put(ctx, "gallery/abc-my car.jpg", strings.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	if _, ok := fake.objects["/images/gallery/abc-my car.jpg"]; !ok {
+		t.Fatalf("Expected object stored under bucket path, have %v", fake.objects)
+	}
+
+	info, err := backend.Stat(ctx, "gallery/abc-my car.jpg")
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size != int64(len(data)) || info.ContentType != "image/jpeg" {
+		t.Errorf("Unexpected object info: %+v", info)
+	}
+	if info.ModTime.IsZero() {
+		t.Error("Expected modification time from Last-Modified")
+	}
+
+	rc, _, err := backend.Get(ctx, "gallery/abc-my car.jpg")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != data {
+		t.Errorf("Expected %q, got %q", data, got)
+	}
+
+	if err := backend.Delete(ctx, "gallery/abc-my car.jpg"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := backend.Get(ctx, "gallery/abc-my car.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestS3ObjectSeek(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+
+	backend, err := NewS3Backend(S3Config{
+		Endpoint:     server.URL,
+		Bucket:       "images",
+		AccessKey:    "access",
+		SecretKey:    "secret",
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+
+	data := "0123456789"
+	if err := backend.Put(ctx, "a.jpg", strings.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	rc, _, err := backend.Get(ctx, "a.jpg")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer rc.Close()
+
+	seeker, ok := rc.(io.ReadSeeker)
+	if !ok {
+		t.Fatal("Expected S3 objects to be seekable")
+	}
+
+	size, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil || size != 10 {
+		t.Fatalf("Expected size 10, got %d (%v)", size, err)
+	}
+
+	if _, err := seeker.Seek(6, io.SeekStart); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	got, err := io.ReadAll(seeker)
+	if err != nil || string(got) != "6789" {
+		t.Errorf("Expected %q after seeking, got %q (%v)", "6789", got, err)
+	}
+}
+
+func TestUriEncode(t *testing.T) {
+	tests := []struct {
+		in          string
+		encodeSlash bool
+		expected    string
+	}{
+		{"gallery/a b.jpg", false, "gallery/a%20b.jpg"},
+		{"gallery/a+b(1).jpg", false, "gallery/a%2Bb%281%29.jpg"},
+		{"a/b", true, "a%2Fb"},
+		{"safe-_.~", false, "safe-_.~"},
+	}
+
+	for _, tt := range tests {
+		if got := uriEncode(tt.in, tt.encodeSlash); got != tt.expected {
+			t.Errorf("uriEncode(%q) = %q, expected %q", tt.in, got, tt.expected)
+		}
+	}
+}
+
+func TestS3BackendPing(t *testing.T) {
+	server := httptest.NewServer(newFakeS3())
+	defer server.Close()
+
+	for _, tt := range []struct {
+		bucket  string
+		wantErr bool
+	}{
+		{"images", false},
+		{"missing", true},
+	} {
+		backend, err := NewS3Backend(S3Config{
+			Endpoint:     server.URL,
+			Bucket:       tt.bucket,
+			AccessKey:    "access",
+			SecretKey:    "secret",
+			UsePathStyle: true,
+		})
+		if err != nil {
+			t.Fatalf("Failed to create backend: %v", err)
+		}
+		if err := backend.Ping(context.Background()); (err != nil) != tt.wantErr {
+			t.Errorf("Ping(%s) error = %v, wantErr %v", tt.bucket, err, tt.wantErr)
+		}
+	}
+}
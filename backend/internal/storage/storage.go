@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Backend stores image bytes outside of the database. Keys are slash
+// separated paths such as "gallery/3f9a1c2b-car.jpg".
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns a streaming reader for the object. Callers must close it.
+	// The built-in backends return readers that also implement io.Seeker so
+	// they can be served with http.ServeContent.
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// Ping reports whether the backend is reachable and usable
+	Ping(ctx context.Context) error
+}
+
+// New returns the backend selected by cfg.StorageBackend
+func New(cfg *config.Config) (Backend, error) {
+	switch cfg.StorageBackend {
+	case "", "local":
+		return NewLocalBackend(cfg.StorageLocalDir)
+	case "s3":
+		return NewS3Backend(S3Config{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// Durable reports whether the backend selected by cfg keeps images across
+// restarts and deploys
+func Durable(cfg *config.Config) bool {
+	switch cfg.StorageBackend {
+	case "", "local":
+		return cfg.StorageLocalDurable
+	default:
+		return true
+	}
+}
+
+// NewKey builds a unique object key for an image in a folder
+func NewKey(folder, filename string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s-%s", folder, hex.EncodeToString(b), sanitizeFilename(filename)), nil
+}
+
+// sanitizeFilename keeps the base name of an upload and replaces anything
+// that is awkward in a URL or on disk
+func sanitizeFilename(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 || name == "." || name == "/" {
+		return "image"
+	}
+	return b.String()
+}
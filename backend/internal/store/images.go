@@ -0,0 +1,139 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/imaging"
+	"github.com/Jakeito/TestWebsite/backend/internal/logging"
+	"github.com/Jakeito/TestWebsite/backend/internal/storage"
+)
+
+// SaveImage strips identifying metadata from an image, writes it and its
+// resized variants to the storage backend and records them in images
+func SaveImage(ctx context.Context, images ImageStore, blobs storage.Backend, folder, filename, contentType string, data []byte) (int, error) {
+	data, meta, err := imaging.Sanitize(data)
+	if err != nil {
+		return 0, fmt.Errorf("error sanitizing image: %w", err)
+	}
+
+	key, err := storage.NewKey(folder, filename)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return 0, fmt.Errorf("error storing image: %w", err)
+	}
+
+	id, err := images.CreateImage(ctx, &NewImage{
+		Folder:      folder,
+		Filename:    filename,
+		Key:         key,
+		Size:        len(data),
+		ContentType: contentType,
+		Hash:        ContentHash(data),
+	})
+	if err != nil {
+		// Don't leave an orphaned object behind
+		if delErr := blobs.Delete(ctx, key); delErr != nil {
+			logging.FromContext(ctx).Error("error removing orphaned object", "key", key, "error", delErr)
+		}
+		return 0, err
+	}
+
+	if err := images.SaveImageMetadata(ctx, id, meta); err != nil {
+		logging.FromContext(ctx).Error("error saving image metadata", "image_id", id, "error", err)
+	}
+
+	// The original is already stored, so a failed variant is not fatal
+	if err := GenerateVariants(ctx, images, blobs, id, key, contentType, data); err != nil {
+		logging.FromContext(ctx).Error("error generating variants", "image_id", id, "error", err)
+	}
+
+	return id, nil
+}
+
+// GenerateVariants renders the resized variants of an image, stores them next
+// to the original and records them along with the original's dimensions
+func GenerateVariants(ctx context.Context, images ImageStore, blobs storage.Backend, id int, key, contentType string, data []byte) error {
+	// Re-encoding would drop the animation of GIFs, so they are served as is
+	if contentType == "image/gif" {
+		return nil
+	}
+
+	result, err := imaging.Process(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	variants := make([]VariantFile, 0, len(result.Variants))
+	for _, v := range result.Variants {
+		vkey := variantKey(key, v.Variant, v.Format)
+		if err := blobs.Put(ctx, vkey, bytes.NewReader(v.Data), int64(len(v.Data)), v.ContentType); err != nil {
+			return fmt.Errorf("error storing %s variant: %w", v.Variant, err)
+		}
+		variants = append(variants, VariantFile{
+			Variant:     v.Variant,
+			Format:      v.Format,
+			ContentType: v.ContentType,
+			Width:       v.Width,
+			Height:      v.Height,
+			Key:         vkey,
+			Size:        len(v.Data),
+			Hash:        ContentHash(v.Data),
+		})
+	}
+
+	return images.SaveVariants(ctx, id, result.Width, result.Height, variants)
+}
+
+// variantKey derives the storage key of a variant from the original's key,
+// e.g. gallery/3f9a-car.jpg -> gallery/3f9a-car-thumbnail.jpg
+func variantKey(key, variant, format string) string {
+	ext := format
+	if format == "jpeg" {
+		ext = "jpg"
+	}
+	return strings.TrimSuffix(key, path.Ext(key)) + "-" + variant + "." + ext
+}
+
+// ContentHash returns the hex SHA-256 of stored image bytes
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteImage removes an image record and its stored objects. It returns
+// ErrNotFound if no image has the given ID.
+func DeleteImage(ctx context.Context, images ImageStore, blobs storage.Backend, id int) error {
+	keys, err := images.DeleteImage(ctx, id)
+	if err != nil {
+		return err
+	}
+	deleteObjects(ctx, blobs, keys)
+	return nil
+}
+
+// DeleteAllImages clears every image record and the objects they reference
+func DeleteAllImages(ctx context.Context, images ImageStore, blobs storage.Backend) error {
+	keys, err := images.DeleteAllImages(ctx)
+	if err != nil {
+		return err
+	}
+	deleteObjects(ctx, blobs, keys)
+	return nil
+}
+
+func deleteObjects(ctx context.Context, blobs storage.Backend, keys []string) {
+	for _, key := range keys {
+		if err := blobs.Delete(ctx, key); err != nil {
+			logging.FromContext(ctx).Error("error deleting stored object", "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,1787 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/imaging"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+// Memory implements every store interface in process. It is meant for tests
+// and local experiments; nothing is persisted.
+type Memory struct {
+	mu     sync.Mutex
+	nextID int
+	now    func() time.Time
+
+	about       map[int]models.AboutContent
+	resume      map[int]models.ResumeSection
+	carBuild    map[int]models.CarBuildEntry
+	parts       map[int]models.Part
+	maintenance map[int]models.MaintenanceSchedule
+	contact     map[int]models.ContactSubmission
+	replies     map[int]models.ContactReply
+	formNonces  map[string]time.Time
+	users       map[int]models.User
+	lockouts    map[int]time.Time
+	outbox      map[int]*memoryEmail
+	sessions    []*memorySession
+	folders     map[int]models.Folder
+	images      map[int]*memoryImage
+
+	revisions []models.ContentRevision
+	trash     map[contentKey]memoryTrashed
+}
+
+// contentKey identifies a record with revision history
+type contentKey struct {
+	contentType string
+	id          int
+}
+
+// memoryTrashed is a deleted record, moved out of its type's map
+type memoryTrashed struct {
+	item      interface{}
+	title     string
+	version   int
+	deletedAt time.Time
+}
+
+type memorySession struct {
+	familyID  string
+	userID    int
+	tokenHash string
+	expiresAt time.Time
+	rotated   bool
+	revoked   bool
+}
+
+type memoryEmail struct {
+	email       OutboxEmail
+	nextAttempt time.Time
+	lastError   string
+	done        bool // sent or given up on
+}
+
+type memoryImage struct {
+	image    models.GalleryImage
+	file     ImageFile
+	legacy   []byte
+	variants []VariantFile
+}
+
+var (
+	_ AboutStore       = (*Memory)(nil)
+	_ ResumeStore      = (*Memory)(nil)
+	_ CarBuildStore    = (*Memory)(nil)
+	_ PartStore        = (*Memory)(nil)
+	_ MaintenanceStore = (*Memory)(nil)
+	_ ContactStore     = (*Memory)(nil)
+	_ OutboxStore      = (*Memory)(nil)
+	_ UserStore        = (*Memory)(nil)
+	_ SessionStore     = (*Memory)(nil)
+	_ FolderStore      = (*Memory)(nil)
+	_ ImageStore       = (*Memory)(nil)
+	_ RevisionStore    = (*Memory)(nil)
+)
+
+// NewMemory returns an empty store holding the default image folders
+func NewMemory() *Memory {
+	m := &Memory{
+		now:         func() time.Time { return time.Now().UTC() },
+		about:       make(map[int]models.AboutContent),
+		resume:      make(map[int]models.ResumeSection),
+		carBuild:    make(map[int]models.CarBuildEntry),
+		parts:       make(map[int]models.Part),
+		maintenance: make(map[int]models.MaintenanceSchedule),
+		contact:     make(map[int]models.ContactSubmission),
+		replies:     make(map[int]models.ContactReply),
+		formNonces:  make(map[string]time.Time),
+		users:       make(map[int]models.User),
+		lockouts:    make(map[int]time.Time),
+		outbox:      make(map[int]*memoryEmail),
+		folders:     make(map[int]models.Folder),
+		images:      make(map[int]*memoryImage),
+		trash:       make(map[contentKey]memoryTrashed),
+	}
+	for i, f := range []struct{ slug, title string }{
+		{"gallery", "Gallery"}, {"about", "About"}, {"carbuild", "Car Build"}, {"hero", "Hero"},
+	} {
+		m.CreateFolder(context.Background(), &models.Folder{Slug: f.slug, Title: f.title, Visibility: "public", SortOrder: i})
+	}
+	return m
+}
+
+// SetClock replaces the clock used for timestamps and expiry, so tests can
+// move time forward
+func (m *Memory) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// id hands out IDs from a single sequence; callers hold mu
+func (m *Memory) id() int {
+	m.nextID++
+	return m.nextID
+}
+
+// sortedKeys returns the keys of a map in ascending order
+func sortedKeys[T any](items map[int]T) []int {
+	keys := make([]int, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// listPage re-sorts items, already in the list's default order, by the field
+// opts names and returns the requested page and the total count. Ties keep the
+// default order, matching the database.
+func listPage[T any](items []T, opts ListOptions, fields map[string]func(a, b T) int) ([]T, int, error) {
+	if opts.Sort != "" {
+		compare, ok := fields[opts.Sort]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown sort field %q", opts.Sort)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if opts.Desc {
+				return compare(items[j], items[i]) < 0
+			}
+			return compare(items[i], items[j]) < 0
+		})
+	}
+
+	total := len(items)
+	items = items[min(opts.Offset, total):]
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		items = items[:opts.Limit]
+	}
+	return items, total, nil
+}
+
+// compareTimes orders nil after every time, as Postgres does in ascending order
+func compareTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// compareInts orders nil after every number, as Postgres does in ascending order
+func compareInts(a, b *int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return cmp.Compare(*a, *b)
+}
+
+// compareFloats orders nil after every number, as Postgres does in ascending order
+func compareFloats(a, b *float64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return cmp.Compare(*a, *b)
+}
+
+// statusMatches reports whether content in state p passes the filter; callers hold mu
+func (m *Memory) statusMatches(p models.Publication, f StatusFilter) bool {
+	if f.Status != "" && p.Status != f.Status {
+		return false
+	}
+	return !f.Live || p.Live(m.now())
+}
+
+var aboutSorts = map[string]func(a, b models.AboutContent) int{
+	"created_at": func(a, b models.AboutContent) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updated_at": func(a, b models.AboutContent) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	"publish_at": func(a, b models.AboutContent) int { return compareTimes(a.PublishAt, b.PublishAt) },
+	"title":      func(a, b models.AboutContent) int { return strings.Compare(a.Title, b.Title) },
+}
+
+func (m *Memory) ListAboutContent(ctx context.Context, filter AboutFilter) ([]models.AboutContent, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var contents []models.AboutContent
+	for _, id := range sortedKeys(m.about) {
+		if m.statusMatches(m.about[id].Publication, filter.StatusFilter) {
+			contents = append(contents, m.about[id])
+		}
+	}
+	sort.SliceStable(contents, func(i, j int) bool { return contents[i].CreatedAt.After(contents[j].CreatedAt) })
+	return listPage(contents, filter.ListOptions, aboutSorts)
+}
+
+func (m *Memory) GetAboutContent(ctx context.Context, id int) (*models.AboutContent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content, ok := m.about[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &content, nil
+}
+
+func (m *Memory) CreateAboutContent(ctx context.Context, content *models.AboutContent, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content.ID = m.id()
+	content.Version = 1
+	content.CreatedAt = m.now()
+	content.UpdatedAt = content.CreatedAt
+	if err := m.revise(models.AboutContentType, content.ID, content.Version, models.RevisionCreate, author, content); err != nil {
+		return err
+	}
+	m.about[content.ID] = *content
+	return nil
+}
+
+func (m *Memory) UpdateAboutContent(ctx context.Context, content *models.AboutContent, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.about[content.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if content.Version != 0 && content.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	content.Version = existing.Version + 1
+	content.CreatedAt = existing.CreatedAt
+	content.UpdatedAt = m.now()
+	if err := m.revise(models.AboutContentType, content.ID, content.Version, models.RevisionUpdate, author, content); err != nil {
+		return err
+	}
+	m.about[content.ID] = *content
+	return nil
+}
+
+func (m *Memory) DeleteAboutContent(ctx context.Context, id int, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content, ok := m.about[id]
+	if !ok {
+		return ErrNotFound
+	}
+	content.Version++
+	if err := m.revise(models.AboutContentType, id, content.Version, models.RevisionDelete, author, content); err != nil {
+		return err
+	}
+	delete(m.about, id)
+	m.trash[contentKey{models.AboutContentType, id}] = memoryTrashed{content, content.Title, content.Version, m.now()}
+	return nil
+}
+
+func (m *Memory) RestoreAboutContent(ctx context.Context, id int, author *int) (*models.AboutContent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := contentKey{models.AboutContentType, id}
+	trashed, ok := m.trash[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	content := trashed.item.(models.AboutContent)
+	content.Version++
+	if err := m.revise(models.AboutContentType, id, content.Version, models.RevisionRestore, author, content); err != nil {
+		return nil, err
+	}
+	delete(m.trash, key)
+	m.about[id] = content
+	return &content, nil
+}
+
+var resumeSorts = map[string]func(a, b models.ResumeSection) int{
+	"display_order": func(a, b models.ResumeSection) int { return cmp.Compare(a.DisplayOrder, b.DisplayOrder) },
+	"start_date":    func(a, b models.ResumeSection) int { return compareTimes(a.StartDate, b.StartDate) },
+	"end_date":      func(a, b models.ResumeSection) int { return compareTimes(a.EndDate, b.EndDate) },
+	"title":         func(a, b models.ResumeSection) int { return strings.Compare(a.Title, b.Title) },
+	"publish_at":    func(a, b models.ResumeSection) int { return compareTimes(a.PublishAt, b.PublishAt) },
+	"created_at":    func(a, b models.ResumeSection) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+func (m *Memory) ListResumeSections(ctx context.Context, filter ResumeFilter) ([]models.ResumeSection, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sections []models.ResumeSection
+	for _, id := range sortedKeys(m.resume) {
+		section := m.resume[id]
+		if (filter.SectionType == "" || section.SectionType == filter.SectionType) && m.statusMatches(section.Publication, filter.StatusFilter) {
+			sections = append(sections, section)
+		}
+	}
+	sort.SliceStable(sections, func(i, j int) bool {
+		if sections[i].DisplayOrder != sections[j].DisplayOrder {
+			return sections[i].DisplayOrder < sections[j].DisplayOrder
+		}
+		a, b := sections[i].StartDate, sections[j].StartDate
+		return a != nil && (b == nil || a.After(*b))
+	})
+	return listPage(sections, filter.ListOptions, resumeSorts)
+}
+
+func (m *Memory) GetResumeSection(ctx context.Context, id int) (*models.ResumeSection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	section, ok := m.resume[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &section, nil
+}
+
+func (m *Memory) CreateResumeSection(ctx context.Context, section *models.ResumeSection, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	section.ID = m.id()
+	section.Version = 1
+	section.CreatedAt = m.now()
+	section.UpdatedAt = section.CreatedAt
+	if err := m.revise(models.ResumeContentType, section.ID, section.Version, models.RevisionCreate, author, section); err != nil {
+		return err
+	}
+	m.resume[section.ID] = *section
+	return nil
+}
+
+func (m *Memory) UpdateResumeSection(ctx context.Context, section *models.ResumeSection, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.resume[section.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if section.Version != 0 && section.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	section.Version = existing.Version + 1
+	section.CreatedAt = existing.CreatedAt
+	section.UpdatedAt = m.now()
+	if err := m.revise(models.ResumeContentType, section.ID, section.Version, models.RevisionUpdate, author, section); err != nil {
+		return err
+	}
+	m.resume[section.ID] = *section
+	return nil
+}
+
+func (m *Memory) DeleteResumeSection(ctx context.Context, id int, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	section, ok := m.resume[id]
+	if !ok {
+		return ErrNotFound
+	}
+	section.Version++
+	if err := m.revise(models.ResumeContentType, id, section.Version, models.RevisionDelete, author, section); err != nil {
+		return err
+	}
+	delete(m.resume, id)
+	m.trash[contentKey{models.ResumeContentType, id}] = memoryTrashed{section, section.Title, section.Version, m.now()}
+	return nil
+}
+
+func (m *Memory) RestoreResumeSection(ctx context.Context, id int, author *int) (*models.ResumeSection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := contentKey{models.ResumeContentType, id}
+	trashed, ok := m.trash[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	section := trashed.item.(models.ResumeSection)
+	section.Version++
+	if err := m.revise(models.ResumeContentType, id, section.Version, models.RevisionRestore, author, section); err != nil {
+		return nil, err
+	}
+	delete(m.trash, key)
+	m.resume[id] = section
+	return &section, nil
+}
+
+var carBuildSorts = map[string]func(a, b models.CarBuildEntry) int{
+	"display_order": func(a, b models.CarBuildEntry) int { return cmp.Compare(a.DisplayOrder, b.DisplayOrder) },
+	"date":          func(a, b models.CarBuildEntry) int { return a.Date.Compare(b.Date) },
+	"cost":          func(a, b models.CarBuildEntry) int { return compareFloats(a.Cost, b.Cost) },
+	"mileage":       func(a, b models.CarBuildEntry) int { return compareInts(a.Mileage, b.Mileage) },
+	"title":         func(a, b models.CarBuildEntry) int { return strings.Compare(a.Title, b.Title) },
+	"category":      func(a, b models.CarBuildEntry) int { return strings.Compare(a.Category, b.Category) },
+	"publish_at":    func(a, b models.CarBuildEntry) int { return compareTimes(a.PublishAt, b.PublishAt) },
+	"created_at":    func(a, b models.CarBuildEntry) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+func (m *Memory) ListCarBuildEntries(ctx context.Context, filter CarBuildFilter) ([]models.CarBuildEntry, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []models.CarBuildEntry
+	for _, id := range sortedKeys(m.carBuild) {
+		if carBuildMatches(m.carBuild[id], &filter) && m.statusMatches(m.carBuild[id].Publication, filter.StatusFilter) {
+			entries = append(entries, m.carBuild[id])
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].DisplayOrder != entries[j].DisplayOrder {
+			return entries[i].DisplayOrder < entries[j].DisplayOrder
+		}
+		return entries[i].Date.After(entries[j].Date)
+	})
+	return listPage(entries, filter.ListOptions, carBuildSorts)
+}
+
+func carBuildMatches(e models.CarBuildEntry, f *CarBuildFilter) bool {
+	if f.Category != "" && e.Category != f.Category {
+		return false
+	}
+	if f.Since != nil && e.Date.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !e.Date.Before(*f.Until) {
+		return false
+	}
+	if (f.MinCost != nil || f.MaxCost != nil) && e.Cost == nil {
+		return false
+	}
+	if f.MinCost != nil && *e.Cost < *f.MinCost {
+		return false
+	}
+	if f.MaxCost != nil && *e.Cost > *f.MaxCost {
+		return false
+	}
+	return true
+}
+
+func (m *Memory) GetCarBuildEntry(ctx context.Context, id int) (*models.CarBuildEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.carBuild[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (m *Memory) CreateCarBuildEntry(ctx context.Context, entry *models.CarBuildEntry, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry.ID = m.id()
+	entry.Version = 1
+	entry.CreatedAt = m.now()
+	entry.UpdatedAt = entry.CreatedAt
+	if err := m.revise(models.CarBuildContentType, entry.ID, entry.Version, models.RevisionCreate, author, entry); err != nil {
+		return err
+	}
+	m.carBuild[entry.ID] = *entry
+	return nil
+}
+
+func (m *Memory) UpdateCarBuildEntry(ctx context.Context, entry *models.CarBuildEntry, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.carBuild[entry.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if entry.Version != 0 && entry.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	entry.Version = existing.Version + 1
+	entry.CreatedAt = existing.CreatedAt
+	entry.UpdatedAt = m.now()
+	if err := m.revise(models.CarBuildContentType, entry.ID, entry.Version, models.RevisionUpdate, author, entry); err != nil {
+		return err
+	}
+	m.carBuild[entry.ID] = *entry
+	return nil
+}
+
+func (m *Memory) DeleteCarBuildEntry(ctx context.Context, id int, author *int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.carBuild[id]
+	if !ok {
+		return ErrNotFound
+	}
+	entry.Version++
+	if err := m.revise(models.CarBuildContentType, id, entry.Version, models.RevisionDelete, author, entry); err != nil {
+		return err
+	}
+	delete(m.carBuild, id)
+	m.trash[contentKey{models.CarBuildContentType, id}] = memoryTrashed{entry, entry.Title, entry.Version, m.now()}
+	return nil
+}
+
+func (m *Memory) RestoreCarBuildEntry(ctx context.Context, id int, author *int) (*models.CarBuildEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := contentKey{models.CarBuildContentType, id}
+	trashed, ok := m.trash[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	entry := trashed.item.(models.CarBuildEntry)
+	entry.Version++
+	if err := m.revise(models.CarBuildContentType, id, entry.Version, models.RevisionRestore, author, entry); err != nil {
+		return nil, err
+	}
+	delete(m.trash, key)
+	m.carBuild[id] = entry
+	return &entry, nil
+}
+
+var partSorts = map[string]func(a, b models.Part) int{
+	"purchase_date": func(a, b models.Part) int { return compareTimes(a.PurchaseDate, b.PurchaseDate) },
+	"name":          func(a, b models.Part) int { return strings.Compare(a.Name, b.Name) },
+	"price":         func(a, b models.Part) int { return cmp.Compare(a.Price, b.Price) },
+	"vendor":        func(a, b models.Part) int { return strings.Compare(a.Vendor, b.Vendor) },
+	"category":      func(a, b models.Part) int { return strings.Compare(a.Category, b.Category) },
+	"status":        func(a, b models.Part) int { return strings.Compare(a.Status, b.Status) },
+	"created_at":    func(a, b models.Part) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+// matchingParts returns the parts that pass the filter, newest purchase first
+// and undated ones last; callers hold mu
+func (m *Memory) matchingParts(f *PartFilter) []models.Part {
+	var parts []models.Part
+	keys := sortedKeys(m.parts)
+	for i := len(keys) - 1; i >= 0; i-- {
+		if partMatches(m.parts[keys[i]], f) {
+			parts = append(parts, m.parts[keys[i]])
+		}
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		a, b := parts[i].PurchaseDate, parts[j].PurchaseDate
+		return a != nil && (b == nil || a.After(*b))
+	})
+	return parts
+}
+
+func partMatches(p models.Part, f *PartFilter) bool {
+	if f.EntryID != nil && (p.EntryID == nil || *p.EntryID != *f.EntryID) {
+		return false
+	}
+	if f.Status != "" && p.Status != f.Status {
+		return false
+	}
+	if f.Category != "" && p.Category != f.Category {
+		return false
+	}
+	if f.Vendor != "" && p.Vendor != f.Vendor {
+		return false
+	}
+	if (f.Since != nil || f.Until != nil) && p.PurchaseDate == nil {
+		return false
+	}
+	if f.Since != nil && p.PurchaseDate.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !p.PurchaseDate.Before(*f.Until) {
+		return false
+	}
+	return true
+}
+
+func (m *Memory) ListParts(ctx context.Context, filter PartFilter) ([]models.Part, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return listPage(m.matchingParts(&filter), filter.ListOptions, partSorts)
+}
+
+func (m *Memory) GetPart(ctx context.Context, id int) (*models.Part, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	part, ok := m.parts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &part, nil
+}
+
+// checkPartEntry reports a link to a build entry that was never created;
+// deleted entries still count, as their rows do in the database. Callers hold mu.
+func (m *Memory) checkPartEntry(part *models.Part) error {
+	if part.EntryID == nil {
+		return nil
+	}
+	if _, ok := m.carBuild[*part.EntryID]; ok {
+		return nil
+	}
+	if _, ok := m.trash[contentKey{models.CarBuildContentType, *part.EntryID}]; ok {
+		return nil
+	}
+	return ErrInvalidReference
+}
+
+func (m *Memory) CreatePart(ctx context.Context, part *models.Part) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkPartEntry(part); err != nil {
+		return err
+	}
+	part.ID = m.id()
+	part.CreatedAt = m.now()
+	part.UpdatedAt = part.CreatedAt
+	m.parts[part.ID] = *part
+	return nil
+}
+
+func (m *Memory) UpdatePart(ctx context.Context, part *models.Part) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.parts[part.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := m.checkPartEntry(part); err != nil {
+		return err
+	}
+	part.CreatedAt = existing.CreatedAt
+	part.UpdatedAt = m.now()
+	m.parts[part.ID] = *part
+	return nil
+}
+
+func (m *Memory) DeletePart(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.parts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.parts, id)
+	return nil
+}
+
+func (m *Memory) PartSpend(ctx context.Context, filter PartFilter, group string) (*models.PartSpend, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !slices.Contains(SpendGroups, group) {
+		return nil, fmt.Errorf("unknown spend group %q", group)
+	}
+	spend := &models.PartSpend{Group: group, Totals: []models.SpendTotal{}}
+	index := make(map[string]int)
+	for _, part := range m.matchingParts(&filter) {
+		if part.Status == models.PartReturned {
+			continue
+		}
+		var key string
+		switch group {
+		case "category":
+			key = part.Category
+		case "month":
+			if part.PurchaseDate != nil {
+				key = part.PurchaseDate.Format("2006-01")
+			}
+		case "vendor":
+			key = part.Vendor
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(spend.Totals)
+			index[key] = i
+			spend.Totals = append(spend.Totals, models.SpendTotal{Key: key})
+		}
+		spend.Totals[i].Total += part.Price * float64(part.Quantity)
+		spend.Totals[i].Parts++
+	}
+
+	sort.SliceStable(spend.Totals, func(i, j int) bool {
+		a, b := spend.Totals[i], spend.Totals[j]
+		if (a.Key == "") != (b.Key == "") {
+			return b.Key == ""
+		}
+		if group != "month" && a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Key < b.Key
+	})
+	// Round to cents, as the database's DECIMAL sums are
+	for i := range spend.Totals {
+		spend.Totals[i].Total = math.Round(spend.Totals[i].Total*100) / 100
+		spend.Total += spend.Totals[i].Total
+	}
+	spend.Total = math.Round(spend.Total*100) / 100
+	return spend, nil
+}
+
+func (m *Memory) ListMaintenanceSchedules(ctx context.Context) ([]models.MaintenanceSchedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedules := []models.MaintenanceSchedule{}
+	for _, id := range sortedKeys(m.maintenance) {
+		schedules = append(schedules, m.maintenance[id])
+	}
+	sort.SliceStable(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+	return schedules, nil
+}
+
+func (m *Memory) GetMaintenanceSchedule(ctx context.Context, id int) (*models.MaintenanceSchedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.maintenance[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &schedule, nil
+}
+
+func (m *Memory) CreateMaintenanceSchedule(ctx context.Context, schedule *models.MaintenanceSchedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule.ID = m.id()
+	schedule.NotifiedStatus = ""
+	schedule.CreatedAt = m.now()
+	schedule.UpdatedAt = schedule.CreatedAt
+	m.maintenance[schedule.ID] = *schedule
+	return nil
+}
+
+func (m *Memory) UpdateMaintenanceSchedule(ctx context.Context, schedule *models.MaintenanceSchedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.maintenance[schedule.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	schedule.NotifiedStatus = ""
+	schedule.CreatedAt = existing.CreatedAt
+	schedule.UpdatedAt = m.now()
+	m.maintenance[schedule.ID] = *schedule
+	return nil
+}
+
+func (m *Memory) DeleteMaintenanceSchedule(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.maintenance[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.maintenance, id)
+	return nil
+}
+
+func (m *Memory) SetMaintenanceNotified(ctx context.Context, statuses map[int]string, emails []OutboxEmail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, status := range statuses {
+		schedule, ok := m.maintenance[id]
+		if !ok {
+			continue
+		}
+		schedule.NotifiedStatus = status
+		m.maintenance[id] = schedule
+	}
+	m.queueEmails(emails)
+	return nil
+}
+
+func (m *Memory) LatestMileage(ctx context.Context) (*models.MileageReading, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var latest *models.MileageReading
+	for _, id := range sortedKeys(m.carBuild) {
+		entry := m.carBuild[id]
+		if entry.Mileage == nil || (latest != nil && entry.Date.Before(latest.Date)) {
+			continue
+		}
+		latest = &models.MileageReading{Mileage: *entry.Mileage, Date: entry.Date, EntryID: entry.ID}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+// revise records a revision of a record as it is after a change. Callers hold
+// mu and call it before applying the change, so a failure leaves nothing behind.
+func (m *Memory) revise(contentType string, id, version int, action string, author *int, content interface{}) error {
+	snapshot, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	revision := models.ContentRevision{
+		ID:          m.id(),
+		ContentType: contentType,
+		ContentID:   id,
+		Version:     version,
+		Action:      action,
+		AuthorID:    author,
+		Snapshot:    snapshot,
+		CreatedAt:   m.now(),
+	}
+	if author != nil {
+		revision.Author = m.users[*author].Username
+	}
+	m.revisions = append(m.revisions, revision)
+	return nil
+}
+
+func (m *Memory) ListRevisions(ctx context.Context, contentType string, contentID int, opts ListOptions) ([]models.ContentRevision, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var revisions []models.ContentRevision
+	for i := len(m.revisions) - 1; i >= 0; i-- {
+		if r := m.revisions[i]; r.ContentType == contentType && r.ContentID == contentID {
+			r.Snapshot = nil
+			revisions = append(revisions, r)
+		}
+	}
+	return listPage(revisions, opts, nil)
+}
+
+func (m *Memory) GetRevision(ctx context.Context, contentType string, contentID, version int) (*models.ContentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.revisions {
+		if r.ContentType == contentType && r.ContentID == contentID && r.Version == version {
+			return &r, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) ListTrash(ctx context.Context) ([]models.TrashItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var items []models.TrashItem
+	for key, t := range m.trash {
+		items = append(items, models.TrashItem{
+			ContentType: key.contentType,
+			ID:          key.id,
+			Title:       t.title,
+			Version:     t.version,
+			DeletedAt:   t.deletedAt,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	return items, nil
+}
+
+func (m *Memory) CreateContactSubmission(ctx context.Context, submission *models.ContactSubmission, emails []OutboxEmail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	submission.ID = m.id()
+	submission.IsRead = false
+	submission.CreatedAt = m.now()
+	m.contact[submission.ID] = *submission
+	m.queueEmails(emails)
+	return nil
+}
+
+func (m *Memory) ListContactSubmissions(ctx context.Context, filter ContactFilter) ([]models.ContactSubmission, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var submissions []models.ContactSubmission
+	for _, id := range sortedKeys(m.contact) {
+		submission := m.contact[id]
+		if contactMatches(&submission, &filter) {
+			submissions = append(submissions, m.withReplies(submission, false))
+		}
+	}
+	sort.SliceStable(submissions, func(i, j int) bool {
+		if !submissions[i].CreatedAt.Equal(submissions[j].CreatedAt) {
+			return submissions[i].CreatedAt.After(submissions[j].CreatedAt)
+		}
+		return submissions[i].ID > submissions[j].ID
+	})
+	return listPage(submissions, filter.ListOptions, contactSorts)
+}
+
+var contactSorts = map[string]func(a, b models.ContactSubmission) int{
+	"created_at": func(a, b models.ContactSubmission) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"name":       func(a, b models.ContactSubmission) int { return strings.Compare(a.Name, b.Name) },
+	"email":      func(a, b models.ContactSubmission) int { return strings.Compare(a.Email, b.Email) },
+}
+
+func contactMatches(s *models.ContactSubmission, f *ContactFilter) bool {
+	flag := func(want *bool, got bool) bool { return want == nil || *want == got }
+	if !flag(f.Spam, s.IsSpam) || !flag(f.Archived, s.IsArchived) ||
+		!flag(f.Read, s.IsRead) || !flag(f.Starred, s.IsStarred) {
+		return false
+	}
+	if f.Since != nil && s.CreatedAt.Before(*f.Since) {
+		return false
+	}
+	if f.Until != nil && !s.CreatedAt.Before(*f.Until) {
+		return false
+	}
+	text := strings.ToLower(strings.Join([]string{s.Name, s.Email, s.Subject, s.Message}, " "))
+	for _, word := range strings.Fields(strings.ToLower(f.Search)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// withReplies fills in the reply summary of a submission, and the replies
+// themselves when full is set. Callers hold m.mu.
+func (m *Memory) withReplies(submission models.ContactSubmission, full bool) models.ContactSubmission {
+	submission.ReplyCount = 0
+	submission.LastRepliedAt = nil
+	submission.Replies = nil
+	for _, id := range sortedKeys(m.replies) {
+		reply := m.replies[id]
+		if reply.SubmissionID != submission.ID {
+			continue
+		}
+		submission.ReplyCount++
+		created := reply.CreatedAt
+		submission.LastRepliedAt = &created
+		if reply.AuthorID != nil {
+			reply.Author = m.users[*reply.AuthorID].Username
+		}
+		if full {
+			submission.Replies = append(submission.Replies, reply)
+		}
+	}
+	return submission
+}
+
+func (m *Memory) GetContactSubmission(ctx context.Context, id int) (*models.ContactSubmission, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	submission, ok := m.contact[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	submission = m.withReplies(submission, true)
+	return &submission, nil
+}
+
+func (m *Memory) UpdateContactSubmission(ctx context.Context, id int, update models.ContactSubmissionUpdate) (*models.ContactSubmission, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	submission, ok := m.contact[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if update.IsRead != nil {
+		submission.IsRead = *update.IsRead
+	}
+	if update.IsArchived != nil {
+		submission.IsArchived = *update.IsArchived
+	}
+	if update.IsStarred != nil {
+		submission.IsStarred = *update.IsStarred
+	}
+	if update.IsSpam != nil && *update.IsSpam != submission.IsSpam {
+		submission.IsSpam = *update.IsSpam
+		submission.SpamReason = ""
+		if submission.IsSpam {
+			submission.SpamReason = ManualSpamReason
+		}
+	}
+	m.contact[id] = submission
+
+	submission = m.withReplies(submission, false)
+	return &submission, nil
+}
+
+func (m *Memory) CountUnreadContactSubmissions(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, submission := range m.contact {
+		if !submission.IsRead && !submission.IsSpam && !submission.IsArchived {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *Memory) CreateContactReply(ctx context.Context, reply *models.ContactReply) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.contact[reply.SubmissionID]; !ok {
+		return ErrNotFound
+	}
+	reply.ID = m.id()
+	reply.CreatedAt = m.now()
+	m.replies[reply.ID] = *reply
+	if reply.AuthorID != nil {
+		reply.Author = m.users[*reply.AuthorID].Username
+	}
+	return nil
+}
+
+func (m *Memory) UseFormNonce(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, used := m.formNonces[nonce]; used {
+		return false, nil
+	}
+	now := m.now()
+	for n, exp := range m.formNonces {
+		if exp.Before(now) {
+			delete(m.formNonces, n)
+		}
+	}
+	m.formNonces[nonce] = expires
+	return true, nil
+}
+
+func (m *Memory) DeleteContactSubmission(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.contact[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.contact, id)
+	for replyID, reply := range m.replies {
+		if reply.SubmissionID == id {
+			delete(m.replies, replyID)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, user := range m.users {
+		if user.Email == email {
+			return m.user(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.user(user), nil
+}
+
+// user fills in the remaining lockout of a stored user; callers hold mu
+func (m *Memory) user(user models.User) *models.User {
+	if until, ok := m.lockouts[user.ID]; ok && until.After(m.now()) {
+		user.LockedFor = until.Sub(m.now())
+	}
+	return &user
+}
+
+func (m *Memory) RecordLoginFailure(ctx context.Context, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	user.FailedLogins++
+	m.users[userID] = user
+	return user.FailedLogins, nil
+}
+
+func (m *Memory) LockUser(ctx context.Context, userID int, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return ErrNotFound
+	}
+	m.lockouts[userID] = m.now().Add(d)
+	return nil
+}
+
+func (m *Memory) ResetLoginFailures(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	user.FailedLogins = 0
+	m.users[userID] = user
+	delete(m.lockouts, userID)
+	return nil
+}
+
+func (m *Memory) CreateUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.users {
+		if existing.Email == user.Email {
+			return ErrConflict
+		}
+	}
+	user.ID = m.id()
+	user.CreatedAt = m.now()
+	user.UpdatedAt = user.CreatedAt
+	m.users[user.ID] = *user
+	return nil
+}
+
+func (m *Memory) CreateSession(ctx context.Context, familyID string, userID int, tokenHash string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions = append(m.sessions, &memorySession{
+		familyID:  familyID,
+		userID:    userID,
+		tokenHash: tokenHash,
+		expiresAt: m.now().Add(ttl),
+	})
+	return nil
+}
+
+func (m *Memory) RotateSession(ctx context.Context, oldHash, newHash string, ttl time.Duration) (string, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var session *memorySession
+	for _, s := range m.sessions {
+		if s.tokenHash == oldHash {
+			session = s
+		}
+	}
+	switch {
+	case session == nil:
+		return "", 0, ErrSessionNotFound
+	case session.revoked:
+		return "", 0, ErrSessionRevoked
+	case session.rotated:
+		m.revokeFamily(session.familyID)
+		return "", 0, ErrRefreshTokenReused
+	case !session.expiresAt.After(m.now()):
+		return "", 0, ErrSessionExpired
+	}
+
+	session.rotated = true
+	m.sessions = append(m.sessions, &memorySession{
+		familyID:  session.familyID,
+		userID:    session.userID,
+		tokenHash: newHash,
+		expiresAt: m.now().Add(ttl),
+	})
+	return session.familyID, session.userID, nil
+}
+
+func (m *Memory) revokeFamily(familyID string) {
+	for _, s := range m.sessions {
+		if s.familyID == familyID {
+			s.revoked = true
+		}
+	}
+}
+
+func (m *Memory) RevokeSession(ctx context.Context, familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeFamily(familyID)
+	return nil
+}
+
+func (m *Memory) IsSessionActive(ctx context.Context, familyID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sessions {
+		if s.familyID == familyID && !s.revoked && s.expiresAt.After(m.now()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// folder returns a copy of a folder with its image count filled in; callers hold mu
+func (m *Memory) folder(f models.Folder) models.Folder {
+	f.ImageCount = 0
+	for _, img := range m.images {
+		if img.image.Folder == f.Slug {
+			f.ImageCount++
+		}
+	}
+	f.CoverURL = ""
+	if f.CoverImageID != nil {
+		f.CoverURL = fmt.Sprintf("/api/image/%d", *f.CoverImageID)
+	}
+	return f
+}
+
+func (m *Memory) GetFolder(ctx context.Context, slug string) (*models.Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.folders {
+		if f.Slug == slug {
+			folder := m.folder(f)
+			return &folder, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *Memory) ListFolders(ctx context.Context, publicOnly bool) ([]models.Folder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folders := []models.Folder{}
+	for _, id := range sortedKeys(m.folders) {
+		f := m.folders[id]
+		if publicOnly && f.Visibility != "public" {
+			continue
+		}
+		folders = append(folders, m.folder(f))
+	}
+	sort.SliceStable(folders, func(i, j int) bool {
+		if folders[i].SortOrder != folders[j].SortOrder {
+			return folders[i].SortOrder < folders[j].SortOrder
+		}
+		return folders[i].Title < folders[j].Title
+	})
+	return folders, nil
+}
+
+// checkFolder validates the unique slug and cover image of a folder write; callers hold mu
+func (m *Memory) checkFolder(folder *models.Folder) error {
+	for id, f := range m.folders {
+		if f.Slug == folder.Slug && id != folder.ID {
+			return ErrConflict
+		}
+	}
+	if folder.CoverImageID != nil {
+		if _, ok := m.images[*folder.CoverImageID]; !ok {
+			return ErrInvalidReference
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateFolder(ctx context.Context, folder *models.Folder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	folder.ID = 0
+	if err := m.checkFolder(folder); err != nil {
+		return err
+	}
+	folder.ID = m.id()
+	folder.CreatedAt = m.now()
+	folder.UpdatedAt = folder.CreatedAt
+	m.folders[folder.ID] = *folder
+	*folder = m.folder(*folder)
+	return nil
+}
+
+func (m *Memory) UpdateFolder(ctx context.Context, folder *models.Folder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.folders[folder.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := m.checkFolder(folder); err != nil {
+		return err
+	}
+
+	if existing.Slug != folder.Slug {
+		for _, img := range m.images {
+			if img.image.Folder == existing.Slug {
+				img.image.Folder = folder.Slug
+			}
+		}
+	}
+
+	folder.CreatedAt = existing.CreatedAt
+	folder.UpdatedAt = m.now()
+	m.folders[folder.ID] = *folder
+	*folder = m.folder(*folder)
+	return nil
+}
+
+func (m *Memory) DeleteFolder(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.folders[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if m.folder(f).ImageCount > 0 {
+		return ErrInUse
+	}
+	delete(m.folders, id)
+	return nil
+}
+
+// folderImages returns the IDs of a folder's images in display order; callers hold mu
+func (m *Memory) folderImages(folder string) []int {
+	var ids []int
+	for _, id := range sortedKeys(m.images) {
+		if m.images[id].image.Folder == folder {
+			ids = append(ids, id)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		a, b := m.images[ids[i]].image, m.images[ids[j]].image
+		if a.DisplayOrder != b.DisplayOrder {
+			return a.DisplayOrder < b.DisplayOrder
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+	return ids
+}
+
+var imageSorts = map[string]func(a, b models.GalleryImage) int{
+	"display_order": func(a, b models.GalleryImage) int { return cmp.Compare(a.DisplayOrder, b.DisplayOrder) },
+	"created_at":    func(a, b models.GalleryImage) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"filename":      func(a, b models.GalleryImage) int { return strings.Compare(a.Filename, b.Filename) },
+	"taken_at":      func(a, b models.GalleryImage) int { return compareTimes(a.TakenAt, b.TakenAt) },
+}
+
+func (m *Memory) ListImages(ctx context.Context, folder string, opts ListOptions) ([]models.GalleryImage, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	images := []models.GalleryImage{}
+	for _, id := range m.folderImages(folder) {
+		images = append(images, m.images[id].image)
+	}
+	return listPage(images, opts, imageSorts)
+}
+
+func (m *Memory) ImageAltTexts(ctx context.Context, ids []int, publicOnly bool) (map[int]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alts := make(map[int]string)
+	for _, id := range ids {
+		img, ok := m.images[id]
+		if !ok || publicOnly && !m.folderPublic(img.image.Folder) {
+			continue
+		}
+		alts[id] = img.image.AltText
+	}
+	return alts, nil
+}
+
+func (m *Memory) GetImageFile(ctx context.Context, id int) (*ImageFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	file := img.file
+	file.Folder = img.image.Folder
+	return &file, nil
+}
+
+func (m *Memory) ListVariants(ctx context.Context, imageID int) ([]VariantFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[imageID]
+	if !ok {
+		return nil, nil
+	}
+	return append([]VariantFile(nil), img.variants...), nil
+}
+
+func (m *Memory) LegacyImageData(ctx context.Context, id int) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[id]
+	if !ok || img.legacy == nil {
+		return nil, ErrNotFound
+	}
+	return img.legacy, nil
+}
+
+// AddLegacyImage records an image whose bytes are held inline, as rows were
+// before the storage backend existed
+func (m *Memory) AddLegacyImage(folder, filename, contentType string, data []byte) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.id()
+	now := m.now()
+	m.images[id] = &memoryImage{
+		image: models.GalleryImage{
+			ID: id, URL: fmt.Sprintf("/api/image/%d", id), Folder: folder, Filename: filename,
+			ContentType: contentType, CreatedAt: now, UpdatedAt: now,
+		},
+		file:   ImageFile{ID: id, ContentType: contentType, CreatedAt: now},
+		legacy: data,
+	}
+	return id
+}
+
+func (m *Memory) SaveContentHash(ctx context.Context, imageID, variantID int, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[imageID]
+	if !ok {
+		return nil
+	}
+	if variantID == 0 {
+		img.file.Hash = hash
+		return nil
+	}
+	for i := range img.variants {
+		if img.variants[i].ID == variantID {
+			img.variants[i].Hash = hash
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateImage(ctx context.Context, image *NewImage) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.folderExists(image.Folder) {
+		return 0, ErrInvalidReference
+	}
+
+	id := m.id()
+	now := m.now()
+	m.images[id] = &memoryImage{
+		image: models.GalleryImage{
+			ID:          id,
+			URL:         fmt.Sprintf("/api/image/%d", id),
+			Folder:      image.Folder,
+			Filename:    image.Filename,
+			ContentType: image.ContentType,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		},
+		file: ImageFile{
+			ID:          id,
+			Key:         image.Key,
+			ContentType: image.ContentType,
+			Hash:        image.Hash,
+			CreatedAt:   now,
+		},
+	}
+	return id, nil
+}
+
+// folderExists reports whether a folder slug exists; callers hold mu
+func (m *Memory) folderExists(slug string) bool {
+	for _, f := range m.folders {
+		if f.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// folderPublic reports whether a folder slug exists and is public; callers
+// hold mu
+func (m *Memory) folderPublic(slug string) bool {
+	for _, f := range m.folders {
+		if f.Slug == slug {
+			return f.Visibility == "public"
+		}
+	}
+	return false
+}
+
+func (m *Memory) SaveImageMetadata(ctx context.Context, id int, meta *imaging.Metadata) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[id]
+	if !ok || meta == nil {
+		return nil
+	}
+	img.image.TakenAt = meta.TakenAt
+	img.image.CameraMake = meta.CameraMake
+	img.image.CameraModel = meta.CameraModel
+	img.image.LensModel = meta.LensModel
+	img.image.ExposureTime = meta.ExposureTime
+	img.image.FNumber = meta.FNumber
+	img.image.ISO = meta.ISO
+	img.image.FocalLength = meta.FocalLength
+	return nil
+}
+
+func (m *Memory) SaveVariants(ctx context.Context, imageID, width, height int, variants []VariantFile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[imageID]
+	if !ok {
+		return nil
+	}
+	img.image.Width = &width
+	img.image.Height = &height
+	img.file.Width = width
+
+	for _, v := range variants {
+		replaced := false
+		for i, existing := range img.variants {
+			if existing.Variant == v.Variant && existing.Format == v.Format {
+				v.ID = existing.ID
+				v.CreatedAt = existing.CreatedAt
+				img.variants[i] = v
+				replaced = true
+			}
+		}
+		if !replaced {
+			v.ID = m.id()
+			v.CreatedAt = m.now()
+			img.variants = append(img.variants, v)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) UpdateImage(ctx context.Context, id int, update models.GalleryImageUpdate) (*models.GalleryImage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if update.Folder != nil && *update.Folder != img.image.Folder {
+		if !m.folderExists(*update.Folder) {
+			return nil, ErrInvalidReference
+		}
+		if update.DisplayOrder == nil {
+			order := 0
+			for _, other := range m.images {
+				if other.image.Folder == *update.Folder && other.image.DisplayOrder >= order {
+					order = other.image.DisplayOrder + 1
+				}
+			}
+			img.image.DisplayOrder = order
+		}
+		img.image.Folder = *update.Folder
+	}
+	if update.Caption != nil {
+		img.image.Caption = *update.Caption
+	}
+	if update.AltText != nil {
+		img.image.AltText = *update.AltText
+	}
+	if update.DisplayOrder != nil {
+		img.image.DisplayOrder = *update.DisplayOrder
+	}
+	img.image.UpdatedAt = m.now()
+
+	image := img.image
+	return &image, nil
+}
+
+func (m *Memory) ReorderImages(ctx context.Context, folder string, ids []int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.folderImages(folder)
+	inFolder := make(map[int]bool, len(current))
+	for _, id := range current {
+		inFolder[id] = true
+	}
+
+	order := make([]int, 0, len(current))
+	listed := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if !inFolder[id] || listed[id] {
+			return fmt.Errorf("%w: %d", ErrImageNotInFolder, id)
+		}
+		listed[id] = true
+		order = append(order, id)
+	}
+	for _, id := range current {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+
+	for i, id := range order {
+		if m.images[id].image.DisplayOrder != i {
+			m.images[id].image.DisplayOrder = i
+			m.images[id].image.UpdatedAt = m.now()
+		}
+	}
+	return nil
+}
+
+func (m *Memory) DeleteImage(ctx context.Context, id int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	img, ok := m.images[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(m.images, id)
+	m.clearCover(id)
+	return imageKeys(img), nil
+}
+
+func (m *Memory) DeleteAllImages(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var keys []string
+	for id, img := range m.images {
+		keys = append(keys, imageKeys(img)...)
+		m.clearCover(id)
+	}
+	m.images = make(map[int]*memoryImage)
+	return keys, nil
+}
+
+// clearCover unsets the cover of folders using a deleted image; callers hold mu
+func (m *Memory) clearCover(imageID int) {
+	for id, f := range m.folders {
+		if f.CoverImageID != nil && *f.CoverImageID == imageID {
+			f.CoverImageID = nil
+			m.folders[id] = f
+		}
+	}
+}
+
+func imageKeys(img *memoryImage) []string {
+	var keys []string
+	for _, v := range img.variants {
+		keys = append(keys, v.Key)
+	}
+	if img.file.Key != "" {
+		keys = append(keys, img.file.Key)
+	}
+	return keys
+}
+
+// queueEmails adds emails to the outbox; callers hold mu
+func (m *Memory) queueEmails(emails []OutboxEmail) {
+	for _, email := range emails {
+		email.ID = m.id()
+		email.Attempts = 0
+		m.outbox[email.ID] = &memoryEmail{email: email, nextAttempt: m.now()}
+	}
+}
+
+func (m *Memory) ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]OutboxEmail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var emails []OutboxEmail
+	for _, id := range sortedKeys(m.outbox) {
+		entry := m.outbox[id]
+		if entry.done || entry.nextAttempt.After(m.now()) {
+			continue
+		}
+		if len(emails) == limit {
+			break
+		}
+		entry.nextAttempt = m.now().Add(lease)
+		emails = append(emails, entry.email)
+	}
+	return emails, nil
+}
+
+func (m *Memory) MarkEmailSent(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.outbox[id]
+	if !ok {
+		return ErrNotFound
+	}
+	entry.done = true
+	return nil
+}
+
+func (m *Memory) MarkEmailFailed(ctx context.Context, id int, retryIn time.Duration, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.outbox[id]
+	if !ok {
+		return ErrNotFound
+	}
+	entry.email.Attempts++
+	entry.lastError = reason
+	entry.nextAttempt = m.now().Add(retryIn)
+	entry.done = retryIn == 0
+	return nil
+}
@@ -0,0 +1,362 @@
+// Package store defines the persistence interfaces the HTTP handlers depend
+// on. The Postgres implementation is *database.DB; Memory keeps everything in
+// process for tests.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Jakeito/TestWebsite/backend/internal/imaging"
+	"github.com/Jakeito/TestWebsite/backend/internal/models"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a write would duplicate a unique value
+	ErrConflict = errors.New("already exists")
+	// ErrInvalidReference is returned when a write refers to a missing record
+	ErrInvalidReference = errors.New("referenced record not found")
+	// ErrInUse is returned when deleting a record that others still refer to
+	ErrInUse = errors.New("still in use")
+	// ErrVersionConflict is returned when a conditional update finds the
+	// record at a different version than the caller read
+	ErrVersionConflict = errors.New("version conflict")
+
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionRevoked     = errors.New("session revoked")
+	ErrSessionExpired     = errors.New("session expired")
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+
+	// ErrImageNotInFolder is returned by ReorderImages when an ID does not
+	// belong to the folder being reordered or is listed more than once
+	ErrImageNotInFolder = errors.New("image not in folder")
+)
+
+// ListOptions orders and pages a list. Sort names one of the fields the list
+// allows, or is empty for the list's default order, which also breaks ties.
+type ListOptions struct {
+	Sort string
+	Desc bool
+	// Limit is the page size, or 0 for no limit
+	Limit  int
+	Offset int
+}
+
+// Fields each list can be sorted on
+var (
+	AboutSorts    = []string{"created_at", "updated_at", "publish_at", "title"}
+	ResumeSorts   = []string{"display_order", "start_date", "end_date", "title", "publish_at", "created_at"}
+	CarBuildSorts = []string{"display_order", "date", "cost", "mileage", "title", "category", "publish_at", "created_at"}
+	ContactSorts  = []string{"created_at", "name", "email"}
+	ImageSorts    = []string{"display_order", "created_at", "filename", "taken_at"}
+	PartSorts     = []string{"purchase_date", "name", "price", "vendor", "category", "status", "created_at"}
+)
+
+// List methods return one page of results and the total number that match.
+//
+// Content updates bump the version and write it back. When the version passed
+// in is non-zero, the update only applies if the record is still at that
+// version and returns ErrVersionConflict otherwise.
+//
+// Every content write records a revision by author, who may be nil. Deletes
+// are soft: the record moves to the trash, where the content stores no longer
+// see it until it is restored.
+//
+// Content gets return a record whatever its status; handlers serving the
+// public check models.Publication.Live themselves.
+
+// StatusFilter selects content by publishing state. Live keeps only what the
+// public may see, as models.Publication.Live decides at the current time.
+type StatusFilter struct {
+	Status string
+	Live   bool
+}
+
+// AboutFilter selects about content; empty fields match all
+type AboutFilter struct {
+	StatusFilter
+	ListOptions
+}
+
+type AboutStore interface {
+	ListAboutContent(ctx context.Context, filter AboutFilter) ([]models.AboutContent, int, error)
+	GetAboutContent(ctx context.Context, id int) (*models.AboutContent, error)
+	CreateAboutContent(ctx context.Context, content *models.AboutContent, author *int) error
+	UpdateAboutContent(ctx context.Context, content *models.AboutContent, author *int) error
+	DeleteAboutContent(ctx context.Context, id int, author *int) error
+	RestoreAboutContent(ctx context.Context, id int, author *int) (*models.AboutContent, error)
+}
+
+// ResumeFilter selects resume sections; empty fields match all
+type ResumeFilter struct {
+	SectionType string
+	StatusFilter
+	ListOptions
+}
+
+type ResumeStore interface {
+	ListResumeSections(ctx context.Context, filter ResumeFilter) ([]models.ResumeSection, int, error)
+	GetResumeSection(ctx context.Context, id int) (*models.ResumeSection, error)
+	CreateResumeSection(ctx context.Context, section *models.ResumeSection, author *int) error
+	UpdateResumeSection(ctx context.Context, section *models.ResumeSection, author *int) error
+	DeleteResumeSection(ctx context.Context, id int, author *int) error
+	RestoreResumeSection(ctx context.Context, id int, author *int) (*models.ResumeSection, error)
+}
+
+// CarBuildFilter selects car build entries; nil and empty fields match all
+type CarBuildFilter struct {
+	Category string
+	// Since and Until bound the entry date; Until is exclusive
+	Since *time.Time
+	Until *time.Time
+	// MinCost and MaxCost bound the cost; entries without one never match
+	MinCost *float64
+	MaxCost *float64
+	StatusFilter
+	ListOptions
+}
+
+type CarBuildStore interface {
+	ListCarBuildEntries(ctx context.Context, filter CarBuildFilter) ([]models.CarBuildEntry, int, error)
+	GetCarBuildEntry(ctx context.Context, id int) (*models.CarBuildEntry, error)
+	CreateCarBuildEntry(ctx context.Context, entry *models.CarBuildEntry, author *int) error
+	UpdateCarBuildEntry(ctx context.Context, entry *models.CarBuildEntry, author *int) error
+	DeleteCarBuildEntry(ctx context.Context, id int, author *int) error
+	RestoreCarBuildEntry(ctx context.Context, id int, author *int) (*models.CarBuildEntry, error)
+}
+
+// Groups PartStore.PartSpend can break spend down by
+var SpendGroups = []string{"category", "month", "vendor"}
+
+// PartFilter selects parts; nil and empty fields match all
+type PartFilter struct {
+	EntryID  *int
+	Status   string
+	Category string
+	Vendor   string
+	// Since and Until bound the purchase date; Until is exclusive. Parts
+	// without a purchase date never match.
+	Since *time.Time
+	Until *time.Time
+	ListOptions
+}
+
+// PartStore records the parts bought for the car build. Parts are listed
+// newest purchase first by default, undated ones last.
+type PartStore interface {
+	ListParts(ctx context.Context, filter PartFilter) ([]models.Part, int, error)
+	GetPart(ctx context.Context, id int) (*models.Part, error)
+	// CreatePart and UpdatePart return ErrInvalidReference when the part is
+	// linked to a missing build entry
+	CreatePart(ctx context.Context, part *models.Part) error
+	UpdatePart(ctx context.Context, part *models.Part) error
+	DeletePart(ctx context.Context, id int) error
+	// PartSpend totals price times quantity over the parts that match filter,
+	// leaving out returned parts and ignoring the list options. The totals are
+	// grouped by one of SpendGroups: months run in order, categories and
+	// vendors from the largest spend down. Parts without a value for the
+	// group come last, under an empty key.
+	PartSpend(ctx context.Context, filter PartFilter, group string) (*models.PartSpend, error)
+}
+
+// MaintenanceStore keeps the car's maintenance schedule, and the mileage
+// readings it comes due by
+type MaintenanceStore interface {
+	// ListMaintenanceSchedules lists every schedule by name
+	ListMaintenanceSchedules(ctx context.Context) ([]models.MaintenanceSchedule, error)
+	GetMaintenanceSchedule(ctx context.Context, id int) (*models.MaintenanceSchedule, error)
+	CreateMaintenanceSchedule(ctx context.Context, schedule *models.MaintenanceSchedule) error
+	// UpdateMaintenanceSchedule replaces a schedule's fields and clears its
+	// notified status, so a reminder goes out again when it next comes due
+	UpdateMaintenanceSchedule(ctx context.Context, schedule *models.MaintenanceSchedule) error
+	DeleteMaintenanceSchedule(ctx context.Context, id int) error
+	// SetMaintenanceNotified records the status each schedule, by ID, was
+	// last notified for and queues the reminder emails in one transaction,
+	// so a reminder is never queued without being recorded. Schedules
+	// deleted in the meantime are skipped.
+	SetMaintenanceNotified(ctx context.Context, statuses map[int]string, emails []OutboxEmail) error
+	// LatestMileage returns the reading on the most recently dated build
+	// entry that has one, drafts included but not deleted entries. It returns
+	// ErrNotFound before the first reading.
+	LatestMileage(ctx context.Context) (*models.MileageReading, error)
+}
+
+// RevisionStore reads the history the content stores record. Revisions are
+// kept when their record is deleted.
+type RevisionStore interface {
+	// ListRevisions lists a record's revisions newest first, leaving out
+	// their snapshots
+	ListRevisions(ctx context.Context, contentType string, contentID int, opts ListOptions) ([]models.ContentRevision, int, error)
+	// GetRevision returns the revision that left a record at version
+	GetRevision(ctx context.Context, contentType string, contentID, version int) (*models.ContentRevision, error)
+	// ListTrash lists deleted records of every type, most recently deleted first
+	ListTrash(ctx context.Context) ([]models.TrashItem, error)
+}
+
+// ManualSpamReason is the spam reason of submissions an admin marked as spam
+const ManualSpamReason = "marked as spam by an admin"
+
+// ContactFilter selects contact submissions; the zero value matches all. Nil
+// flags match either state.
+type ContactFilter struct {
+	Spam     *bool
+	Archived *bool
+	Read     *bool
+	Starred  *bool
+	// Since and Until bound the submission time; Until is exclusive
+	Since *time.Time
+	Until *time.Time
+	// Search matches words in the name, email, subject and message
+	Search string
+	ListOptions
+}
+
+type ContactStore interface {
+	// CreateContactSubmission inserts a submission and queues emails about it
+	// in the same transaction
+	CreateContactSubmission(ctx context.Context, submission *models.ContactSubmission, emails []OutboxEmail) error
+	// ListContactSubmissions lists submissions newest first by default
+	ListContactSubmissions(ctx context.Context, filter ContactFilter) ([]models.ContactSubmission, int, error)
+	// GetContactSubmission returns a submission with its replies
+	GetContactSubmission(ctx context.Context, id int) (*models.ContactSubmission, error)
+	UpdateContactSubmission(ctx context.Context, id int, update models.ContactSubmissionUpdate) (*models.ContactSubmission, error)
+	// CountUnreadContactSubmissions counts unread submissions in the inbox,
+	// leaving out spam and archived ones
+	CountUnreadContactSubmissions(ctx context.Context) (int, error)
+	DeleteContactSubmission(ctx context.Context, id int) error
+
+	// CreateContactReply records a reply note; it returns ErrNotFound when
+	// the submission does not exist
+	CreateContactReply(ctx context.Context, reply *models.ContactReply) error
+
+	// UseFormNonce records the nonce of a contact form token as used until
+	// expires, and reports whether it was still unused
+	UseFormNonce(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+type UserStore interface {
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByID(ctx context.Context, id int) (*models.User, error)
+	// CreateUser inserts a user with PasswordHash already set
+	CreateUser(ctx context.Context, user *models.User) error
+	// RecordLoginFailure counts a failed login and returns the number of
+	// consecutive failures
+	RecordLoginFailure(ctx context.Context, userID int) (int, error)
+	// LockUser refuses logins to the account for d
+	LockUser(ctx context.Context, userID int, d time.Duration) error
+	// ResetLoginFailures clears the failure count and any lockout
+	ResetLoginFailures(ctx context.Context, userID int) error
+}
+
+// OutboxEmail is an email waiting in the outbox to be sent
+type OutboxEmail struct {
+	ID       int
+	To       string
+	ReplyTo  string
+	Subject  string
+	Body     string
+	Attempts int // failed attempts so far
+}
+
+// OutboxStore queues emails for the mail worker, so they survive restarts and
+// mail server outages
+type OutboxStore interface {
+	// ClaimEmails returns up to limit emails that are due and hides them from
+	// other workers for lease, in case this one dies mid-send
+	ClaimEmails(ctx context.Context, limit int, lease time.Duration) ([]OutboxEmail, error)
+	MarkEmailSent(ctx context.Context, id int) error
+	// MarkEmailFailed records a failed attempt and retries after retryIn, or
+	// gives up on the email when retryIn is zero
+	MarkEmailFailed(ctx context.Context, id int, retryIn time.Duration, reason string) error
+}
+
+// SessionStore tracks refresh token families. Each login starts a family;
+// every refresh rotates to a new token in the same family.
+type SessionStore interface {
+	CreateSession(ctx context.Context, familyID string, userID int, tokenHash string, ttl time.Duration) error
+	// RotateSession exchanges a refresh token for a new one in the same
+	// family. Presenting a token that was already rotated revokes the family
+	// and returns ErrRefreshTokenReused.
+	RotateSession(ctx context.Context, oldHash, newHash string, ttl time.Duration) (familyID string, userID int, err error)
+	RevokeSession(ctx context.Context, familyID string) error
+	IsSessionActive(ctx context.Context, familyID string) (bool, error)
+}
+
+type FolderStore interface {
+	GetFolder(ctx context.Context, slug string) (*models.Folder, error)
+	ListFolders(ctx context.Context, publicOnly bool) ([]models.Folder, error)
+	CreateFolder(ctx context.Context, folder *models.Folder) error
+	// UpdateFolder replaces a folder by ID; renaming the slug moves its images
+	UpdateFolder(ctx context.Context, folder *models.Folder) error
+	// DeleteFolder returns ErrInUse while the folder still holds images
+	DeleteFolder(ctx context.Context, id int) error
+}
+
+// ImageFile locates the stored bytes of an original image
+type ImageFile struct {
+	ID          int
+	Folder      string
+	Key         string // empty for rows whose bytes are still in image_data
+	ContentType string
+	Width       int
+	Hash        string
+	CreatedAt   time.Time
+}
+
+// VariantFile locates a resized rendition of an image
+type VariantFile struct {
+	ID          int
+	Variant     string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Key         string
+	Size        int
+	Hash        string
+	CreatedAt   time.Time
+}
+
+// NewImage is an original image already written to the storage backend
+type NewImage struct {
+	Folder      string
+	Filename    string
+	Key         string
+	Size        int
+	ContentType string
+	Hash        string
+}
+
+// ImageStore records gallery images and their variants. The bytes themselves
+// live in a storage.Backend; see SaveImage.
+type ImageStore interface {
+	ListImages(ctx context.Context, folder string, opts ListOptions) ([]models.GalleryImage, int, error)
+	GetImageFile(ctx context.Context, id int) (*ImageFile, error)
+	// ImageAltTexts maps the IDs of the images that exist to their alt text,
+	// for content that embeds them. With publicOnly, images in folders that
+	// are not public are left out.
+	ImageAltTexts(ctx context.Context, ids []int, publicOnly bool) (map[int]string, error)
+	ListVariants(ctx context.Context, imageID int) ([]VariantFile, error)
+	// LegacyImageData returns bytes of images stored before the storage backend
+	LegacyImageData(ctx context.Context, id int) ([]byte, error)
+	// SaveContentHash records the hash of an image, or of one of its variants
+	// when variantID is non-zero
+	SaveContentHash(ctx context.Context, imageID, variantID int, hash string) error
+
+	CreateImage(ctx context.Context, image *NewImage) (int, error)
+	// SaveImageMetadata records the EXIF fields of an image and marks it sanitized
+	SaveImageMetadata(ctx context.Context, id int, meta *imaging.Metadata) error
+	// SaveVariants records an image's dimensions and upserts its variants
+	SaveVariants(ctx context.Context, imageID, width, height int, variants []VariantFile) error
+
+	UpdateImage(ctx context.Context, id int, update models.GalleryImageUpdate) (*models.GalleryImage, error)
+	// ReorderImages sets the display order of a folder in one transaction.
+	// The listed images come first; any left out keep their relative order.
+	ReorderImages(ctx context.Context, folder string, ids []int) error
+
+	// DeleteImage removes an image and returns the storage keys it used
+	DeleteImage(ctx context.Context, id int) ([]string, error)
+	DeleteAllImages(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS gallery_images;
+DROP TABLE IF EXISTS contact_submissions;
+DROP TABLE IF EXISTS car_build_entries;
+DROP TABLE IF EXISTS resume_sections;
+DROP TABLE IF EXISTS about_content;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,16 @@
+-- Refresh token sessions. Every refresh token gets its own row; rows that
+-- descend from the same login share a family_id, which is also the "sid"
+-- claim carried by access tokens.
+CREATE TABLE IF NOT EXISTS sessions (
+    id SERIAL PRIMARY KEY,
+    family_id VARCHAR(64) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_family ON sessions(family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
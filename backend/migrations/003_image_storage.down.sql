@@ -0,0 +1,5 @@
+-- Images whose bytes only live in the storage backend cannot be moved back
+-- into image_data here, so this fails while any exist.
+ALTER TABLE gallery_images ALTER COLUMN image_data SET NOT NULL;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS size_bytes;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS storage_key;
@@ -0,0 +1,6 @@
+-- Image bytes move out of the database into a storage backend. Rows that
+-- still carry image_data are served from it until `server migrate-images`
+-- copies them out.
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS storage_key VARCHAR(500);
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS size_bytes BIGINT;
+ALTER TABLE gallery_images ALTER COLUMN image_data DROP NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS gallery_image_variants;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS height;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS width;
@@ -0,0 +1,20 @@
+-- Original image dimensions
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS width INTEGER;
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS height INTEGER;
+
+-- Resized renditions generated from each stored image
+CREATE TABLE IF NOT EXISTS gallery_image_variants (
+    id SERIAL PRIMARY KEY,
+    image_id INTEGER NOT NULL REFERENCES gallery_images(id) ON DELETE CASCADE,
+    variant VARCHAR(50) NOT NULL, -- thumbnail, medium, large
+    format VARCHAR(20) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    storage_key VARCHAR(500) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (image_id, variant, format)
+);
+
+CREATE INDEX IF NOT EXISTS idx_gallery_image_variants_image ON gallery_image_variants(image_id);
@@ -0,0 +1,9 @@
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS sanitized_at;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS focal_length;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS iso;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS f_number;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS exposure_time;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS lens_model;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS camera_model;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS camera_make;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS taken_at;
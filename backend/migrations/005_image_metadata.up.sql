@@ -0,0 +1,11 @@
+-- Camera metadata extracted from EXIF before it is stripped from served bytes
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS taken_at TIMESTAMP;
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS camera_make VARCHAR(100);
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS camera_model VARCHAR(100);
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS lens_model VARCHAR(255);
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS exposure_time VARCHAR(20);
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS f_number REAL;
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS iso INTEGER;
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS focal_length REAL;
+-- Set once EXIF has been stripped; images stored earlier are cleaned by `server sanitize-images`
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS sanitized_at TIMESTAMP;
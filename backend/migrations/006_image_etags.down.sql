@@ -0,0 +1,2 @@
+ALTER TABLE gallery_image_variants DROP COLUMN IF EXISTS content_hash;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS content_hash;
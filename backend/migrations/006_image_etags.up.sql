@@ -0,0 +1,3 @@
+-- SHA-256 of the stored bytes, used as the ETag when serving images
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+ALTER TABLE gallery_image_variants ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
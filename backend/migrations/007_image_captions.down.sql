@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_gallery_images_folder_order;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS alt_text;
+ALTER TABLE gallery_images DROP COLUMN IF EXISTS caption;
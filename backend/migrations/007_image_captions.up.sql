@@ -0,0 +1,6 @@
+-- Captions and alt text for gallery images
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS caption TEXT;
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS alt_text VARCHAR(500);
+ALTER TABLE gallery_images ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_gallery_images_folder_order ON gallery_images(folder, display_order);
@@ -0,0 +1,2 @@
+ALTER TABLE gallery_images DROP CONSTRAINT IF EXISTS gallery_images_folder_fkey;
+DROP TABLE IF EXISTS folders;
@@ -0,0 +1,32 @@
+-- Image folders (albums), previously a hardcoded list in the handlers
+CREATE TABLE IF NOT EXISTS folders (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(50) UNIQUE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    cover_image_id INTEGER REFERENCES gallery_images(id) ON DELETE SET NULL,
+    visibility VARCHAR(20) NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'private')),
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO folders (slug, title, sort_order) VALUES
+    ('gallery', 'Gallery', 0),
+    ('about', 'About', 1),
+    ('carbuild', 'Car Build', 2),
+    ('hero', 'Hero', 3)
+ON CONFLICT (slug) DO NOTHING;
+
+-- Keep any images stored under a folder outside the original four
+INSERT INTO folders (slug, title, sort_order)
+SELECT DISTINCT folder, folder, 100 FROM gallery_images
+ON CONFLICT (slug) DO NOTHING;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'gallery_images_folder_fkey') THEN
+        ALTER TABLE gallery_images ADD CONSTRAINT gallery_images_folder_fkey
+            FOREIGN KEY (folder) REFERENCES folders(slug) ON UPDATE CASCADE;
+    END IF;
+END $$;
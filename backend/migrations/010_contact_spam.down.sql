@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_contact_submissions_spam;
+ALTER TABLE contact_submissions DROP COLUMN IF EXISTS spam_reason;
+ALTER TABLE contact_submissions DROP COLUMN IF EXISTS is_spam;
@@ -0,0 +1,5 @@
+-- Submissions flagged by the contact form spam checks stay out of the inbox
+ALTER TABLE contact_submissions ADD COLUMN IF NOT EXISTS is_spam BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE contact_submissions ADD COLUMN IF NOT EXISTS spam_reason VARCHAR(255);
+
+CREATE INDEX IF NOT EXISTS idx_contact_submissions_spam ON contact_submissions(is_spam, created_at DESC);
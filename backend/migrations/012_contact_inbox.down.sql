@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS contact_replies;
+DROP INDEX IF EXISTS idx_contact_submissions_unread;
+DROP INDEX IF EXISTS idx_contact_submissions_search;
+ALTER TABLE contact_submissions DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE contact_submissions DROP COLUMN IF EXISTS is_starred;
+ALTER TABLE contact_submissions DROP COLUMN IF EXISTS is_archived;
@@ -0,0 +1,23 @@
+-- Inbox state, full-text search and reply notes for contact submissions
+ALTER TABLE contact_submissions ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE contact_submissions ADD COLUMN IF NOT EXISTS is_starred BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE contact_submissions ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(name, '') || ' ' || coalesce(email, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(subject, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(message, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_contact_submissions_search ON contact_submissions USING GIN(search_vector);
+CREATE INDEX IF NOT EXISTS idx_contact_submissions_unread ON contact_submissions(created_at)
+    WHERE NOT is_read AND NOT is_spam AND NOT is_archived;
+
+CREATE TABLE IF NOT EXISTS contact_replies (
+    id SERIAL PRIMARY KEY,
+    submission_id INTEGER NOT NULL REFERENCES contact_submissions(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    note TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_replies_submission ON contact_replies(submission_id, created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE car_build_entries DROP COLUMN IF EXISTS version;
+ALTER TABLE resume_sections DROP COLUMN IF EXISTS version;
+ALTER TABLE about_content DROP COLUMN IF EXISTS version;
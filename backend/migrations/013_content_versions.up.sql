@@ -0,0 +1,5 @@
+-- Version counters for optimistic concurrency: every update bumps the
+-- version, and clients send the version they edited in If-Match
+ALTER TABLE about_content ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE resume_sections ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE car_build_entries ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS content_revisions;
+ALTER TABLE car_build_entries DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE resume_sections DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE about_content DROP COLUMN IF EXISTS deleted_at;
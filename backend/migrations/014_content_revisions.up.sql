@@ -0,0 +1,18 @@
+-- Revision history and soft delete for about content, resume sections and
+-- car build entries. Every write stores a snapshot of the record as it was
+-- afterwards, keyed by the record's version.
+ALTER TABLE about_content ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE resume_sections ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE car_build_entries ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS content_revisions (
+    id SERIAL PRIMARY KEY,
+    content_type VARCHAR(20) NOT NULL, -- about, resume, carbuild
+    content_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL, -- create, update, delete, restore
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (content_type, content_id, version)
+);
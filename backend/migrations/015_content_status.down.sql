@@ -0,0 +1,6 @@
+ALTER TABLE car_build_entries DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE car_build_entries DROP COLUMN IF EXISTS status;
+ALTER TABLE resume_sections DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE resume_sections DROP COLUMN IF EXISTS status;
+ALTER TABLE about_content DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE about_content DROP COLUMN IF EXISTS status;
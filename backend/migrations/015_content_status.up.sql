@@ -0,0 +1,20 @@
+-- Publishing workflow for about content, resume sections and car build
+-- entries. Existing content stays published; new content starts as a draft.
+-- Scheduled content is public once publish_at has passed.
+ALTER TABLE about_content ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE about_content ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+ALTER TABLE resume_sections ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE resume_sections ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+ALTER TABLE car_build_entries ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE car_build_entries ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+
+UPDATE about_content SET publish_at = created_at WHERE publish_at IS NULL;
+UPDATE resume_sections SET publish_at = created_at WHERE publish_at IS NULL;
+UPDATE car_build_entries SET publish_at = created_at WHERE publish_at IS NULL;
+
+ALTER TABLE about_content ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE resume_sections ALTER COLUMN status SET DEFAULT 'draft';
+ALTER TABLE car_build_entries ALTER COLUMN status SET DEFAULT 'draft';
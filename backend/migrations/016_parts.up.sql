@@ -0,0 +1,20 @@
+-- Parts bought for the car build. Each may be linked to the build entry it
+-- went into; spend is price times quantity, leaving out returned parts.
+CREATE TABLE IF NOT EXISTS parts (
+    id SERIAL PRIMARY KEY,
+    entry_id INTEGER REFERENCES car_build_entries(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    brand VARCHAR(100),
+    part_number VARCHAR(100),
+    vendor VARCHAR(100),
+    category VARCHAR(100),
+    price DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (price >= 0),
+    quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    purchase_date DATE,
+    status VARCHAR(20) NOT NULL DEFAULT 'ordered' CHECK (status IN ('ordered', 'installed', 'returned')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_parts_entry_id ON parts(entry_id);
+CREATE INDEX IF NOT EXISTS idx_parts_purchase_date ON parts(purchase_date);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS maintenance_schedules;
+DROP INDEX IF EXISTS idx_car_build_entries_mileage;
+ALTER TABLE car_build_entries DROP COLUMN IF EXISTS mileage;
@@ -0,0 +1,23 @@
+-- Odometer readings on build entries, and the maintenance that falls due
+-- every so many miles or months. The latest entry with a mileage is taken
+-- as the car's current mileage.
+ALTER TABLE car_build_entries ADD COLUMN IF NOT EXISTS mileage INTEGER CHECK (mileage >= 0);
+
+CREATE INDEX IF NOT EXISTS idx_car_build_entries_mileage ON car_build_entries(date DESC, id DESC)
+    WHERE mileage IS NOT NULL AND deleted_at IS NULL;
+
+-- notified_status is the status a reminder was last sent for, so each item
+-- is only notified once per change
+CREATE TABLE IF NOT EXISTS maintenance_schedules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    interval_miles INTEGER CHECK (interval_miles > 0),
+    interval_months INTEGER CHECK (interval_months > 0),
+    last_done_mileage INTEGER CHECK (last_done_mileage >= 0),
+    last_done_at DATE,
+    notified_status VARCHAR(20) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (interval_miles IS NOT NULL OR interval_months IS NOT NULL)
+);
@@ -0,0 +1 @@
+-- Nothing to undo: PNGs sanitized again stay sanitized
@@ -0,0 +1,2 @@
+-- PNG text and eXIf chunks were not stripped before; have `server sanitize-images` clean stored PNGs again
+UPDATE gallery_images SET sanitized_at = NULL WHERE content_type = 'image/png' AND storage_key IS NOT NULL;
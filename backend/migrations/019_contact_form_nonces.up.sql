@@ -0,0 +1,8 @@
+-- Nonces of contact form tokens that have been submitted, so each token is
+-- only accepted once. Rows are dropped once the token would have expired.
+CREATE TABLE IF NOT EXISTS contact_form_nonces (
+    nonce VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_contact_form_nonces_expires ON contact_form_nonces(expires_at);
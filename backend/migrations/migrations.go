@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL migration files so the server binary
+// always carries the schema it expects.
+//
+// Each migration is a pair of files named NNN_name.up.sql and
+// NNN_name.down.sql, applied in order of NNN.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
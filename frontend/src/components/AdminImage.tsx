@@ -0,0 +1,35 @@
+import { useState, useEffect } from 'react';
+import { galleryService } from '../services/api';
+
+interface AdminImageProps {
+  url: string;
+  width: number;
+  alt: string;
+  style?: React.CSSProperties;
+}
+
+// AdminImage shows a gallery image fetched with the admin's token, so images
+// in private folders load too
+export default function AdminImage({ url, width, alt, style }: AdminImageProps) {
+  const [src, setSrc] = useState<string>();
+
+  useEffect(() => {
+    let objectURL: string | undefined;
+    let cancelled = false;
+    galleryService
+      .image(url, width)
+      .then((response) => {
+        if (cancelled) return;
+        objectURL = URL.createObjectURL(response.data);
+        setSrc(objectURL);
+      })
+      .catch((err) => console.error('Error loading image:', err));
+
+    return () => {
+      cancelled = true;
+      if (objectURL) URL.revokeObjectURL(objectURL);
+    };
+  }, [url, width]);
+
+  return <img src={src} alt={alt} style={style} />;
+}
@@ -1,4 +1,5 @@
 import { useState, useEffect } from 'react';
+import { imageSrcSet } from '../utils/imageLoader';
 import { useCarousel } from '../context/CarouselContext';
 
 interface ImageCarouselProps {
@@ -86,7 +87,9 @@ export default function ImageCarousel({ folder, interval = 5000, children }: Ima
               }}
             >
               <img
-                src={image}
+                src={`${image}?w=960`}
+                srcSet={imageSrcSet(image)}
+                sizes="100vw"
                 alt=""
                 style={{
                   width: '100%',
@@ -148,7 +151,9 @@ export default function ImageCarousel({ folder, interval = 5000, children }: Ima
           }}
         >
           <img
-            src={image}
+            src={`${image}?w=960`}
+            srcSet={imageSrcSet(image)}
+            sizes="100vw"
             alt=""
             style={{
               width: '100%',
@@ -1,11 +1,13 @@
 import { useState, useEffect } from 'react';
+import { imageSrcSet } from '../utils/imageLoader';
+import type { GalleryImage, GalleryImageList } from '../types';
 
 interface ImageGalleryProps {
   folder: string;
 }
 
 export default function ImageGallery({ folder }: ImageGalleryProps) {
-  const [images, setImages] = useState<string[]>([]);
+  const [images, setImages] = useState<GalleryImage[]>([]);
 
   useEffect(() => {
     loadImages();
@@ -13,13 +15,13 @@ export default function ImageGallery({ folder }: ImageGalleryProps) {
 
   const loadImages = async () => {
     try {
-      const response = await fetch(`/api/images?folder=${folder}`);
-      const imagePaths = await response.json();
+      const response = await fetch(`/api/images?folder=${folder}&v=2`);
+      const list: GalleryImageList = await response.json();
       
       console.log('Gallery loading images from folder:', folder);
-      console.log('Gallery found images:', imagePaths);
+      console.log('Gallery found images:', list.images);
       
-      setImages(imagePaths || []);
+      setImages(list.images || []);
     } catch (error) {
       console.error('Error loading images:', error);
       setImages([]);
@@ -32,27 +34,37 @@ export default function ImageGallery({ folder }: ImageGalleryProps) {
 
   return (
     <div style={{ display: 'flex', flexDirection: 'column', gap: '2rem' }}>
-      {images.map((image, idx) => (
-        <div 
-          key={idx}
+      {images.map((image) => (
+        <figure 
+          key={image.id}
           style={{ 
             width: '100%',
             borderRadius: '12px',
             overflow: 'hidden',
             boxShadow: '0 4px 12px rgba(0, 0, 0, 0.3)',
-            border: '1px solid #262626'
+            border: '1px solid #262626',
+            margin: 0
           }}
         >
           <img 
-            src={image} 
-            alt=""
+            src={`${image.url}?w=960`}
+            srcSet={imageSrcSet(image.url)}
+            sizes="100vw"
+            alt={image.alt_text}
+            width={image.width}
+            height={image.height}
             style={{ 
               width: '100%',
               height: 'auto',
               display: 'block'
             }}
           />
-        </div>
+          {image.caption && (
+            <figcaption style={{ padding: '0.75rem 1rem', color: '#a3a3a3', fontSize: '0.9rem' }}>
+              {image.caption}
+            </figcaption>
+          )}
+        </figure>
       ))}
     </div>
   );
@@ -1,14 +1,24 @@
+import { useEffect, useState } from 'react';
 import { Link, useNavigate, useLocation } from 'react-router-dom';
-import { authService } from '../services/api';
+import { authService, contactService } from '../services/api';
 
 export default function Navbar() {
   const navigate = useNavigate();
   const location = useLocation();
   const isAuthenticated = authService.isAuthenticated();
   const isAdmin = authService.isAdmin();
+  const [unread, setUnread] = useState(0);
 
-  const handleLogout = () => {
-    authService.logout();
+  useEffect(() => {
+    if (!isAdmin) return;
+    contactService
+      .unreadCount()
+      .then((response) => setUnread(response.data.unread))
+      .catch((err) => console.error('Error loading unread count:', err));
+  }, [isAdmin, location.pathname]);
+
+  const handleLogout = async () => {
+    await authService.logout();
     navigate('/login');
   };
 
@@ -40,7 +50,10 @@ export default function Navbar() {
             <>
               {isAdmin && (
                 <li>
-                  <Link to="/admin" className={isActive('/admin')}>Admin</Link>
+                  <Link to="/admin" className={isActive('/admin')}>
+                    Admin
+                    {unread > 0 && <span className="badge" title={`${unread} unread messages`}>{unread}</span>}
+                  </Link>
                 </li>
               )}
               <li>
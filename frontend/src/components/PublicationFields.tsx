@@ -0,0 +1,53 @@
+import type { ContentStatus } from '../types';
+
+export interface PublicationForm {
+  status: ContentStatus;
+  // Local date and time from a datetime-local input
+  publish_at: string;
+}
+
+export const emptyPublication: PublicationForm = { status: 'draft', publish_at: '' };
+
+// toPublicationForm converts a record's publishing state for the form inputs
+export const toPublicationForm = (item: { status: ContentStatus; publish_at?: string }): PublicationForm => {
+  if (!item.publish_at) {
+    return { status: item.status, publish_at: '' };
+  }
+  const date = new Date(item.publish_at);
+  const local = new Date(date.getTime() - date.getTimezoneOffset() * 60000);
+  return { status: item.status, publish_at: local.toISOString().slice(0, 16) };
+};
+
+// publishAtValue converts the form input to the API's timestamp format
+export const publishAtValue = (form: PublicationForm) =>
+  form.publish_at ? new Date(form.publish_at).toISOString() : null;
+
+export default function PublicationFields({ value, onChange }: { value: PublicationForm; onChange: (value: PublicationForm) => void }) {
+  return (
+    <div style={{ display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '1rem' }}>
+      <div className="form-group">
+        <label htmlFor="status">Status</label>
+        <select
+          id="status"
+          value={value.status}
+          onChange={(e) => onChange({ ...value, status: e.target.value as ContentStatus })}
+        >
+          <option value="draft">Draft</option>
+          <option value="scheduled">Scheduled</option>
+          <option value="published">Published</option>
+          <option value="archived">Archived</option>
+        </select>
+      </div>
+      <div className="form-group">
+        <label htmlFor="publish_at">Publish At{value.status === 'scheduled' && ' *'}</label>
+        <input
+          type="datetime-local"
+          id="publish_at"
+          value={value.publish_at}
+          onChange={(e) => onChange({ ...value, publish_at: e.target.value })}
+          required={value.status === 'scheduled'}
+        />
+      </div>
+    </div>
+  );
+}
@@ -146,7 +146,8 @@ export default function About() {
                   />
                 )}
                 <h2>{item.title}</h2>
-                <p>{item.content}</p>
+                {/* The API sanitizes the HTML it renders from Markdown */}
+                <div className="markdown" dangerouslySetInnerHTML={{ __html: item.content_html ?? '' }} />
               </div>
             ))
           )}
@@ -1,22 +1,36 @@
 import { useState, useEffect } from 'react';
+import AdminImage from '../components/AdminImage';
 import ImageCarousel from '../components/ImageCarousel';
-import { aboutService, resumeService, carBuildService, contactService, galleryService } from '../services/api';
-import type { AboutContent, ResumeSection, CarBuildEntry, ContactSubmission } from '../types';
+import PublicationFields, { emptyPublication, publishAtValue, toPublicationForm } from '../components/PublicationFields';
+import { aboutService, resumeService, carBuildService, contactService, errorMessage, galleryService, maintenanceService, partService, previewService, trashService } from '../services/api';
+import type { AboutContent, ResumeSection, CarBuildEntry, ContactFilter, ContactSubmission, ContactSubmissionUpdate, ContentType, Folder, GalleryImage, MaintenanceDue, MaintenanceReport, Part, PartStatus, PreviewLink, TrashItem } from '../types';
+
+const CONTACT_PAGE_SIZE = 20;
 
 export default function Admin() {
-  const [activeTab, setActiveTab] = useState<'about' | 'resume' | 'carbuild' | 'contact' | 'images'>('about');
+  const [activeTab, setActiveTab] = useState<'about' | 'resume' | 'carbuild' | 'parts' | 'maintenance' | 'contact' | 'images' | 'trash'>('about');
   const [aboutItems, setAboutItems] = useState<AboutContent[]>([]);
   const [resumeItems, setResumeItems] = useState<ResumeSection[]>([]);
   const [carBuildItems, setCarBuildItems] = useState<CarBuildEntry[]>([]);
+  const [partItems, setPartItems] = useState<Part[]>([]);
+  const [maintenanceReport, setMaintenanceReport] = useState<MaintenanceReport | null>(null);
   const [contactItems, setContactItems] = useState<ContactSubmission[]>([]);
-  const [galleryImages, setGalleryImages] = useState<any[]>([]);
+  const [contactFilter, setContactFilter] = useState<ContactFilter>('inbox');
+  const [contactSearch, setContactSearch] = useState('');
+  const [contactPage, setContactPage] = useState(1);
+  const [contactTotal, setContactTotal] = useState(0);
+  const [replyNotes, setReplyNotes] = useState<Record<number, string>>({});
+  const [galleryImages, setGalleryImages] = useState<GalleryImage[]>([]);
+  const [trashItems, setTrashItems] = useState<TrashItem[]>([]);
+  const [folders, setFolders] = useState<Folder[]>([]);
   const [selectedFolder, setSelectedFolder] = useState('gallery');
   const [uploadFiles, setUploadFiles] = useState<File[]>([]);
   const [uploadMessage, setUploadMessage] = useState('');
 
   // About form state
-  const [aboutForm, setAboutForm] = useState({ title: '', content: '', image_url: '' });
+  const [aboutForm, setAboutForm] = useState({ title: '', content: '', image_url: '', ...emptyPublication });
   const [editingAboutId, setEditingAboutId] = useState<number | null>(null);
+  const [editingAboutVersion, setEditingAboutVersion] = useState<number | undefined>();
 
   // Resume form state
   const [resumeForm, setResumeForm] = useState({
@@ -27,8 +41,10 @@ export default function Admin() {
     start_date: '',
     end_date: '',
     display_order: 0,
+    ...emptyPublication,
   });
   const [editingResumeId, setEditingResumeId] = useState<number | null>(null);
+  const [editingResumeVersion, setEditingResumeVersion] = useState<number | undefined>();
 
   // CarBuild form state
   const [carBuildForm, setCarBuildForm] = useState({
@@ -37,52 +53,129 @@ export default function Admin() {
     date: new Date().toISOString().split('T')[0],
     category: '',
     cost: '',
+    mileage: '',
     image_urls: '',
     display_order: 0,
+    ...emptyPublication,
   });
   const [editingCarBuildId, setEditingCarBuildId] = useState<number | null>(null);
+  const [editingCarBuildVersion, setEditingCarBuildVersion] = useState<number | undefined>();
+
+  // Part form state
+  const emptyPartForm = {
+    name: '',
+    brand: '',
+    part_number: '',
+    vendor: '',
+    category: '',
+    price: '',
+    quantity: 1,
+    purchase_date: '',
+    status: 'ordered' as PartStatus,
+    entry_id: '',
+  };
+  const [partForm, setPartForm] = useState(emptyPartForm);
+  const [editingPartId, setEditingPartId] = useState<number | null>(null);
+
+  // Maintenance form state; blank last done fields default to the latest
+  // mileage and today
+  const emptyMaintenanceForm = {
+    name: '',
+    description: '',
+    interval_miles: '',
+    interval_months: '',
+    last_done_mileage: '',
+    last_done_at: '',
+  };
+  const [maintenanceForm, setMaintenanceForm] = useState(emptyMaintenanceForm);
+  const [editingMaintenanceId, setEditingMaintenanceId] = useState<number | null>(null);
 
   useEffect(() => {
     loadData();
-  }, [activeTab, selectedFolder]);
+  }, [activeTab, selectedFolder, contactFilter, contactPage]);
 
   const loadData = async () => {
     try {
       if (activeTab === 'about') {
-        const response = await aboutService.getAll();
+        const response = await aboutService.listAll();
         setAboutItems(response.data || []);
       } else if (activeTab === 'resume') {
-        const response = await resumeService.getAll();
+        const response = await resumeService.listAll();
         setResumeItems(response.data || []);
       } else if (activeTab === 'carbuild') {
-        const response = await carBuildService.getAll();
+        const response = await carBuildService.listAll();
         setCarBuildItems(response.data || []);
+      } else if (activeTab === 'parts') {
+        const [partsResponse, entriesResponse] = await Promise.all([partService.getAll(), carBuildService.listAll()]);
+        setPartItems(partsResponse.data || []);
+        setCarBuildItems(entriesResponse.data || []);
+      } else if (activeTab === 'maintenance') {
+        const response = await maintenanceService.report();
+        setMaintenanceReport(response.data);
       } else if (activeTab === 'contact') {
-        const response = await contactService.getAll();
+        const response = await contactService.getAll({
+          filter: contactFilter,
+          q: contactSearch || undefined,
+          page: contactPage,
+          per_page: CONTACT_PAGE_SIZE,
+        });
         setContactItems(response.data || []);
+        setContactTotal(Number(response.headers['x-total-count'] || 0));
       } else if (activeTab === 'images') {
-        const response = await galleryService.list(selectedFolder);
-        setGalleryImages(response.data || []);
+        const [imagesResponse, foldersResponse] = await Promise.all([
+          galleryService.list(selectedFolder),
+          galleryService.folders(),
+        ]);
+        setGalleryImages(imagesResponse.data?.images || []);
+        setFolders(foldersResponse.data || []);
+      } else if (activeTab === 'trash') {
+        const response = await trashService.list();
+        setTrashItems(response.data || []);
       }
     } catch (err) {
       console.error('Error loading data:', err);
     }
   };
 
+  const handlePreview = async (type: ContentType, id: number) => {
+    try {
+      const response = await previewService.create(type, id);
+      const link: PreviewLink = response.data;
+      const url = new URL(link.url, window.location.origin).toString();
+      window.prompt(`Preview link, valid until ${new Date(link.expires_at).toLocaleString()}:`, url);
+    } catch (err) {
+      console.error('Error creating preview link:', err);
+      alert(errorMessage(err, 'Could not create a preview link.'));
+    }
+  };
+
+  // Trash handlers
+  const handleTrashRestore = async (item: TrashItem) => {
+    try {
+      await trashService.restore(item.content_type, item.id);
+      loadData();
+    } catch (err) {
+      console.error('Error restoring from trash:', err);
+      alert(errorMessage(err, 'Could not restore the item.'));
+    }
+  };
+
   // About handlers
   const handleAboutSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     try {
+      const data = { ...aboutForm, publish_at: publishAtValue(aboutForm) };
       if (editingAboutId) {
-        await aboutService.update(editingAboutId, aboutForm);
+        await aboutService.update(editingAboutId, data, editingAboutVersion);
         setEditingAboutId(null);
       } else {
-        await aboutService.create(aboutForm);
+        await aboutService.create(data);
       }
-      setAboutForm({ title: '', content: '', image_url: '' });
+      setAboutForm({ title: '', content: '', image_url: '', ...emptyPublication });
       loadData();
     } catch (err) {
       console.error('Error submitting about:', err);
+      alert(errorMessage(err, 'Could not save the content.'));
     }
   };
 
@@ -102,8 +195,10 @@ export default function Admin() {
       title: item.title,
       content: item.content,
       image_url: item.image_url || '',
+      ...toPublicationForm(item),
     });
     setEditingAboutId(item.id);
+    setEditingAboutVersion(item.version);
   };
 
   // Resume handlers
@@ -114,9 +209,10 @@ export default function Admin() {
         ...resumeForm,
         start_date: resumeForm.start_date || null,
         end_date: resumeForm.end_date || null,
+        publish_at: publishAtValue(resumeForm),
       };
       if (editingResumeId) {
-        await resumeService.update(editingResumeId, data);
+        await resumeService.update(editingResumeId, data, editingResumeVersion);
         setEditingResumeId(null);
       } else {
         await resumeService.create(data);
@@ -129,10 +225,12 @@ export default function Admin() {
         start_date: '',
         end_date: '',
         display_order: 0,
+        ...emptyPublication,
       });
       loadData();
     } catch (err) {
       console.error('Error submitting resume:', err);
+      alert(errorMessage(err, 'Could not save the resume section.'));
     }
   };
 
@@ -149,15 +247,17 @@ export default function Admin() {
 
   const handleResumeEdit = (item: ResumeSection) => {
     setResumeForm({
-      section_type: item.section_type,
+      section_type: item.section_type.toLowerCase(),
       title: item.title,
       subtitle: item.subtitle || '',
       description: item.description || '',
       start_date: item.start_date ? new Date(item.start_date).toISOString().split('T')[0] : '',
       end_date: item.end_date ? new Date(item.end_date).toISOString().split('T')[0] : '',
       display_order: item.display_order,
+      ...toPublicationForm(item),
     });
     setEditingResumeId(item.id);
+    setEditingResumeVersion(item.version);
   };
 
   // CarBuild handlers
@@ -172,11 +272,13 @@ export default function Admin() {
       const data = {
         ...carBuildForm,
         cost: carBuildForm.cost ? parseFloat(carBuildForm.cost) : null,
+        mileage: carBuildForm.mileage ? parseInt(carBuildForm.mileage) : null,
         image_urls: imageUrls,
+        publish_at: publishAtValue(carBuildForm),
       };
 
       if (editingCarBuildId) {
-        await carBuildService.update(editingCarBuildId, data);
+        await carBuildService.update(editingCarBuildId, data, editingCarBuildVersion);
         setEditingCarBuildId(null);
       } else {
         await carBuildService.create(data);
@@ -187,12 +289,15 @@ export default function Admin() {
         date: new Date().toISOString().split('T')[0],
         category: '',
         cost: '',
+        mileage: '',
         image_urls: '',
         display_order: 0,
+        ...emptyPublication,
       });
       loadData();
     } catch (err) {
       console.error('Error submitting car build:', err);
+      alert(errorMessage(err, 'Could not save the car build entry.'));
     }
   };
 
@@ -214,10 +319,123 @@ export default function Admin() {
       date: new Date(item.date).toISOString().split('T')[0],
       category: item.category || '',
       cost: item.cost ? item.cost.toString() : '',
+      mileage: item.mileage != null ? item.mileage.toString() : '',
       image_urls: (item.image_urls || []).join(', '),
       display_order: item.display_order,
+      ...toPublicationForm(item),
     });
     setEditingCarBuildId(item.id);
+    setEditingCarBuildVersion(item.version);
+  };
+
+  // Part handlers
+  const handlePartSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    try {
+      const data = {
+        ...partForm,
+        price: partForm.price ? parseFloat(partForm.price) : 0,
+        purchase_date: partForm.purchase_date ? new Date(partForm.purchase_date).toISOString() : null,
+        entry_id: partForm.entry_id ? Number(partForm.entry_id) : null,
+      };
+      if (editingPartId) {
+        await partService.update(editingPartId, data);
+        setEditingPartId(null);
+      } else {
+        await partService.create(data);
+      }
+      setPartForm(emptyPartForm);
+      loadData();
+    } catch (err) {
+      console.error('Error submitting part:', err);
+      alert(errorMessage(err, 'Could not save the part.'));
+    }
+  };
+
+  const handlePartDelete = async (id: number) => {
+    if (confirm('Are you sure?')) {
+      try {
+        await partService.delete(id);
+        loadData();
+      } catch (err) {
+        console.error('Error deleting part:', err);
+      }
+    }
+  };
+
+  const handlePartEdit = (item: Part) => {
+    setPartForm({
+      name: item.name,
+      brand: item.brand || '',
+      part_number: item.part_number || '',
+      vendor: item.vendor || '',
+      category: item.category || '',
+      price: item.price.toString(),
+      quantity: item.quantity,
+      purchase_date: item.purchase_date ? item.purchase_date.split('T')[0] : '',
+      status: item.status,
+      entry_id: item.entry_id ? item.entry_id.toString() : '',
+    });
+    setEditingPartId(item.id);
+  };
+
+  // Maintenance handlers
+  const handleMaintenanceSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    const number = (value: string) => (value ? parseInt(value) : null);
+    try {
+      const data = {
+        ...maintenanceForm,
+        interval_miles: number(maintenanceForm.interval_miles),
+        interval_months: number(maintenanceForm.interval_months),
+        last_done_mileage: number(maintenanceForm.last_done_mileage),
+        last_done_at: maintenanceForm.last_done_at ? new Date(maintenanceForm.last_done_at).toISOString() : null,
+      };
+      if (editingMaintenanceId) {
+        await maintenanceService.update(editingMaintenanceId, data);
+        setEditingMaintenanceId(null);
+      } else {
+        await maintenanceService.create(data);
+      }
+      setMaintenanceForm(emptyMaintenanceForm);
+      loadData();
+    } catch (err) {
+      console.error('Error submitting maintenance:', err);
+      alert(errorMessage(err, 'Could not save the maintenance schedule.'));
+    }
+  };
+
+  const handleMaintenanceDone = async (id: number) => {
+    try {
+      await maintenanceService.done(id);
+      loadData();
+    } catch (err) {
+      console.error('Error completing maintenance:', err);
+      alert(errorMessage(err, 'Could not mark the job done.'));
+    }
+  };
+
+  const handleMaintenanceDelete = async (id: number) => {
+    if (confirm('Are you sure?')) {
+      try {
+        await maintenanceService.delete(id);
+        loadData();
+      } catch (err) {
+        console.error('Error deleting maintenance:', err);
+      }
+    }
+  };
+
+  const handleMaintenanceEdit = (item: MaintenanceDue) => {
+    setMaintenanceForm({
+      name: item.name,
+      description: item.description || '',
+      interval_miles: item.interval_miles?.toString() || '',
+      interval_months: item.interval_months?.toString() || '',
+      last_done_mileage: item.last_done_mileage?.toString() || '',
+      last_done_at: item.last_done_at ? item.last_done_at.split('T')[0] : '',
+    });
+    setEditingMaintenanceId(item.id);
   };
 
   // Contact handlers
@@ -232,6 +450,36 @@ export default function Admin() {
     }
   };
 
+  const handleContactUpdate = async (id: number, update: ContactSubmissionUpdate) => {
+    try {
+      await contactService.update(id, update);
+      loadData();
+    } catch (err) {
+      console.error('Error updating contact:', err);
+    }
+  };
+
+  const handleContactSearch = (e: React.FormEvent) => {
+    e.preventDefault();
+    if (contactPage === 1) {
+      loadData();
+    } else {
+      setContactPage(1);
+    }
+  };
+
+  const handleContactReply = async (id: number) => {
+    const note = (replyNotes[id] || '').trim();
+    if (!note) return;
+    try {
+      await contactService.addReply(id, note);
+      setReplyNotes({ ...replyNotes, [id]: '' });
+      loadData();
+    } catch (err) {
+      console.error('Error saving reply note:', err);
+    }
+  };
+
   // Gallery handlers
   const handleImageUpload = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -254,12 +502,7 @@ export default function Admin() {
     }
   };
 
-  const handleImageDelete = async (imageUrl: string) => {
-    // Extract ID from URL like "/api/image/123"
-    const match = imageUrl.match(/\/api\/image\/(\d+)/);
-    if (!match) return;
-    
-    const id = parseInt(match[1]);
+  const handleImageDelete = async (id: number) => {
     if (confirm('Are you sure you want to delete this image?')) {
       try {
         await galleryService.delete(id);
@@ -270,6 +513,42 @@ export default function Admin() {
     }
   };
 
+  const handleImageUpdate = async (id: number, data: { caption?: string; alt_text?: string }) => {
+    try {
+      const response = await galleryService.update(id, data);
+      setGalleryImages((images) => images.map((image) => (image.id === id ? response.data : image)));
+    } catch (err) {
+      console.error('Error updating image:', err);
+    }
+  };
+
+  const handleImageMove = async (index: number, offset: number) => {
+    const target = index + offset;
+    if (target < 0 || target >= galleryImages.length) return;
+    const reordered = [...galleryImages];
+    [reordered[index], reordered[target]] = [reordered[target], reordered[index]];
+    setGalleryImages(reordered);
+    try {
+      const response = await galleryService.reorder(selectedFolder, reordered.map((image) => image.id));
+      setGalleryImages(response.data?.images || reordered);
+    } catch (err) {
+      console.error('Error reordering images:', err);
+      loadData();
+    }
+  };
+
+  const handleFolderCreate = async () => {
+    const title = prompt('Folder title');
+    if (!title) return;
+    const slug = title.toLowerCase().replace(/[^a-z0-9]+/g, '-').replace(/^-+|-+$/g, '');
+    try {
+      await galleryService.createFolder({ slug, title });
+      setSelectedFolder(slug);
+    } catch (err) {
+      console.error('Error creating folder:', err);
+    }
+  };
+
   const handleFileChange = (e: React.ChangeEvent<HTMLInputElement>) => {
     if (e.target.files) {
       setUploadFiles(Array.from(e.target.files));
@@ -288,7 +567,7 @@ export default function Admin() {
 
           {/* Tab Navigation */}
           <div style={{ display: 'flex', gap: '1rem', marginBottom: '2rem', borderBottom: '2px solid #262626', paddingBottom: '1rem' }}>
-            {(['about', 'resume', 'carbuild', 'contact', 'images'] as const).map((tab) => (
+            {(['about', 'resume', 'carbuild', 'parts', 'maintenance', 'contact', 'images', 'trash'] as const).map((tab) => (
               <button
                 key={tab}
                 onClick={() => setActiveTab(tab)}
@@ -333,6 +612,7 @@ export default function Admin() {
                       rows={6}
                       required
                     />
+                    <small style={{ color: '#999' }}>Markdown supported. Embed gallery images with ![alt](image:ID).</small>
                   </div>
                   <div className="form-group">
                     <label htmlFor="image_url">Image URL</label>
@@ -343,6 +623,10 @@ export default function Admin() {
                       onChange={(e) => setAboutForm({ ...aboutForm, image_url: e.target.value })}
                     />
                   </div>
+                  <PublicationFields
+                    value={aboutForm}
+                    onChange={(publication) => setAboutForm({ ...aboutForm, ...publication })}
+                  />
                   <button type="submit" style={{ marginRight: '0.5rem' }}>
                     {editingAboutId ? 'Update' : 'Create'}
                   </button>
@@ -351,7 +635,7 @@ export default function Admin() {
                       type="button"
                       onClick={() => {
                         setEditingAboutId(null);
-                        setAboutForm({ title: '', content: '', image_url: '' });
+                        setAboutForm({ title: '', content: '', image_url: '', ...emptyPublication });
                       }}
                       style={{ background: '#404040' }}
                     >
@@ -365,12 +649,20 @@ export default function Admin() {
                 <h3 style={{ color: '#fff', marginBottom: '1rem' }}>About Content ({aboutItems.length})</h3>
                 {aboutItems.map((item) => (
                   <div key={item.id} className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)', marginBottom: '1rem' }}>
-                    <h4>{item.title}</h4>
+                    <h4>
+                      {item.title}{' '}
+                      <span style={{ color: '#a3a3a3', fontSize: '0.8rem', textTransform: 'capitalize' }}>{item.status}</span>
+                    </h4>
                     <p style={{ color: '#a3a3a3', marginBottom: '1rem' }}>{item.content.substring(0, 100)}...</p>
                     <div style={{ display: 'flex', gap: '0.5rem' }}>
                       <button onClick={() => handleAboutEdit(item)} style={{ background: '#1f2937', flex: 1 }}>
                         Edit
                       </button>
+                      {item.status !== 'published' && (
+                        <button onClick={() => handlePreview('about', item.id)} style={{ background: '#1f2937', flex: 1 }}>
+                          Preview
+                        </button>
+                      )}
                       <button onClick={() => handleAboutDelete(item.id)} className="danger" style={{ flex: 1 }}>
                         Delete
                       </button>
@@ -389,14 +681,19 @@ export default function Admin() {
                 <form onSubmit={handleResumeSubmit}>
                   <div className="form-group">
                     <label htmlFor="section_type">Section Type *</label>
-                    <input
-                      type="text"
+                    <select
                       id="section_type"
-                      placeholder="e.g., Experience, Education, Skills"
                       value={resumeForm.section_type}
                       onChange={(e) => setResumeForm({ ...resumeForm, section_type: e.target.value })}
                       required
-                    />
+                    >
+                      <option value="">Choose a type</option>
+                      <option value="experience">Experience</option>
+                      <option value="education">Education</option>
+                      <option value="skills">Skills</option>
+                      <option value="projects">Projects</option>
+                      <option value="certifications">Certifications</option>
+                    </select>
                   </div>
                   <div className="form-group">
                     <label htmlFor="title">Title *</label>
@@ -425,6 +722,7 @@ export default function Admin() {
                       onChange={(e) => setResumeForm({ ...resumeForm, description: e.target.value })}
                       rows={4}
                     />
+                    <small style={{ color: '#999' }}>Markdown supported. Embed gallery images with ![alt](image:ID).</small>
                   </div>
                   <div style={{ display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '1rem' }}>
                     <div className="form-group">
@@ -455,6 +753,10 @@ export default function Admin() {
                       onChange={(e) => setResumeForm({ ...resumeForm, display_order: parseInt(e.target.value) })}
                     />
                   </div>
+                  <PublicationFields
+                    value={resumeForm}
+                    onChange={(publication) => setResumeForm({ ...resumeForm, ...publication })}
+                  />
                   <button type="submit" style={{ marginRight: '0.5rem' }}>
                     {editingResumeId ? 'Update' : 'Create'}
                   </button>
@@ -471,6 +773,7 @@ export default function Admin() {
                           start_date: '',
                           end_date: '',
                           display_order: 0,
+                          ...emptyPublication,
                         });
                       }}
                       style={{ background: '#404040' }}
@@ -485,7 +788,10 @@ export default function Admin() {
                 <h3 style={{ color: '#fff', marginBottom: '1rem' }}>Resume Sections ({resumeItems.length})</h3>
                 {resumeItems.map((item) => (
                   <div key={item.id} className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)', marginBottom: '1rem' }}>
-                    <h4>{item.title}</h4>
+                    <h4>
+                      {item.title}{' '}
+                      <span style={{ color: '#a3a3a3', fontSize: '0.8rem', textTransform: 'capitalize' }}>{item.status}</span>
+                    </h4>
                     <p style={{ color: '#a3a3a3', fontSize: '0.9rem', marginBottom: '1rem' }}>
                       {item.section_type} {item.subtitle && `• ${item.subtitle}`}
                     </p>
@@ -493,6 +799,11 @@ export default function Admin() {
                       <button onClick={() => handleResumeEdit(item)} style={{ background: '#1f2937', flex: 1 }}>
                         Edit
                       </button>
+                      {item.status !== 'published' && (
+                        <button onClick={() => handlePreview('resume', item.id)} style={{ background: '#1f2937', flex: 1 }}>
+                          Preview
+                        </button>
+                      )}
                       <button onClick={() => handleResumeDelete(item.id)} className="danger" style={{ flex: 1 }}>
                         Delete
                       </button>
@@ -528,6 +839,7 @@ export default function Admin() {
                       rows={6}
                       required
                     />
+                    <small style={{ color: '#999' }}>Markdown supported. Embed gallery images with ![alt](image:ID).</small>
                   </div>
                   <div style={{ display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '1rem' }}>
                     <div className="form-group">
@@ -550,15 +862,28 @@ export default function Admin() {
                       />
                     </div>
                   </div>
-                  <div className="form-group">
-                    <label htmlFor="cost">Cost ($)</label>
-                    <input
-                      type="number"
-                      id="cost"
-                      step="0.01"
-                      value={carBuildForm.cost}
-                      onChange={(e) => setCarBuildForm({ ...carBuildForm, cost: e.target.value })}
-                    />
+                  <div style={{ display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '1rem' }}>
+                    <div className="form-group">
+                      <label htmlFor="cost">Cost ($)</label>
+                      <input
+                        type="number"
+                        id="cost"
+                        step="0.01"
+                        value={carBuildForm.cost}
+                        onChange={(e) => setCarBuildForm({ ...carBuildForm, cost: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="mileage">Mileage</label>
+                      <input
+                        type="number"
+                        id="mileage"
+                        min="0"
+                        value={carBuildForm.mileage}
+                        onChange={(e) => setCarBuildForm({ ...carBuildForm, mileage: e.target.value })}
+                        placeholder="Odometer reading"
+                      />
+                    </div>
                   </div>
                   <div className="form-group">
                     <label htmlFor="image_urls">Image URLs (comma-separated)</label>
@@ -579,6 +904,10 @@ export default function Admin() {
                       onChange={(e) => setCarBuildForm({ ...carBuildForm, display_order: parseInt(e.target.value) })}
                     />
                   </div>
+                  <PublicationFields
+                    value={carBuildForm}
+                    onChange={(publication) => setCarBuildForm({ ...carBuildForm, ...publication })}
+                  />
                   <button type="submit" style={{ marginRight: '0.5rem' }}>
                     {editingCarBuildId ? 'Update' : 'Create'}
                   </button>
@@ -593,8 +922,10 @@ export default function Admin() {
                           date: new Date().toISOString().split('T')[0],
                           category: '',
                           cost: '',
+                          mileage: '',
                           image_urls: '',
                           display_order: 0,
+                          ...emptyPublication,
                         });
                       }}
                       style={{ background: '#404040' }}
@@ -609,15 +940,23 @@ export default function Admin() {
                 <h3 style={{ color: '#fff', marginBottom: '1rem' }}>Car Build Entries ({carBuildItems.length})</h3>
                 {carBuildItems.map((item) => (
                   <div key={item.id} className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)', marginBottom: '1rem' }}>
-                    <h4>{item.title}</h4>
+                    <h4>
+                      {item.title}{' '}
+                      <span style={{ color: '#a3a3a3', fontSize: '0.8rem', textTransform: 'capitalize' }}>{item.status}</span>
+                    </h4>
                     <p style={{ color: '#a3a3a3', fontSize: '0.9rem', marginBottom: '1rem' }}>
-                      {new Date(item.date).toLocaleDateString()} {item.category && `• ${item.category}`} {item.cost && `• $${item.cost}`}
+                      {new Date(item.date).toLocaleDateString()} {item.category && `• ${item.category}`} {item.cost && `• $${item.cost}`} {item.mileage != null && `• ${item.mileage.toLocaleString()} mi`}
                     </p>
                     <p style={{ color: '#d4d4d4', marginBottom: '1rem' }}>{item.description.substring(0, 100)}...</p>
                     <div style={{ display: 'flex', gap: '0.5rem' }}>
                       <button onClick={() => handleCarBuildEdit(item)} style={{ background: '#1f2937', flex: 1 }}>
                         Edit
                       </button>
+                      {item.status !== 'published' && (
+                        <button onClick={() => handlePreview('carbuild', item.id)} style={{ background: '#1f2937', flex: 1 }}>
+                          Preview
+                        </button>
+                      )}
                       <button onClick={() => handleCarBuildDelete(item.id)} className="danger" style={{ flex: 1 }}>
                         Delete
                       </button>
@@ -628,10 +967,322 @@ export default function Admin() {
             </div>
           )}
 
+          {/* Parts Tab */}
+          {activeTab === 'parts' && (
+            <div>
+              <div className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)' }}>
+                <h2>{editingPartId ? 'Edit' : 'Add'} Part</h2>
+                <form onSubmit={handlePartSubmit}>
+                  <div className="form-group">
+                    <label htmlFor="part_name">Name *</label>
+                    <input
+                      type="text"
+                      id="part_name"
+                      value={partForm.name}
+                      onChange={(e) => setPartForm({ ...partForm, name: e.target.value })}
+                      required
+                    />
+                  </div>
+                  <div style={{ display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '1rem' }}>
+                    <div className="form-group">
+                      <label htmlFor="part_brand">Brand</label>
+                      <input
+                        type="text"
+                        id="part_brand"
+                        value={partForm.brand}
+                        onChange={(e) => setPartForm({ ...partForm, brand: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="part_number">Part Number</label>
+                      <input
+                        type="text"
+                        id="part_number"
+                        value={partForm.part_number}
+                        onChange={(e) => setPartForm({ ...partForm, part_number: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="part_vendor">Vendor</label>
+                      <input
+                        type="text"
+                        id="part_vendor"
+                        value={partForm.vendor}
+                        onChange={(e) => setPartForm({ ...partForm, vendor: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="part_category">Category</label>
+                      <input
+                        type="text"
+                        id="part_category"
+                        value={partForm.category}
+                        onChange={(e) => setPartForm({ ...partForm, category: e.target.value })}
+                        placeholder="Defaults to the build entry's"
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="part_price">Unit Price ($)</label>
+                      <input
+                        type="number"
+                        id="part_price"
+                        step="0.01"
+                        min="0"
+                        value={partForm.price}
+                        onChange={(e) => setPartForm({ ...partForm, price: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="part_quantity">Quantity</label>
+                      <input
+                        type="number"
+                        id="part_quantity"
+                        min="1"
+                        value={partForm.quantity}
+                        onChange={(e) => setPartForm({ ...partForm, quantity: parseInt(e.target.value) || 1 })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="part_purchase_date">Purchase Date</label>
+                      <input
+                        type="date"
+                        id="part_purchase_date"
+                        value={partForm.purchase_date}
+                        onChange={(e) => setPartForm({ ...partForm, purchase_date: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="part_status">Status</label>
+                      <select
+                        id="part_status"
+                        value={partForm.status}
+                        onChange={(e) => setPartForm({ ...partForm, status: e.target.value as PartStatus })}
+                      >
+                        <option value="ordered">Ordered</option>
+                        <option value="installed">Installed</option>
+                        <option value="returned">Returned</option>
+                      </select>
+                    </div>
+                  </div>
+                  <div className="form-group">
+                    <label htmlFor="part_entry">Build Entry</label>
+                    <select
+                      id="part_entry"
+                      value={partForm.entry_id}
+                      onChange={(e) => setPartForm({ ...partForm, entry_id: e.target.value })}
+                    >
+                      <option value="">None</option>
+                      {carBuildItems.map((entry) => (
+                        <option key={entry.id} value={entry.id}>{entry.title}</option>
+                      ))}
+                    </select>
+                  </div>
+                  <button type="submit" style={{ marginRight: '0.5rem' }}>
+                    {editingPartId ? 'Update' : 'Create'}
+                  </button>
+                  {editingPartId && (
+                    <button
+                      type="button"
+                      onClick={() => {
+                        setEditingPartId(null);
+                        setPartForm(emptyPartForm);
+                      }}
+                      style={{ background: '#404040' }}
+                    >
+                      Cancel
+                    </button>
+                  )}
+                </form>
+              </div>
+
+              <div style={{ marginTop: '2rem' }}>
+                <h3 style={{ color: '#fff', marginBottom: '1rem' }}>Parts ({partItems.length})</h3>
+                {partItems.map((item) => (
+                  <div key={item.id} className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)', marginBottom: '1rem' }}>
+                    <h4>
+                      {item.name}{' '}
+                      <span style={{ color: '#a3a3a3', fontSize: '0.8rem', textTransform: 'capitalize' }}>{item.status}</span>
+                    </h4>
+                    <p style={{ color: '#a3a3a3', fontSize: '0.9rem', marginBottom: '1rem' }}>
+                      {[item.brand, item.part_number, item.vendor, item.category].filter(Boolean).join(' • ')}
+                      {' • '}
+                      {item.quantity} × ${item.price.toFixed(2)}
+                      {item.purchase_date && ` • ${new Date(item.purchase_date).toLocaleDateString('en-US', { timeZone: 'UTC' })}`}
+                    </p>
+                    <div style={{ display: 'flex', gap: '0.5rem' }}>
+                      <button onClick={() => handlePartEdit(item)} style={{ background: '#1f2937', flex: 1 }}>
+                        Edit
+                      </button>
+                      <button onClick={() => handlePartDelete(item.id)} className="danger" style={{ flex: 1 }}>
+                        Delete
+                      </button>
+                    </div>
+                  </div>
+                ))}
+              </div>
+            </div>
+          )}
+
+          {/* Maintenance Tab */}
+          {activeTab === 'maintenance' && (
+            <div>
+              <div className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)' }}>
+                <h2>{editingMaintenanceId ? 'Edit' : 'Add'} Maintenance</h2>
+                <form onSubmit={handleMaintenanceSubmit}>
+                  <div className="form-group">
+                    <label htmlFor="maintenance_name">Name *</label>
+                    <input
+                      type="text"
+                      id="maintenance_name"
+                      value={maintenanceForm.name}
+                      onChange={(e) => setMaintenanceForm({ ...maintenanceForm, name: e.target.value })}
+                      placeholder="Oil change"
+                      required
+                    />
+                  </div>
+                  <div className="form-group">
+                    <label htmlFor="maintenance_description">Description</label>
+                    <textarea
+                      id="maintenance_description"
+                      value={maintenanceForm.description}
+                      onChange={(e) => setMaintenanceForm({ ...maintenanceForm, description: e.target.value })}
+                      rows={2}
+                    />
+                  </div>
+                  <div style={{ display: 'grid', gridTemplateColumns: '1fr 1fr', gap: '1rem' }}>
+                    <div className="form-group">
+                      <label htmlFor="maintenance_interval_miles">Every (miles)</label>
+                      <input
+                        type="number"
+                        id="maintenance_interval_miles"
+                        min="1"
+                        value={maintenanceForm.interval_miles}
+                        onChange={(e) => setMaintenanceForm({ ...maintenanceForm, interval_miles: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="maintenance_interval_months">Every (months)</label>
+                      <input
+                        type="number"
+                        id="maintenance_interval_months"
+                        min="1"
+                        value={maintenanceForm.interval_months}
+                        onChange={(e) => setMaintenanceForm({ ...maintenanceForm, interval_months: e.target.value })}
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="maintenance_last_done_mileage">Last Done At (miles)</label>
+                      <input
+                        type="number"
+                        id="maintenance_last_done_mileage"
+                        min="0"
+                        value={maintenanceForm.last_done_mileage}
+                        onChange={(e) => setMaintenanceForm({ ...maintenanceForm, last_done_mileage: e.target.value })}
+                        placeholder="Latest mileage"
+                      />
+                    </div>
+                    <div className="form-group">
+                      <label htmlFor="maintenance_last_done_at">Last Done On</label>
+                      <input
+                        type="date"
+                        id="maintenance_last_done_at"
+                        value={maintenanceForm.last_done_at}
+                        onChange={(e) => setMaintenanceForm({ ...maintenanceForm, last_done_at: e.target.value })}
+                      />
+                    </div>
+                  </div>
+                  <button type="submit" style={{ marginRight: '0.5rem' }}>
+                    {editingMaintenanceId ? 'Update' : 'Create'}
+                  </button>
+                  {editingMaintenanceId && (
+                    <button
+                      type="button"
+                      onClick={() => {
+                        setEditingMaintenanceId(null);
+                        setMaintenanceForm(emptyMaintenanceForm);
+                      }}
+                      style={{ background: '#404040' }}
+                    >
+                      Cancel
+                    </button>
+                  )}
+                </form>
+              </div>
+
+              <div style={{ marginTop: '2rem' }}>
+                <h3 style={{ color: '#fff', marginBottom: '1rem' }}>Schedule ({maintenanceReport?.items.length || 0})</h3>
+                <p style={{ color: '#a3a3a3', marginBottom: '1rem' }}>
+                  {maintenanceReport?.mileage
+                    ? `Latest mileage: ${maintenanceReport.mileage.mileage.toLocaleString()} mi on ${new Date(maintenanceReport.mileage.date).toLocaleDateString('en-US', { timeZone: 'UTC' })}`
+                    : 'No mileage recorded yet. Add one to a car build entry.'}
+                </p>
+                {maintenanceReport?.items.map((item) => (
+                  <div key={item.id} className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)', marginBottom: '1rem' }}>
+                    <h4>
+                      {item.name}{' '}
+                      <span
+                        style={{
+                          color: item.status === 'overdue' ? '#dc2626' : item.status === 'upcoming' ? '#f59e0b' : '#a3a3a3',
+                          fontSize: '0.8rem',
+                          textTransform: 'capitalize',
+                        }}
+                      >
+                        {item.status}
+                      </span>
+                    </h4>
+                    <p style={{ color: '#a3a3a3', fontSize: '0.9rem', marginBottom: '1rem' }}>
+                      {[
+                        item.interval_miles && `Every ${item.interval_miles.toLocaleString()} mi`,
+                        item.interval_months && `Every ${item.interval_months} months`,
+                        item.due_mileage != null && `Due at ${item.due_mileage.toLocaleString()} mi`,
+                        item.due_date && `Due by ${new Date(item.due_date).toLocaleDateString('en-US', { timeZone: 'UTC' })}`,
+                      ]
+                        .filter(Boolean)
+                        .join(' • ')}
+                    </p>
+                    <div style={{ display: 'flex', gap: '0.5rem' }}>
+                      <button onClick={() => handleMaintenanceDone(item.id)} style={{ flex: 1 }}>
+                        Mark Done
+                      </button>
+                      <button onClick={() => handleMaintenanceEdit(item)} style={{ background: '#1f2937', flex: 1 }}>
+                        Edit
+                      </button>
+                      <button onClick={() => handleMaintenanceDelete(item.id)} className="danger" style={{ flex: 1 }}>
+                        Delete
+                      </button>
+                    </div>
+                  </div>
+                ))}
+              </div>
+            </div>
+          )}
+
           {/* Contact Tab */}
           {activeTab === 'contact' && (
             <div>
-              <h3 style={{ color: '#fff', marginBottom: '1rem' }}>Contact Submissions ({contactItems.length})</h3>
+              <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', marginBottom: '1rem' }}>
+                <h3 style={{ color: '#fff' }}>Contact Submissions ({contactTotal})</h3>
+                <form onSubmit={handleContactSearch} style={{ display: 'flex', gap: '0.5rem' }}>
+                  <input
+                    type="search"
+                    placeholder="Search messages"
+                    value={contactSearch}
+                    onChange={(e) => setContactSearch(e.target.value)}
+                  />
+                  <select
+                    value={contactFilter}
+                    onChange={(e) => {
+                      setContactFilter(e.target.value as ContactFilter);
+                      setContactPage(1);
+                    }}
+                  >
+                    <option value="inbox">Inbox</option>
+                    <option value="archived">Archived</option>
+                    <option value="spam">Spam</option>
+                    <option value="all">All</option>
+                  </select>
+                </form>
+              </div>
               {contactItems.length === 0 ? (
                 <div className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)' }}>
                   <p style={{ color: '#a3a3a3' }}>No contact submissions yet.</p>
@@ -659,14 +1310,67 @@ export default function Admin() {
                         {item.is_read ? 'Read' : 'Unread'}
                       </span>
                     </div>
+                    {item.is_spam && (
+                      <p style={{ color: '#f59e0b', fontSize: '0.85rem', marginBottom: '0.5rem' }}>
+                        Spam: {item.spam_reason}
+                      </p>
+                    )}
                     {item.subject && <h5 style={{ marginBottom: '0.5rem' }}>Subject: {item.subject}</h5>}
                     <p style={{ color: '#d4d4d4', marginBottom: '1rem', whiteSpace: 'pre-wrap' }}>{item.message}</p>
-                    <button onClick={() => handleContactDelete(item.id)} className="danger" style={{ width: '100%' }}>
-                      Delete
-                    </button>
+                    {item.reply_count > 0 && item.last_replied_at && (
+                      <p style={{ color: '#a3a3a3', fontSize: '0.85rem', marginBottom: '0.5rem' }}>
+                        {item.reply_count} reply note{item.reply_count === 1 ? '' : 's'}, last {new Date(item.last_replied_at).toLocaleString()}
+                      </p>
+                    )}
+                    <div style={{ display: 'flex', gap: '0.5rem', marginBottom: '0.5rem' }}>
+                      <input
+                        type="text"
+                        placeholder="Reply note"
+                        value={replyNotes[item.id] || ''}
+                        onChange={(e) => setReplyNotes({ ...replyNotes, [item.id]: e.target.value })}
+                        style={{ flex: 1 }}
+                      />
+                      <button onClick={() => handleContactReply(item.id)} className="secondary">
+                        Add Note
+                      </button>
+                    </div>
+                    <div style={{ display: 'flex', gap: '0.5rem', flexWrap: 'wrap' }}>
+                      <button onClick={() => handleContactUpdate(item.id, { is_read: !item.is_read })} className="secondary">
+                        Mark {item.is_read ? 'Unread' : 'Read'}
+                      </button>
+                      <button onClick={() => handleContactUpdate(item.id, { is_starred: !item.is_starred })} className="secondary">
+                        {item.is_starred ? 'Unstar' : 'Star'}
+                      </button>
+                      <button onClick={() => handleContactUpdate(item.id, { is_archived: !item.is_archived })} className="secondary">
+                        {item.is_archived ? 'Unarchive' : 'Archive'}
+                      </button>
+                      <button onClick={() => handleContactUpdate(item.id, { is_spam: !item.is_spam })} className="secondary">
+                        {item.is_spam ? 'Not Spam' : 'Spam'}
+                      </button>
+                      <button onClick={() => handleContactDelete(item.id)} className="danger">
+                        Delete
+                      </button>
+                    </div>
                   </div>
                 ))
               )}
+              {contactTotal > CONTACT_PAGE_SIZE && (
+                <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center' }}>
+                  <button onClick={() => setContactPage(contactPage - 1)} disabled={contactPage === 1} className="secondary">
+                    Previous
+                  </button>
+                  <span style={{ color: '#a3a3a3' }}>
+                    Page {contactPage} of {Math.ceil(contactTotal / CONTACT_PAGE_SIZE)}
+                  </span>
+                  <button
+                    onClick={() => setContactPage(contactPage + 1)}
+                    disabled={contactPage * CONTACT_PAGE_SIZE >= contactTotal}
+                    className="secondary"
+                  >
+                    Next
+                  </button>
+                </div>
+              )}
             </div>
           )}
 
@@ -695,11 +1399,15 @@ export default function Admin() {
                       fontSize: '1rem',
                     }}
                   >
-                    <option value="gallery">Gallery</option>
-                    <option value="about">About</option>
-                    <option value="carbuild">Car Build</option>
-                    <option value="hero">Hero</option>
+                    {folders.map((folder) => (
+                      <option key={folder.id} value={folder.slug}>
+                        {folder.title}{folder.visibility === 'private' ? ' (private)' : ''}
+                      </option>
+                    ))}
                   </select>
+                  <button type="button" onClick={handleFolderCreate} style={{ marginTop: '0.5rem' }}>
+                    New Folder
+                  </button>
                 </div>
 
                 {/* Upload Form */}
@@ -766,9 +1474,9 @@ export default function Admin() {
                     gridTemplateColumns: 'repeat(auto-fill, minmax(200px, 1fr))',
                     gap: '1rem',
                   }}>
-                    {galleryImages.map((imageUrl, index) => (
+                    {galleryImages.map((image, index) => (
                       <div
-                        key={index}
+                        key={image.id}
                         className="card"
                         style={{
                           background: 'rgba(26, 26, 26, 0.95)',
@@ -776,9 +1484,10 @@ export default function Admin() {
                           padding: '0.5rem',
                         }}
                       >
-                        <img
-                          src={`${import.meta.env.VITE_API_URL || ''}${imageUrl}`}
-                          alt={`Gallery image ${index + 1}`}
+                        <AdminImage
+                          url={image.url}
+                          width={320}
+                          alt={image.alt_text || `Gallery image ${index + 1}`}
                           style={{
                             width: '100%',
                             height: '150px',
@@ -787,8 +1496,49 @@ export default function Admin() {
                             marginBottom: '0.5rem',
                           }}
                         />
+                        {image.camera_model && (
+                          <p style={{ color: '#a3a3a3', fontSize: '0.8rem', margin: '0 0 0.5rem' }}>
+                            {[
+                              image.camera_model,
+                              image.focal_length && `${image.focal_length}mm`,
+                              image.f_number && `f/${image.f_number}`,
+                              image.exposure_time && `${image.exposure_time}s`,
+                              image.iso && `ISO ${image.iso}`,
+                            ].filter(Boolean).join(' · ')}
+                          </p>
+                        )}
+                        <input
+                          type="text"
+                          placeholder="Alt text"
+                          defaultValue={image.alt_text}
+                          onBlur={(e) => e.target.value !== image.alt_text && handleImageUpdate(image.id, { alt_text: e.target.value })}
+                          style={{ width: '100%', marginBottom: '0.5rem' }}
+                        />
+                        <input
+                          type="text"
+                          placeholder="Caption"
+                          defaultValue={image.caption}
+                          onBlur={(e) => e.target.value !== image.caption && handleImageUpdate(image.id, { caption: e.target.value })}
+                          style={{ width: '100%', marginBottom: '0.5rem' }}
+                        />
+                        <div style={{ display: 'flex', gap: '0.5rem', marginBottom: '0.5rem' }}>
+                          <button
+                            onClick={() => handleImageMove(index, -1)}
+                            disabled={index === 0}
+                            style={{ flex: 1, padding: '0.25rem' }}
+                          >
+                            ←
+                          </button>
+                          <button
+                            onClick={() => handleImageMove(index, 1)}
+                            disabled={index === galleryImages.length - 1}
+                            style={{ flex: 1, padding: '0.25rem' }}
+                          >
+                            →
+                          </button>
+                        </div>
                         <button
-                          onClick={() => handleImageDelete(imageUrl)}
+                          onClick={() => handleImageDelete(image.id)}
                           className="danger"
                           style={{ width: '100%', padding: '0.5rem', fontSize: '0.9rem' }}
                         >
@@ -801,6 +1551,26 @@ export default function Admin() {
               </div>
             </div>
           )}
+
+          {/* Trash Tab */}
+          {activeTab === 'trash' && (
+            <div>
+              <h3 style={{ color: '#fff', marginBottom: '1rem' }}>Deleted Items ({trashItems.length})</h3>
+              {trashItems.length === 0 && <p style={{ color: '#a3a3a3' }}>The trash is empty.</p>}
+              {trashItems.map((item) => (
+                <div key={`${item.content_type}-${item.id}`} className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)', marginBottom: '1rem' }}>
+                  <h4>{item.title}</h4>
+                  <p style={{ color: '#a3a3a3', fontSize: '0.9rem', marginBottom: '1rem' }}>
+                    {item.content_type === 'carbuild' ? 'Car Build' : item.content_type.charAt(0).toUpperCase() + item.content_type.slice(1)}
+                    {' • '}deleted {new Date(item.deleted_at).toLocaleString()}
+                  </p>
+                  <button onClick={() => handleTrashRestore(item)} style={{ background: '#1f2937' }}>
+                    Restore
+                  </button>
+                </div>
+              ))}
+            </div>
+          )}
         </div>
       </div>
     </ImageCarousel>
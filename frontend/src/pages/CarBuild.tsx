@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react';
-import { carBuildService } from '../services/api';
-import type { CarBuildEntry } from '../types';
+import { carBuildService, partService } from '../services/api';
+import type { CarBuildEntry, PartSpend, SpendGroup } from '../types';
 import ImageGallery from '../components/ImageGallery';
 import ImageCarousel from '../components/ImageCarousel';
 
@@ -8,11 +8,36 @@ export default function CarBuild() {
   const [entries, setEntries] = useState<CarBuildEntry[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState('');
+  const [spend, setSpend] = useState<PartSpend[]>([]);
 
   useEffect(() => {
     loadEntries();
+    loadSpend();
   }, []);
 
+  // The budget is optional; the page works without it
+  const loadSpend = async () => {
+    try {
+      const groups: SpendGroup[] = ['category', 'month', 'vendor'];
+      const responses = await Promise.all(groups.map((group) => partService.spend(group)));
+      setSpend(responses.map((response) => response.data));
+    } catch (err) {
+      console.error('Error loading the parts budget:', err);
+    }
+  };
+
+  const formatMoney = (amount: number) =>
+    amount.toLocaleString('en-US', { style: 'currency', currency: 'USD' });
+
+  const spendLabel = (group: SpendGroup, key: string) => {
+    if (!key) return group === 'month' ? 'Undated' : 'Other';
+    if (group === 'month') {
+      const [year, month] = key.split('-').map(Number);
+      return new Date(year, month - 1).toLocaleDateString('en-US', { month: 'short', year: 'numeric' });
+    }
+    return key;
+  };
+
   const loadEntries = async () => {
     try {
       const response = await carBuildService.getAll();
@@ -46,6 +71,27 @@ export default function CarBuild() {
             <p>Follow my automotive build journey</p>
           </div>
 
+          {spend.length > 0 && spend[0].total > 0 && (
+            <div className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)', marginBottom: '2rem' }}>
+              <h2>💰 Build Budget</h2>
+              <p className="subtitle">{formatMoney(spend[0].total)} spent on parts so far</p>
+              <div className="grid" style={{ gridTemplateColumns: 'repeat(auto-fit, minmax(220px, 1fr))', marginTop: '1rem' }}>
+                {spend.map((breakdown) => (
+                  <div key={breakdown.group}>
+                    <h3 style={{ textTransform: 'capitalize' }}>By {breakdown.group}</h3>
+                    <ul>
+                      {breakdown.totals.map((total) => (
+                        <li key={total.key}>
+                          {spendLabel(breakdown.group, total.key)}: {formatMoney(total.total)}
+                        </li>
+                      ))}
+                    </ul>
+                  </div>
+                ))}
+              </div>
+            </div>
+          )}
+
           {entries.length === 0 ? (
             <>
               <div className="card" style={{ background: 'rgba(26, 26, 26, 0.95)', backdropFilter: 'blur(10px)' }}>
@@ -174,6 +220,7 @@ export default function CarBuild() {
                     {formatDate(entry.date)}
                     {entry.category && ` • ${entry.category}`}
                     {entry.cost && ` • $${entry.cost.toFixed(2)}`}
+                    {entry.mileage != null && ` • ${entry.mileage.toLocaleString()} mi`}
                   </p>
                   
                   {entry.image_urls && entry.image_urls.length > 0 && (
@@ -189,7 +236,7 @@ export default function CarBuild() {
                     </div>
                   )}
                   
-                  <p>{entry.description}</p>
+                  <div className="markdown" dangerouslySetInnerHTML={{ __html: entry.description_html ?? '' }} />
                 </div>
               </div>
             ))}
@@ -1,5 +1,5 @@
-import { useState } from 'react';
-import { contactService } from '../services/api';
+import { useEffect, useState } from 'react';
+import { contactService, errorMessage } from '../services/api';
 import type { ContactSubmissionForm } from '../types';
 import ImageCarousel from '../components/ImageCarousel';
 
@@ -13,6 +13,22 @@ export default function Contact() {
   const [success, setSuccess] = useState(false);
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
+  // Honeypot and form token for the server's spam checks
+  const [website, setWebsite] = useState('');
+  const [formToken, setFormToken] = useState('');
+
+  const loadFormToken = async () => {
+    try {
+      const response = await contactService.token();
+      setFormToken(response.data.token);
+    } catch (err) {
+      console.error(err);
+    }
+  };
+
+  useEffect(() => {
+    loadFormToken();
+  }, []);
 
   const handleChange = (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement>) => {
     setFormData({
@@ -28,7 +44,7 @@ export default function Contact() {
     setSuccess(false);
 
     try {
-      await contactService.submit(formData);
+      await contactService.submit({ ...formData, website, form_token: formToken });
       setSuccess(true);
       setFormData({
         name: '',
@@ -36,8 +52,9 @@ export default function Contact() {
         subject: '',
         message: '',
       });
+      loadFormToken();
     } catch (err) {
-      setError('Failed to send message. Please try again.');
+      setError(errorMessage(err, 'Failed to send message. Please try again.'));
       console.error(err);
     } finally {
       setLoading(false);
@@ -112,6 +129,19 @@ export default function Contact() {
               />
             </div>
 
+            <div aria-hidden="true" style={{ position: 'absolute', left: '-10000px' }}>
+              <label htmlFor="website">Website</label>
+              <input
+                type="text"
+                id="website"
+                name="website"
+                value={website}
+                onChange={(e) => setWebsite(e.target.value)}
+                tabIndex={-1}
+                autoComplete="off"
+              />
+            </div>
+
             <button type="submit" disabled={loading}>
               {loading ? 'Sending...' : 'Send Message'}
             </button>
@@ -1,6 +1,6 @@
 import { useState } from 'react';
 import { useNavigate } from 'react-router-dom';
-import { authService } from '../services/api';
+import { authService, errorMessage } from '../services/api';
 import type { LoginRequest } from '../types';
 
 export default function Login() {
@@ -27,8 +27,8 @@ export default function Login() {
     try {
       await authService.login(formData);
       navigate('/');
-    } catch (err: any) {
-      setError(err.response?.data || 'Login failed. Please check your credentials.');
+    } catch (err) {
+      setError(errorMessage(err, 'Login failed. Please check your credentials.'));
       console.error(err);
     } finally {
       setLoading(false);
@@ -150,7 +150,9 @@ export default function Resume() {
                           {formatDate(item.start_date)} - {formatDate(item.end_date)}
                         </p>
                       )}
-                      {item.description && <p>{item.description}</p>}
+                      {item.description_html && (
+                        <div className="markdown" dangerouslySetInnerHTML={{ __html: item.description_html }} />
+                      )}
                     </div>
                   ))}
                 </div>
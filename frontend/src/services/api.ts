@@ -1,5 +1,5 @@
 import axios from 'axios';
-import type { LoginRequest, LoginResponse } from '../types';
+import type { ContactQuery, ContactSubmissionUpdate, ContentStatus, ContentType, Folder, GalleryImage, LoginRequest, LoginResponse, PartStatus, Problem, SpendGroup } from '../types';
 
 const API_URL = import.meta.env.VITE_API_URL || '/api';
 
@@ -16,19 +16,90 @@ api.interceptors.request.use((config) => {
   return config;
 });
 
+const storeSession = (data: LoginResponse) => {
+  localStorage.setItem('token', data.token);
+  localStorage.setItem('refresh_token', data.refresh_token);
+  localStorage.setItem('user', JSON.stringify(data.user));
+};
+
+const clearSession = () => {
+  localStorage.removeItem('token');
+  localStorage.removeItem('refresh_token');
+  localStorage.removeItem('user');
+};
+
+// Share a single in-flight refresh between concurrent 401s so the rotated
+// refresh token is only presented once.
+let refreshPromise: Promise<string | null> | null = null;
+
+const refreshAccessToken = (): Promise<string | null> => {
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    return Promise.resolve(null);
+  }
+  if (!refreshPromise) {
+    refreshPromise = axios
+      .post<LoginResponse>(`${API_URL}/token/refresh`, { refresh_token: refreshToken })
+      .then((response) => {
+        storeSession(response.data);
+        return response.data.token;
+      })
+      .catch(() => {
+        clearSession();
+        return null;
+      })
+      .finally(() => {
+        refreshPromise = null;
+      });
+  }
+  return refreshPromise;
+};
+
+// Retry once with a fresh access token when the current one has expired
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config;
+    if (error.response?.status === 401 && original && !original._retry && !original.url?.startsWith('/login')) {
+      original._retry = true;
+      const token = await refreshAccessToken();
+      if (token) {
+        original.headers.Authorization = `Bearer ${token}`;
+        return api(original);
+      }
+    }
+    return Promise.reject(error);
+  }
+);
+
+// Turn a failed request into a message for the user: the field errors of a
+// validation problem, else its detail, else the fallback
+export const errorMessage = (err: unknown, fallback: string): string => {
+  const problem: Problem | undefined = axios.isAxiosError(err) ? err.response?.data : undefined;
+  if (problem?.fields) {
+    return Object.values(problem.fields).join(' ');
+  }
+  return problem?.detail || fallback;
+};
+
 export const authService = {
   login: async (credentials: LoginRequest): Promise<LoginResponse> => {
     const response = await api.post<LoginResponse>('/login', credentials);
     if (response.data.token) {
-      localStorage.setItem('token', response.data.token);
-      localStorage.setItem('user', JSON.stringify(response.data.user));
+      storeSession(response.data);
     }
     return response.data;
   },
 
-  logout: () => {
-    localStorage.removeItem('token');
-    localStorage.removeItem('user');
+  logout: async () => {
+    if (localStorage.getItem('token')) {
+      try {
+        await api.post('/logout');
+      } catch (error) {
+        console.error('Error revoking session:', error);
+      }
+    }
+    clearSession();
   },
 
   getCurrentUser: () => {
@@ -46,30 +117,82 @@ export const authService = {
   },
 };
 
+// ifMatch makes an update conditional on the version that was edited, so a
+// stale form is refused with 412 rather than overwriting newer changes
+const ifMatch = (version?: number) =>
+  version ? { headers: { 'If-Match': `"v${version}"` } } : undefined;
+
 export const aboutService = {
   getAll: () => api.get('/about'),
+  listAll: (status?: ContentStatus) => api.get('/admin/about', { params: { status } }),
   create: (data: any) => api.post('/about', data),
-  update: (id: number, data: any) => api.put(`/about/${id}`, data),
+  update: (id: number, data: any, version?: number) =>
+    api.put(`/about/${id}`, data, ifMatch(version)),
   delete: (id: number) => api.delete(`/about/${id}`),
 };
 
 export const resumeService = {
   getAll: () => api.get('/resume'),
+  listAll: (status?: ContentStatus) => api.get('/admin/resume', { params: { status } }),
   create: (data: any) => api.post('/resume', data),
-  update: (id: number, data: any) => api.put(`/resume/${id}`, data),
+  update: (id: number, data: any, version?: number) =>
+    api.put(`/resume/${id}`, data, ifMatch(version)),
   delete: (id: number) => api.delete(`/resume/${id}`),
 };
 
 export const carBuildService = {
   getAll: () => api.get('/carbuild'),
+  listAll: (status?: ContentStatus) => api.get('/admin/carbuild', { params: { status } }),
   create: (data: any) => api.post('/carbuild', data),
-  update: (id: number, data: any) => api.put(`/carbuild/${id}`, data),
+  update: (id: number, data: any, version?: number) =>
+    api.put(`/carbuild/${id}`, data, ifMatch(version)),
   delete: (id: number) => api.delete(`/carbuild/${id}`),
 };
 
+export const partService = {
+  getAll: (params: { entry_id?: number; status?: PartStatus } = {}) => api.get('/parts', { params }),
+  spend: (group: SpendGroup) => api.get(`/parts/spend/${group}`),
+  create: (data: any) => api.post('/parts', data),
+  update: (id: number, data: any) => api.put(`/parts/${id}`, data),
+  delete: (id: number) => api.delete(`/parts/${id}`),
+};
+
+export const maintenanceService = {
+  report: () => api.get('/maintenance'),
+  due: () => api.get('/maintenance/due'),
+  create: (data: any) => api.post('/maintenance', data),
+  update: (id: number, data: any) => api.put(`/maintenance/${id}`, data),
+  done: (id: number, data: { mileage?: number; date?: string } = {}) => api.post(`/maintenance/${id}/done`, data),
+  delete: (id: number) => api.delete(`/maintenance/${id}`),
+};
+
+export const revisionService = {
+  list: (type: ContentType, id: number, params: { limit?: number; offset?: number } = {}) =>
+    api.get(`/${type}/${id}/revisions`, { params }),
+  get: (type: ContentType, id: number, version: number) => api.get(`/${type}/${id}/revisions/${version}`),
+  diff: (type: ContentType, id: number, from: number, to: number) =>
+    api.get(`/${type}/${id}/revisions/diff`, { params: { from, to } }),
+  restore: (type: ContentType, id: number, version: number, current?: number) =>
+    api.post(`/${type}/${id}/revisions/${version}/restore`, null, ifMatch(current)),
+};
+
+export const previewService = {
+  create: (type: ContentType, id: number) => api.post(`/${type}/${id}/preview`),
+};
+
+export const trashService = {
+  list: () => api.get('/trash'),
+  restore: (type: ContentType, id: number) => api.post(`/trash/${type}/${id}/restore`),
+};
+
 export const contactService = {
+  token: () => api.get('/contact/token'),
   submit: (data: any) => api.post('/contact', data),
-  getAll: () => api.get('/contact'),
+  getAll: (params: ContactQuery = {}) => api.get('/contact', { params }),
+  get: (id: number) => api.get(`/contact/${id}`),
+  update: (id: number, data: ContactSubmissionUpdate) => api.patch(`/contact/${id}`, data),
+  unreadCount: () => api.get('/contact/unread-count'),
+  addReply: (id: number, note: string) => api.post(`/contact/${id}/replies`, { note }),
   delete: (id: number) => api.delete(`/contact/${id}`),
 };
 
@@ -82,8 +205,19 @@ export const galleryService = {
     formData.append('folder', folder);
     return api.post('/gallery/upload', formData);
   },
-  list: (folder: string) => api.get(`/gallery/images?folder=${folder}`),
+  list: (folder: string) => api.get(`/gallery/images?folder=${folder}&v=2`),
+  update: (id: number, data: Partial<Pick<GalleryImage, 'caption' | 'alt_text' | 'folder' | 'display_order'>>) =>
+    api.patch(`/gallery/image/${id}`, data),
+  reorder: (folder: string, imageIds: number[]) =>
+    api.put('/gallery/order', { folder, image_ids: imageIds }),
   delete: (id: number) => api.delete(`/gallery/image/${id}`),
+  folders: () => api.get('/gallery/folders'),
+  createFolder: (data: Partial<Folder>) => api.post('/gallery/folders', data),
+  updateFolder: (id: number, data: Partial<Folder>) => api.put(`/gallery/folders/${id}`, data),
+  deleteFolder: (id: number) => api.delete(`/gallery/folders/${id}`),
+  // Images in private folders need the admin's token, which an <img> can't send
+  image: (url: string, width: number) =>
+    api.get<Blob>(url, { baseURL: import.meta.env.VITE_API_URL || '', params: { w: width }, responseType: 'blob' }),
 };
 
 export default api;
@@ -11,6 +11,9 @@ vi.mock('../services/api', () => ({
     isAdmin: () => false,
     logout: vi.fn(),
   },
+  contactService: {
+    unreadCount: vi.fn(),
+  },
 }));
 
 describe('Navbar Component', () => {
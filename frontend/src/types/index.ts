@@ -7,41 +7,160 @@ export interface User {
   updated_at: string;
 }
 
-export interface AboutContent {
+export type ContentStatus = 'draft' | 'scheduled' | 'published' | 'archived';
+
+// Publishing state shared by about content, resume sections and car build entries
+export interface Publication {
+  status: ContentStatus;
+  publish_at?: string;
+}
+
+export interface AboutContent extends Publication {
   id: number;
   title: string;
+  // Markdown source, and the sanitized HTML the API renders it to
   content: string;
+  content_html?: string;
   image_url?: string;
+  version: number;
   created_at: string;
   updated_at: string;
 }
 
-export interface ResumeSection {
+export interface ResumeSection extends Publication {
   id: number;
   section_type: string;
   title: string;
   subtitle?: string;
   description?: string;
+  description_html?: string;
   start_date?: string;
   end_date?: string;
   display_order: number;
+  version: number;
   created_at: string;
   updated_at: string;
 }
 
-export interface CarBuildEntry {
+export interface CarBuildEntry extends Publication {
   id: number;
   title: string;
   description: string;
+  description_html?: string;
   date: string;
   category?: string;
   cost?: number;
+  mileage?: number;
   image_urls?: string[];
   display_order: number;
+  version: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export type PartStatus = 'ordered' | 'installed' | 'returned';
+
+// A part bought for the car build; price is per unit
+export interface Part {
+  id: number;
+  entry_id?: number;
+  name: string;
+  brand?: string;
+  part_number?: string;
+  vendor?: string;
+  category?: string;
+  price: number;
+  quantity: number;
+  purchase_date?: string;
+  status: PartStatus;
   created_at: string;
   updated_at: string;
 }
 
+export type SpendGroup = 'category' | 'month' | 'vendor';
+
+export interface SpendTotal {
+  key: string;
+  total: number;
+  parts: number;
+}
+
+// Spend on parts, leaving out returned ones, broken down by group
+export interface PartSpend {
+  group: SpendGroup;
+  total: number;
+  totals: SpendTotal[];
+}
+
+export type MaintenanceStatus = 'ok' | 'upcoming' | 'overdue';
+
+// A job that comes due every interval_miles or interval_months, whichever
+// comes first, with when it is next due as of the latest mileage
+export interface MaintenanceDue {
+  id: number;
+  name: string;
+  description?: string;
+  interval_miles?: number;
+  interval_months?: number;
+  last_done_mileage?: number;
+  last_done_at?: string;
+  status: MaintenanceStatus;
+  due_mileage?: number;
+  due_date?: string;
+  miles_remaining?: number;
+  days_remaining?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// The latest odometer reading in the build log
+export interface MileageReading {
+  mileage: number;
+  date: string;
+  entry_id: number;
+}
+
+export interface MaintenanceReport {
+  mileage: MileageReading | null;
+  items: MaintenanceDue[];
+}
+
+export type ContentType = 'about' | 'resume' | 'carbuild';
+
+export interface ContentRevision {
+  id: number;
+  content_type: ContentType;
+  content_id: number;
+  version: number;
+  action: 'create' | 'update' | 'delete' | 'restore';
+  author_id?: number;
+  author?: string;
+  snapshot?: Record<string, unknown>;
+  created_at: string;
+}
+
+export interface RevisionDiff {
+  content_type: ContentType;
+  content_id: number;
+  from: number;
+  to: number;
+  changes: { field: string; from: unknown; to: unknown }[];
+}
+
+export interface TrashItem {
+  content_type: ContentType;
+  id: number;
+  title: string;
+  version: number;
+  deleted_at: string;
+}
+
+export interface PreviewLink {
+  token: string;
+  url: string;
+  expires_at: string;
+}
+
 export interface ContactSubmissionForm {
   name: string;
   email: string;
@@ -52,15 +171,106 @@ export interface ContactSubmissionForm {
 export interface ContactSubmission extends ContactSubmissionForm {
   id: number;
   is_read: boolean;
+  is_archived: boolean;
+  is_starred: boolean;
+  is_spam: boolean;
+  spam_reason?: string;
+  reply_count: number;
+  last_replied_at?: string;
+  replies?: ContactReply[];
+  created_at: string;
+}
+
+export interface ContactReply {
+  id: number;
+  submission_id: number;
+  author_id?: number;
+  author?: string;
+  note: string;
   created_at: string;
 }
 
+export type ContactFilter = 'inbox' | 'archived' | 'spam' | 'all';
+
+export interface ContactQuery {
+  filter?: ContactFilter;
+  read?: boolean;
+  starred?: boolean;
+  since?: string;
+  until?: string;
+  q?: string;
+  page?: number;
+  per_page?: number;
+}
+
+export interface ContactSubmissionUpdate {
+  is_read?: boolean;
+  is_archived?: boolean;
+  is_starred?: boolean;
+  is_spam?: boolean;
+}
+
+export interface GalleryImage {
+  id: number;
+  url: string;
+  folder: string;
+  filename: string;
+  content_type: string;
+  caption: string;
+  alt_text: string;
+  display_order: number;
+  width?: number;
+  height?: number;
+  taken_at?: string;
+  camera_make?: string;
+  camera_model?: string;
+  lens_model?: string;
+  exposure_time?: string;
+  f_number?: number;
+  iso?: number;
+  focal_length?: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Folder {
+  id: number;
+  slug: string;
+  title: string;
+  description?: string;
+  cover_image_id?: number;
+  cover_url?: string;
+  visibility: 'public' | 'private';
+  sort_order: number;
+  image_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface GalleryImageList {
+  version: number;
+  folder: string;
+  images: GalleryImage[];
+}
+
 export interface LoginRequest {
   email: string;
   password: string;
 }
 
+// Error body returned by the API (RFC 7807 problem details)
+export interface Problem {
+  type: string;
+  title: string;
+  status: number;
+  code: string;
+  detail?: string;
+  fields?: Record<string, string>;
+}
+
 export interface LoginResponse {
   token: string;
+  refresh_token: string;
+  expires_in: number;
   user: User;
 }
@@ -82,3 +82,16 @@ export const formatImageTitle = (path: string): string => {
     .replace(/[-_]/g, ' ')
     .replace(/\b\w/g, l => l.toUpperCase());
 };
+
+/**
+ * Widths of the resized variants the backend generates for uploaded images
+ */
+export const IMAGE_VARIANT_WIDTHS = [320, 960, 1920] as const;
+
+/**
+ * Build a srcSet for an /api/image/{id} URL so the browser downloads a
+ * resized variant instead of the original
+ */
+export const imageSrcSet = (url: string): string => {
+  return IMAGE_VARIANT_WIDTHS.map(w => `${url}?w=${w} ${w}w`).join(', ');
+};